@@ -0,0 +1,137 @@
+package speculatively
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// minTrackedSamples is the number of latencies a LatencyTracker must record
+// before it is used to derive an adaptive patience.
+const minTrackedSamples = 10
+
+// LatencyTracker records the latencies of recent successful attempts, from
+// which WithAdaptivePatience derives the patience for subsequent calls.
+//
+// A LatencyTracker is safe for concurrent use.
+type LatencyTracker struct {
+	samples []time.Duration
+	next    int
+	full    bool
+	mu      sync.Mutex
+}
+
+// NewLatencyTracker creates a LatencyTracker that remembers the given number
+// of most recent latencies.
+func NewLatencyTracker(size int) *LatencyTracker {
+	if size < minTrackedSamples {
+		size = minTrackedSamples
+	}
+	return &LatencyTracker{samples: make([]time.Duration, size)}
+}
+
+// Record adds a latency to the tracker, evicting the oldest one if the
+// tracker is full.
+func (t *LatencyTracker) Record(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % len(t.samples)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// Quantile returns the latency at the given quantile (between 0.0 and 1.0) of
+// the recorded latencies, or false if too few latencies have been recorded.
+func (t *LatencyTracker) Quantile(q float64) (time.Duration, bool) {
+	samples := t.Samples()
+	if len(samples) < minTrackedSamples {
+		return 0, false
+	}
+	return newDistribution(samples).quantile(q), true
+}
+
+// Samples returns a copy of the recorded latencies, oldest first.
+func (t *LatencyTracker) Samples() []time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		return append([]time.Duration(nil), t.samples[:t.next]...)
+	}
+	samples := make([]time.Duration, 0, len(t.samples))
+	samples = append(samples, t.samples[t.next:]...)
+	return append(samples, t.samples[:t.next]...)
+}
+
+// latencyProfileVersion identifies the format produced by Export.
+const latencyProfileVersion = 1
+
+type latencyProfile struct {
+	Version int     `json:"version"`
+	Samples []int64 `json:"samples_ns"`
+}
+
+// Export serializes the recorded latencies as JSON, so that they may be
+// persisted across restarts and restored via Import.
+func (t *LatencyTracker) Export() ([]byte, error) {
+	samples := t.Samples()
+	profile := latencyProfile{
+		Version: latencyProfileVersion,
+		Samples: make([]int64, len(samples)),
+	}
+	for i, d := range samples {
+		profile.Samples[i] = int64(d)
+	}
+	return json.Marshal(profile)
+}
+
+// Import replaces the recorded latencies with those serialized by Export.  If
+// the profile holds more latencies than the tracker can remember, only the
+// most recent ones are kept.
+func (t *LatencyTracker) Import(data []byte) error {
+	var profile latencyProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return fmt.Errorf("speculatively: invalid latency profile: %w", err)
+	}
+	if profile.Version != latencyProfileVersion {
+		return fmt.Errorf("speculatively: unsupported latency profile version %d", profile.Version)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	samples := profile.Samples
+	if len(samples) > len(t.samples) {
+		samples = samples[len(samples)-len(t.samples):]
+	}
+	for i, ns := range samples {
+		t.samples[i] = time.Duration(ns)
+	}
+	t.next = len(samples) % len(t.samples)
+	t.full = len(samples) == len(t.samples)
+	return nil
+}
+
+// WithAdaptivePatience records the latency of every successful attempt in the
+// given LatencyTracker and, once it has recorded enough latencies, uses the
+// latency at the given quantile (e.g. 0.95) as the patience of each call
+// instead of the patience given to Do.
+func WithAdaptivePatience(t *LatencyTracker, quantile float64) Option {
+	return func(c *config) {
+		c.tracker = t
+		c.quantile = quantile
+	}
+}
+
+// patience returns the patience to use for a call given the default
+// patience.
+func (c *config) patience(patience time.Duration) time.Duration {
+	if c.tracker == nil {
+		return patience
+	}
+	if adaptive, ok := c.tracker.Quantile(c.quantile); ok && adaptive > 0 {
+		return adaptive
+	}
+	return patience
+}
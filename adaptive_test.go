@@ -0,0 +1,103 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewLatencyTracker(minTrackedSamples)
+	if _, ok := tracker.Quantile(0.5); ok {
+		t.Fatalf("expected no quantile from empty tracker")
+	}
+
+	// Record more samples than the tracker can hold, so that the oldest
+	// (and largest) ones are evicted
+	for i := 2 * minTrackedSamples; i > 0; i-- {
+		tracker.Record(time.Duration(i) * time.Millisecond)
+	}
+	if p, ok := tracker.Quantile(1); !ok || p != minTrackedSamples*time.Millisecond {
+		t.Errorf("expected max = %s, got %s (ok = %v)", minTrackedSamples*time.Millisecond, p, ok)
+	}
+	if p, ok := tracker.Quantile(0); !ok || p != time.Millisecond {
+		t.Errorf("expected min = %s, got %s (ok = %v)", time.Millisecond, p, ok)
+	}
+}
+
+func TestLatencyTrackerExportImport(t *testing.T) {
+	t.Parallel()
+
+	src := NewLatencyTracker(20)
+	for i := 1; i <= 15; i++ {
+		src.Record(time.Duration(i) * time.Millisecond)
+	}
+	data, err := src.Export()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("roundtrip", func(t *testing.T) {
+		t.Parallel()
+
+		dst := NewLatencyTracker(20)
+		if err := dst.Import(data); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, q := range []float64{0, 0.5, 0.95, 1} {
+			want, _ := src.Quantile(q)
+			got, ok := dst.Quantile(q)
+			if !ok || got != want {
+				t.Errorf("expected quantile %v = %s, got %s (ok = %v)", q, want, got, ok)
+			}
+		}
+	})
+
+	t.Run("smaller tracker keeps most recent", func(t *testing.T) {
+		t.Parallel()
+
+		dst := NewLatencyTracker(minTrackedSamples)
+		if err := dst.Import(data); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if p, _ := dst.Quantile(0); p != 6*time.Millisecond {
+			t.Errorf("expected min = %s, got %s", 6*time.Millisecond, p)
+		}
+	})
+
+	t.Run("invalid profiles", func(t *testing.T) {
+		t.Parallel()
+
+		dst := NewLatencyTracker(20)
+		for _, data := range []string{`not json`, `{"version":99,"samples_ns":[1]}`} {
+			if err := dst.Import([]byte(data)); err == nil {
+				t.Errorf("expected error importing %q", data)
+			}
+		}
+	})
+}
+
+func TestWithAdaptivePatience(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewLatencyTracker(minTrackedSamples)
+	for i := 0; i < minTrackedSamples; i++ {
+		tracker.Record(5 * time.Millisecond)
+	}
+
+	// The learned patience of 5ms is far shorter than the given patience, so
+	// a slow thunk must be hedged
+	thunk := newSimpleTestThunk(1, nil, 50*time.Millisecond)
+	opts := []Option{WithAdaptivePatience(tracker, 0.95), WithMaxAttempts(2)}
+	if _, err := Do(context.Background(), time.Minute, thunk.call, opts...); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if callCount := thunk.callCount(); callCount != 2 {
+		t.Errorf("expected Thunk to run %d times, got %d", 2, callCount)
+	}
+	if p, _ := tracker.Quantile(1); p < 50*time.Millisecond {
+		t.Errorf("expected successful attempt latency to be recorded, max = %s", p)
+	}
+}
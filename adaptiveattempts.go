@@ -0,0 +1,122 @@
+package speculatively
+
+import "sync"
+
+const (
+	// adaptiveAttemptsWindow is the number of recent calls whose winning
+	// attempts an AdaptiveAttempts remembers.
+	adaptiveAttemptsWindow = 1000
+	// minAdaptiveAttemptsSamples is the number of calls an AdaptiveAttempts
+	// must observe before it starts adjusting its limit.
+	minAdaptiveAttemptsSamples = 100
+	// adaptiveAttemptsTolerance is the fraction of calls that may need more
+	// attempts than the limit allows.
+	adaptiveAttemptsTolerance = 0.01
+)
+
+// AdaptiveAttempts adjusts the maximum number of attempts per call, within
+// hard bounds, based on which attempts recently won their calls: the limit
+// shrinks when the first hedge (or the first attempt) nearly always wins and
+// grows when later hedges win often because deep tails are common.
+//
+// The limit is always one more than the number of attempts needed by 99% of
+// recent calls, so that winners beyond the typical depth can be observed.
+//
+// An AdaptiveAttempts is safe for concurrent use and is typically shared
+// across many calls.
+type AdaptiveAttempts struct {
+	lo, hi  int
+	winners []int
+	next    int
+	full    bool
+	mu      sync.Mutex
+}
+
+// NewAdaptiveAttempts creates an AdaptiveAttempts whose limit stays between
+// lo and hi attempts per call, starting at hi.
+func NewAdaptiveAttempts(lo, hi int) *AdaptiveAttempts {
+	if lo < 1 {
+		lo = 1
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return &AdaptiveAttempts{
+		lo:      lo,
+		hi:      hi,
+		winners: make([]int, adaptiveAttemptsWindow),
+	}
+}
+
+// WithAdaptiveMaxAttempts limits the attempts of every call to the current
+// limit of the given AdaptiveAttempts, and records which attempt won each
+// call in it.  If WithMaxAttempts is also given, the lower limit applies.
+func WithAdaptiveMaxAttempts(a *AdaptiveAttempts) Option {
+	return func(c *config) {
+		c.adaptiveAttempts = a
+	}
+}
+
+// Limit returns the current maximum number of attempts per call.
+func (a *AdaptiveAttempts) Limit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	n := a.next
+	if a.full {
+		n = len(a.winners)
+	}
+	if n < minAdaptiveAttemptsSamples {
+		return a.hi
+	}
+
+	counts := make([]int, a.hi)
+	for _, w := range a.winners[:n] {
+		counts[w]++
+	}
+	needed, covered := 0, 0
+	for needed < a.hi && float64(covered) < (1-adaptiveAttemptsTolerance)*float64(n) {
+		covered += counts[needed]
+		needed++
+	}
+
+	limit := needed + 1
+	if limit < a.lo {
+		limit = a.lo
+	}
+	if limit > a.hi {
+		limit = a.hi
+	}
+	return limit
+}
+
+// clone returns a new AdaptiveAttempts with the same bounds as a.
+func (a *AdaptiveAttempts) clone() *AdaptiveAttempts {
+	return NewAdaptiveAttempts(a.lo, a.hi)
+}
+
+// record notes the index of the attempt that won a call.
+func (a *AdaptiveAttempts) record(winner int) {
+	if winner >= a.hi {
+		winner = a.hi - 1
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.winners[a.next] = winner
+	a.next = (a.next + 1) % len(a.winners)
+	if a.next == 0 {
+		a.full = true
+	}
+}
+
+// attemptLimit returns the maximum number of attempts for a call starting
+// now, or 0 for no limit.
+func (c *config) attemptLimit() int {
+	limit := c.maxAttempts
+	if c.adaptiveAttempts != nil {
+		if adaptive := c.adaptiveAttempts.Limit(); limit <= 0 || adaptive < limit {
+			limit = adaptive
+		}
+	}
+	return limit
+}
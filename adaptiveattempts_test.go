@@ -0,0 +1,58 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveAttempts(t *testing.T) {
+	t.Parallel()
+
+	a := NewAdaptiveAttempts(2, 4)
+	if limit := a.Limit(); limit != 4 {
+		t.Fatalf("expected initial limit = %d, got %d", 4, limit)
+	}
+
+	// The first attempt nearly always wins, so a single hedge suffices
+	for i := 0; i < minAdaptiveAttemptsSamples; i++ {
+		a.record(0)
+	}
+	if limit := a.Limit(); limit != 2 {
+		t.Fatalf("expected limit to shrink to %d, got %d", 2, limit)
+	}
+
+	// Deep tails become common, so more hedges are allowed
+	for i := 0; i < minAdaptiveAttemptsSamples; i++ {
+		a.record(2)
+	}
+	if limit := a.Limit(); limit != 4 {
+		t.Fatalf("expected limit to grow to %d, got %d", 4, limit)
+	}
+
+	// Winners beyond the hard bound count against the hard bound
+	b := NewAdaptiveAttempts(1, 3)
+	for i := 0; i < minAdaptiveAttemptsSamples; i++ {
+		b.record(10)
+	}
+	if limit := b.Limit(); limit != 3 {
+		t.Fatalf("expected limit capped at %d, got %d", 3, limit)
+	}
+}
+
+func TestWithAdaptiveMaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	a := NewAdaptiveAttempts(1, 3)
+	for i := 0; i < minAdaptiveAttemptsSamples; i++ {
+		a.record(0)
+	}
+
+	thunk := newSimpleTestThunk(1, nil, 30*time.Millisecond)
+	if _, err := Do(context.Background(), 5*time.Millisecond, thunk.call, WithAdaptiveMaxAttempts(a)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if callCount := thunk.callCount(); callCount != 2 {
+		t.Errorf("expected Thunk to run %d times, got %d", 2, callCount)
+	}
+}
@@ -0,0 +1,128 @@
+package speculatively
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrTooFewSamples is returned by Advisor.Advise when given too few latencies
+// to make a recommendation.
+var ErrTooFewSamples = errors.New("speculatively: too few latency samples")
+
+// Advisor recommends the patience that minimizes tail latency within a budget
+// of extra load, based on recorded attempt latencies.
+//
+// Recommendations assume that attempts have independent latencies drawn from
+// the recorded distribution and that at most one hedge is launched per call.
+type Advisor struct {
+	// Quantile of call latency to minimize, e.g. 0.99.
+	Quantile float64
+	// MaxExtraLoad is the largest acceptable fraction of calls that hedge,
+	// e.g. 0.05 for at most 5% extra attempts.
+	MaxExtraLoad float64
+}
+
+// Advice is a patience recommendation made by an Advisor.
+type Advice struct {
+	// Patience is the recommended patience.
+	Patience time.Duration
+	// ExtraLoad is the predicted fraction of calls that hedge.
+	ExtraLoad float64
+	// Latency is the predicted call latency at the Advisor's quantile.
+	Latency time.Duration
+	// BaselineLatency is the call latency at the Advisor's quantile without
+	// any hedging.
+	BaselineLatency time.Duration
+}
+
+// Advise recommends a patience given a set of recorded attempt latencies,
+// e.g. from LatencyTracker.Samples.  If no patience keeps the extra load
+// within budget, the recommendation is to never hedge in practice, i.e. a
+// patience equal to the largest recorded latency.
+func (a Advisor) Advise(samples []time.Duration) (Advice, error) {
+	if len(samples) < minTrackedSamples {
+		return Advice{}, ErrTooFewSamples
+	}
+	d := newDistribution(samples)
+	baseline := d.quantile(a.Quantile)
+	best := Advice{
+		Patience:        d.max(),
+		Latency:         baseline,
+		BaselineLatency: baseline,
+	}
+	for _, patience := range d.sorted {
+		extra := d.survival(patience)
+		if extra > a.MaxExtraLoad {
+			continue
+		}
+		if latency := d.hedgedQuantile(patience, a.Quantile); latency < best.Latency {
+			best.Patience = patience
+			best.ExtraLoad = extra
+			best.Latency = latency
+		}
+	}
+	return best, nil
+}
+
+// distribution is the empirical distribution of a set of latencies.
+type distribution struct {
+	sorted []time.Duration
+}
+
+func newDistribution(samples []time.Duration) distribution {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return distribution{sorted}
+}
+
+func (d distribution) max() time.Duration {
+	return d.sorted[len(d.sorted)-1]
+}
+
+// survival returns the fraction of latencies greater than x.
+func (d distribution) survival(x time.Duration) float64 {
+	idx := sort.Search(len(d.sorted), func(i int) bool { return d.sorted[i] > x })
+	return float64(len(d.sorted)-idx) / float64(len(d.sorted))
+}
+
+func (d distribution) quantile(q float64) time.Duration {
+	idx := int(q * float64(len(d.sorted)-1))
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(d.sorted) {
+		idx = len(d.sorted) - 1
+	}
+	return d.sorted[idx]
+}
+
+// hedgedSurvival returns the probability that a call hedged after the given
+// patience takes longer than x, i.e. that both the first attempt and the
+// hedge launched at patience are still running at x.
+func (d distribution) hedgedSurvival(patience, x time.Duration) float64 {
+	s := d.survival(x)
+	if x < patience {
+		return s
+	}
+	return s * d.survival(x-patience)
+}
+
+// hedgedQuantile returns the call latency at quantile q when hedging after
+// the given patience.
+func (d distribution) hedgedQuantile(patience time.Duration, q float64) time.Duration {
+	// The latency of a hedged call is always one of the recorded latencies,
+	// or the patience plus one of them.
+	candidates := make([]time.Duration, 0, 2*len(d.sorted))
+	candidates = append(candidates, d.sorted...)
+	for _, l := range d.sorted {
+		candidates = append(candidates, patience+l)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+	idx := sort.Search(len(candidates), func(i int) bool {
+		return d.hedgedSurvival(patience, candidates[i]) <= 1-q
+	})
+	if idx == len(candidates) {
+		idx--
+	}
+	return candidates[idx]
+}
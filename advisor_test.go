@@ -0,0 +1,60 @@
+package speculatively
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdvisor(t *testing.T) {
+	t.Parallel()
+
+	// 5% of attempts hit a deep tail
+	var samples []time.Duration
+	for i := 0; i < 95; i++ {
+		samples = append(samples, 10*time.Millisecond)
+	}
+	for i := 0; i < 5; i++ {
+		samples = append(samples, 500*time.Millisecond)
+	}
+
+	t.Run("hedging within budget", func(t *testing.T) {
+		t.Parallel()
+
+		advice, err := Advisor{Quantile: 0.99, MaxExtraLoad: 0.06}.Advise(samples)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		want := Advice{
+			Patience:        10 * time.Millisecond,
+			ExtraLoad:       0.05,
+			Latency:         20 * time.Millisecond,
+			BaselineLatency: 500 * time.Millisecond,
+		}
+		if advice != want {
+			t.Errorf("expected advice %+v, got %+v", want, advice)
+		}
+	})
+
+	t.Run("budget too small to help", func(t *testing.T) {
+		t.Parallel()
+
+		advice, err := Advisor{Quantile: 0.99, MaxExtraLoad: 0.01}.Advise(samples)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if advice.Patience != 500*time.Millisecond || advice.ExtraLoad != 0 {
+			t.Errorf("expected advice not to hedge, got %+v", advice)
+		}
+		if advice.Latency != advice.BaselineLatency {
+			t.Errorf("expected no latency improvement, got %+v", advice)
+		}
+	})
+
+	t.Run("too few samples", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := (Advisor{Quantile: 0.99}).Advise(samples[:1]); err != ErrTooFewSamples {
+			t.Errorf("expected err = %s, got %v", ErrTooFewSamples, err)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoMaxAttempts is returned by DoAll when called without WithMaxAttempts.
+var ErrNoMaxAttempts = errors.New("speculatively: no max attempts")
+
+// AttemptResult is the result of a single attempt of a call to DoAll.
+type AttemptResult[T any] struct {
+	Attempt Attempt
+	Val     T
+	Err     error
+	Elapsed time.Duration
+
+	// Duplicates are the other attempts that returned the same result, if
+	// WithDedup or WithDedupFunc is set.
+	Duplicates []Attempt
+}
+
+// WithFirstResults makes DoAll return as soon as n attempts have succeeded,
+// e.g. to gather 2 price quotes from whichever providers respond fastest, and
+// cancel the attempts still running, whose error is set to
+// context.Canceled.  Values less than 1 mean every attempt must finish,
+// which is the default.
+func WithFirstResults(n int) Option {
+	return func(c *config) {
+		c.firstResults = n
+	}
+}
+
+// DoAll executes a Thunk the number of times given via WithMaxAttempts,
+// launching each attempt after waiting for the given patience duration as Do
+// does, and returns the result of every attempt once all of them have
+// finished, e.g. so that callers can compare, merge or vote on them rather
+// than take the first.
+//
+// Hedges are subject to the same Options as with Do, e.g. they withdraw from
+// the Budget given via WithBudget, if any, and are suppressed by an ErrorGate
+// or InflightLimit, so that fewer results may be returned than requested.
+// As with Do, attempts may launch the next attempt early via HedgeNow, or
+// postpone it via Progress, and every attempt is reported to Hooks and
+// metrics.  Since no single attempt wins, the OnWinner and OnTermination
+// hooks are not called, and calls are not recorded as exemplars.
+//
+// Given WithFirstResults, DoAll instead returns as soon as enough attempts
+// have succeeded, canceling the others, whose results are discarded as set
+// by WithCleanup should they succeed anyway.  Given WithDedup or
+// WithDedupFunc, identical results are merged.
+//
+// If ctx is done before every attempt has finished, the results of the
+// attempts launched so far are returned along with its error, with the error
+// of every attempt yet to finish set to ctx's error.
+func DoAll[T any](ctx context.Context, patience time.Duration, thunk Thunk[T], opts ...Option) ([]AttemptResult[T], error) {
+	cfg := newConfig(opts)
+	if cfg.maxAttempts < 1 {
+		return nil, ErrNoMaxAttempts
+	}
+	c, end := startCall(ctx, cfg, func(int) (task[T], bool) {
+		return task[T]{thunk: thunk}, true
+	})
+	defer end()
+	ctx, cfg = c.ctx, c.cfg
+
+	received := map[int]result[T]{}
+	// results returns the result of every attempt, with the error of every
+	// attempt yet to finish set to err, and notifies those attempts that
+	// they lost
+	results := func(err error) []AttemptResult[T] {
+		all := make([]AttemptResult[T], len(c.attempts))
+		for i, a := range c.attempts {
+			all[i].Attempt = a
+			r, ok := received[i]
+			if !ok {
+				all[i].Err = err
+				cfg.hooks.loser(a)
+				continue
+			}
+			all[i].Val, all[i].Err, all[i].Elapsed = r.val, r.err, r.elapsed
+		}
+		return dedup(cfg, all)
+	}
+
+	if t, ok := c.peek(); ok {
+		c.launch(t)
+	}
+	every := cfg.patience(patience)
+	ticker := cfg.newTicker(every)
+	defer ticker.Stop()
+
+	hedging := true
+	succeeded := 0
+	for {
+		if len(c.running) == 0 {
+			// Launch the next attempt right away rather than wait with
+			// nothing running, unless it is suppressed
+			launched := len(c.attempts)
+			if hedging {
+				hedging = c.hedge()
+			}
+			if len(c.attempts) == launched {
+				return results(nil), nil
+			}
+			ticker.Reset(every)
+			continue
+		}
+		var tick <-chan time.Time
+		if hedging {
+			tick = ticker.C()
+		}
+		select {
+		case r := <-c.out:
+			delete(c.running, r.attempt)
+			c.delivered[r.attempt] = r.elapsed
+			c.errs[r.attempt] = r.err
+			received[r.attempt] = r
+			if r.err == nil {
+				succeeded++
+			}
+			if cfg.firstResults > 0 && succeeded >= cfg.firstResults {
+				return results(context.Canceled), nil
+			}
+		case <-tick:
+			hedging = c.hedge()
+		case <-c.info.hedgeNow:
+			if hedging {
+				hedging = c.hedge()
+				ticker.Reset(every)
+			}
+		case <-c.info.progress:
+			ticker.Reset(every)
+		case <-c.grants():
+			hedging = c.grant()
+			ticker.Reset(every)
+		case <-ctx.Done():
+			return results(ctx.Err()), ctx.Err()
+		}
+	}
+}
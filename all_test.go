@@ -0,0 +1,189 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoAll(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	thunk := newTestThunk([]result[int]{
+		{val: 1},
+		{err: failed},
+		{val: 3},
+	}, []time.Duration{
+		50 * time.Millisecond,
+		0,
+		0,
+	})
+	start := time.Now()
+	results, err := DoAll(context.Background(), 10*time.Millisecond, thunk.call, WithMaxAttempts(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected every attempt to finish, returned after %s", elapsed)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected %d results, got %d", 3, len(results))
+	}
+	for i, r := range results {
+		if r.Attempt.Index != i {
+			t.Errorf("expected results[%d].Attempt.Index = %d, got %d", i, i, r.Attempt.Index)
+		}
+	}
+	if results[0].Val != 1 || results[1].Err != failed || results[2].Val != 3 {
+		t.Errorf("expected results 1, %v, 3, got %+v", failed, results)
+	}
+	if results[0].Elapsed < 50*time.Millisecond {
+		t.Errorf("expected results[0].Elapsed >= %s, got %s", 50*time.Millisecond, results[0].Elapsed)
+	}
+}
+
+func TestDoAllLaunchesRemainingAttempts(t *testing.T) {
+	t.Parallel()
+
+	// Attempts finishing before patience elapses don't hold up the next
+	start := time.Now()
+	results, err := DoAll(context.Background(), time.Second, newSimpleTestThunk(1, nil, 0).call, WithMaxAttempts(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("expected %d results, got %d", 3, len(results))
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected attempts to be launched right away, took %s", elapsed)
+	}
+}
+
+func TestDoAllBudget(t *testing.T) {
+	t.Parallel()
+
+	budget := NewBudget(0, 1)
+	results, err := DoAll(context.Background(), 10*time.Millisecond, newSimpleTestThunk(1, nil, 0).call, WithMaxAttempts(3), WithBudget(budget))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected %d results, got %d", 2, len(results))
+	}
+}
+
+func TestDoAllErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := DoAll(context.Background(), time.Second, newSimpleTestThunk(1, nil, 0).call); err != ErrNoMaxAttempts {
+		t.Errorf("expected err = %v, got %v", ErrNoMaxAttempts, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	thunk := newTestThunk([]result[int]{{val: 1}, {val: 2}}, []time.Duration{0, time.Second})
+	results, err := DoAll(ctx, 10*time.Millisecond, thunk.call, WithMaxAttempts(2))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected err = %v, got %v", context.DeadlineExceeded, err)
+	}
+	if len(results) != 2 || results[0].Val != 1 || results[0].Err != nil || results[1].Err != context.DeadlineExceeded {
+		t.Errorf("expected results 1, %v, got %+v", context.DeadlineExceeded, results)
+	}
+}
+
+func TestDoAllFirstResults(t *testing.T) {
+	t.Parallel()
+
+	thunk := newTestThunk([]result[int]{{val: 1}, {val: 2}, {val: 3}, {val: 4}}, []time.Duration{
+		time.Second,
+		5 * time.Millisecond,
+		5 * time.Millisecond,
+		time.Second,
+	})
+	start := time.Now()
+	results, err := DoAll(context.Background(), 10*time.Millisecond, thunk.call, WithMaxAttempts(4), WithFirstResults(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected call to return once 2 attempts succeeded, took %s", elapsed)
+	}
+	var vals []int
+	for _, r := range results {
+		if r.Err == nil {
+			vals = append(vals, r.Val)
+		}
+	}
+	if len(vals) != 2 || vals[0] != 2 || vals[1] != 3 {
+		t.Errorf("expected successful results [2 3], got %v", vals)
+	}
+	if results[0].Err != context.Canceled {
+		t.Errorf("expected slow attempt to be canceled, got %v", results[0].Err)
+	}
+}
+
+func TestDoAllFirstResultsCleanup(t *testing.T) {
+	t.Parallel()
+
+	// The first attempt ignores cancelation and succeeds after the call
+	// has returned
+	cleaned := make(chan int, 1)
+	var losers int64
+	_, err := DoAll(context.Background(), 5*time.Millisecond, func(ctx context.Context) (int, error) {
+		if !IsHedge(ctx) {
+			time.Sleep(30 * time.Millisecond)
+			return 1, nil
+		}
+		return 2, nil
+	},
+		WithMaxAttempts(2),
+		WithFirstResults(1),
+		WithCleanup(func(v int) { cleaned <- v }),
+		WithHooks(Hooks{OnLoser: func(Attempt) { atomic.AddInt64(&losers, 1) }}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	select {
+	case v := <-cleaned:
+		if v != 1 {
+			t.Errorf("expected late result 1 to be cleaned up, got %d", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected late result to be cleaned up")
+	}
+	if n := atomic.LoadInt64(&losers); n != 1 {
+		t.Errorf("expected 1 loser, got %d", n)
+	}
+}
+
+func TestDoAllSharedOptions(t *testing.T) {
+	t.Parallel()
+
+	var launched int64
+	gate := NewErrorGate(0, time.Minute)
+	for i := 0; i < minErrorGateSamples; i++ {
+		gate.record(true)
+	}
+	h := NewHedger(5*time.Millisecond,
+		WithMaxAttempts(3),
+		WithErrorGate(gate),
+		WithHooks(Hooks{OnLaunch: func(Attempt) { atomic.AddInt64(&launched, 1) }}),
+	)
+	results, err := DoAll(context.Background(), h.patience, newSimpleTestThunk(1, nil, 20*time.Millisecond).call, h.opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected closed error gate to suppress hedges, got %d results", len(results))
+	}
+	if n := atomic.LoadInt64(&launched); n != 1 {
+		t.Errorf("expected launch hook to be called once, got %d", n)
+	}
+	if s := h.Stats(); s.Calls != 1 {
+		t.Errorf("expected call to be counted in stats, got %d calls", s.Calls)
+	}
+}
@@ -0,0 +1,195 @@
+package speculatively
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type attemptKey struct{}
+
+// attemptInfo is stored in the context of every attempt.
+type attemptInfo struct {
+	attempt Attempt
+	call    *callInfo
+}
+
+// callInfo is the state of a call that its attempts may inspect.
+type callInfo struct {
+	cfg         *config
+	maxAttempts int
+	checkpoints CheckpointStore
+	launched    int64
+	live        *liveCall
+
+	// hedgeNow receives requests from attempts to launch the next attempt
+	// without waiting for patience to elapse
+	hedgeNow chan struct{}
+
+	// progress receives reports from attempts that they are making
+	// progress, postponing the next attempt
+	progress chan struct{}
+
+	// held is the number of holds on the call by attempts waiting on its
+	// consumer, and resumed the time the last of them was released, during
+	// which the call is not stalled
+	mu      sync.Mutex
+	held    int
+	resumed time.Time
+
+	// idempotencyKey is shared by every attempt, if WithIdempotencyKeys is
+	// set
+	idempotencyKey string
+
+	// won holds the winning attempt and its result as a wonResult, if
+	// WithConsistencyCheck is set
+	won atomic.Value
+
+	// diverged holds the late results already reported as diverging, if
+	// WithDedup or WithDedupFunc is set
+	diverged divergences
+
+	// ended is the time the call ended, in Unix nanoseconds, or 0
+	ended int64
+}
+
+func withAttempt(ctx context.Context, info *attemptInfo) context.Context {
+	return context.WithValue(ctx, attemptKey{}, info)
+}
+
+func attemptFromContext(ctx context.Context) (*attemptInfo, bool) {
+	info, ok := ctx.Value(attemptKey{}).(*attemptInfo)
+	return info, ok
+}
+
+// AttemptFromContext returns the Attempt being executed with the given
+// context, or false if the context does not belong to an attempt.
+func AttemptFromContext(ctx context.Context) (Attempt, bool) {
+	info, ok := attemptFromContext(ctx)
+	if !ok {
+		return Attempt{}, false
+	}
+	return info.attempt, true
+}
+
+// IsHedge reports whether the given context belongs to a hedged attempt, i.e.
+// any attempt after the first, so that thunks can make their own decisions,
+// e.g. to skip expensive logging or caching work when running as a hedge.
+func IsHedge(ctx context.Context) bool {
+	a, ok := AttemptFromContext(ctx)
+	return ok && a.Index > 0
+}
+
+// AttemptBudgetRemaining returns the number of further attempts that the call
+// to which the given context belongs may still launch, as limited by its max
+// attempts and the hedges currently available in its Budget.  It returns
+// false if the context does not belong to an attempt or the call's attempts
+// are unlimited.
+func AttemptBudgetRemaining(ctx context.Context) (int, bool) {
+	info, ok := attemptFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	cfg, maxAttempts := info.call.cfg, info.call.maxAttempts
+	if maxAttempts <= 0 && cfg.budget == nil {
+		return 0, false
+	}
+
+	remaining := math.MaxInt32
+	if maxAttempts > 0 {
+		remaining = maxAttempts - int(atomic.LoadInt64(&info.call.launched))
+	}
+	if cfg.budget != nil {
+		if hedges := int(cfg.budget.Remaining()); hedges < remaining {
+			remaining = hedges
+		}
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// WithAttemptContext decorates the context of every attempt with the given
+// func, e.g. to attach tracing baggage or request metadata such as the
+// attempt index and whether it is a hedge, so that downstream services can
+// correlate duplicate requests belonging to the same call.  It may be given
+// more than once, in which case the funcs are applied in order.
+func WithAttemptContext(fn func(ctx context.Context, a Attempt) context.Context) Option {
+	return func(c *config) {
+		c.attemptContext = append(c.attemptContext, fn)
+	}
+}
+
+// HedgeNow asks the call to which the given context belongs to launch its
+// next attempt right away instead of waiting for patience to elapse, e.g.
+// because the attempt can tell that it is stuck, after which the wait for
+// further attempts starts over.  The next attempt is subject to the same
+// limits as any other hedge.  It reports false if the context does not
+// belong to an attempt.
+func HedgeNow(ctx context.Context) bool {
+	info, ok := attemptFromContext(ctx)
+	if !ok {
+		return false
+	}
+	select {
+	case info.call.hedgeNow <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Progress reports that the attempt to which the given context belongs is
+// making progress, e.g. because it received another chunk of a stream, which
+// restarts the wait for the call's next attempt from now.  Attempts that
+// report progress at least once per patience duration are therefore only
+// hedged once they stall.  It reports false if the context does not belong
+// to an attempt.
+func Progress(ctx context.Context) bool {
+	info, ok := attemptFromContext(ctx)
+	if !ok {
+		return false
+	}
+	select {
+	case info.call.progress <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// hold reports that the attempt to which the given context belongs is
+// waiting on the consumer of the call's results, e.g. for a streamed item to
+// be accepted, rather than stalled, so that no attempt is launched until the
+// returned func is called to release the hold, after which the wait for the
+// next attempt starts over.
+func hold(ctx context.Context) (release func()) {
+	info, ok := attemptFromContext(ctx)
+	if !ok {
+		return func() {}
+	}
+	call := info.call
+	call.mu.Lock()
+	call.held++
+	call.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			call.mu.Lock()
+			call.held--
+			call.resumed = call.cfg.now()
+			call.mu.Unlock()
+			Progress(ctx)
+		})
+	}
+}
+
+// holding reports whether the call was held when its ticker ticked at the
+// given time, i.e. whether it still is, or was released since.
+func (c *callInfo) holding(tick time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.held > 0 || !tick.After(c.resumed)
+}
@@ -0,0 +1,164 @@
+package speculatively
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAttemptContext(t *testing.T) {
+	t.Parallel()
+
+	type observed struct {
+		index     int
+		hedge     bool
+		remaining int
+		limited   bool
+	}
+	var (
+		seen []observed
+		mu   sync.Mutex
+	)
+	thunk := func(ctx context.Context) (int, error) {
+		a, ok := AttemptFromContext(ctx)
+		if !ok {
+			t.Errorf("expected attempt in context")
+		}
+		remaining, limited := AttemptBudgetRemaining(ctx)
+		mu.Lock()
+		seen = append(seen, observed{a.Index, IsHedge(ctx), remaining, limited})
+		mu.Unlock()
+		return a.Index, sleep(ctx, 50*time.Millisecond)
+	}
+
+	_, err := Do(context.Background(), 5*time.Millisecond, thunk, WithMaxAttempts(3), WithBudget(NewBudget(0, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []observed{
+		{index: 0, hedge: false, remaining: 1, limited: true},
+		{index: 1, hedge: true, remaining: 0, limited: true},
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("expected %d attempts, got %+v", len(want), seen)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Errorf("attempt %d: expected %+v, got %+v", i, want[i], seen[i])
+		}
+	}
+}
+
+func TestAttemptContextOutsideAttempt(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	if _, ok := AttemptFromContext(ctx); ok {
+		t.Errorf("expected no attempt in plain context")
+	}
+	if IsHedge(ctx) {
+		t.Errorf("expected plain context not to be a hedge")
+	}
+	if _, ok := AttemptBudgetRemaining(ctx); ok {
+		t.Errorf("expected no attempt budget in plain context")
+	}
+	if HedgeNow(ctx) {
+		t.Errorf("expected HedgeNow to fail in plain context")
+	}
+}
+
+func TestWithAttemptContext(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+	var (
+		mu   sync.Mutex
+		seen = map[string]bool{}
+	)
+	thunk := func(ctx context.Context) (int, error) {
+		mu.Lock()
+		seen[ctx.Value(key{}).(string)] = true
+		mu.Unlock()
+		if IsHedge(ctx) {
+			return 2, nil
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	decorate := func(ctx context.Context, a Attempt) context.Context {
+		return context.WithValue(ctx, key{}, fmt.Sprintf("attempt=%d hedge=%t", a.Index, a.Index > 0))
+	}
+	suffix := func(ctx context.Context, _ Attempt) context.Context {
+		return context.WithValue(ctx, key{}, ctx.Value(key{}).(string)+" call=abc")
+	}
+
+	if _, err := Do(context.Background(), 5*time.Millisecond, thunk, WithAttemptContext(decorate), WithAttemptContext(suffix)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, want := range []string{"attempt=0 hedge=false call=abc", "attempt=1 hedge=true call=abc"} {
+		if !seen[want] {
+			t.Errorf("expected attempt context %q, got %v", want, seen)
+		}
+	}
+}
+
+func TestHedgeNow(t *testing.T) {
+	t.Parallel()
+
+	thunk := func(ctx context.Context) (int, error) {
+		if !IsHedge(ctx) {
+			if !HedgeNow(ctx) {
+				t.Errorf("expected HedgeNow to succeed in attempt context")
+			}
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return 1, nil
+	}
+
+	start := time.Now()
+	val, err := Do(context.Background(), time.Second, thunk, WithMaxAttempts(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 1 {
+		t.Errorf("expected hedge to win, got %d", val)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected hedge to launch without waiting for patience, took %s", elapsed)
+	}
+}
+
+func TestHedgeNowSuppressed(t *testing.T) {
+	t.Parallel()
+
+	var suppressed []Suppression
+	var mu sync.Mutex
+	hooks := Hooks{OnHedgeSuppressed: func(reason Suppression) {
+		mu.Lock()
+		suppressed = append(suppressed, reason)
+		mu.Unlock()
+	}}
+	thunk := func(ctx context.Context) (int, error) {
+		HedgeNow(ctx)
+		return 0, sleep(ctx, 50*time.Millisecond)
+	}
+
+	_, err := Do(context.Background(), time.Second, thunk, WithBudget(NewBudget(0, 0)), WithHooks(hooks))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(suppressed) != 1 || suppressed[0] != SuppressedByBudget {
+		t.Errorf("expected hedge to be suppressed by budget, got %v", suppressed)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Package awsspeculatively hedges requests made with the AWS SDK for Go v2.
+*/
+package awsspeculatively
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/mccutchen/speculatively"
+)
+
+// IdempotentOperations are the operations hedged by default, which read
+// data without side effects.
+var IdempotentOperations = map[string]bool{
+	// S3
+	"GetObject":        true,
+	"GetObjectTagging": true,
+	"HeadBucket":       true,
+	"HeadObject":       true,
+	"ListObjects":      true,
+	"ListObjectsV2":    true,
+
+	// DynamoDB
+	"BatchGetItem":  true,
+	"DescribeTable": true,
+	"GetItem":       true,
+	"Query":         true,
+	"Scan":          true,
+}
+
+// Hedging hedges the requests of idempotent operations made by AWS SDK
+// clients.  Add it to a client's middleware stack via its APIOptions, either
+// for every operation or for a single call:
+//
+//	h := &awsspeculatively.Hedging{Patience: 50 * time.Millisecond}
+//	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+//		o.APIOptions = append(o.APIOptions, h.AddMiddleware)
+//	})
+//
+// Each request is sent immediately, and sent again in parallel every time
+// Patience elapses without a response, up to the limits set by Options.  The
+// first response (or error) is used and all other requests are canceled.
+//
+// Hedging happens within each attempt made by the SDK's retryer, so that a
+// request that fails is retried as usual while a request that is merely
+// slow is hedged, without multiplying retries by hedges.
+type Hedging struct {
+	// Patience is how long to wait for a response before sending the
+	// request again.
+	Patience time.Duration
+
+	// Options customize the hedging of every request, e.g. to cap the
+	// number of attempts or share a Budget.
+	Options []speculatively.Option
+
+	// Operations are the names of the operations to hedge, e.g.
+	// "GetObject".  If nil, IdempotentOperations are hedged.
+	Operations map[string]bool
+}
+
+// AddMiddleware adds the hedging middleware to the given stack.
+func (h *Hedging) AddMiddleware(stack *middleware.Stack) error {
+	if err := stack.Finalize.Insert(&hedgeMiddleware{h}, "Retry", middleware.After); err != nil {
+		if err := stack.Finalize.Add(&hedgeMiddleware{h}, middleware.Before); err != nil {
+			return err
+		}
+	}
+	return stack.Deserialize.Add(releaseMiddleware{}, middleware.After)
+}
+
+func (h *Hedging) hedged(ctx context.Context) bool {
+	operations := h.Operations
+	if operations == nil {
+		operations = IdempotentOperations
+	}
+	return operations[awsmiddleware.GetOperationName(ctx)]
+}
+
+// hedgeMiddleware sends each request that reaches it one or more times in
+// parallel.
+type hedgeMiddleware struct {
+	h *Hedging
+}
+
+func (*hedgeMiddleware) ID() string { return "SpeculativeHedging" }
+
+// finalized is the result of an attempt.
+type finalized struct {
+	out      middleware.FinalizeOutput
+	metadata middleware.Metadata
+	cancel   context.CancelFunc
+}
+
+func (m *hedgeMiddleware) HandleFinalize(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	req, ok := in.Request.(*smithyhttp.Request)
+	if !ok || !m.h.hedged(ctx) {
+		return next.HandleFinalize(ctx, in)
+	}
+
+	// Every attempt needs its own copy of the request body
+	var body []byte
+	if stream := req.GetStream(); stream != nil {
+		var err error
+		if body, err = io.ReadAll(stream); err != nil {
+			return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+		}
+	}
+
+	opts := append([]speculatively.Option{
+		speculatively.WithCleanup(func(f finalized) { f.cancel() }),
+	}, m.h.Options...)
+	f, err := speculatively.Do(ctx, m.h.Patience, func(attemptCtx context.Context) (finalized, error) {
+		attemptIn := in
+		if body == nil {
+			attemptIn.Request = req.Clone()
+		} else {
+			clone, err := req.SetStream(bytes.NewReader(body))
+			if err != nil {
+				return finalized{}, err
+			}
+			attemptIn.Request = clone
+		}
+		return finalize(ctx, attemptCtx, attemptIn, next)
+	}, opts...)
+	return f.out, f.metadata, err
+}
+
+type releaseKey struct{}
+
+// finalize sends a single attempt of a request.  The attempt is canceled
+// along with attemptCtx until a response is received, after which only
+// closing the response body or ctx being done cancels it, so that the body of
+// the winning response, e.g. of a GetObject call, can still be read once
+// every other attempt has been canceled.
+func finalize(ctx, attemptCtx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (finalized, error) {
+	ctx, keep, cancel := speculatively.Detach(ctx, attemptCtx)
+
+	out, metadata, err := next.HandleFinalize(context.WithValue(ctx, releaseKey{}, cancel), in)
+	keep()
+	if err != nil {
+		cancel()
+		return finalized{}, err
+	}
+	return finalized{out: out, metadata: metadata, cancel: cancel}, nil
+}
+
+// releaseMiddleware makes closing the body of each raw response cancel the
+// hedged attempt that received it.
+type releaseMiddleware struct{}
+
+func (releaseMiddleware) ID() string { return "SpeculativeRelease" }
+
+func (releaseMiddleware) HandleDeserialize(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+	out, metadata, err := next.HandleDeserialize(ctx, in)
+	cancel, ok := ctx.Value(releaseKey{}).(context.CancelFunc)
+	if !ok {
+		return out, metadata, err
+	}
+	if resp, ok := out.RawResponse.(*smithyhttp.Response); ok && resp.Body != nil {
+		resp.Body = speculatively.CancelOnClose(resp.Body, cancel)
+	}
+	return out, metadata, err
+}
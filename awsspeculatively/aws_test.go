@@ -0,0 +1,132 @@
+package awsspeculatively
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/mccutchen/speculatively"
+)
+
+// newTestClient returns an S3 client backed by a server that stalls the
+// first request it receives until it is canceled, along with the number of
+// requests the server received and a channel that receives a value when
+// the stalled request is canceled.
+func newTestClient(t *testing.T, h *Hedging) (*s3.Client, *int64, chan struct{}) {
+	t.Helper()
+	var requests int64
+	canceled := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body) //nolint:errcheck
+		if atomic.AddInt64(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				canceled <- struct{}{}
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		w.Write([]byte(strings.Repeat("x", 64<<10))) //nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+		APIOptions:   []func(*middleware.Stack) error{h.AddMiddleware},
+	})
+	return client, &requests, canceled
+}
+
+func TestGetObject(t *testing.T) {
+	t.Parallel()
+
+	h := &Hedging{
+		Patience: 25 * time.Millisecond,
+		Options:  []speculatively.Option{speculatively.WithMaxAttempts(2)},
+	}
+	client, requests, canceled := newTestClient(t, h)
+
+	out, err := client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer out.Body.Close()
+
+	// The winning response body must still be readable once the losing
+	// request has been canceled
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Errorf("expected losing request to be canceled")
+	}
+	body, err := io.ReadAll(out.Body)
+	if err != nil {
+		t.Fatalf("failed to read body: %s", err)
+	}
+	if len(body) != 64<<10 {
+		t.Errorf("expected %d bytes, got %d", 64<<10, len(body))
+	}
+	if got := atomic.LoadInt64(requests); got != 2 {
+		t.Errorf("expected 2 requests, got %d", got)
+	}
+}
+
+func TestNonIdempotentOperation(t *testing.T) {
+	t.Parallel()
+
+	h := &Hedging{
+		Patience: 25 * time.Millisecond,
+		Options:  []speculatively.Option{speculatively.WithMaxAttempts(2)},
+	}
+	client, requests, _ := newTestClient(t, h)
+
+	_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   strings.NewReader("payload"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := atomic.LoadInt64(requests); got != 1 {
+		t.Errorf("expected 1 request, got %d", got)
+	}
+}
+
+func TestOperationsOverride(t *testing.T) {
+	t.Parallel()
+
+	h := &Hedging{
+		Patience:   25 * time.Millisecond,
+		Options:    []speculatively.Option{speculatively.WithMaxAttempts(2)},
+		Operations: map[string]bool{"PutObject": true},
+	}
+	client, requests, _ := newTestClient(t, h)
+
+	// The request body must be sent in full by every attempt
+	_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("key"),
+		Body:   strings.NewReader("payload"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := atomic.LoadInt64(requests); got != 2 {
+		t.Errorf("expected 2 requests, got %d", got)
+	}
+}
@@ -0,0 +1,70 @@
+package speculatively
+
+import "sync"
+
+// Budget limits hedged attempts to a fraction of the calls made, so that
+// speculative execution cannot multiply the load on a struggling dependency.
+//
+// Every call deposits ratio tokens into the budget, up to a maximum of burst
+// tokens, and every hedged attempt (i.e. every attempt after the first)
+// withdraws a whole token.  When the budget is empty, hedges are suppressed,
+// or queued until tokens are deposited if WithHedgePriority is set.
+//
+// A Budget is safe for concurrent use and starts out full.
+type Budget struct {
+	ratio  float64
+	burst  float64
+	tokens float64
+	mu     sync.Mutex
+
+	// waiters holds the hedges queued by WithHedgePriority, and seq
+	// numbers them in order of arrival
+	waiters hedgeQueue
+	seq     uint64
+}
+
+// NewBudget creates a Budget allowing ratio hedges per call (e.g. 0.1 for at
+// most 10% extra attempts) with up to burst hedges banked for bursts of slow
+// calls.  A burst of less than 1 is treated as 1.
+func NewBudget(ratio float64, burst int) *Budget {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Budget{
+		ratio:  ratio,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// Remaining returns the number of hedges currently available.
+func (b *Budget) Remaining() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+// clone returns a new, full Budget with the same parameters as b.
+func (b *Budget) clone() *Budget {
+	return NewBudget(b.ratio, int(b.burst))
+}
+
+func (b *Budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.grant()
+}
+
+func (b *Budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,37 @@
+package speculatively
+
+import "testing"
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+
+	b := NewBudget(0.5, 2)
+	if remaining := b.Remaining(); remaining != 2 {
+		t.Fatalf("expected new budget to be full, got %v", remaining)
+	}
+
+	for i := 0; i < 2; i++ {
+		if !b.withdraw() {
+			t.Fatalf("expected withdrawal %d to succeed", i)
+		}
+	}
+	if b.withdraw() {
+		t.Fatalf("expected withdrawal from empty budget to fail")
+	}
+
+	b.deposit()
+	if b.withdraw() {
+		t.Fatalf("expected withdrawal of partial token to fail")
+	}
+	b.deposit()
+	if !b.withdraw() {
+		t.Fatalf("expected withdrawal after two deposits to succeed")
+	}
+
+	for i := 0; i < 10; i++ {
+		b.deposit()
+	}
+	if remaining := b.Remaining(); remaining != 2 {
+		t.Fatalf("expected budget capped at burst, got %v", remaining)
+	}
+}
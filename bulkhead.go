@@ -0,0 +1,81 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBulkheadFull is returned by a Thunk wrapped by BulkheadMiddleware that
+// could not enter its Bulkhead.
+var ErrBulkheadFull = errors.New("speculatively: bulkhead full")
+
+// Bulkhead caps the number of concurrent executions against a resource,
+// e.g. a fragile dependency, across every Thunk wrapped with it via
+// BulkheadMiddleware, and thus across every call and attempt executing them.
+//
+// A Bulkhead is safe for concurrent use.
+type Bulkhead struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewBulkhead creates a Bulkhead allowing up to limit concurrent executions.
+// Executions beyond the limit wait up to maxWait for a slot to free up
+// before failing with ErrBulkheadFull: if maxWait is zero, they fail right
+// away, and if it is negative, they wait until their context is done.
+func NewBulkhead(limit int, maxWait time.Duration) *Bulkhead {
+	return &Bulkhead{slots: make(chan struct{}, limit), maxWait: maxWait}
+}
+
+// InUse returns the number of executions currently within b.
+func (b *Bulkhead) InUse() int {
+	return len(b.slots)
+}
+
+// BulkheadMiddleware limits the concurrent executions of the wrapped Thunk,
+// along with every other Thunk wrapped with the same Bulkhead, to those
+// allowed by b.
+func BulkheadMiddleware[T any](b *Bulkhead) Middleware[T] {
+	return func(thunk Thunk[T]) Thunk[T] {
+		return func(ctx context.Context) (T, error) {
+			if err := b.enter(ctx); err != nil {
+				var zero T
+				return zero, err
+			}
+			defer b.leave()
+			return thunk(ctx)
+		}
+	}
+}
+
+// enter waits for a slot according to b's maxWait.
+func (b *Bulkhead) enter(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if b.maxWait == 0 {
+		return ErrBulkheadFull
+	}
+
+	var timeout <-chan time.Time
+	if b.maxWait > 0 {
+		timer := time.NewTimer(b.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bulkhead) leave() {
+	<-b.slots
+}
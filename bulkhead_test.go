@@ -0,0 +1,63 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBulkheadMiddleware(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		maxWait    time.Duration
+		ctxTimeout time.Duration
+		wantErr    error
+	}{
+		"reject":         {maxWait: 0, wantErr: ErrBulkheadFull},
+		"queue timeout":  {maxWait: 10 * time.Millisecond, wantErr: ErrBulkheadFull},
+		"queue admitted": {maxWait: time.Second},
+		"queue until context done": {
+			maxWait:    -1,
+			ctxTimeout: 10 * time.Millisecond,
+			wantErr:    context.DeadlineExceeded,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewBulkhead(1, tc.maxWait)
+			release := make(chan struct{})
+			entered := make(chan struct{})
+			blocking := Wrap(func(context.Context) (int, error) {
+				close(entered)
+				<-release
+				return 1, nil
+			}, BulkheadMiddleware[int](b))
+			go blocking(context.Background()) //nolint:errcheck
+			<-entered
+			if n := b.InUse(); n != 1 {
+				t.Errorf("expected 1 execution in use, got %d", n)
+			}
+
+			// The slot is released shortly after the excess execution
+			// starts waiting, which only helps if it queues long enough
+			time.AfterFunc(50*time.Millisecond, func() { close(release) })
+			ctx := context.Background()
+			if tc.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.ctxTimeout)
+				defer cancel()
+			}
+			val, err := Wrap(newSimpleTestThunk(2, nil, 0).call, BulkheadMiddleware[int](b))(ctx)
+			if err != tc.wantErr {
+				t.Fatalf("expected err = %v, got %v", tc.wantErr, err)
+			}
+			if err == nil && val != 2 {
+				t.Errorf("expected val = %d, got %d", 2, val)
+			}
+		})
+	}
+}
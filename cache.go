@@ -0,0 +1,109 @@
+package speculatively
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Cache stores serialized results shared across processes, e.g. in Redis or
+// memcached, for use with RevalidatingCache.
+type Cache interface {
+	// Get returns the value stored under the given key, or false if there
+	// is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores a value under the given key, to expire after the given
+	// TTL.
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+}
+
+// Codec serializes results of type T to be stored in a Cache.
+type Codec[T any] struct {
+	Marshal   func(T) ([]byte, error)
+	Unmarshal func([]byte) (T, error)
+}
+
+// JSONCodec returns a Codec that serializes results as JSON.
+func JSONCodec[T any]() Codec[T] {
+	return Codec[T]{
+		Marshal: func(val T) ([]byte, error) {
+			return json.Marshal(val)
+		},
+		Unmarshal: func(data []byte) (T, error) {
+			var val T
+			err := json.Unmarshal(data, &val)
+			return val, err
+		},
+	}
+}
+
+// errShortEntry is returned when decoding a cached entry too short to hold
+// its timestamp.
+var errShortEntry = errors.New("speculatively: cached entry too short")
+
+// encode serializes an entry as the time it was fetched, in Unix nanoseconds,
+// followed by its value.
+func (c Codec[T]) encode(entry cached[T]) ([]byte, error) {
+	val, err := c.Marshal(entry.val)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 8, 8+len(val))
+	binary.BigEndian.PutUint64(buf, uint64(entry.fetched.UnixNano()))
+	return append(buf, val...), nil
+}
+
+func (c Codec[T]) decode(data []byte) (cached[T], error) {
+	if len(data) < 8 {
+		return cached[T]{}, errShortEntry
+	}
+	val, err := c.Unmarshal(data[8:])
+	if err != nil {
+		return cached[T]{}, err
+	}
+	return cached[T]{val: val, fetched: time.Unix(0, int64(binary.BigEndian.Uint64(data)))}, nil
+}
+
+// MemoryCache is a Cache that stores values in process memory, e.g. for
+// tests.  Expired values are removed when they are next requested.
+//
+// The zero value is ready to use.  A MemoryCache is safe for concurrent use.
+type MemoryCache struct {
+	values map[string]memoryValue
+	mu     sync.Mutex
+}
+
+type memoryValue struct {
+	val     []byte
+	expires time.Time
+}
+
+// Get implements Cache.
+func (m *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !time.Now().Before(v.expires) {
+		delete(m.values, key)
+		return nil, false, nil
+	}
+	return v.val, true, nil
+}
+
+// Set implements Cache.
+func (m *MemoryCache) Set(_ context.Context, key string, val []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values == nil {
+		m.values = map[string]memoryValue{}
+	}
+	m.values[key] = memoryValue{val: val, expires: time.Now().Add(ttl)}
+	return nil
+}
@@ -0,0 +1,85 @@
+package speculatively
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	t.Parallel()
+
+	var m MemoryCache
+	ctx := context.Background()
+	if _, ok, err := m.Get(ctx, "key"); ok || err != nil {
+		t.Errorf("expected miss, got ok = %v, err = %v", ok, err)
+	}
+	if err := m.Set(ctx, "key", []byte("value"), 20*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, ok, _ := m.Get(ctx, "key"); !ok || string(got) != "value" {
+		t.Errorf("expected value %q, got %q, ok = %v", "value", got, ok)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok, _ := m.Get(ctx, "key"); ok {
+		t.Errorf("expected expired value to be missing")
+	}
+}
+
+func TestRevalidatingCacheSharedCache(t *testing.T) {
+	t.Parallel()
+
+	// Two instances sharing a Cache share their results
+	shared := &MemoryCache{}
+	newCache := func() *RevalidatingCache[[]string] {
+		return &RevalidatingCache[[]string]{
+			Fresh:    time.Hour,
+			Patience: time.Second,
+			Cache:    shared,
+		}
+	}
+	first, second := newCache(), newCache()
+	fetches := 0
+	thunk := func(context.Context) ([]string, error) {
+		fetches++
+		return []string{"fetch", strconv.Itoa(fetches)}, nil
+	}
+
+	for _, c := range []*RevalidatingCache[[]string]{first, second} {
+		got, err := c.Do(context.Background(), "key", thunk)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(got) != 2 || got[1] != "1" {
+			t.Errorf("expected result of first fetch, got %v", got)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("expected 1 fetch, got %d", fetches)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	codec := Codec[string]{
+		Marshal:   func(s string) ([]byte, error) { return []byte(s), nil },
+		Unmarshal: func(data []byte) (string, error) { return string(data), nil },
+	}
+	fetched := time.Unix(0, 1234567890)
+	data, err := codec.encode(cached[string]{val: "value", fetched: fetched})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	entry, err := codec.decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if entry.val != "value" || !entry.fetched.Equal(fetched) {
+		t.Errorf("expected entry (value, %s), got (%s, %s)", fetched, entry.val, entry.fetched)
+	}
+	if _, err := codec.decode([]byte{1, 2}); err != errShortEntry {
+		t.Errorf("expected error %v, got %v", errShortEntry, err)
+	}
+}
@@ -0,0 +1,94 @@
+package speculatively
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Canary gradually rolls out alternate implementations raced by DoRace: only
+// the given percentage of calls race every Thunk, while the rest run the
+// first Thunk, the incumbent, alone.  Raising the percentage as the
+// candidates prove themselves makes hedging double as a rollout mechanism.
+//
+// A Canary is safe for concurrent use.
+type Canary struct {
+	percent float64
+
+	mu       sync.Mutex
+	calls    int64
+	canaries int64
+	variants []VariantStats
+}
+
+// VariantStats summarizes the outcomes of one of the Thunks raced by DoRace.
+type VariantStats struct {
+	// Attempts is the number of times the variant was executed.
+	Attempts int64
+	// Wins is the number of calls won by the variant.
+	Wins int64
+}
+
+// NewCanary creates a Canary that races every Thunk in the given percentage
+// (between 0 and 100) of calls.
+func NewCanary(percent float64) *Canary {
+	return &Canary{percent: percent}
+}
+
+// WithCanary limits the Thunks raced by DoRace to the first one, except in
+// the percentage of calls selected by the given Canary.
+func WithCanary(c *Canary) Option {
+	return func(cfg *config) {
+		cfg.canary = c
+	}
+}
+
+// Calls returns the number of calls made with c, and how many of them raced
+// every Thunk.
+func (c *Canary) Calls() (calls, canaries int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls, c.canaries
+}
+
+// Variants returns the stats of every variant, indexed by the position of its
+// Thunk.
+func (c *Canary) Variants() []VariantStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]VariantStats(nil), c.variants...)
+}
+
+// order restricts the given launch order to the incumbent, unless the call
+// is selected to race every variant.
+func (c *Canary) order(order []int) []int {
+	canary := rand.Float64()*100 < c.percent
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if canary {
+		c.canaries++
+		return order
+	}
+	return []int{0}
+}
+
+func (c *Canary) attempt(variant int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.variant(variant).Attempts++
+}
+
+func (c *Canary) win(variant int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.variant(variant).Wins++
+}
+
+// variant returns the stats of the given variant.  It must be called with
+// c.mu held.
+func (c *Canary) variant(i int) *VariantStats {
+	for len(c.variants) <= i {
+		c.variants = append(c.variants, VariantStats{})
+	}
+	return &c.variants[i]
+}
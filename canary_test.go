@@ -0,0 +1,70 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCanary(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		percent          float64
+		wantCanaries     int64
+		wantCandidateWon int64
+	}{
+		"no canaries":  {percent: 0, wantCanaries: 0, wantCandidateWon: 0},
+		"all canaries": {percent: 100, wantCanaries: 10, wantCandidateWon: 10},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewCanary(tc.percent)
+			incumbent := func(ctx context.Context) (string, error) {
+				select {
+				case <-time.After(50 * time.Millisecond):
+					return "incumbent", nil
+				case <-ctx.Done():
+					return "", ctx.Err()
+				}
+			}
+			candidate := func(context.Context) (string, error) { return "candidate", nil }
+
+			for i := 0; i < 10; i++ {
+				got, err := DoRace(context.Background(), time.Millisecond, []Thunk[string]{incumbent, candidate}, WithCanary(c))
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				want := "incumbent"
+				if tc.wantCanaries > 0 {
+					want = "candidate"
+				}
+				if got != want {
+					t.Errorf("expected result %q, got %q", want, got)
+				}
+			}
+
+			calls, canaries := c.Calls()
+			if calls != 10 || canaries != tc.wantCanaries {
+				t.Errorf("expected 10 calls and %d canaries, got %d and %d", tc.wantCanaries, calls, canaries)
+			}
+			variants := c.Variants()
+			if len(variants) == 0 || variants[0].Attempts != 10 {
+				t.Fatalf("expected incumbent to run in every call, got %+v", variants)
+			}
+			var candidateWon int64
+			if len(variants) > 1 {
+				candidateWon = variants[1].Wins
+			}
+			if candidateWon != tc.wantCandidateWon {
+				t.Errorf("expected candidate to win %d calls, got %d", tc.wantCandidateWon, candidateWon)
+			}
+			if wins := variants[0].Wins + candidateWon; wins != 10 {
+				t.Errorf("expected 10 wins in total, got %d", wins)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ErrInjected is the error injected by InjectChaos when Chaos.Err is nil.
+var ErrInjected = errors.New("speculatively: injected fault")
+
+// Chaos describes the adverse conditions injected by InjectChaos.
+type Chaos struct {
+	// Latency returns the delay to inject before each execution, e.g. a
+	// func returned by UniformLatency, ExponentialLatency or
+	// SampleLatencies.  If nil, no delay is injected.
+	Latency func() time.Duration
+
+	// ErrorRate is the fraction (between 0.0 and 1.0) of executions that
+	// fail with Err, after their injected delay, instead of calling the
+	// underlying Thunk.
+	ErrorRate float64
+
+	// Err is the error injected into failing executions.  If nil,
+	// ErrInjected is used.
+	Err error
+
+	// HangRate is the fraction (between 0.0 and 1.0) of executions that
+	// hang until their context is done.
+	HangRate float64
+}
+
+// InjectChaos wraps a Thunk so that its executions are subjected to the
+// latency, errors and hangs described by the given Chaos.
+//
+// Like InjectLatency, this is intended for tests and staging environments, to
+// validate that patience, budgets and error classifiers behave as expected
+// under adverse conditions.  Injected delays and hangs respect context
+// cancelation.
+func InjectChaos[T any](thunk Thunk[T], c Chaos) Thunk[T] {
+	return func(ctx context.Context) (T, error) {
+		var zero T
+		if c.HangRate > 0 && rand.Float64() < c.HangRate {
+			<-ctx.Done()
+			return zero, ctx.Err()
+		}
+		if c.Latency != nil {
+			if err := sleep(ctx, c.Latency()); err != nil {
+				return zero, err
+			}
+		}
+		if c.ErrorRate > 0 && rand.Float64() < c.ErrorRate {
+			if c.Err != nil {
+				return zero, c.Err
+			}
+			return zero, ErrInjected
+		}
+		return thunk(ctx)
+	}
+}
+
+// UniformLatency returns a func that draws latencies uniformly at random
+// between lo and hi, for use with Chaos.
+func UniformLatency(lo, hi time.Duration) func() time.Duration {
+	return func() time.Duration {
+		if hi <= lo {
+			return lo
+		}
+		return lo + time.Duration(rand.Int63n(int64(hi-lo)))
+	}
+}
+
+// ExponentialLatency returns a func that draws latencies at random from an
+// exponential distribution with the given mean, whose long tail resembles
+// that of many real services, for use with Chaos.
+func ExponentialLatency(mean time.Duration) func() time.Duration {
+	return func() time.Duration {
+		return time.Duration(rand.ExpFloat64() * float64(mean))
+	}
+}
@@ -0,0 +1,77 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInjectChaos(t *testing.T) {
+	t.Parallel()
+
+	errCustom := errors.New("custom")
+	testCases := map[string]struct {
+		chaos      Chaos
+		wantErr    error
+		wantMinDur time.Duration
+	}{
+		"no chaos": {},
+		"latency": {
+			chaos:      Chaos{Latency: UniformLatency(20*time.Millisecond, 20*time.Millisecond)},
+			wantMinDur: 20 * time.Millisecond,
+		},
+		"errors": {
+			chaos:   Chaos{ErrorRate: 1},
+			wantErr: ErrInjected,
+		},
+		"custom errors": {
+			chaos:   Chaos{ErrorRate: 1, Err: errCustom},
+			wantErr: errCustom,
+		},
+		"hangs": {
+			chaos:      Chaos{HangRate: 1},
+			wantErr:    context.DeadlineExceeded,
+			wantMinDur: 50 * time.Millisecond,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			thunk := newSimpleTestThunk(1, nil, 0)
+
+			start := time.Now()
+			val, err := InjectChaos(thunk.call, tc.chaos)(ctx)
+			if err != tc.wantErr {
+				t.Fatalf("expected err = %v, got %v", tc.wantErr, err)
+			}
+			if err == nil && val != 1 {
+				t.Errorf("expected val = %d, got %d", 1, val)
+			}
+			if elapsed := time.Since(start); elapsed < tc.wantMinDur {
+				t.Errorf("expected delay of at least %s, got %s", tc.wantMinDur, elapsed)
+			}
+		})
+	}
+}
+
+func TestLatencyDistributions(t *testing.T) {
+	t.Parallel()
+
+	uniform := UniformLatency(10*time.Millisecond, 20*time.Millisecond)
+	var total time.Duration
+	for i := 0; i < 1000; i++ {
+		d := uniform()
+		if d < 10*time.Millisecond || d >= 20*time.Millisecond {
+			t.Fatalf("expected uniform latency in [10ms, 20ms), got %s", d)
+		}
+		total += ExponentialLatency(10 * time.Millisecond)()
+	}
+	if mean := total / 1000; mean < 5*time.Millisecond || mean > 15*time.Millisecond {
+		t.Errorf("expected exponential latency with mean around 10ms, got %s", mean)
+	}
+}
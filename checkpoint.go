@@ -0,0 +1,89 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNoCheckpoints is returned when saving a checkpoint from a context that
+// does not belong to an attempt of a call using WithCheckpoints.
+var ErrNoCheckpoints = errors.New("speculatively: checkpoints not enabled")
+
+// CheckpointStore holds the partial progress of a single call, shared by all
+// of its attempts, so that a hedge can resume from where a slow attempt got
+// stuck instead of starting from scratch (e.g. the offset reached by a large
+// download).
+//
+// Implementations must be safe for concurrent use, since attempts run in
+// parallel.
+type CheckpointStore interface {
+	// Save records a checkpoint, replacing any previous one.
+	Save(ctx context.Context, checkpoint interface{}) error
+	// Load returns the most recent checkpoint, or false if none has been
+	// saved.
+	Load(ctx context.Context) (checkpoint interface{}, ok bool, err error)
+}
+
+// WithCheckpoints gives the attempts of every call access to a CheckpointStore
+// via SaveCheckpoint and LoadCheckpoint.  A new store is created for each call
+// by the given func, e.g. to persist checkpoints under a key identifying the
+// operation.  If newStore is nil, checkpoints are kept in memory.
+func WithCheckpoints(newStore func() CheckpointStore) Option {
+	if newStore == nil {
+		newStore = func() CheckpointStore { return &memoryCheckpoints{} }
+	}
+	return func(c *config) {
+		c.newCheckpoints = newStore
+	}
+}
+
+// SaveCheckpoint records the partial progress of the attempt to which the
+// given context belongs, for later attempts of the same call to resume from.
+func SaveCheckpoint(ctx context.Context, checkpoint interface{}) error {
+	info, ok := attemptFromContext(ctx)
+	if !ok || info.call.checkpoints == nil {
+		return ErrNoCheckpoints
+	}
+	return info.call.checkpoints.Save(ctx, checkpoint)
+}
+
+// LoadCheckpoint returns the most recent checkpoint saved by any attempt of
+// the call to which the given context belongs, or false if there is none.  A
+// checkpoint that is not a C yields a *TypeError.
+func LoadCheckpoint[C any](ctx context.Context) (C, bool, error) {
+	var zero C
+	info, ok := attemptFromContext(ctx)
+	if !ok || info.call.checkpoints == nil {
+		return zero, false, nil
+	}
+	checkpoint, ok, err := info.call.checkpoints.Load(ctx)
+	if err != nil || !ok {
+		return zero, false, err
+	}
+	typed, err := As[C](checkpoint, nil)
+	if err != nil {
+		return zero, false, err
+	}
+	return typed, true, nil
+}
+
+// memoryCheckpoints is the default, in-memory CheckpointStore.
+type memoryCheckpoints struct {
+	checkpoint interface{}
+	ok         bool
+	mu         sync.Mutex
+}
+
+func (m *memoryCheckpoints) Save(_ context.Context, checkpoint interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoint, m.ok = checkpoint, true
+	return nil
+}
+
+func (m *memoryCheckpoints) Load(_ context.Context) (interface{}, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkpoint, m.ok, nil
+}
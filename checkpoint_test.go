@@ -0,0 +1,69 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	// Each attempt resumes from the last checkpoint and makes progress in
+	// steps, but only the first attempt stalls partway through
+	const total = 10
+	thunk := func(ctx context.Context) (int, error) {
+		offset, _, err := LoadCheckpoint[int](ctx)
+		if err != nil {
+			return 0, err
+		}
+		start := offset
+		for ; offset < total; offset++ {
+			if !IsHedge(ctx) && offset == 5 {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			if err := SaveCheckpoint(ctx, offset+1); err != nil {
+				return 0, err
+			}
+		}
+		return start, nil
+	}
+
+	resumedFrom, err := Do(context.Background(), 10*time.Millisecond, thunk, WithCheckpoints(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resumedFrom != 5 {
+		t.Errorf("expected hedge to resume from offset %d, got %d", 5, resumedFrom)
+	}
+}
+
+func TestCheckpointsDisabled(t *testing.T) {
+	t.Parallel()
+
+	_, err := Do(context.Background(), time.Second, func(ctx context.Context) (int, error) {
+		if _, ok, _ := LoadCheckpoint[int](ctx); ok {
+			t.Errorf("expected no checkpoint")
+		}
+		return 0, SaveCheckpoint(ctx, 1)
+	})
+	if err != ErrNoCheckpoints {
+		t.Errorf("expected err = %s, got %v", ErrNoCheckpoints, err)
+	}
+}
+
+func TestCheckpointTypeMismatch(t *testing.T) {
+	t.Parallel()
+
+	_, err := Do(context.Background(), time.Second, func(ctx context.Context) (int, error) {
+		if err := SaveCheckpoint(ctx, "not an int"); err != nil {
+			return 0, err
+		}
+		_, _, err := LoadCheckpoint[int](ctx)
+		return 0, err
+	}, WithCheckpoints(nil))
+	if _, ok := err.(*TypeError); !ok {
+		t.Errorf("expected *TypeError, got %v", err)
+	}
+}
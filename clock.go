@@ -0,0 +1,63 @@
+package speculatively
+
+import "time"
+
+// Clock is a source of time for the scheduling of attempts, so that tests
+// can control when hedges are launched rather than waiting in real time.
+// See the speculativelytest package for a fake implementation.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTicker returns a Ticker that ticks every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker.
+type Ticker interface {
+	// C returns the channel on which ticks are delivered.
+	C() <-chan time.Time
+	// Reset stops the Ticker and resets its period to d.
+	Reset(d time.Duration)
+	// Stop turns off the Ticker.
+	Stop()
+}
+
+// WithClock schedules attempts, measures their latency, checks the staleness
+// of their results and feeds the ErrorGate set via WithErrorGate, if any,
+// according to the given Clock rather than the system clock.  Budgets,
+// latency trackers and other state shared across calls otherwise keep using
+// the system clock.
+func WithClock(c Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = c
+	}
+}
+
+// now returns the current time according to the configured Clock.
+func (c *config) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// since returns the time elapsed since t according to the configured Clock.
+func (c *config) since(t time.Time) time.Duration {
+	return c.now().Sub(t)
+}
+
+func (c *config) newTicker(d time.Duration) Ticker {
+	if c.clock == nil {
+		return systemTicker{time.NewTicker(d)}
+	}
+	return c.clock.NewTicker(d)
+}
+
+// systemTicker adapts a time.Ticker to the Ticker interface.
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
@@ -0,0 +1,123 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a Clock whose tickers only tick when told to.
+type manualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []chan time.Time
+	created chan struct{}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) NewTicker(time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.tickers = append(c.tickers, ch)
+	c.created <- struct{}{}
+	return manualTicker(ch)
+}
+
+// tick advances the clock by d and ticks every ticker.
+func (c *manualClock) tick(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, ch := range c.tickers {
+		select {
+		case ch <- c.now:
+		default:
+		}
+	}
+}
+
+type manualTicker chan time.Time
+
+func (t manualTicker) C() <-chan time.Time { return t }
+func (manualTicker) Reset(time.Duration)   {}
+func (manualTicker) Stop()                 {}
+
+func TestWithClock(t *testing.T) {
+	t.Parallel()
+
+	clock := &manualClock{now: time.Unix(1000, 0), created: make(chan struct{}, 1)}
+	var (
+		mu      sync.Mutex
+		started []time.Time
+		elapsed []time.Duration
+	)
+	hooks := Hooks{
+		OnLaunch: func(a Attempt) {
+			mu.Lock()
+			defer mu.Unlock()
+			started = append(started, a.Start)
+		},
+		OnDone: func(_ Attempt, d time.Duration, _ error) {
+			mu.Lock()
+			defer mu.Unlock()
+			elapsed = append(elapsed, d)
+		},
+	}
+	hedged := make(chan struct{})
+	result := make(chan int)
+	go func() {
+		// The patience is far longer than the test, so only the clock can
+		// launch the hedge
+		val, _ := Do(context.Background(), time.Hour, func(ctx context.Context) (int, error) {
+			if IsHedge(ctx) {
+				close(hedged)
+				return 2, nil
+			}
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}, WithClock(clock), WithHooks(hooks), WithMaxAttempts(2))
+		result <- val
+	}()
+
+	<-clock.created
+	clock.tick(time.Hour)
+	<-hedged
+	if val := <-result; val != 2 {
+		t.Errorf("expected val = %d, got %d", 2, val)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(started) != 2 || !started[0].Equal(time.Unix(1000, 0)) || !started[1].Equal(time.Unix(1000, 0).Add(time.Hour)) {
+		t.Errorf("expected attempts started according to the clock, got %v", started)
+	}
+	if len(elapsed) < 1 || elapsed[0] != 0 {
+		t.Errorf("expected hedge elapsed time of 0 according to the clock, got %v", elapsed)
+	}
+}
+
+func TestWithClockStaleness(t *testing.T) {
+	t.Parallel()
+
+	// The result is decades old by the system clock, but fresh by the
+	// given one
+	clock := &manualClock{now: time.Unix(1000, 0), created: make(chan struct{}, 1)}
+	thunk := newTestThunk([]result[int]{{val: 1}}, []time.Duration{0})
+	val, err := Do(context.Background(), time.Hour, func(ctx context.Context) (timestamped, error) {
+		val, err := thunk.call(ctx)
+		return timestamped{id: val, ts: time.Unix(995, 0)}, err
+	}, WithClock(clock), WithMaxStaleness(10*time.Second), WithMaxAttempts(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val.id != 1 || thunk.callCount() != 1 {
+		t.Errorf("expected fresh result of the first attempt, got %v after %d attempts", val, thunk.callCount())
+	}
+}
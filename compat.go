@@ -0,0 +1,67 @@
+package speculatively
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// AnyThunk is an untyped Thunk, as used by call sites written before Thunk
+// took a type parameter.
+type AnyThunk = Thunk[interface{}]
+
+// DoAny speculatively executes an untyped Thunk.  It exists to ease migrating
+// call sites written against the interface{}-returning usage pattern, which
+// may adopt Options immediately and typed Thunks incrementally via Typed and
+// As.  See Do for details.
+func DoAny(ctx context.Context, patience time.Duration, thunk AnyThunk, opts ...Option) (interface{}, error) {
+	return Do(ctx, patience, thunk, opts...)
+}
+
+// TypeError is returned when an untyped result cannot be converted to the
+// expected type.
+type TypeError struct {
+	// Value is the offending result.
+	Value interface{}
+	// Want is the name of the expected type.
+	Want string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("speculatively: unexpected result type %T, want %s", e.Value, e.Want)
+}
+
+// As converts the result of an untyped Thunk to type T, returning a *TypeError
+// if the value is not a T.  A non-nil err is passed through unchanged, and a
+// nil value converts to the zero value of T.
+func As[T any](val interface{}, err error) (T, error) {
+	var zero T
+	if err != nil || val == nil {
+		return zero, err
+	}
+	typed, ok := val.(T)
+	if !ok {
+		return zero, &TypeError{
+			Value: val,
+			Want:  reflect.TypeOf((*T)(nil)).Elem().String(),
+		}
+	}
+	return typed, nil
+}
+
+// Typed adapts an untyped Thunk into a Thunk[T], whose results are converted
+// using As.
+func Typed[T any](thunk AnyThunk) Thunk[T] {
+	return func(ctx context.Context) (T, error) {
+		return As[T](thunk(ctx))
+	}
+}
+
+// Untyped adapts a Thunk[T] into an untyped Thunk, for use by code that has
+// not yet been migrated to typed Thunks.
+func Untyped[T any](thunk Thunk[T]) AnyThunk {
+	return func(ctx context.Context) (interface{}, error) {
+		return thunk(ctx)
+	}
+}
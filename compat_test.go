@@ -0,0 +1,60 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoAny(t *testing.T) {
+	t.Parallel()
+
+	thunk := newSimpleTestThunk(1, nil, 0)
+	result, err := DoAny(context.Background(), 10*time.Millisecond, Untyped(thunk.call), WithMaxAttempts(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	val, err := As[int](result, err)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 1 {
+		t.Errorf("expected val = %d, got %d", 1, val)
+	}
+}
+
+func TestTyped(t *testing.T) {
+	t.Parallel()
+
+	untyped := func(_ context.Context) (interface{}, error) {
+		return "not an int", nil
+	}
+	_, err := Typed[int](untyped)(context.Background())
+
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected *TypeError, got %#v", err)
+	}
+	if typeErr.Want != "int" {
+		t.Errorf("expected Want = %q, got %q", "int", typeErr.Want)
+	}
+	if msg := "speculatively: unexpected result type string, want int"; err.Error() != msg {
+		t.Errorf("expected error %q, got %q", msg, err)
+	}
+}
+
+func TestAs(t *testing.T) {
+	t.Parallel()
+
+	wantErr := errors.New("error")
+	if _, err := As[int]("ignored", wantErr); err != wantErr {
+		t.Errorf("expected err = %s, got %v", wantErr, err)
+	}
+	if val, err := As[*int](nil, nil); err != nil || val != nil {
+		t.Errorf("expected nil value to convert to zero value, got %v, %v", val, err)
+	}
+	if val, err := As[error](wantErr, nil); err != nil || val != wantErr {
+		t.Errorf("expected conversion to interface type, got %v, %v", val, err)
+	}
+}
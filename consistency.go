@@ -0,0 +1,48 @@
+package speculatively
+
+// WithConsistencyCheck compares the result of every attempt that succeeds
+// after its call has already been won, i.e. a late result, to the winning
+// result using the given equality func, and reports those that differ via
+// the OnDivergence hook and the Divergences stat.  Replicas serving
+// inconsistent data would otherwise go unnoticed, since hedging returns
+// whichever result arrives first.  Given WithDedup or WithDedupFunc, each
+// distinct divergent result is only reported once per call.
+//
+// Only attempts that complete despite their cancelation produce late
+// results, so divergence is detected more reliably with Thunks that finish
+// reading a response that has already arrived rather than abandoning it.
+func WithConsistencyCheck[T any](equal func(a, b T) bool) Option {
+	return func(c *config) {
+		c.equal = func(a, b interface{}) bool {
+			// Nil interface values assert to the zero value of T
+			av, _ := a.(T)
+			bv, _ := b.(T)
+			return equal(av, bv)
+		}
+	}
+}
+
+// wonResult is the winning attempt of a call and its result.
+type wonResult struct {
+	attempt Attempt
+	val     interface{}
+}
+
+// checkConsistency compares the late result of the given attempt with the
+// result of its call, if WithConsistencyCheck is set.
+func (c *config) checkConsistency(info *callInfo, a Attempt, val interface{}) {
+	if c.equal == nil {
+		return
+	}
+	won, ok := info.won.Load().(wonResult)
+	if !ok {
+		return
+	}
+	if !c.equal(won.val, val) {
+		if c.resultKey != nil && !info.diverged.first(c.resultKey(val)) {
+			return
+		}
+		c.stats.diverged()
+		c.hooks.diverged(won.attempt, a)
+	}
+}
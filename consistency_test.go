@@ -0,0 +1,95 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithConsistencyCheck(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		firstResult    string
+		wantDivergence bool
+	}{
+		"consistent":   {firstResult: "v2", wantDivergence: false},
+		"inconsistent": {firstResult: "v1", wantDivergence: true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// The first attempt ignores cancelation and completes after
+			// the hedge has won
+			thunk := func(ctx context.Context) (string, error) {
+				if !IsHedge(ctx) {
+					time.Sleep(50 * time.Millisecond)
+					return tc.firstResult, nil
+				}
+				return "v2", nil
+			}
+			var (
+				mu          sync.Mutex
+				divergences [][2]int
+			)
+			done := make(chan struct{})
+			h := NewHedger(10*time.Millisecond,
+				WithMaxAttempts(2),
+				WithConsistencyCheck(func(a, b string) bool { return a == b }),
+				WithHooks(Hooks{
+					OnDivergence: func(winner, other Attempt) {
+						mu.Lock()
+						defer mu.Unlock()
+						divergences = append(divergences, [2]int{winner.Index, other.Index})
+					},
+					OnLateResult: func(Attempt, time.Duration) { close(done) },
+				}),
+			)
+
+			got, err := DoWith(context.Background(), h, thunk)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != "v2" {
+				t.Errorf("expected result %q, got %q", "v2", got)
+			}
+			<-done
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got := len(divergences) > 0; got != tc.wantDivergence {
+				t.Fatalf("expected divergence = %v, got %v", tc.wantDivergence, divergences)
+			}
+			if tc.wantDivergence && divergences[0] != [2]int{1, 0} {
+				t.Errorf("expected divergence between attempts 1 and 0, got %v", divergences[0])
+			}
+			if n := h.Stats().Divergences; n != int64(len(divergences)) {
+				t.Errorf("expected %d divergences in stats, got %d", len(divergences), n)
+			}
+		})
+	}
+}
+
+func TestWithConsistencyCheckDoRace(t *testing.T) {
+	t.Parallel()
+
+	diverged := make(chan struct{})
+	slow := func(context.Context) (int, error) {
+		time.Sleep(30 * time.Millisecond)
+		return 1, nil
+	}
+	fast := func(context.Context) (int, error) { return 2, nil }
+	DoRace(context.Background(), 5*time.Millisecond, []Thunk[int]{slow, fast}, //nolint:errcheck
+		WithConsistencyCheck(func(a, b int) bool { return a == b }),
+		WithHooks(Hooks{OnDivergence: func(Attempt, Attempt) { close(diverged) }}),
+	)
+	select {
+	case <-diverged:
+	case <-time.After(time.Second):
+		t.Fatalf("expected divergence between raced thunks")
+	}
+}
@@ -0,0 +1,138 @@
+package speculatively
+
+import (
+	"context"
+	"time"
+)
+
+// SplitInfo describes an item of a batch about to be processed by Map or one
+// of its variants, to derive the item's own deadline from the batch's.
+type SplitInfo struct {
+	// Index of the item within the batch.
+	Index int
+	// Items is the number of items in the batch, and Pending the number of
+	// them not yet started, including this one.
+	Items   int
+	Pending int
+	// Concurrency is the number of items processed at once, as set by
+	// WithConcurrency, or Items if unlimited.
+	Concurrency int
+	// Total is the time that remained until the batch's deadline when the
+	// batch started, and Remaining the time that remains now.
+	Total     time.Duration
+	Remaining time.Duration
+}
+
+// waves returns the number of successive waves in which the given number of
+// items are processed.
+func (s SplitInfo) waves(items int) int {
+	return (items + s.Concurrency - 1) / s.Concurrency
+}
+
+// DeadlineSplit returns how long the described item of a batch may take,
+// including all of its attempts.  The item's deadline is further capped by
+// the deadline of the whole batch.
+type DeadlineSplit func(SplitInfo) time.Duration
+
+// WithDeadlineSplit derives a deadline for every item processed by Map,
+// ForEach, DoMap or DoScatter from the deadline of the batch's context, so
+// that slow items early in a batch cannot consume its entire time budget and
+// starve the items after them.  Batches whose context has no deadline are
+// not split.
+func WithDeadlineSplit(split DeadlineSplit) Option {
+	return func(c *config) {
+		c.deadlineSplit = split
+	}
+}
+
+// EvenDeadlines splits the time until a batch's deadline evenly between the
+// successive waves of items processed at once, as fixed when the batch
+// starts.
+func EvenDeadlines() DeadlineSplit {
+	return func(s SplitInfo) time.Duration {
+		return s.Total / time.Duration(s.waves(s.Items))
+	}
+}
+
+// WeightedDeadlines splits the time until a batch's deadline between its
+// items in proportion to their weight, as given by the weight func for the
+// item with each index, e.g. so that larger items get more time.  Each item
+// gets its share of the time available to all of the items processed at
+// once, as fixed when the batch starts.
+//
+// Weight is called for every item of the batch as each item starts, so it
+// should be cheap.  Items without a positive weight share time evenly.
+func WeightedDeadlines(weight func(i int) float64) DeadlineSplit {
+	return func(s SplitInfo) time.Duration {
+		var sum float64
+		for i := 0; i < s.Items; i++ {
+			if w := weight(i); w > 0 {
+				sum += w
+			}
+		}
+		w := weight(s.Index)
+		if sum <= 0 || w <= 0 {
+			return EvenDeadlines()(s)
+		}
+		share := float64(s.Total) * float64(s.Concurrency) * w / sum
+		if share > float64(s.Total) {
+			return s.Total
+		}
+		return time.Duration(share)
+	}
+}
+
+// AdaptiveDeadlines splits the time remaining until a batch's deadline
+// evenly between the successive waves of items not yet started, as each item
+// starts, so that time left over by items that complete early goes to the
+// items after them.
+func AdaptiveDeadlines() DeadlineSplit {
+	return func(s SplitInfo) time.Duration {
+		return s.Remaining / time.Duration(s.waves(s.Pending))
+	}
+}
+
+// splitter derives the context of each item of a batch from the batch's
+// context, according to a DeadlineSplit.
+type splitter struct {
+	split    DeadlineSplit
+	clock    func() time.Time
+	deadline time.Time
+	info     SplitInfo
+}
+
+// newSplitter returns a splitter for a batch of n items, or nil if the
+// batch's deadline is not split.
+func newSplitter(ctx context.Context, cfg *config, n int) *splitter {
+	deadline, ok := ctx.Deadline()
+	if cfg.deadlineSplit == nil || !ok || n == 0 {
+		return nil
+	}
+	concurrency := cfg.concurrency
+	if concurrency < 1 || concurrency > n {
+		concurrency = n
+	}
+	return &splitter{
+		split:    cfg.deadlineSplit,
+		clock:    cfg.now,
+		deadline: deadline,
+		info: SplitInfo{
+			Items:       n,
+			Concurrency: concurrency,
+			Total:       deadline.Sub(cfg.now()),
+		},
+	}
+}
+
+// item returns the context of the item with the given index, which must be
+// canceled once the item is processed.
+func (s *splitter) item(ctx context.Context, i int) (context.Context, context.CancelFunc) {
+	if s == nil {
+		return ctx, func() {}
+	}
+	info := s.info
+	info.Index = i
+	info.Pending = info.Items - i
+	info.Remaining = s.deadline.Sub(s.clock())
+	return context.WithTimeout(ctx, s.split(info))
+}
@@ -0,0 +1,143 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeadlineSplits(t *testing.T) {
+	t.Parallel()
+
+	info := SplitInfo{
+		Index:       1,
+		Items:       4,
+		Pending:     3,
+		Concurrency: 2,
+		Total:       time.Second,
+		Remaining:   900 * time.Millisecond,
+	}
+	weights := []float64{1, 2, 1, 0}
+	testCases := map[string]struct {
+		split DeadlineSplit
+		info  SplitInfo
+		want  time.Duration
+	}{
+		"even": {
+			split: EvenDeadlines(),
+			info:  info,
+			want:  500 * time.Millisecond,
+		},
+		"even sequential": {
+			split: EvenDeadlines(),
+			info:  SplitInfo{Index: 1, Items: 4, Pending: 3, Concurrency: 1, Total: time.Second},
+			want:  250 * time.Millisecond,
+		},
+		"weighted": {
+			split: WeightedDeadlines(func(i int) float64 { return weights[i] }),
+			info:  SplitInfo{Index: 1, Items: 4, Pending: 3, Concurrency: 1, Total: time.Second},
+			want:  500 * time.Millisecond,
+		},
+		"weighted capped at total": {
+			split: WeightedDeadlines(func(i int) float64 { return weights[i] }),
+			info:  info,
+			want:  time.Second,
+		},
+		"weighted without weight": {
+			split: WeightedDeadlines(func(i int) float64 { return weights[i] }),
+			info:  SplitInfo{Index: 3, Items: 4, Pending: 1, Concurrency: 1, Total: time.Second},
+			want:  250 * time.Millisecond,
+		},
+		"adaptive": {
+			split: AdaptiveDeadlines(),
+			info:  info,
+			want:  450 * time.Millisecond,
+		},
+		"adaptive last wave": {
+			split: AdaptiveDeadlines(),
+			info:  SplitInfo{Index: 3, Items: 4, Pending: 1, Concurrency: 2, Total: time.Second, Remaining: 300 * time.Millisecond},
+			want:  300 * time.Millisecond,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.split(tc.info); got != tc.want {
+				t.Errorf("expected split = %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWithDeadlineSplit(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		opts   []Option
+		failed []int
+	}{
+		"unsplit": {},
+		"even": {
+			opts:   []Option{WithDeadlineSplit(EvenDeadlines())},
+			failed: []int{0},
+		},
+		"adaptive": {
+			opts:   []Option{WithDeadlineSplit(AdaptiveDeadlines())},
+			failed: []int{0},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			opts := append([]Option{WithConcurrency(1), WithMaxAttempts(1)}, tc.opts...)
+			// The first item stalls until its deadline, and would starve
+			// every other item without a split
+			err := ForEach(ctx, time.Second, []int{0, 1, 2, 3}, func(ctx context.Context, i int) error {
+				if i == 0 {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return nil
+			}, opts...)
+
+			if tc.failed == nil {
+				if err != context.DeadlineExceeded {
+					t.Fatalf("expected err = %v, got %v", context.DeadlineExceeded, err)
+				}
+				return
+			}
+			var errs ItemErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected ItemErrors, got %v", err)
+			}
+			if len(errs) != len(tc.failed) {
+				t.Fatalf("expected items %v to fail, got %v", tc.failed, errs)
+			}
+			for i, e := range errs {
+				if e.Index != tc.failed[i] || !errors.Is(e.Err, context.DeadlineExceeded) {
+					t.Errorf("expected item %d to exceed its deadline, got %v", tc.failed[i], e)
+				}
+			}
+		})
+	}
+}
+
+func TestDeadlineSplitWithoutDeadline(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig([]Option{WithDeadlineSplit(EvenDeadlines())})
+	if s := newSplitter(context.Background(), cfg, 4); s != nil {
+		t.Fatalf("expected batch without deadline not to be split")
+	}
+	ctx, cancel := (*splitter)(nil).item(context.Background(), 0)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("expected item of unsplit batch to have no deadline")
+	}
+}
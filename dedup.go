@@ -0,0 +1,82 @@
+package speculatively
+
+import "sync"
+
+// WithDedup deduplicates identical successful results, as compared with ==,
+// so that reports and comparisons focus on results that actually differ
+// rather than on many copies of the same one:
+//
+//   - DoAll returns a single AttemptResult for every distinct successful
+//     result, that of the first attempt to return it, listing the other
+//     attempts that returned it as Duplicates;
+//   - WithConsistencyCheck reports each distinct late result that diverges
+//     from the winning result via OnDivergence only once per call.
+//
+// The results of duplicate attempts are discarded as set by WithCleanup.
+func WithDedup[T comparable]() Option {
+	return func(c *config) {
+		c.resultKey = func(val interface{}) interface{} {
+			v, _ := val.(T)
+			return v
+		}
+	}
+}
+
+// WithDedupFunc deduplicates successful results as WithDedup does, for types
+// that are not comparable, treating results for which the given hash func
+// returns the same string as identical.
+func WithDedupFunc[T any](hash func(T) string) Option {
+	return func(c *config) {
+		c.resultKey = func(val interface{}) interface{} {
+			v, _ := val.(T)
+			return hash(v)
+		}
+	}
+}
+
+// dedup merges every successful result of DoAll identical to that of an
+// earlier attempt into the earlier one, if WithDedup or WithDedupFunc is set.
+func dedup[T any](cfg *config, results []AttemptResult[T]) []AttemptResult[T] {
+	if cfg.resultKey == nil {
+		return results
+	}
+	first := map[interface{}]int{}
+	distinct := results[:0]
+	for _, r := range results {
+		if r.Err != nil {
+			distinct = append(distinct, r)
+			continue
+		}
+		key := cfg.resultKey(r.Val)
+		if i, ok := first[key]; ok {
+			distinct[i].Duplicates = append(distinct[i].Duplicates, r.Attempt)
+			cfg.discard(r.Val)
+			continue
+		}
+		first[key] = len(distinct)
+		distinct = append(distinct, r)
+	}
+	return distinct
+}
+
+// divergences records the distinct late results of a call already reported
+// as diverging from its winning result.
+type divergences struct {
+	keys map[interface{}]bool
+	mu   sync.Mutex
+}
+
+// first reports whether the given key has not been reported yet, and records
+// it.
+func (d *divergences) first(key interface{}) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.keys[key] {
+		return false
+	}
+	if d.keys == nil {
+		d.keys = map[interface{}]bool{}
+	}
+	d.keys[key] = true
+	return true
+}
@@ -0,0 +1,185 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDoAllWithDedup(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	thunk := newTestThunk(
+		[]result[int]{{val: 1}, {val: 1}, {val: 2}, {err: failed}, {val: 1}},
+		[]time.Duration{0},
+	)
+	var discarded []int
+	results, err := DoAll(context.Background(), 5*time.Millisecond, thunk.call,
+		WithMaxAttempts(5),
+		WithDedup[int](),
+		WithCleanup(func(v int) { discarded = append(discarded, v) }),
+	)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 distinct results, got %v", results)
+	}
+	want := []struct {
+		val        int
+		err        error
+		duplicates []int
+	}{
+		{val: 1, duplicates: []int{1, 4}},
+		{val: 2},
+		{err: failed},
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Val != w.val || r.Err != w.err {
+			t.Errorf("expected result %d = (%v, %v), got (%v, %v)", i, w.val, w.err, r.Val, r.Err)
+		}
+		if len(r.Duplicates) != len(w.duplicates) {
+			t.Errorf("expected result %d duplicated by attempts %v, got %v", i, w.duplicates, r.Duplicates)
+			continue
+		}
+		for j, a := range r.Duplicates {
+			if a.Index != w.duplicates[j] {
+				t.Errorf("expected result %d duplicated by attempts %v, got %v", i, w.duplicates, r.Duplicates)
+			}
+		}
+	}
+	if len(discarded) != 2 {
+		t.Errorf("expected duplicates to be discarded, got %v", discarded)
+	}
+}
+
+func TestDoAllWithDedupFunc(t *testing.T) {
+	t.Parallel()
+
+	vals := [][]string{{"a", "b"}, {"a", "b"}, {"a"}}
+	results, err := DoAll(context.Background(), time.Millisecond, func(ctx context.Context) ([]string, error) {
+		a, _ := AttemptFromContext(ctx)
+		return vals[a.Index], nil
+	}, WithMaxAttempts(len(vals)), WithDedupFunc(func(v []string) string {
+		return strings.Join(v, ",")
+	}))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 distinct results, got %v", results)
+	}
+	if len(results[0].Duplicates) != 1 || len(results[1].Duplicates) != 0 {
+		t.Errorf("expected only the first result to be duplicated, got %v", results)
+	}
+}
+
+func TestWithConsistencyCheckDedup(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		opts            []Option
+		wantDivergences int
+	}{
+		"without dedup": {wantDivergences: 2},
+		"with dedup":    {opts: []Option{WithDedup[string]()}, wantDivergences: 1},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// The first two attempts ignore cancelation and complete with
+			// the same stale result after the third has won
+			thunk := func(ctx context.Context) (string, error) {
+				a, _ := AttemptFromContext(ctx)
+				if a.Index < 2 {
+					time.Sleep(time.Duration(60-10*a.Index) * time.Millisecond)
+					return "v1", nil
+				}
+				return "v2", nil
+			}
+			var (
+				mu          sync.Mutex
+				divergences int
+				late        sync.WaitGroup
+			)
+			late.Add(2)
+			opts := append([]Option{
+				WithMaxAttempts(3),
+				WithConsistencyCheck(func(a, b string) bool { return a == b }),
+				WithHooks(Hooks{
+					OnDivergence: func(winner, other Attempt) {
+						mu.Lock()
+						defer mu.Unlock()
+						divergences++
+					},
+					OnLateResult: func(Attempt, time.Duration) { late.Done() },
+				}),
+			}, tc.opts...)
+
+			got, err := Do(context.Background(), 10*time.Millisecond, thunk, opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != "v2" {
+				t.Errorf("expected result %q, got %q", "v2", got)
+			}
+			late.Wait()
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if divergences != tc.wantDivergences {
+				t.Errorf("expected %d divergences, got %d", tc.wantDivergences, divergences)
+			}
+		})
+	}
+}
+
+func TestDoRaceWithDedup(t *testing.T) {
+	t.Parallel()
+
+	slow := func(val int) Thunk[int] {
+		return func(context.Context) (int, error) {
+			time.Sleep(30 * time.Millisecond)
+			return val, nil
+		}
+	}
+	fast := func(context.Context) (int, error) { return 2, nil }
+
+	var (
+		mu          sync.Mutex
+		divergences int
+		late        sync.WaitGroup
+	)
+	late.Add(3)
+	_, err := DoRace(context.Background(), 5*time.Millisecond, []Thunk[int]{slow(1), slow(3), slow(3), fast},
+		WithConsistencyCheck(func(a, b int) bool { return a == b }),
+		WithDedup[int](),
+		WithHooks(Hooks{
+			OnDivergence: func(Attempt, Attempt) {
+				mu.Lock()
+				defer mu.Unlock()
+				divergences++
+			},
+			OnLateResult: func(Attempt, time.Duration) { late.Done() },
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	late.Wait()
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if divergences != 2 {
+		t.Errorf("expected %d divergences, got %d", 2, divergences)
+	}
+}
@@ -0,0 +1,68 @@
+package speculatively
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults used by Default, which may be overridden by the corresponding
+// environment variables.
+const (
+	DefaultPatience    = 50 * time.Millisecond // SPECULATIVELY_PATIENCE
+	DefaultMaxAttempts = 2                     // SPECULATIVELY_MAX_ATTEMPTS
+	DefaultBudgetRatio = 0.1                   // SPECULATIVELY_BUDGET_RATIO
+	DefaultBudgetBurst = 10                    // SPECULATIVELY_BUDGET_BURST
+)
+
+var (
+	defaultHedger     *Hedger
+	defaultHedgerOnce sync.Once
+)
+
+// Default returns a process-wide Hedger, created on first use, so that small
+// programs get sensible hedging without explicit setup.
+//
+// It is configured with the Default* constants above, each of which may be
+// overridden by its environment variable.  Durations are parsed by
+// time.ParseDuration, and invalid values are ignored.  A budget ratio of 0
+// disables the budget.
+func Default() *Hedger {
+	defaultHedgerOnce.Do(func() {
+		defaultHedger = newDefaultHedger(os.Getenv)
+	})
+	return defaultHedger
+}
+
+// DoDefault speculatively executes a Thunk according to the policy of the
+// Default Hedger.  See Do for details.
+func DoDefault[T any](ctx context.Context, thunk Thunk[T]) (T, error) {
+	return DoWith(ctx, Default(), thunk)
+}
+
+func newDefaultHedger(getenv func(string) string) *Hedger {
+	patience := DefaultPatience
+	if d, err := time.ParseDuration(getenv("SPECULATIVELY_PATIENCE")); err == nil && d > 0 {
+		patience = d
+	}
+	maxAttempts := DefaultMaxAttempts
+	if n, err := strconv.Atoi(getenv("SPECULATIVELY_MAX_ATTEMPTS")); err == nil {
+		maxAttempts = n
+	}
+	ratio := DefaultBudgetRatio
+	if f, err := strconv.ParseFloat(getenv("SPECULATIVELY_BUDGET_RATIO"), 64); err == nil && f >= 0 {
+		ratio = f
+	}
+	burst := DefaultBudgetBurst
+	if n, err := strconv.Atoi(getenv("SPECULATIVELY_BUDGET_BURST")); err == nil {
+		burst = n
+	}
+
+	opts := []Option{WithMaxAttempts(maxAttempts)}
+	if ratio > 0 {
+		opts = append(opts, WithBudget(NewBudget(ratio, burst)))
+	}
+	return NewHedger(patience, opts...)
+}
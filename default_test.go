@@ -0,0 +1,74 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	if Default() != Default() {
+		t.Fatalf("expected Default to return the same Hedger every time")
+	}
+
+	thunk := newSimpleTestThunk(1, nil, 0)
+	val, err := DoDefault(context.Background(), thunk.call)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 1 {
+		t.Errorf("expected val = %d, got %d", 1, val)
+	}
+}
+
+func TestNewDefaultHedger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		h := newDefaultHedger(func(string) string { return "" })
+		cfg := newConfig(h.opts)
+		if h.Patience() != DefaultPatience {
+			t.Errorf("expected patience = %s, got %s", DefaultPatience, h.Patience())
+		}
+		if cfg.maxAttempts != DefaultMaxAttempts {
+			t.Errorf("expected max attempts = %d, got %d", DefaultMaxAttempts, cfg.maxAttempts)
+		}
+		if cfg.budget == nil || cfg.budget.ratio != DefaultBudgetRatio || cfg.budget.burst != DefaultBudgetBurst {
+			t.Errorf("expected default budget, got %+v", cfg.budget)
+		}
+	})
+
+	t.Run("environment overrides", func(t *testing.T) {
+		t.Parallel()
+
+		env := map[string]string{
+			"SPECULATIVELY_PATIENCE":     "10ms",
+			"SPECULATIVELY_MAX_ATTEMPTS": "3",
+			"SPECULATIVELY_BUDGET_RATIO": "0",
+		}
+		h := newDefaultHedger(func(k string) string { return env[k] })
+		cfg := newConfig(h.opts)
+		if h.Patience() != 10*time.Millisecond {
+			t.Errorf("expected patience = %s, got %s", 10*time.Millisecond, h.Patience())
+		}
+		if cfg.maxAttempts != 3 {
+			t.Errorf("expected max attempts = %d, got %d", 3, cfg.maxAttempts)
+		}
+		if cfg.budget != nil {
+			t.Errorf("expected budget to be disabled, got %+v", cfg.budget)
+		}
+	})
+
+	t.Run("invalid values ignored", func(t *testing.T) {
+		t.Parallel()
+
+		h := newDefaultHedger(func(string) string { return "invalid" })
+		if h.Patience() != DefaultPatience {
+			t.Errorf("expected patience = %s, got %s", DefaultPatience, h.Patience())
+		}
+	})
+}
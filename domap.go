@@ -0,0 +1,82 @@
+package speculatively
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KeyErrors holds the errors of every failed key of a call to DoMap.
+type KeyErrors[K comparable] map[K]error
+
+func (e KeyErrors[K]) Error() string {
+	msgs := make([]string, 0, len(e))
+	for k, err := range e {
+		msgs = append(msgs, fmt.Sprintf("key %v: %s", k, err))
+	}
+	sort.Strings(msgs)
+	return fmt.Sprintf("speculatively: %d keys failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the error of every failed key.
+func (e KeyErrors[K]) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// DoMap speculatively executes every given Thunk, hedging each one as Do
+// does, and returns their results by key.  At most as many Thunks as allowed
+// by WithConcurrency are executed at once, and the given Options otherwise
+// apply to each key's call, e.g. so that every call shares a Budget.
+//
+// Every Thunk is executed even if others fail.  If any of them fails, the
+// errors of the failed keys are returned as KeyErrors.  If ctx is done before
+// every Thunk is executed, its error is returned instead.
+//
+// If WithPartialResults is set, the results of every key that succeeded are
+// returned even if others failed, along with the KeyErrors of the failed
+// keys, and keys whose Thunk was not executed before ctx was done fail with
+// its error.
+func DoMap[K comparable, V any](ctx context.Context, patience time.Duration, thunks map[K]Thunk[V], opts ...Option) (map[K]V, error) {
+	cfg := newConfig(opts)
+	keys := make([]K, 0, len(thunks))
+	for k := range thunks {
+		keys = append(keys, k)
+	}
+	vals := make([]V, len(keys))
+	errs := make([]error, len(keys))
+	executed := make([]bool, len(keys))
+	err := forEachInput(ctx, cfg, len(keys), func(ctx context.Context, i int) {
+		executed[i] = true
+		vals[i], errs[i] = Do(ctx, patience, thunks[keys[i]], opts...)
+	})
+	if err != nil && !cfg.partialResults {
+		return nil, err
+	}
+
+	results := make(map[K]V, len(keys))
+	failed := KeyErrors[K]{}
+	for i, k := range keys {
+		if !executed[i] {
+			errs[i] = err
+		}
+		if errs[i] != nil {
+			failed[k] = errs[i]
+			continue
+		}
+		results[k] = vals[i]
+	}
+	switch {
+	case len(failed) == 0:
+		return results, nil
+	case cfg.partialResults:
+		return results, failed
+	default:
+		return nil, failed
+	}
+}
@@ -0,0 +1,113 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDoMap(t *testing.T) {
+	t.Parallel()
+
+	// The first attempt for every key is slow, so that each one is hedged
+	thunk := func(val int) Thunk[int] {
+		return func(ctx context.Context) (int, error) {
+			if !IsHedge(ctx) {
+				if err := sleep(ctx, time.Second); err != nil {
+					return 0, err
+				}
+			}
+			return val, nil
+		}
+	}
+	start := time.Now()
+	results, err := DoMap(context.Background(), 10*time.Millisecond, map[string]Thunk[int]{
+		"a": thunk(1),
+		"b": thunk(2),
+		"c": thunk(3),
+	}, WithConcurrency(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]int{"a": 1, "b": 2, "c": 3}
+	if len(results) != len(want) {
+		t.Errorf("expected results = %v, got %v", want, results)
+	}
+	for k, v := range want {
+		if results[k] != v {
+			t.Errorf("expected results[%q] = %d, got %d", k, v, results[k])
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected every key to be hedged, took %s", elapsed)
+	}
+}
+
+func TestDoMapErrors(t *testing.T) {
+	t.Parallel()
+
+	notFound := errors.New("not found")
+	results, err := DoMap(context.Background(), time.Second, map[string]Thunk[int]{
+		"a": newSimpleTestThunk(1, nil, 0).call,
+		"b": newSimpleTestThunk(0, notFound, 0).call,
+		"c": newSimpleTestThunk(0, notFound, 0).call,
+	})
+	if results != nil {
+		t.Errorf("expected no results, got %v", results)
+	}
+	var keyErrs KeyErrors[string]
+	if !errors.As(err, &keyErrs) {
+		t.Fatalf("expected KeyErrors, got %v", err)
+	}
+	if len(keyErrs) != 2 || keyErrs["b"] != notFound || keyErrs["c"] != notFound {
+		t.Errorf("expected keys b and c to fail, got %v", keyErrs)
+	}
+	want := "speculatively: 2 keys failed: key b: not found; key c: not found"
+	if err.Error() != want {
+		t.Errorf("expected error %q, got %q", want, err.Error())
+	}
+}
+
+func TestDoMapPartialResults(t *testing.T) {
+	t.Parallel()
+
+	notFound := errors.New("not found")
+	results, err := DoMap(context.Background(), time.Second, map[string]Thunk[int]{
+		"a": newSimpleTestThunk(1, nil, 0).call,
+		"b": newSimpleTestThunk(0, notFound, 0).call,
+		"c": newSimpleTestThunk(3, nil, 0).call,
+	}, WithPartialResults())
+	if want := map[string]int{"a": 1, "c": 3}; !reflect.DeepEqual(results, want) {
+		t.Errorf("expected results = %v, got %v", want, results)
+	}
+	var keyErrs KeyErrors[string]
+	if !errors.As(err, &keyErrs) {
+		t.Fatalf("expected KeyErrors, got %v", err)
+	}
+	if len(keyErrs) != 1 || keyErrs["b"] != notFound {
+		t.Errorf("expected key b to fail, got %v", keyErrs)
+	}
+}
+
+func TestDoMapPartialResultsContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	results, err := DoMap(ctx, time.Second, map[string]Thunk[int]{
+		"a": newSimpleTestThunk(1, nil, time.Second).call,
+		"b": newSimpleTestThunk(2, nil, time.Second).call,
+	}, WithConcurrency(1), WithPartialResults())
+	if len(results) != 0 {
+		t.Errorf("expected no results, got %v", results)
+	}
+	var keyErrs KeyErrors[string]
+	if !errors.As(err, &keyErrs) {
+		t.Fatalf("expected KeyErrors, got %v", err)
+	}
+	if len(keyErrs) != 2 || keyErrs["a"] != context.DeadlineExceeded || keyErrs["b"] != context.DeadlineExceeded {
+		t.Errorf("expected every key to fail with %v, got %v", context.DeadlineExceeded, keyErrs)
+	}
+}
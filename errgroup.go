@@ -0,0 +1,30 @@
+package speculatively
+
+import "context"
+
+// Group runs funcs in goroutines of its own, like errgroup.Group from
+// golang.org/x/sync, which implements it.
+type Group interface {
+	// Go calls f in a new goroutine, blocking first until the group
+	// allows another goroutine to run, e.g. as limited by SetLimit.
+	Go(f func() error)
+}
+
+// GroupRunner returns a Runner that executes attempts in the given Group, so
+// that applications that already limit their concurrency through an
+// errgroup.Group with SetLimit include attempts in that limit.
+//
+// Attempts never return an error to the Group, so that losing attempts
+// being canceled or failing does not cancel the context of a Group created
+// with errgroup.WithContext.  The Group's Wait waits for every attempt,
+// including those of losers still exiting.
+func GroupRunner(g Group) Runner {
+	return RunnerFunc(func(ctx context.Context, _ Attempt, run func(context.Context)) {
+		// Go blocks while the group is at its limit, which must not hold
+		// up the call launching the attempt
+		go g.Go(func() error {
+			run(ctx)
+			return nil
+		})
+	})
+}
@@ -0,0 +1,67 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// limitGroup is a Group that runs up to limit funcs at once, like an
+// errgroup.Group with SetLimit.
+type limitGroup struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+func (g *limitGroup) Go(f func() error) {
+	g.wg.Add(1)
+	g.sem <- struct{}{}
+	go func() {
+		defer func() { <-g.sem }()
+		defer g.wg.Done()
+		f() //nolint:errcheck
+	}()
+}
+
+func TestGroupRunner(t *testing.T) {
+	t.Parallel()
+
+	g := &limitGroup{sem: make(chan struct{}, 1)}
+	var attempts int64
+	release := make(chan struct{})
+	thunk := func(ctx context.Context) (int, error) {
+		atomic.AddInt64(&attempts, 1)
+		select {
+		case <-release:
+			return 1, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	// The group's limit holds back the hedge until the first attempt has
+	// finished
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		got, err := Do(context.Background(), 10*time.Millisecond, thunk, WithRunners(GroupRunner(g)), WithMaxAttempts(2))
+		if err != nil || got != 1 {
+			t.Errorf("expected result 1, got %d, %v", got, err)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt64(&attempts); n != 1 {
+		t.Errorf("expected 1 attempt within the group's limit, got %d", n)
+	}
+	close(release)
+	<-done
+
+	// The hedge still runs in the group once the first attempt's slot is
+	// free
+	for atomic.LoadInt64(&attempts) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	g.wg.Wait()
+}
@@ -0,0 +1,84 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// ErrorCategory is a normalized category of error, so that failure modes can
+// be broken down consistently.
+type ErrorCategory int
+
+// Error categories.
+const (
+	// ErrorApplication is any error not in another category.
+	ErrorApplication ErrorCategory = iota
+	// ErrorTimeout is a deadline or I/O timeout.
+	ErrorTimeout
+	// ErrorCanceled is a context cancelation.
+	ErrorCanceled
+	// ErrorConnection is a failure to establish or use a network connection.
+	ErrorConnection
+)
+
+func (c ErrorCategory) String() string {
+	switch c {
+	case ErrorApplication:
+		return "application"
+	case ErrorTimeout:
+		return "timeout"
+	case ErrorCanceled:
+		return "canceled"
+	case ErrorConnection:
+		return "connection"
+	default:
+		return "unknown"
+	}
+}
+
+// Categorizer derives the ErrorCategory of an error.
+type Categorizer func(error) ErrorCategory
+
+// WithErrorCategorizer sets the Categorizer used to derive the ErrorCategory
+// passed to the OnError hook, instead of CategorizeError.
+func WithErrorCategorizer(fn Categorizer) Option {
+	return func(c *config) {
+		c.categorizer = fn
+	}
+}
+
+// CategorizeError is the default Categorizer, which recognizes context
+// errors, timeouts reported by net.Error, and common connection failures.
+func CategorizeError(err error) ErrorCategory {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorConnection
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return ErrorConnection
+	}
+	return ErrorApplication
+}
+
+// categorize returns the ErrorCategory of the given error.
+func (c *config) categorize(err error) ErrorCategory {
+	if c.categorizer != nil {
+		return c.categorizer(err)
+	}
+	return CategorizeError(err)
+}
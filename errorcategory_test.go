@@ -0,0 +1,99 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCategorizeError(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		err  error
+		want ErrorCategory
+	}{
+		"canceled":           {context.Canceled, ErrorCanceled},
+		"deadline":           {fmt.Errorf("wrapped: %w", context.DeadlineExceeded), ErrorTimeout},
+		"net timeout":        {&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, ErrorTimeout},
+		"connection refused": {&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ErrorConnection},
+		"unexpected eof":     {io.ErrUnexpectedEOF, ErrorConnection},
+		"dns":                {&net.DNSError{Err: "no such host", Name: "example.invalid"}, ErrorConnection},
+		"application":        {errors.New("bad request"), ErrorApplication},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := CategorizeError(tc.err); got != tc.want {
+				t.Errorf("expected category = %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOnError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("default categorizer", func(t *testing.T) {
+		t.Parallel()
+
+		categories := make(chan ErrorCategory, 1)
+		hooks := Hooks{OnError: func(_ Attempt, c ErrorCategory, _ error) { categories <- c }}
+		thunk := newSimpleTestThunk(0, io.ErrUnexpectedEOF, 0)
+
+		if _, err := Do(context.Background(), time.Second, thunk.call, WithHooks(hooks)); err == nil {
+			t.Fatalf("expected error")
+		}
+		if c := <-categories; c != ErrorConnection {
+			t.Errorf("expected category = %s, got %s", ErrorConnection, c)
+		}
+	})
+
+	t.Run("custom categorizer", func(t *testing.T) {
+		t.Parallel()
+
+		categories := make(chan ErrorCategory, 1)
+		hooks := Hooks{OnError: func(_ Attempt, c ErrorCategory, _ error) { categories <- c }}
+		thunk := newSimpleTestThunk(0, io.ErrUnexpectedEOF, 0)
+		categorizer := func(error) ErrorCategory { return ErrorTimeout }
+
+		if _, err := Do(context.Background(), time.Second, thunk.call, WithHooks(hooks), WithErrorCategorizer(categorizer)); err == nil {
+			t.Fatalf("expected error")
+		}
+		if c := <-categories; c != ErrorTimeout {
+			t.Errorf("expected category = %s, got %s", ErrorTimeout, c)
+		}
+	})
+
+	t.Run("not called on success", func(t *testing.T) {
+		t.Parallel()
+
+		hooks := Hooks{OnError: func(Attempt, ErrorCategory, error) { t.Errorf("unexpected call to OnError") }}
+		if _, err := Do(context.Background(), time.Second, newSimpleTestThunk(1, nil, 0).call, WithHooks(hooks)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
+
+func TestErrorCategoryString(t *testing.T) {
+	t.Parallel()
+
+	for c, want := range map[ErrorCategory]string{
+		ErrorApplication:  "application",
+		ErrorTimeout:      "timeout",
+		ErrorCanceled:     "canceled",
+		ErrorConnection:   "connection",
+		ErrorCategory(-1): "unknown",
+	} {
+		if got := c.String(); got != want {
+			t.Errorf("expected %d.String() = %q, got %q", c, want, got)
+		}
+	}
+}
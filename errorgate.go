@@ -0,0 +1,100 @@
+package speculatively
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// errorGateBuckets is the number of buckets an ErrorGate's window is
+	// divided into.
+	errorGateBuckets = 10
+	// minErrorGateSamples is the number of attempts an ErrorGate must observe
+	// within its window before it will close.
+	minErrorGateSamples = 10
+)
+
+// ErrorGate suppresses hedging while the recent error rate of attempts is too
+// high, since extra attempts against a failing dependency mostly add load
+// rather than successes.  Hedging resumes once the error rate recovers.  It
+// is independent of any latency-based policy.
+//
+// An ErrorGate is safe for concurrent use and is typically shared across many
+// calls.
+type ErrorGate struct {
+	threshold float64
+	width     time.Duration
+	buckets   [errorGateBuckets]errorGateBucket
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+type errorGateBucket struct {
+	start  time.Time
+	total  int
+	errors int
+}
+
+// NewErrorGate creates an ErrorGate that suppresses hedging while more than
+// the given fraction (between 0.0 and 1.0) of the attempts completed within
+// the given sliding window failed.
+func NewErrorGate(threshold float64, window time.Duration) *ErrorGate {
+	width := window / errorGateBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return &ErrorGate{
+		threshold: threshold,
+		width:     width,
+		now:       time.Now,
+	}
+}
+
+// WithErrorGate suppresses hedges while the given ErrorGate is closed, and
+// records the outcome of every attempt in it.
+func WithErrorGate(g *ErrorGate) Option {
+	return func(c *config) {
+		c.errorGate = g
+	}
+}
+
+// Open reports whether hedging is currently allowed.
+func (g *ErrorGate) Open() bool {
+	return g.openAt(g.now())
+}
+
+// openAt reports whether hedging is allowed at the given time, e.g. according
+// to the Clock of a call.
+func (g *ErrorGate) openAt(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var total, errors int
+	for _, b := range g.buckets {
+		if now.Sub(b.start) < g.width*errorGateBuckets {
+			total += b.total
+			errors += b.errors
+		}
+	}
+	return total < minErrorGateSamples || float64(errors)/float64(total) <= g.threshold
+}
+
+// record adds the outcome of an attempt to the current bucket.
+func (g *ErrorGate) record(failed bool) {
+	g.recordAt(g.now(), failed)
+}
+
+// recordAt adds the outcome of an attempt that completed at the given time to
+// its bucket.
+func (g *ErrorGate) recordAt(now time.Time, failed bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	start := now.Truncate(g.width)
+	b := &g.buckets[int(start.UnixNano()/int64(g.width))%errorGateBuckets]
+	if !b.start.Equal(start) {
+		*b = errorGateBucket{start: start}
+	}
+	b.total++
+	if failed {
+		b.errors++
+	}
+}
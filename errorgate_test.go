@@ -0,0 +1,69 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestErrorGate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	g := NewErrorGate(0.5, time.Second)
+	g.now = func() time.Time { return now }
+
+	for i := 0; i < minErrorGateSamples-1; i++ {
+		g.record(true)
+	}
+	if !g.Open() {
+		t.Fatalf("expected gate to stay open with too few samples")
+	}
+
+	g.record(true)
+	if g.Open() {
+		t.Fatalf("expected gate to close when error rate exceeds threshold")
+	}
+
+	// Successes bring the error rate back down to the threshold
+	for i := 0; i < minErrorGateSamples; i++ {
+		g.record(false)
+	}
+	if !g.Open() {
+		t.Fatalf("expected gate to open once error rate recovers")
+	}
+
+	// Errors age out of the window
+	for i := 0; i < 3*minErrorGateSamples; i++ {
+		g.record(true)
+	}
+	if g.Open() {
+		t.Fatalf("expected gate to close when error rate exceeds threshold")
+	}
+	now = now.Add(time.Second)
+	if !g.Open() {
+		t.Fatalf("expected gate to open once errors leave the window")
+	}
+}
+
+func TestWithErrorGate(t *testing.T) {
+	t.Parallel()
+
+	g := NewErrorGate(0.1, time.Minute)
+	failing := newSimpleTestThunk(0, errors.New("error"), 0)
+	for i := 0; i < minErrorGateSamples; i++ {
+		Do(context.Background(), time.Second, failing.call, WithErrorGate(g)) //nolint:errcheck
+	}
+	if g.Open() {
+		t.Fatalf("expected failing attempts to close the gate")
+	}
+
+	thunk := newSimpleTestThunk(1, nil, 30*time.Millisecond)
+	if _, err := Do(context.Background(), 5*time.Millisecond, thunk.call, WithErrorGate(g)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if callCount := thunk.callCount(); callCount != 1 {
+		t.Errorf("expected closed gate to suppress hedges, got %d calls", callCount)
+	}
+}
@@ -0,0 +1,154 @@
+package speculatively
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventKind is the kind of an Event.
+type EventKind int
+
+// Event kinds.
+const (
+	// EventLaunch means an attempt was launched.
+	EventLaunch EventKind = iota
+	// EventDone means an attempt's Thunk returned.
+	EventDone
+	// EventWinner means an attempt's successful result was returned.
+	EventWinner
+	// EventSuppressed means a hedge was due but was not launched.
+	EventSuppressed
+	// EventTermination means a call ended, classifying how an attempt
+	// ended.
+	EventTermination
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventLaunch:
+		return "launch"
+	case EventDone:
+		return "done"
+	case EventWinner:
+		return "winner"
+	case EventSuppressed:
+		return "suppressed"
+	case EventTermination:
+		return "termination"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a scheduling decision or outcome recorded by an EventLog.  Fields
+// that do not apply to its Kind are zero.
+type Event struct {
+	Time        time.Time
+	Kind        EventKind
+	Attempt     Attempt
+	Elapsed     time.Duration
+	Err         error
+	Suppression Suppression
+	Termination Termination
+}
+
+func (e Event) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", e.Time.Format(time.RFC3339Nano), e.Kind)
+	if e.Kind == EventSuppressed {
+		fmt.Fprintf(&b, " reason=%s", e.Suppression)
+		return b.String()
+	}
+	fmt.Fprintf(&b, " call=%d attempt=%d", e.Attempt.Call, e.Attempt.Index)
+	if e.Attempt.Target != nil {
+		fmt.Fprintf(&b, " target=%v", e.Attempt.Target)
+	}
+	switch e.Kind {
+	case EventDone:
+		fmt.Fprintf(&b, " elapsed=%s", e.Elapsed)
+		if e.Err != nil {
+			fmt.Fprintf(&b, " err=%q", e.Err)
+		}
+	case EventTermination:
+		fmt.Fprintf(&b, " termination=%s", e.Termination)
+	}
+	return b.String()
+}
+
+// EventLog keeps a fixed-size ring of the most recent scheduler events, so
+// that the decisions leading up to a hedging-related incident can be
+// reconstructed after the fact without debug logging having been enabled.
+//
+// An EventLog is safe for concurrent use.
+type EventLog struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+	full   bool
+}
+
+// NewEventLog creates an EventLog keeping the given number of most recent
+// events.
+func NewEventLog(size int) *EventLog {
+	if size < 1 {
+		size = 1
+	}
+	return &EventLog{events: make([]Event, size)}
+}
+
+// WithEventLog records the events of calls in the given EventLog.  Unlike
+// metrics, events are recorded for every call, regardless of
+// WithTelemetrySampling.
+func WithEventLog(l *EventLog) Option {
+	return WithHooks(Hooks{
+		OnLaunch: func(a Attempt) {
+			l.record(Event{Kind: EventLaunch, Attempt: a})
+		},
+		OnDone: func(a Attempt, elapsed time.Duration, err error) {
+			l.record(Event{Kind: EventDone, Attempt: a, Elapsed: elapsed, Err: err})
+		},
+		OnWinner: func(a Attempt) {
+			l.record(Event{Kind: EventWinner, Attempt: a})
+		},
+		OnHedgeSuppressed: func(s Suppression) {
+			l.record(Event{Kind: EventSuppressed, Suppression: s})
+		},
+		OnTermination: func(a Attempt, t Termination) {
+			l.record(Event{Kind: EventTermination, Attempt: a, Termination: t})
+		},
+	})
+}
+
+// Events returns the events kept, from oldest to newest.
+func (l *EventLog) Events() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]Event(nil), l.events[:l.next]...)
+	}
+	return append(append([]Event(nil), l.events[l.next:]...), l.events[:l.next]...)
+}
+
+// Dump writes the events kept to w, one per line, from oldest to newest.
+func (l *EventLog) Dump(w io.Writer) error {
+	for _, e := range l.Events() {
+		if _, err := fmt.Fprintln(w, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *EventLog) record(e Event) {
+	e.Time = time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events[l.next] = e
+	l.next = (l.next + 1) % len(l.events)
+	if l.next == 0 {
+		l.full = true
+	}
+}
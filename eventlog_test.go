@@ -0,0 +1,81 @@
+package speculatively
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventLog(t *testing.T) {
+	t.Parallel()
+
+	l := NewEventLog(100)
+	thunk := newTestThunk([]result[int]{{val: 1}, {val: 2}}, []time.Duration{time.Second, 5 * time.Millisecond})
+	if _, err := Do(context.Background(), 10*time.Millisecond, thunk.call, WithEventLog(l), WithMaxAttempts(2)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var kinds []string
+	for _, e := range l.Events() {
+		if e.Time.IsZero() {
+			t.Errorf("expected event time to be set")
+		}
+		// The losing attempt may or may not have observed cancelation yet
+		if e.Kind == EventDone && e.Attempt.Index == 0 {
+			continue
+		}
+		kinds = append(kinds, e.Kind.String())
+	}
+	want := "launch launch done winner termination termination"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Errorf("expected events %q, got %q", want, got)
+	}
+
+	var buf bytes.Buffer
+	if err := l.Dump(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(buf.String(), "winner call=") || !strings.Contains(buf.String(), "attempt=1") {
+		t.Errorf("expected dump to describe winner, got:\n%s", buf.String())
+	}
+}
+
+func TestEventLogRing(t *testing.T) {
+	t.Parallel()
+
+	l := NewEventLog(2)
+	l.record(Event{Kind: EventLaunch})
+	l.record(Event{Kind: EventDone, Err: errors.New("fail")})
+	l.record(Event{Kind: EventSuppressed, Suppression: SuppressedByBudget})
+
+	events := l.Events()
+	if len(events) != 2 || events[0].Kind != EventDone || events[1].Kind != EventSuppressed {
+		t.Fatalf("expected the 2 most recent events, got %+v", events)
+	}
+	if s := events[0].String(); !strings.Contains(s, `err="fail"`) {
+		t.Errorf("expected error in %q", s)
+	}
+	if s := events[1].String(); !strings.HasSuffix(s, "suppressed reason=budget") {
+		t.Errorf("expected suppression reason in %q", s)
+	}
+}
+
+func TestEventKindString(t *testing.T) {
+	t.Parallel()
+
+	for k, want := range map[EventKind]string{
+		EventLaunch:      "launch",
+		EventDone:        "done",
+		EventWinner:      "winner",
+		EventSuppressed:  "suppressed",
+		EventTermination: "termination",
+		EventKind(-1):    "unknown",
+	} {
+		if got := k.String(); got != want {
+			t.Errorf("expected %d.String() = %q, got %q", k, want, got)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Command fakeupstream is an example HTTP server with a configurable,
+// long-tailed latency distribution, for use as an upstream of hedgeproxy.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+func main() {
+	var (
+		addr     = flag.String("addr", ":9001", "address to listen on")
+		base     = flag.Duration("base", 5*time.Millisecond, "latency of a typical request")
+		jitter   = flag.Duration("jitter", 5*time.Millisecond, "random latency added to every request")
+		tail     = flag.Duration("tail", 200*time.Millisecond, "latency of a request in the tail")
+		tailRate = flag.Float64("tail-rate", 0.02, "fraction of requests in the tail")
+	)
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		latency := *base
+		if *jitter > 0 {
+			latency += time.Duration(rand.Int63n(int64(*jitter)))
+		}
+		if rand.Float64() < *tailRate {
+			latency = *tail
+		}
+
+		select {
+		case <-time.After(latency):
+			fmt.Fprintf(w, "served by %s after %s\n", *addr, latency)
+		case <-r.Context().Done():
+		}
+	})
+
+	log.Printf("serving on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
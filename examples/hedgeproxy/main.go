@@ -0,0 +1,148 @@
+// Command hedgeproxy is an example HTTP proxy that speculatively executes
+// every request against a set of interchangeable upstreams, so that the
+// effect of different hedging configurations can be evaluated empirically.
+//
+// Example usage, with upstreams started via fakeupstream and load generated
+// via loadgen:
+//
+//	go run ./examples/fakeupstream -addr :9001 &
+//	go run ./examples/fakeupstream -addr :9002 &
+//	go run ./examples/hedgeproxy -upstreams http://localhost:9001,http://localhost:9002 -patience 20ms &
+//	go run ./examples/loadgen -url http://localhost:8080/
+//
+// Hedging activity is published via expvar at /debug/vars.
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"github.com/mccutchen/speculatively/speculativehttp"
+)
+
+var (
+	requests = expvar.NewInt("hedgeproxy.requests")
+	attempts = expvar.NewInt("hedgeproxy.attempts")
+	hedges   = expvar.NewInt("hedgeproxy.hedges")
+	losers   = expvar.NewInt("hedgeproxy.losers")
+	failures = expvar.NewInt("hedgeproxy.failures")
+)
+
+func main() {
+	var (
+		addr        = flag.String("addr", ":8080", "address to listen on")
+		upstreams   = flag.String("upstreams", "", "comma-separated list of upstream base URLs")
+		patience    = flag.Duration("patience", 20*time.Millisecond, "how long to wait before hedging a request")
+		quantile    = flag.Float64("adaptive-quantile", 0.95, "latency quantile used as adaptive patience, with -patience as fallback (0 to disable)")
+		maxAttempts = flag.Int("max-attempts", 2, "maximum attempts per request (0 for no limit)")
+		budgetRatio = flag.Float64("budget-ratio", 0.1, "hedges allowed per request (0 for no budget)")
+		budgetBurst = flag.Int("budget-burst", 10, "hedges that may be banked for bursts of slow requests")
+	)
+	flag.Parse()
+
+	var transports []http.RoundTripper
+	for _, s := range strings.Split(*upstreams, ",") {
+		if s == "" {
+			continue
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			log.Fatalf("invalid upstream %q: %s", s, err)
+		}
+		transports = append(transports, &upstream{target: u})
+	}
+	if len(transports) == 0 {
+		log.Fatal("at least one upstream is required")
+	}
+
+	opts := []speculatively.Option{
+		speculatively.WithMaxAttempts(*maxAttempts),
+		speculatively.WithRetryable(func(err error) bool { return !errors.Is(err, context.Canceled) }),
+		speculatively.WithHooks(speculatively.Hooks{
+			OnLaunch: func(a speculatively.Attempt) {
+				attempts.Add(1)
+				if a.Index > 0 {
+					hedges.Add(1)
+				}
+			},
+			OnLoser: func(speculatively.Attempt) { losers.Add(1) },
+		}),
+	}
+	if *budgetRatio > 0 {
+		opts = append(opts, speculatively.WithBudget(speculatively.NewBudget(*budgetRatio, *budgetBurst)))
+	}
+	if *quantile > 0 {
+		opts = append(opts, speculatively.WithAdaptivePatience(speculatively.NewLatencyTracker(1000), *quantile))
+	}
+
+	// Successive attempts of each request are sent to one upstream after
+	// another, and the winning response stays readable until it has been
+	// copied to the client
+	p := &proxy{
+		transport: &speculativehttp.RoundTripper{
+			Transports: transports,
+			Patience:   *patience,
+			Options:    opts,
+		},
+	}
+	http.Handle("/", p)
+
+	log.Printf("proxying %s to %d upstreams with %s patience", *addr, len(transports), *patience)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// upstream sends requests to its target rather than to their own URL.
+type upstream struct {
+	target *url.URL
+}
+
+func (u *upstream) RoundTrip(r *http.Request) (*http.Response, error) {
+	req := r.Clone(r.Context())
+	req.RequestURI = ""
+	req.URL.Scheme = u.target.Scheme
+	req.URL.Host = u.target.Host
+	req.Host = u.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+type proxy struct {
+	transport http.RoundTripper
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "only GET and HEAD requests may be hedged", http.StatusMethodNotAllowed)
+		return
+	}
+	requests.Add(1)
+
+	req := r.Clone(r.Context())
+	req.RequestURI = ""
+	resp, err := p.transport.RoundTrip(req)
+	if err != nil {
+		failures.Add(1)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		failures.Add(1)
+		log.Printf("error copying response to %s: %s", r.URL, err)
+	}
+}
@@ -0,0 +1,76 @@
+// Command loadgen is an example load generator that reports the latency
+// distribution of requests to a URL, e.g. one served by hedgeproxy.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+func main() {
+	var (
+		target      = flag.String("url", "http://localhost:8080/", "URL to request")
+		concurrency = flag.Int("concurrency", 10, "number of concurrent clients")
+		duration    = flag.Duration("duration", 10*time.Second, "how long to generate load")
+	)
+	flag.Parse()
+
+	var (
+		latencies []time.Duration
+		errors    int
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		deadline  = time.Now().Add(*duration)
+	)
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				start := time.Now()
+				err := get(*target)
+				elapsed := time.Since(start)
+
+				mu.Lock()
+				if err != nil {
+					errors++
+				} else {
+					latencies = append(latencies, elapsed)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(latencies) == 0 {
+		log.Fatalf("no successful requests (%d errors)", errors)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	fmt.Printf("requests: %d ok, %d errors\n", len(latencies), errors)
+	for _, q := range []float64{0.5, 0.9, 0.95, 0.99, 0.999} {
+		fmt.Printf("p%-5g %s\n", q*100, latencies[int(q*float64(len(latencies)-1))])
+	}
+	fmt.Printf("max    %s\n", latencies[len(latencies)-1])
+}
+
+func get(url string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
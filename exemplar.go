@@ -0,0 +1,65 @@
+package speculatively
+
+import (
+	"sync"
+	"time"
+)
+
+// Exemplars keeps a Report of the most recent calls that were slower than a
+// threshold, so that tail latency incidents can be reconstructed after the
+// fact.
+//
+// Exemplars are safe for concurrent use.
+type Exemplars struct {
+	threshold time.Duration
+
+	mu      sync.Mutex
+	reports []Report
+	next    int
+	full    bool
+}
+
+// NewExemplars creates Exemplars keeping the given number of the most recent
+// calls that took longer than the given threshold.
+func NewExemplars(threshold time.Duration, size int) *Exemplars {
+	if size < 1 {
+		size = 1
+	}
+	return &Exemplars{
+		threshold: threshold,
+		reports:   make([]Report, size),
+	}
+}
+
+// WithExemplars records a Report of every call slower than the threshold of
+// the given Exemplars.
+func WithExemplars(e *Exemplars) Option {
+	return func(c *config) {
+		c.exemplars = e
+	}
+}
+
+// Recent returns the Reports kept, from oldest to newest.
+func (e *Exemplars) Recent() []Report {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.full {
+		return append([]Report(nil), e.reports[:e.next]...)
+	}
+	return append(append([]Report(nil), e.reports[e.next:]...), e.reports[:e.next]...)
+}
+
+// slow reports whether a call of the given duration should be recorded.
+func (e *Exemplars) slow(d time.Duration) bool {
+	return d > e.threshold
+}
+
+func (e *Exemplars) record(r Report) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reports[e.next] = r
+	e.next = (e.next + 1) % len(e.reports)
+	if e.next == 0 {
+		e.full = true
+	}
+}
@@ -0,0 +1,39 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExemplars(t *testing.T) {
+	t.Parallel()
+
+	e := NewExemplars(10*time.Millisecond, 2)
+	fast := newSimpleTestThunk(1, nil, 0)
+	if _, err := Do(context.Background(), time.Second, fast.call, WithExemplars(e)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if reports := e.Recent(); len(reports) != 0 {
+		t.Fatalf("expected fast call not to be recorded, got %+v", reports)
+	}
+
+	for _, delay := range []time.Duration{15 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond} {
+		slow := newSimpleTestThunk(1, nil, delay)
+		if _, err := Do(context.Background(), time.Second, slow.call, WithExemplars(e)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// Only the 2 most recent slow calls are kept, oldest first
+	reports := e.Recent()
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(reports))
+	}
+	if !reports[0].Start.Before(reports[1].Start) {
+		t.Errorf("expected reports to be ordered oldest first")
+	}
+	if d := reports[0].Duration; d < 20*time.Millisecond {
+		t.Errorf("expected oldest report to be of the ~20ms call, got %s", d)
+	}
+}
@@ -0,0 +1,69 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// expvarMu serializes looking up and publishing expvar maps.
+var expvarMu sync.Mutex
+
+// WithExpvar publishes counters describing hedging activity via expvar, in a
+// map under the given name, so that existing /debug/vars scraping picks them
+// up.  Every call and Hedger using the same name shares the same counters:
+//
+//   - calls: calls started
+//   - attempts: attempts launched, including hedges
+//   - hedges: hedged attempts launched
+//   - wins: calls that succeeded
+//   - hedge_wins: calls won by a hedged attempt
+//   - cancellations: attempts that ended with a context error
+//
+// It panics if the name is already published as something other than a map.
+func WithExpvar(name string) Option {
+	return WithMetrics(&expvarRecorder{m: expvarMap(name)})
+}
+
+func expvarMap(name string) *expvar.Map {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if v := expvar.Get(name); v != nil {
+		m, ok := v.(*expvar.Map)
+		if !ok {
+			panic(fmt.Sprintf("speculatively: expvar %q is a %T, not a map", name, v))
+		}
+		return m
+	}
+	return expvar.NewMap(name)
+}
+
+type expvarRecorder struct {
+	NopMetricsRecorder
+	m *expvar.Map
+}
+
+func (r *expvarRecorder) RecordAttempt(a Attempt) {
+	if a.Index == 0 {
+		r.m.Add("calls", 1)
+	} else {
+		r.m.Add("hedges", 1)
+	}
+	r.m.Add("attempts", 1)
+}
+
+func (r *expvarRecorder) RecordAttemptDone(_ Attempt, _ time.Duration, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		r.m.Add("cancellations", 1)
+	}
+}
+
+func (r *expvarRecorder) RecordWinner(a Attempt) {
+	r.m.Add("wins", 1)
+	if a.Index > 0 {
+		r.m.Add("hedge_wins", 1)
+	}
+}
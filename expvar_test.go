@@ -0,0 +1,63 @@
+package speculatively
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWithExpvar(t *testing.T) {
+	t.Parallel()
+
+	thunk := func(ctx context.Context) (int, error) {
+		if IsHedge(ctx) {
+			return 2, nil
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	// Counters accumulate across test runs, so every run needs its own name
+	name := fmt.Sprintf("speculatively_test.expvar.%d", time.Now().UnixNano())
+	for i := 0; i < 2; i++ {
+		if _, err := Do(context.Background(), 5*time.Millisecond, thunk, WithExpvar(name), WithMaxAttempts(2)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	m := expvar.Get(name).(*expvar.Map)
+	for key, want := range map[string]int64{
+		"calls":      2,
+		"attempts":   4,
+		"hedges":     2,
+		"wins":       2,
+		"hedge_wins": 2,
+	} {
+		if got := m.Get(key).(*expvar.Int).Value(); got != want {
+			t.Errorf("expected %s = %d, got %d", key, want, got)
+		}
+	}
+
+	// Losers observe cancelation asynchronously
+	for deadline := time.Now().Add(time.Second); m.Get("cancellations") == nil && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if v := m.Get("cancellations"); v == nil || v.(*expvar.Int).Value() < 1 {
+		t.Errorf("expected cancellations to be counted, got %v", v)
+	}
+}
+
+func TestWithExpvarConflict(t *testing.T) {
+	t.Parallel()
+
+	if expvar.Get("speculatively_test.conflict") == nil {
+		expvar.NewInt("speculatively_test.conflict")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	WithExpvar("speculatively_test.conflict")
+}
@@ -0,0 +1,75 @@
+package speculatively
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ItemError is the error returned for a single item of a batch.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e ItemError) Error() string {
+	return fmt.Sprintf("item %d: %s", e.Index, e.Err)
+}
+
+func (e ItemError) Unwrap() error {
+	return e.Err
+}
+
+// ItemErrors holds the errors of every failed item of a batch, in the order
+// of the items.
+type ItemErrors []ItemError
+
+func (e ItemErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("speculatively: %d items failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the error of every failed item.
+func (e ItemErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ForEach speculatively calls fn with every item, hedging each call as Do
+// does, for side-effecting work across many items.  Since hedged calls of fn
+// run concurrently, fn must be safe to call more than once for the same
+// item.  At most as many items as allowed by WithConcurrency are processed
+// at once, and the given Options otherwise apply to each item's call.
+//
+// Unlike Map, a failed item does not stop the processing of the others: once
+// every item has been processed, the errors of the failed items, if any, are
+// returned as ItemErrors.  If ctx is done before every item is processed,
+// its error is returned instead.
+func ForEach[I any](ctx context.Context, patience time.Duration, items []I, fn func(context.Context, I) error, opts ...Option) error {
+	errs := make([]error, len(items))
+	err := forEachInput(ctx, newConfig(opts), len(items), func(ctx context.Context, i int) {
+		_, errs[i] = Do(ctx, patience, func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, fn(ctx, items[i])
+		}, opts...)
+	})
+	if err != nil {
+		return err
+	}
+	var failed ItemErrors
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, ItemError{Index: i, Err: err})
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
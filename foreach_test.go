@@ -0,0 +1,95 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEach(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	done := map[int]int{}
+	var running, peak int64
+	err := ForEach(context.Background(), 10*time.Millisecond, []int{1, 2, 3, 4, 5, 6}, func(ctx context.Context, item int) error {
+		n := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		// The first attempt for every item is slow, so that each one is
+		// hedged
+		if !IsHedge(ctx) {
+			if err := sleep(ctx, time.Second); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done[item]++
+		return nil
+	}, WithConcurrency(3), WithMaxAttempts(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for item := 1; item <= 6; item++ {
+		if done[item] != 1 {
+			t.Errorf("expected item %d to be done once, got %d", item, done[item])
+		}
+	}
+	if got := atomic.LoadInt64(&peak); got > 6 {
+		t.Errorf("expected at most %d attempts running at once, got %d", 6, got)
+	}
+}
+
+func TestForEachErrors(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	var processed int64
+	err := ForEach(context.Background(), time.Second, []int{1, 2, 3, 4}, func(ctx context.Context, item int) error {
+		atomic.AddInt64(&processed, 1)
+		if item%2 == 0 {
+			return failed
+		}
+		return nil
+	}, WithConcurrency(1))
+
+	var itemErrs ItemErrors
+	if !errors.As(err, &itemErrs) {
+		t.Fatalf("expected ItemErrors, got %v", err)
+	}
+	if len(itemErrs) != 2 || itemErrs[0].Index != 1 || itemErrs[1].Index != 3 {
+		t.Errorf("expected items 1 and 3 to fail, got %v", itemErrs)
+	}
+	if !errors.Is(itemErrs[0], failed) {
+		t.Errorf("expected item error to wrap %v, got %v", failed, itemErrs[0])
+	}
+	if got := atomic.LoadInt64(&processed); got != 4 {
+		t.Errorf("expected every item to be processed, got %d", got)
+	}
+	want := "speculatively: 2 items failed: item 1: failed; item 3: failed"
+	if err.Error() != want {
+		t.Errorf("expected error %q, got %q", want, err.Error())
+	}
+}
+
+func TestForEachContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := ForEach(ctx, time.Second, []int{1, 2, 3}, func(ctx context.Context, item int) error {
+		return sleep(ctx, time.Second)
+	}, WithConcurrency(1))
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected err = %v, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,49 @@
+package speculatively
+
+import "time"
+
+// Timestamped may be implemented by results that know when the data they
+// hold was produced, for use with WithMaxStaleness.
+type Timestamped interface {
+	Timestamp() time.Time
+}
+
+// WithMaxStaleness rejects successful results implementing Timestamped whose
+// timestamp is older than the given staleness bound.  Rather than ending the
+// call, a stale result immediately launches the next attempt, if any, and the
+// race continues for a fresher result.
+//
+// If no fresh result arrives before the attempts are exhausted, an attempt
+// fails or the context is done, the freshest stale result is returned
+// instead.
+func WithMaxStaleness(d time.Duration) Option {
+	return func(c *config) {
+		c.staleness = d
+	}
+}
+
+// isStale reports whether val is a Timestamped result older than the
+// configured staleness bound.
+func (c *config) isStale(val interface{}) bool {
+	if c.staleness <= 0 {
+		return false
+	}
+	ts, ok := c.asTimestamped(val)
+	return ok && c.since(ts.Timestamp()) > c.staleness
+}
+
+// fresher reports whether the Timestamped result a is fresher than b.
+func (c *config) fresher(a, b interface{}) bool {
+	tsA, _ := c.asTimestamped(a)
+	tsB, _ := c.asTimestamped(b)
+	return tsA.Timestamp().After(tsB.Timestamp())
+}
+
+// asTimestamped returns val as a Timestamped result, if it is one.
+func (c *config) asTimestamped(val interface{}) (Timestamped, bool) {
+	if c.timestamped != nil {
+		return c.timestamped(val)
+	}
+	ts, ok := val.(Timestamped)
+	return ts, ok
+}
@@ -0,0 +1,109 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type timestamped struct {
+	id  int
+	age time.Duration
+	ts  time.Time
+}
+
+func (t timestamped) Timestamp() time.Time {
+	return t.ts
+}
+
+// newFreshnessThunk returns a Thunk whose successive attempts produce results
+// of the given ages after the given delay.
+func newFreshnessThunk(delay time.Duration, ages ...time.Duration) *testThunk {
+	results := make([]result[int], len(ages))
+	for i := range ages {
+		results[i] = result[int]{val: i}
+	}
+	return newTestThunk(results, []time.Duration{delay})
+}
+
+func TestWithMaxStaleness(t *testing.T) {
+	t.Parallel()
+
+	doTimestamped := func(ctx context.Context, ages []time.Duration, opts ...Option) (timestamped, error) {
+		thunk := newFreshnessThunk(0, ages...)
+		return Do(ctx, time.Second, func(ctx context.Context) (timestamped, error) {
+			id, err := thunk.call(ctx)
+			if err != nil {
+				return timestamped{}, err
+			}
+			age := ages[id]
+			return timestamped{id: id, age: age, ts: time.Now().Add(-age)}, nil
+		}, opts...)
+	}
+
+	t.Run("stale result triggers race for a fresh one", func(t *testing.T) {
+		t.Parallel()
+
+		ages := []time.Duration{time.Hour, time.Second}
+		val, err := doTimestamped(context.Background(), ages, WithMaxStaleness(time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val.id != 1 {
+			t.Errorf("expected fresh result from attempt 1, got %+v", val)
+		}
+	})
+
+	t.Run("freshest stale result returned when attempts exhausted", func(t *testing.T) {
+		t.Parallel()
+
+		ages := []time.Duration{2 * time.Hour, time.Hour, 3 * time.Hour}
+		cleaned := make(chan timestamped, len(ages))
+		val, err := doTimestamped(context.Background(), ages,
+			WithMaxStaleness(time.Minute),
+			WithMaxAttempts(3),
+			WithCleanup(func(v timestamped) { cleaned <- v }),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val.id != 1 {
+			t.Errorf("expected freshest stale result from attempt 1, got %+v", val)
+		}
+		if n := len(cleaned); n != 2 {
+			t.Errorf("expected 2 discarded results to be cleaned up, got %d", n)
+		}
+	})
+
+	t.Run("stale result preferred over error", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int
+		val, err := Do(context.Background(), time.Second, func(ctx context.Context) (timestamped, error) {
+			calls++
+			if calls > 1 {
+				return timestamped{}, errors.New("error")
+			}
+			return timestamped{ts: time.Now().Add(-time.Hour)}, nil
+		}, WithMaxStaleness(time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val.ts.IsZero() {
+			t.Errorf("expected stale result, got %+v", val)
+		}
+	})
+
+	t.Run("results without timestamps are never stale", func(t *testing.T) {
+		t.Parallel()
+
+		thunk := newSimpleTestThunk(1, nil, 0)
+		if _, err := Do(context.Background(), time.Second, thunk.call, WithMaxStaleness(time.Nanosecond)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if callCount := thunk.callCount(); callCount != 1 {
+			t.Errorf("expected Thunk to run %d times, got %d", 1, callCount)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+/*
+Package grpcspeculatively provides gRPC client interceptors that hedge calls
+using speculatively.
+*/
+package grpcspeculatively
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// UnaryClientInterceptor hedges unary calls, invoking each call again in
+// parallel every time patience elapses without a reply, up to the limits set
+// by the given Options.  The first reply (or error) is returned.
+//
+// Only use it for idempotent methods, since hedged calls may be executed by
+// the server more than once.
+//
+// Call options that receive the header, trailer or peer of a call, e.g.
+// grpc.Header, receive those of the winning attempt only.
+func UnaryClientInterceptor(patience time.Duration, opts ...speculatively.Option) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		msg, ok := reply.(proto.Message)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		typ := msg.ProtoReflect().Type()
+		winner, err := speculatively.Do(ctx, patience, func(ctx context.Context) (unaryReply, error) {
+			attemptOpts, publish := privateCallOptions(callOpts)
+			r := typ.New().Interface()
+			return unaryReply{msg: r, publish: publish}, invoker(ctx, method, req, r, cc, attemptOpts...)
+		}, opts...)
+		if winner.publish != nil {
+			winner.publish()
+		}
+		if err != nil {
+			return err
+		}
+		proto.Reset(msg)
+		proto.Merge(msg, winner.msg)
+		return nil
+	}
+}
+
+// unaryReply is the reply received by an attempt of a unary call, along with
+// a func that publishes the attempt's call options.
+type unaryReply struct {
+	msg     proto.Message
+	publish func()
+}
+
+// privateCallOptions returns a copy of opts in which the options that receive
+// the header, trailer or peer of a call receive them into values private to
+// an attempt instead, so that concurrent attempts do not race, along with a
+// func that copies the private values into those of the original options.
+func privateCallOptions(opts []grpc.CallOption) ([]grpc.CallOption, func()) {
+	private := make([]grpc.CallOption, len(opts))
+	var copies []func()
+	for i, opt := range opts {
+		switch o := opt.(type) {
+		case grpc.HeaderCallOption:
+			md := &metadata.MD{}
+			private[i] = grpc.Header(md)
+			copies = append(copies, func() { *o.HeaderAddr = *md })
+		case grpc.TrailerCallOption:
+			md := &metadata.MD{}
+			private[i] = grpc.Trailer(md)
+			copies = append(copies, func() { *o.TrailerAddr = *md })
+		case grpc.PeerCallOption:
+			p := &peer.Peer{}
+			private[i] = grpc.Peer(p)
+			copies = append(copies, func() { *o.PeerAddr = *p })
+		default:
+			private[i] = opt
+		}
+	}
+	return private, func() {
+		for _, c := range copies {
+			c()
+		}
+	}
+}
+
+// StreamClientInterceptor hedges server-streaming calls, which are slow to
+// return their first message, by racing the establishment of each stream and
+// the receipt of its first message across attempts.  Once an attempt
+// receives its first message, the call commits to its stream and cancels all
+// others.  A new attempt is made every time patience elapses, up to the
+// limits set by the given Options.
+//
+// Client-streaming and bidirectional calls, and calls whose messages are not
+// protobuf messages, are not hedged.  Only use it for idempotent methods,
+// since hedged calls may be executed by the server more than once.
+//
+// Since the stream is only established once its first message is requested,
+// Header returns empty metadata until RecvMsg has been called.  Call options
+// that receive the header, trailer or peer of a call, e.g. grpc.Header,
+// receive those of the winning stream only, once its first message is
+// received and again once it is done.
+func StreamClientInterceptor(patience time.Duration, opts ...speculatively.Option) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if desc.ClientStreams || !desc.ServerStreams {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+		return &hedgedStream{
+			ctx:      ctx,
+			patience: patience,
+			opts:     opts,
+			open: func(ctx context.Context) (grpc.ClientStream, func(), error) {
+				attemptOpts, publish := privateCallOptions(callOpts)
+				stream, err := streamer(ctx, desc, cc, method, attemptOpts...)
+				return stream, publish, err
+			},
+		}, nil
+	}
+}
+
+// hedgedStream is a server-streaming ClientStream whose request is sent over
+// several streams until one of them receives the first message, after which
+// every method is delegated to the winning stream.
+type hedgedStream struct {
+	ctx      context.Context
+	patience time.Duration
+	opts     []speculatively.Option
+	open     func(context.Context) (grpc.ClientStream, func(), error)
+
+	// req and closed hold the request sent and whether the sending side was
+	// closed before the stream was established, stream is the winning
+	// stream, once the first message was received, cancel cancels its
+	// context and publish publishes its call options, all guarded by mu
+	req     interface{}
+	closed  bool
+	stream  grpc.ClientStream
+	cancel  context.CancelFunc
+	publish func()
+	mu      sync.Mutex
+
+	// err is the error that ended the race, only accessed by RecvMsg
+	err error
+}
+
+// winner returns the winning stream, or nil if there is none yet.
+func (s *hedgedStream) winner() grpc.ClientStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream
+}
+
+// request returns the request sent and whether the sending side was closed
+// before the stream was established.
+func (s *hedgedStream) request() (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.req, s.closed
+}
+
+// attempt is an established stream and its first message.
+type attempt struct {
+	stream  grpc.ClientStream
+	msg     proto.Message
+	cancel  context.CancelFunc
+	publish func()
+}
+
+func (s *hedgedStream) SendMsg(m interface{}) error {
+	s.mu.Lock()
+	stream := s.stream
+	if stream == nil {
+		s.req = m
+	}
+	s.mu.Unlock()
+	if stream != nil {
+		return stream.SendMsg(m)
+	}
+	return nil
+}
+
+func (s *hedgedStream) CloseSend() error {
+	s.mu.Lock()
+	stream := s.stream
+	if stream == nil {
+		s.closed = true
+	}
+	s.mu.Unlock()
+	if stream != nil {
+		return stream.CloseSend()
+	}
+	return nil
+}
+
+func (s *hedgedStream) RecvMsg(m interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	stream := s.winner()
+	if stream == nil {
+		s.race(m)
+		return s.err
+	}
+	err := stream.RecvMsg(m)
+	if err != nil {
+		// The stream is done, so publish its trailer and release its
+		// context
+		s.mu.Lock()
+		s.publish()
+		s.cancel()
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// race opens a stream per attempt and commits to the first one to receive a
+// message, which is stored in m.
+func (s *hedgedStream) race(m interface{}) {
+	var typ protoreflect.MessageType
+	msg, ok := m.(proto.Message)
+	if ok {
+		typ = msg.ProtoReflect().Type()
+	} else {
+		// Messages that cannot be cloned are received over a single stream
+		s.opts = append(s.opts[:len(s.opts):len(s.opts)], speculatively.WithMaxAttempts(1))
+	}
+
+	opts := append([]speculatively.Option{
+		speculatively.WithCleanup(func(a attempt) { a.cancel() }),
+	}, s.opts...)
+	winner, err := speculatively.Do(s.ctx, s.patience, func(ctx context.Context) (attempt, error) {
+		return s.attempt(ctx, m, typ)
+	}, opts...)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.mu.Lock()
+	s.stream, s.cancel, s.publish = winner.stream, winner.cancel, winner.publish
+	s.publish()
+	s.mu.Unlock()
+	if msg != nil {
+		proto.Reset(msg)
+		proto.Merge(msg, winner.msg)
+	}
+}
+
+// attempt opens a stream, sends the request over it and receives the first
+// message.  The stream is canceled along with ctx until its first message is
+// received, after which it remains open until it is done or the call's own
+// context is done, so that the winning stream can still be read once every
+// other attempt has been canceled.  The first message is received into m if
+// typ is nil, and into a new message of type typ otherwise.
+func (s *hedgedStream) attempt(ctx context.Context, m interface{}, typ protoreflect.MessageType) (attempt, error) {
+	streamCtx, keep, cancel := speculatively.Detach(s.ctx, ctx)
+	a, err := s.recvFirst(streamCtx, m, typ)
+	keep()
+	if err != nil {
+		cancel()
+		return attempt{}, err
+	}
+	a.cancel = cancel
+	return a, nil
+}
+
+func (s *hedgedStream) recvFirst(ctx context.Context, m interface{}, typ protoreflect.MessageType) (attempt, error) {
+	stream, publish, err := s.open(ctx)
+	if err != nil {
+		return attempt{}, err
+	}
+	req, closed := s.request()
+	if req != nil {
+		if err := stream.SendMsg(req); err != nil {
+			return attempt{}, err
+		}
+	}
+	if closed {
+		if err := stream.CloseSend(); err != nil {
+			return attempt{}, err
+		}
+	}
+	var msg proto.Message
+	if typ != nil {
+		msg = typ.New().Interface()
+		m = msg
+	}
+	if err := stream.RecvMsg(m); err != nil {
+		return attempt{}, err
+	}
+	return attempt{stream: stream, msg: msg, publish: publish}, nil
+}
+
+func (s *hedgedStream) Header() (metadata.MD, error) {
+	stream := s.winner()
+	if stream == nil {
+		return metadata.MD{}, nil
+	}
+	return stream.Header()
+}
+
+func (s *hedgedStream) Trailer() metadata.MD {
+	stream := s.winner()
+	if stream == nil {
+		return nil
+	}
+	return stream.Trailer()
+}
+
+func (s *hedgedStream) Context() context.Context {
+	stream := s.winner()
+	if stream == nil {
+		return s.ctx
+	}
+	return stream.Context()
+}
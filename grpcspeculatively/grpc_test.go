@@ -0,0 +1,230 @@
+package grpcspeculatively
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testServer serves a test service whose first call to each method stalls
+// until it is canceled.
+type testServer struct {
+	calls    int64
+	canceled chan struct{}
+}
+
+func (s *testServer) stall(ctx context.Context) bool {
+	if atomic.AddInt64(&s.calls, 1) > 1 {
+		return false
+	}
+	select {
+	case <-ctx.Done():
+		s.canceled <- struct{}{}
+	case <-time.After(time.Second):
+	}
+	return true
+}
+
+var (
+	echoDesc  = &grpc.StreamDesc{StreamName: "Echo", ServerStreams: true}
+	watchDesc = &grpc.StreamDesc{StreamName: "Watch", ServerStreams: true}
+)
+
+func newTestConn(t *testing.T, opts ...grpc.DialOption) (*grpc.ClientConn, *testServer) {
+	t.Helper()
+
+	srv := &testServer{canceled: make(chan struct{}, 1)}
+	gs := grpc.NewServer()
+	gs.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Test",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &wrapperspb.StringValue{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if srv.stall(ctx) {
+					grpc.SetTrailer(ctx, metadata.Pairs("reply", "stalled")) //nolint:errcheck
+					return wrapperspb.String("stalled"), nil
+				}
+				grpc.SetTrailer(ctx, metadata.Pairs("reply", req.Value)) //nolint:errcheck
+				return req, nil
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Watch",
+			ServerStreams: true,
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				req := &wrapperspb.StringValue{}
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				if srv.stall(stream.Context()) {
+					return stream.SendMsg(wrapperspb.String("stalled"))
+				}
+				for i := 0; i < 3; i++ {
+					if err := stream.SendMsg(req); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}},
+	}, srv)
+
+	ln := bufconn.Listen(1 << 20)
+	go gs.Serve(ln)
+	t.Cleanup(gs.Stop)
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.Dial("bufnet", opts...)
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, srv
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	conn, srv := newTestConn(t, grpc.WithUnaryInterceptor(
+		UnaryClientInterceptor(25*time.Millisecond, speculatively.WithMaxAttempts(2)),
+	))
+
+	reply := &wrapperspb.StringValue{}
+	if err := conn.Invoke(context.Background(), "/test.Test/Echo", wrapperspb.String("hello"), reply); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if reply.Value != "hello" {
+		t.Errorf("expected reply %q, got %q", "hello", reply.Value)
+	}
+	if got := atomic.LoadInt64(&srv.calls); got != 2 {
+		t.Errorf("expected 2 calls, got %d", got)
+	}
+	select {
+	case <-srv.canceled:
+	case <-time.After(time.Second):
+		t.Errorf("expected losing call to be canceled")
+	}
+}
+
+func TestUnaryClientInterceptorCallOptions(t *testing.T) {
+	t.Parallel()
+
+	conn, _ := newTestConn(t, grpc.WithUnaryInterceptor(
+		UnaryClientInterceptor(25*time.Millisecond, speculatively.WithMaxAttempts(2)),
+	))
+
+	var (
+		header, trailer metadata.MD
+		p               peer.Peer
+	)
+	reply := &wrapperspb.StringValue{}
+	err := conn.Invoke(context.Background(), "/test.Test/Echo", wrapperspb.String("hello"), reply,
+		grpc.Header(&header), grpc.Trailer(&trailer), grpc.Peer(&p))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := trailer.Get("reply"); len(got) != 1 || got[0] != "hello" {
+		t.Errorf("expected trailer of winning attempt, got %v", trailer)
+	}
+	if header == nil {
+		t.Errorf("expected header of winning attempt")
+	}
+	if p.Addr == nil {
+		t.Errorf("expected peer of winning attempt")
+	}
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	conn, srv := newTestConn(t, grpc.WithStreamInterceptor(
+		StreamClientInterceptor(25*time.Millisecond, speculatively.WithMaxAttempts(2)),
+	))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var trailer metadata.MD
+	stream, err := conn.NewStream(ctx, watchDesc, "/test.Test/Watch", grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := stream.SendMsg(wrapperspb.String("hello")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The winning stream must still deliver every message once the losing
+	// stream has been canceled
+	var got []string
+	for {
+		msg := &wrapperspb.StringValue{}
+		err := stream.RecvMsg(msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		got = append(got, msg.Value)
+	}
+	if len(got) != 3 || got[0] != "hello" || got[2] != "hello" {
+		t.Errorf("expected 3 messages from winning stream, got %q", got)
+	}
+	if calls := atomic.LoadInt64(&srv.calls); calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+	select {
+	case <-srv.canceled:
+	case <-time.After(time.Second):
+		t.Errorf("expected losing stream to be canceled")
+	}
+	if md := stream.Trailer(); md == nil {
+		t.Errorf("expected trailer from winning stream")
+	}
+	if trailer == nil {
+		t.Errorf("expected trailer call option to receive trailer from winning stream")
+	}
+}
+
+func TestStreamClientInterceptorError(t *testing.T) {
+	t.Parallel()
+
+	conn, _ := newTestConn(t, grpc.WithStreamInterceptor(
+		StreamClientInterceptor(25*time.Millisecond, speculatively.WithMaxAttempts(2)),
+	))
+
+	stream, err := conn.NewStream(context.Background(), echoDesc, "/test.Test/Unknown")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	stream.SendMsg(wrapperspb.String("hello"))
+	stream.CloseSend()
+	for i := 0; i < 2; i++ {
+		if err := stream.RecvMsg(&wrapperspb.StringValue{}); err == nil {
+			t.Errorf("expected error from unknown method")
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package speculatively
+
+import (
+	"container/heap"
+	"time"
+)
+
+// WithHedgePriority queues the hedges of every call using this option in its
+// Budget, rather than suppressing them, when the Budget is exhausted.  Tokens
+// deposited into the Budget are then granted to queued hedges, across every
+// call sharing it, in order of decreasing priority, and among hedges of equal
+// priority in order of increasing deadline of their call's context, so that
+// the most deadline-critical calls get the scarce hedges rather than
+// whichever call happened to ask first.  Calls without a deadline come last.
+//
+// A queued hedge still counts as suppressed, and is launched as soon as a
+// token is granted to it, if its call is still running.  WithHedgePriority
+// has no effect without WithBudget.
+func WithHedgePriority(priority int) Option {
+	return func(c *config) {
+		c.hedgePriority = priority
+		c.queueHedges = true
+	}
+}
+
+// hedgeWaiter is a hedge queued in a Budget until a token is granted to it.
+type hedgeWaiter struct {
+	priority int
+	deadline time.Time
+	seq      uint64
+
+	// granted receives a token once it is granted, and index is the
+	// position of the waiter in its queue, or -1 once it is dequeued
+	granted chan struct{}
+	index   int
+}
+
+// before reports whether w should be granted a token before other.
+func (w *hedgeWaiter) before(other *hedgeWaiter) bool {
+	if w.priority != other.priority {
+		return w.priority > other.priority
+	}
+	if !w.deadline.Equal(other.deadline) {
+		switch {
+		case w.deadline.IsZero():
+			return false
+		case other.deadline.IsZero():
+			return true
+		}
+		return w.deadline.Before(other.deadline)
+	}
+	return w.seq < other.seq
+}
+
+// hedgeQueue is a heap of hedgeWaiters, the first of which is next to be
+// granted a token.
+type hedgeQueue []*hedgeWaiter
+
+func (q hedgeQueue) Len() int           { return len(q) }
+func (q hedgeQueue) Less(i, j int) bool { return q[i].before(q[j]) }
+
+func (q hedgeQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *hedgeQueue) Push(x interface{}) {
+	w := x.(*hedgeWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *hedgeQueue) Pop() interface{} {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
+
+// enqueue queues a hedge with the given priority and deadline until a token
+// is granted to it.
+func (b *Budget) enqueue(priority int, deadline time.Time) *hedgeWaiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	w := &hedgeWaiter{
+		priority: priority,
+		deadline: deadline,
+		seq:      b.seq,
+		granted:  make(chan struct{}, 1),
+	}
+	heap.Push(&b.waiters, w)
+	b.grant()
+	return w
+}
+
+// dequeue removes a hedge from the queue, returning its token if one was
+// granted to it but not yet used.
+func (b *Budget) dequeue(w *hedgeWaiter) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if w.index >= 0 {
+		heap.Remove(&b.waiters, w.index)
+		return
+	}
+	select {
+	case <-w.granted:
+		b.restore()
+	default:
+	}
+}
+
+// refund returns a granted token that could not be used.
+func (b *Budget) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.restore()
+}
+
+// restore returns a withdrawn token.  It must be called with b.mu held.
+func (b *Budget) restore() {
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.grant()
+}
+
+// grant grants whole tokens to queued hedges in order.  It must be called
+// with b.mu held.
+func (b *Budget) grant() {
+	for b.tokens >= 1 && len(b.waiters) > 0 {
+		w := heap.Pop(&b.waiters).(*hedgeWaiter)
+		b.tokens--
+		w.granted <- struct{}{}
+	}
+}
@@ -0,0 +1,125 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHedgeQueueOrder(t *testing.T) {
+	t.Parallel()
+
+	b := NewBudget(1, 1)
+	if !b.withdraw() {
+		t.Fatalf("expected withdrawal from full budget to succeed")
+	}
+
+	now := time.Now()
+	waiters := map[string]*hedgeWaiter{
+		"low":         b.enqueue(-1, now),
+		"no-deadline": b.enqueue(0, time.Time{}),
+		"late":        b.enqueue(0, now.Add(time.Second)),
+		"early":       b.enqueue(0, now.Add(time.Millisecond)),
+		"high":        b.enqueue(1, time.Time{}),
+	}
+	for _, want := range []string{"high", "early", "late", "no-deadline", "low"} {
+		b.deposit()
+		for name, w := range waiters {
+			select {
+			case <-w.granted:
+				if name != want {
+					t.Fatalf("expected token granted to %q, got %q", want, name)
+				}
+				delete(waiters, name)
+			default:
+			}
+		}
+		if _, ok := waiters[want]; ok {
+			t.Fatalf("expected token granted to %q", want)
+		}
+	}
+	if remaining := b.Remaining(); remaining != 0 {
+		t.Fatalf("expected every token granted, got %v remaining", remaining)
+	}
+}
+
+func TestHedgeQueueDequeue(t *testing.T) {
+	t.Parallel()
+
+	b := NewBudget(1, 1)
+	b.withdraw()
+	queued := b.enqueue(0, time.Time{})
+	granted := b.enqueue(1, time.Time{})
+	b.deposit()
+
+	// Giving up a granted token passes it on to the next waiter
+	b.dequeue(granted)
+	select {
+	case <-queued.granted:
+	default:
+		t.Fatalf("expected token passed on to queued hedge")
+	}
+
+	// Giving up a place in the queue keeps deposits in the budget
+	abandoned := b.enqueue(0, time.Time{})
+	b.dequeue(abandoned)
+	b.deposit()
+	if remaining := b.Remaining(); remaining != 1 {
+		t.Fatalf("expected budget to keep deposit, got %v remaining", remaining)
+	}
+}
+
+func TestWithHedgePriority(t *testing.T) {
+	t.Parallel()
+
+	b := NewBudget(0.5, 1)
+	b.withdraw()
+
+	var suppressed []Suppression
+	winner := -1
+	thunk := newTestThunk([]result[int]{{val: 1}, {val: 2}}, []time.Duration{time.Second, 0})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		b.deposit()
+	}()
+	val, err := Do(context.Background(), 10*time.Millisecond, thunk.call,
+		WithBudget(b),
+		WithHedgePriority(0),
+		WithMaxAttempts(2),
+		WithHooks(Hooks{
+			OnHedgeSuppressed: func(s Suppression) { suppressed = append(suppressed, s) },
+			OnWinner:          func(a Attempt) { winner = a.Index },
+		}),
+	)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if val != 2 || winner != 1 {
+		t.Errorf("expected queued hedge to win, got val %d from attempt %d", val, winner)
+	}
+	if len(suppressed) == 0 || suppressed[0] != SuppressedByBudget {
+		t.Errorf("expected hedge suppressed by budget before being granted, got %v", suppressed)
+	}
+}
+
+func TestWithHedgePriorityCanceled(t *testing.T) {
+	t.Parallel()
+
+	b := NewBudget(0.5, 1)
+	b.withdraw()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	thunk := newSimpleTestThunk(1, nil, time.Second)
+	_, err := Do(ctx, 10*time.Millisecond, thunk.call, WithBudget(b), WithHedgePriority(0))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected err = %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// The call's deposit and this one would have been granted to its
+	// queued hedge had it not given up its place
+	b.deposit()
+	if remaining := b.Remaining(); remaining != 1 {
+		t.Errorf("expected budget to keep deposits, got %v remaining", remaining)
+	}
+}
@@ -0,0 +1,49 @@
+package speculatively
+
+import (
+	"context"
+	"time"
+)
+
+// Hedger bundles a patience duration and a set of Options into a reusable
+// speculative execution policy.  Any state referenced by its Options (e.g. a
+// Budget) is shared by every call made through the Hedger.
+//
+// A Hedger is safe for concurrent use.
+type Hedger struct {
+	patience time.Duration
+	opts     []Option
+	stats    *stats
+}
+
+// NewHedger creates a Hedger that waits for the given patience duration
+// between subsequent attempts, customized by the given Options.
+func NewHedger(patience time.Duration, opts ...Option) *Hedger {
+	s := &stats{}
+	if buckets := newConfig(opts).latencyBuckets; buckets != nil {
+		s.latency = newLatencyHistograms(buckets)
+	}
+	return &Hedger{
+		patience: patience,
+		opts:     append([]Option{withStats(s)}, opts...),
+		stats:    s,
+	}
+}
+
+// Patience returns the duration h waits between subsequent attempts.
+func (h *Hedger) Patience() time.Duration {
+	return h.patience
+}
+
+// Options returns the Options h was created with, e.g. for use with the
+// variants of Do that do not accept a Hedger directly.  Calls made with these
+// Options are included in h's Stats.
+func (h *Hedger) Options() []Option {
+	return append([]Option(nil), h.opts...)
+}
+
+// DoWith speculatively executes a Thunk according to the policy of the given
+// Hedger.  See Do for details.
+func DoWith[T any](ctx context.Context, h *Hedger, thunk Thunk[T]) (T, error) {
+	return Do(ctx, h.patience, thunk, h.opts...)
+}
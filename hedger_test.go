@@ -0,0 +1,149 @@
+package speculatively
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoWith(t *testing.T) {
+	t.Parallel()
+
+	thunk := newSimpleTestThunk(1, nil, 50*time.Millisecond)
+	h := NewHedger(5*time.Millisecond, WithMaxAttempts(3))
+
+	val, err := DoWith(context.Background(), h, thunk.call)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 1 {
+		t.Errorf("expected val = %d, got %d", 1, val)
+	}
+	if callCount := thunk.callCount(); callCount != 3 {
+		t.Errorf("expected Thunk to run %d times, got %d", 3, callCount)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid policy", func(t *testing.T) {
+		t.Parallel()
+
+		budget := NewBudget(0.1, 2)
+		h := NewHedger(10*time.Millisecond, WithMaxAttempts(2), WithBudget(budget))
+
+		report, err := h.SelfTest(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if report.Calls != selfTestCalls {
+			t.Errorf("expected %d calls, got %d", selfTestCalls, report.Calls)
+		}
+		if report.Hedges == 0 {
+			t.Errorf("expected some hedges to be launched")
+		}
+		if report.MaxAttemptsPerCall > 2 {
+			t.Errorf("expected at most 2 attempts per call, got %d", report.MaxAttemptsPerCall)
+		}
+		if remaining := budget.Remaining(); remaining != 2 {
+			t.Errorf("expected self test not to draw from real budget, %v remaining", remaining)
+		}
+	})
+
+	t.Run("shared state untouched", func(t *testing.T) {
+		t.Parallel()
+
+		tracker := NewLatencyTracker(100)
+		gate := NewErrorGate(0.1, time.Minute)
+		var launched int64
+		h := NewHedger(10*time.Millisecond,
+			WithMaxAttempts(2),
+			WithAdaptivePatience(tracker, 0.9),
+			WithErrorGate(gate),
+			WithInflightLimit(NewInflightLimit(1)),
+			WithHooks(Hooks{OnLaunch: func(Attempt) { atomic.AddInt64(&launched, 1) }}),
+		)
+
+		report, err := h.SelfTest(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if report.Hedges == 0 {
+			t.Errorf("expected some hedges to be launched")
+		}
+		if n := len(tracker.Samples()); n != 0 {
+			t.Errorf("expected self test not to feed real latency tracker, got %d samples", n)
+		}
+		if n := atomic.LoadInt64(&launched); n != 0 {
+			t.Errorf("expected self test not to call real hooks, got %d launches", n)
+		}
+		if s := h.Stats(); s.Calls != 0 {
+			t.Errorf("expected self test not to count in stats, got %d calls", s.Calls)
+		}
+	})
+
+	t.Run("leak under frozen clock", func(t *testing.T) {
+		t.Parallel()
+
+		// Canceled attempts never exit until the test is over, and the clock
+		// never moves, so only the system clock can end the wait for them
+		release := make(chan struct{})
+		defer close(release)
+		leaky := func(ctx context.Context, d time.Duration) error {
+			err := sleep(ctx, d)
+			if err != nil {
+				<-release
+			}
+			return err
+		}
+		h := NewHedger(10*time.Millisecond, WithMaxAttempts(2), WithClock(frozenClock{time.Unix(1000, 0)}))
+
+		type outcome struct {
+			report SelfTestReport
+			err    error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			report, err := h.selfTest(context.Background(), leaky)
+			done <- outcome{report, err}
+		}()
+
+		select {
+		case o := <-done:
+			if o.err == nil {
+				t.Errorf("expected leaked attempts to fail the self test")
+			}
+			if o.report.Leaked == 0 {
+				t.Errorf("expected leaked attempts to be reported")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("self test did not finish under a frozen clock")
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		h := NewHedger(10 * time.Millisecond)
+		if _, err := h.SelfTest(ctx); err != context.Canceled {
+			t.Errorf("expected err = %s, got %v", context.Canceled, err)
+		}
+	})
+}
+
+// frozenClock is a Clock whose time never moves, but whose tickers tick in
+// real time.
+type frozenClock struct {
+	now time.Time
+}
+
+func (c frozenClock) Now() time.Time { return c.now }
+
+func (frozenClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
@@ -0,0 +1,154 @@
+package speculatively
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets are the histogram bucket bounds used by
+// WithLatencyHistograms when none are given.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// WithLatencyHistograms makes a Hedger maintain histograms of the latency of
+// its attempts with the given bucket upper bounds, or DefaultLatencyBuckets
+// if none are given, which are included in its Stats.  It has no effect on
+// calls not made through a Hedger.
+func WithLatencyHistograms(buckets ...time.Duration) Option {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	bounds := append([]time.Duration(nil), buckets...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+	return func(c *config) {
+		c.latencyBuckets = bounds
+	}
+}
+
+// LatencyHistograms hold the latency of attempts, split by first attempts vs
+// hedges and by winners vs losers, i.e. attempts that did not produce the
+// result of their call.
+type LatencyHistograms struct {
+	FirstWinners Histogram
+	FirstLosers  Histogram
+	HedgeWinners Histogram
+	HedgeLosers  Histogram
+
+	// LoserExits holds the time it took attempts still running when their
+	// call ended to observe cancelation and exit.
+	LoserExits Histogram
+}
+
+// Histogram is a snapshot of a latency histogram.
+type Histogram struct {
+	// Bounds are the inclusive upper bounds of every bucket but the last,
+	// which holds every observation greater than the last bound.
+	Bounds []time.Duration
+	// Counts holds the number of observations in every bucket, so it has
+	// one more element than Bounds.
+	Counts []int64
+	// Sum is the sum of every observation.
+	Sum time.Duration
+}
+
+// Count returns the total number of observations.
+func (h Histogram) Count() int64 {
+	var n int64
+	for _, c := range h.Counts {
+		n += c
+	}
+	return n
+}
+
+// Mean returns the mean observation, or 0 if there are none.
+func (h Histogram) Mean() time.Duration {
+	n := h.Count()
+	if n == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(n)
+}
+
+// latencyHistograms accumulates LatencyHistograms.
+type latencyHistograms struct {
+	firstWinners, firstLosers, hedgeWinners, hedgeLosers *histogram
+	loserExits                                           *histogram
+}
+
+func newLatencyHistograms(bounds []time.Duration) *latencyHistograms {
+	return &latencyHistograms{
+		firstWinners: newHistogram(bounds),
+		firstLosers:  newHistogram(bounds),
+		hedgeWinners: newHistogram(bounds),
+		hedgeLosers:  newHistogram(bounds),
+		loserExits:   newHistogram(bounds),
+	}
+}
+
+func (l *latencyHistograms) observe(a Attempt, elapsed time.Duration, won bool) {
+	switch {
+	case a.Index == 0 && won:
+		l.firstWinners.observe(elapsed)
+	case a.Index == 0:
+		l.firstLosers.observe(elapsed)
+	case won:
+		l.hedgeWinners.observe(elapsed)
+	default:
+		l.hedgeLosers.observe(elapsed)
+	}
+}
+
+func (l *latencyHistograms) snapshot() *LatencyHistograms {
+	return &LatencyHistograms{
+		FirstWinners: l.firstWinners.snapshot(),
+		FirstLosers:  l.firstLosers.snapshot(),
+		HedgeWinners: l.hedgeWinners.snapshot(),
+		HedgeLosers:  l.hedgeLosers.snapshot(),
+		LoserExits:   l.loserExits.snapshot(),
+	}
+}
+
+type histogram struct {
+	bounds []time.Duration
+	counts []int64
+	sum    int64
+}
+
+func newHistogram(bounds []time.Duration) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)+1),
+	}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := sort.Search(len(h.bounds), func(i int) bool { return d <= h.bounds[i] })
+	atomic.AddInt64(&h.counts[i], 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Bounds: append([]time.Duration(nil), h.bounds...),
+		Counts: make([]int64, len(h.counts)),
+		Sum:    time.Duration(atomic.LoadInt64(&h.sum)),
+	}
+	for i := range h.counts {
+		s.Counts[i] = atomic.LoadInt64(&h.counts[i])
+	}
+	return s
+}
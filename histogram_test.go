@@ -0,0 +1,89 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithLatencyHistograms(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		h := NewHedger(time.Second)
+		if _, err := DoWith(context.Background(), h, newSimpleTestThunk(1, nil, 0).call); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if s := h.Stats(); s.Latency != nil {
+			t.Errorf("expected no latency histograms, got %+v", s.Latency)
+		}
+	})
+
+	t.Run("split by attempt and outcome", func(t *testing.T) {
+		t.Parallel()
+
+		buckets := []time.Duration{100 * time.Millisecond, 10 * time.Millisecond}
+		h := NewHedger(10*time.Millisecond, WithLatencyHistograms(buckets...), WithMaxAttempts(2))
+
+		slow := newTestThunk([]result[int]{{val: 1}, {val: 2}}, []time.Duration{time.Second, 5 * time.Millisecond})
+		if _, err := DoWith(context.Background(), h, slow.call); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := DoWith(context.Background(), h, newSimpleTestThunk(1, nil, 0).call); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// The losing first attempt is observed once it exits
+		var s Stats
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if s = h.Stats(); s.Latency.FirstLosers.Count() > 0 {
+				break
+			}
+		}
+
+		l := s.Latency
+		if got := l.FirstWinners.Counts; len(got) != 3 || got[0] != 1 {
+			t.Errorf("expected 1 first winner in the first bucket, got %v", got)
+		}
+		if got := l.HedgeWinners.Counts; got[0] != 1 {
+			t.Errorf("expected 1 hedge winner in the first bucket, got %v", got)
+		}
+		if got := l.FirstLosers.Counts; got[1] != 1 {
+			t.Errorf("expected 1 first loser in the second bucket, got %v", got)
+		}
+		if n := l.HedgeLosers.Count(); n != 0 {
+			t.Errorf("expected no hedge losers, got %d", n)
+		}
+		if bounds := l.FirstWinners.Bounds; bounds[0] != 10*time.Millisecond || bounds[1] != 100*time.Millisecond {
+			t.Errorf("expected sorted bounds, got %v", bounds)
+		}
+		if mean := l.FirstLosers.Mean(); mean < 10*time.Millisecond || mean > time.Second {
+			t.Errorf("expected first loser mean ~15ms, got %s", mean)
+		}
+	})
+}
+
+func TestHistogram(t *testing.T) {
+	t.Parallel()
+
+	h := newHistogram([]time.Duration{time.Millisecond, time.Second})
+	for _, d := range []time.Duration{0, time.Millisecond, 2 * time.Millisecond, time.Minute} {
+		h.observe(d)
+	}
+
+	s := h.snapshot()
+	if got := s.Counts; got[0] != 2 || got[1] != 1 || got[2] != 1 {
+		t.Errorf("expected counts = [2 1 1], got %v", got)
+	}
+	if n := s.Count(); n != 4 {
+		t.Errorf("expected count = %d, got %d", 4, n)
+	}
+	if want := (time.Minute + 3*time.Millisecond) / 4; s.Mean() != want {
+		t.Errorf("expected mean = %s, got %s", want, s.Mean())
+	}
+	if mean := (Histogram{}).Mean(); mean != 0 {
+		t.Errorf("expected empty mean = 0, got %s", mean)
+	}
+}
@@ -0,0 +1,224 @@
+package speculatively
+
+import "time"
+
+// Attempt describes a single execution of a Thunk within a call.
+type Attempt struct {
+	// Index of the attempt within its call, starting at 0 for the first
+	// attempt.
+	Index int
+	// Target the attempt was executed against, e.g. the replica chosen by
+	// DoReplicas, or nil.
+	Target interface{}
+	// Start is the time the attempt was launched.
+	Start time.Time
+	// Call identifies the call the attempt belongs to, unique within the
+	// process, to correlate the attempts of concurrent calls.
+	Call uint64
+}
+
+// Hooks are callbacks invoked as a call progresses, e.g. to integrate with an
+// application's observability or cancelation machinery.  Any hook may be nil.
+type Hooks struct {
+	// OnLoser is called for every attempt still running when another
+	// attempt wins the call.  It is intended to implement "tied requests",
+	// where the application sends an explicit cancelation message to the
+	// server handling the losing attempt rather than only canceling it on
+	// the client side.
+	//
+	// OnLoser is called in its own goroutine.
+	OnLoser func(Attempt)
+
+	// OnWinner is called with the attempt whose successful result is
+	// returned by the call.  It is not called when the call fails.
+	//
+	// Unlike OnLoser, OnWinner is called synchronously before the call
+	// returns, so it must not block.
+	OnWinner func(Attempt)
+
+	// OnLaunch is called synchronously when an attempt is launched, so it
+	// must not block.
+	OnLaunch func(Attempt)
+
+	// OnDone is called with the elapsed time and error of every attempt
+	// once its Thunk returns, from the attempt's own goroutine.
+	OnDone func(a Attempt, elapsed time.Duration, err error)
+
+	// OnHedgeSuppressed is called synchronously every time a hedge is due
+	// but is not launched, with the reason why, so it must not block.
+	OnHedgeSuppressed func(Suppression)
+
+	// OnLateResult is called with the elapsed time of every attempt that
+	// returns a successful result after its call has already ended, from the
+	// attempt's own goroutine.  Frequent late results suggest that patience
+	// is too short, so hedges almost always complete uselessly.
+	OnLateResult func(a Attempt, elapsed time.Duration)
+
+	// OnLoserExit is called with the time it took every attempt still
+	// running when its call ended to observe cancelation and exit, from the
+	// attempt's own goroutine.  A long tail indicates Thunks that ignore
+	// their context and hold on to resources longer than expected.
+	OnLoserExit func(a Attempt, lag time.Duration)
+
+	// OnTermination is called synchronously with how every attempt ended
+	// once the call ends, including attempts still running, and a hedge
+	// that was due but suppressed when the call ended, if any.  It must not
+	// block.
+	OnTermination func(Attempt, Termination)
+
+	// OnError is called with the ErrorCategory of every error returned by
+	// an attempt, as derived by the Categorizer set via
+	// WithErrorCategorizer, from the attempt's own goroutine.
+	OnError func(a Attempt, category ErrorCategory, err error)
+
+	// OnDivergence is called with the winning attempt and another attempt
+	// whose successful result differs from the winner's, as determined by
+	// WithConsistencyCheck, from the other attempt's own goroutine.
+	OnDivergence func(winner, other Attempt)
+}
+
+// Suppression is the reason a hedge was not launched when due.
+type Suppression int
+
+// Reasons hedges are suppressed.
+const (
+	// SuppressedByBudget means the call's Budget was exhausted.
+	SuppressedByBudget Suppression = iota
+	// SuppressedByErrorGate means the call's ErrorGate was closed.
+	SuppressedByErrorGate
+	// SuppressedByInflightLimit means the call's InflightLimit was reached.
+	SuppressedByInflightLimit
+	// SuppressedBySemaphore means the weight of the call's Semaphore was
+	// not available.
+	SuppressedBySemaphore
+)
+
+func (s Suppression) String() string {
+	switch s {
+	case SuppressedByBudget:
+		return "budget"
+	case SuppressedByErrorGate:
+		return "error_gate"
+	case SuppressedByInflightLimit:
+		return "inflight_limit"
+	case SuppressedBySemaphore:
+		return "semaphore"
+	default:
+		return "unknown"
+	}
+}
+
+// WithHooks registers the given Hooks.  It may be given more than once, in
+// which case every registered hook is called.
+func WithHooks(h Hooks) Option {
+	return func(c *config) {
+		c.hooks = append(c.hooks, h)
+		c.userHooks = append(c.userHooks, h)
+	}
+}
+
+// withTelemetryHooks registers the given Hooks, which only record telemetry
+// and are skipped for calls not sampled by WithTelemetrySampling.
+func withTelemetryHooks(h Hooks) Option {
+	return func(c *config) {
+		c.hooks = append(c.hooks, h)
+	}
+}
+
+// hookList dispatches events to every registered set of Hooks.
+type hookList []Hooks
+
+func (l hookList) loser(a Attempt) {
+	for _, h := range l {
+		if h.OnLoser != nil {
+			go h.OnLoser(a)
+		}
+	}
+}
+
+func (l hookList) launch(a Attempt) {
+	for _, h := range l {
+		if h.OnLaunch != nil {
+			h.OnLaunch(a)
+		}
+	}
+}
+
+func (l hookList) done(a Attempt, elapsed time.Duration, err error) {
+	for _, h := range l {
+		if h.OnDone != nil {
+			h.OnDone(a, elapsed, err)
+		}
+	}
+}
+
+func (l hookList) suppressed(s Suppression) {
+	for _, h := range l {
+		if h.OnHedgeSuppressed != nil {
+			h.OnHedgeSuppressed(s)
+		}
+	}
+}
+
+func (l hookList) late(a Attempt, elapsed time.Duration) {
+	for _, h := range l {
+		if h.OnLateResult != nil {
+			h.OnLateResult(a, elapsed)
+		}
+	}
+}
+
+func (l hookList) loserExit(a Attempt, lag time.Duration) {
+	for _, h := range l {
+		if h.OnLoserExit != nil {
+			h.OnLoserExit(a, lag)
+		}
+	}
+}
+
+func (l hookList) hasTermination() bool {
+	for _, h := range l {
+		if h.OnTermination != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (l hookList) terminated(a Attempt, t Termination) {
+	for _, h := range l {
+		if h.OnTermination != nil {
+			h.OnTermination(a, t)
+		}
+	}
+}
+
+func (l hookList) failed(cfg *config, a Attempt, err error) {
+	var category ErrorCategory
+	categorized := false
+	for _, h := range l {
+		if h.OnError == nil {
+			continue
+		}
+		if !categorized {
+			category, categorized = cfg.categorize(err), true
+		}
+		h.OnError(a, category, err)
+	}
+}
+
+func (l hookList) diverged(winner, other Attempt) {
+	for _, h := range l {
+		if h.OnDivergence != nil {
+			h.OnDivergence(winner, other)
+		}
+	}
+}
+
+func (l hookList) winner(a Attempt) {
+	for _, h := range l {
+		if h.OnWinner != nil {
+			h.OnWinner(a)
+		}
+	}
+}
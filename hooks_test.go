@@ -0,0 +1,204 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnLoser(t *testing.T) {
+	t.Parallel()
+
+	rec := &replicaRecorder{delays: map[string]time.Duration{
+		"slow": time.Second,
+		"fast": 5 * time.Millisecond,
+	}}
+	replicas := Replicas[string]{List: []string{"slow", "fast"}}
+
+	losers := make(chan Attempt, 2)
+	hooks := Hooks{OnLoser: func(a Attempt) { losers <- a }}
+
+	val, err := DoReplicas(context.Background(), 10*time.Millisecond, replicas, rec.call, WithHooks(hooks))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != "fast" {
+		t.Errorf("expected val = %q, got %q", "fast", val)
+	}
+
+	select {
+	case a := <-losers:
+		if a.Index != 0 || a.Target != "slow" {
+			t.Errorf("expected loser to be attempt 0 against %q, got %#v", "slow", a)
+		}
+		if a.Start.IsZero() {
+			t.Errorf("expected loser start time to be set")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected OnLoser to be called")
+	}
+
+	select {
+	case a := <-losers:
+		t.Errorf("unexpected extra loser: %#v", a)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestOnWinner(t *testing.T) {
+	t.Parallel()
+
+	t.Run("winner reported before return", func(t *testing.T) {
+		t.Parallel()
+
+		rec := &replicaRecorder{delays: map[string]time.Duration{
+			"slow": time.Second,
+			"fast": 5 * time.Millisecond,
+		}}
+		replicas := Replicas[string]{List: []string{"slow", "fast"}}
+
+		var winners []Attempt
+		hooks := Hooks{OnWinner: func(a Attempt) { winners = append(winners, a) }}
+
+		_, err := DoReplicas(context.Background(), 10*time.Millisecond, replicas, rec.call, WithHooks(hooks))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(winners) != 1 {
+			t.Fatalf("expected 1 winner, got %d", len(winners))
+		}
+		if a := winners[0]; a.Index != 1 || a.Target != "fast" {
+			t.Errorf("expected winner to be attempt 1 against %q, got %#v", "fast", a)
+		}
+	})
+
+	t.Run("no winner on failure", func(t *testing.T) {
+		t.Parallel()
+
+		thunk := newSimpleTestThunk(0, errors.New("fail"), 0)
+		called := false
+		hooks := Hooks{OnWinner: func(Attempt) { called = true }}
+
+		if _, err := Do(context.Background(), time.Second, thunk.call, WithHooks(hooks)); err == nil {
+			t.Fatalf("expected error")
+		}
+		if called {
+			t.Errorf("expected OnWinner not to be called")
+		}
+	})
+}
+
+func TestOnLaunchAndOnDone(t *testing.T) {
+	t.Parallel()
+
+	thunk := newTestThunk([]result[int]{{err: errors.New("fail")}, {val: 1}}, []time.Duration{time.Second, 5 * time.Millisecond})
+
+	var (
+		mu       sync.Mutex
+		launched []int
+		done     = map[int]error{}
+	)
+	hooks := Hooks{
+		OnLaunch: func(a Attempt) {
+			mu.Lock()
+			defer mu.Unlock()
+			launched = append(launched, a.Index)
+		},
+		OnDone: func(a Attempt, elapsed time.Duration, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if elapsed <= 0 {
+				t.Errorf("expected positive elapsed time, got %s", elapsed)
+			}
+			done[a.Index] = err
+		},
+	}
+
+	if _, err := Do(context.Background(), 10*time.Millisecond, thunk.call, WithHooks(hooks), WithMaxAttempts(2)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(launched) != 2 || launched[0] != 0 || launched[1] != 1 {
+		t.Errorf("expected attempts [0 1] to be launched, got %v", launched)
+	}
+	if err, ok := done[1]; !ok || err != nil {
+		t.Errorf("expected attempt 1 to be done without error, got %v", err)
+	}
+}
+
+func TestOnHedgeSuppressed(t *testing.T) {
+	t.Parallel()
+
+	thunk := newSimpleTestThunk(1, nil, 50*time.Millisecond)
+	budget := NewBudget(0, 1)
+	budget.withdraw()
+
+	var suppressed []Suppression
+	hooks := Hooks{OnHedgeSuppressed: func(s Suppression) { suppressed = append(suppressed, s) }}
+
+	if _, err := Do(context.Background(), 10*time.Millisecond, thunk.call, WithBudget(budget), WithHooks(hooks)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(suppressed) == 0 {
+		t.Fatalf("expected hedges to be suppressed")
+	}
+	for _, s := range suppressed {
+		if s != SuppressedByBudget {
+			t.Errorf("expected suppression by %s, got %s", SuppressedByBudget, s)
+		}
+	}
+}
+
+func TestSuppressionString(t *testing.T) {
+	t.Parallel()
+
+	for s, want := range map[Suppression]string{
+		SuppressedByBudget:        "budget",
+		SuppressedByErrorGate:     "error_gate",
+		SuppressedByInflightLimit: "inflight_limit",
+		SuppressedBySemaphore:     "semaphore",
+		Suppression(-1):           "unknown",
+	} {
+		if got := s.String(); got != want {
+			t.Errorf("expected %d.String() = %q, got %q", s, want, got)
+		}
+	}
+}
+
+func TestOnLateResult(t *testing.T) {
+	t.Parallel()
+
+	// The first attempt ignores cancelation and completes successfully
+	// after the hedge wins
+	thunk := func(ctx context.Context) (int, error) {
+		if IsHedge(ctx) {
+			return 2, nil
+		}
+		time.Sleep(20 * time.Millisecond)
+		return 1, nil
+	}
+
+	late := make(chan Attempt, 1)
+	hooks := Hooks{OnLateResult: func(a Attempt, _ time.Duration) { late <- a }}
+	h := NewHedger(5*time.Millisecond, WithHooks(hooks))
+
+	if _, err := DoWith(context.Background(), h, thunk); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	select {
+	case a := <-late:
+		if a.Index != 0 {
+			t.Errorf("expected late result from attempt 0, got %d", a.Index)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected OnLateResult to be called")
+	}
+	if n := h.Stats().LateResults; n != 1 {
+		t.Errorf("expected 1 late result, got %d", n)
+	}
+}
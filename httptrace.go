@@ -0,0 +1,86 @@
+package speculatively
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// HTTPPhases are the durations of the phases of the HTTP requests made by an
+// attempt, as observed via net/http/httptrace.  If an attempt makes more than
+// one request, the durations are summed.
+type HTTPPhases struct {
+	// DNS is the time spent resolving host names.
+	DNS time.Duration
+	// Connect is the time spent establishing TCP connections.
+	Connect time.Duration
+	// TLS is the time spent on TLS handshakes.
+	TLS time.Duration
+	// TTFB is the time from when a request was fully written to when the
+	// first byte of its response was received, i.e. the server's time.
+	TTFB time.Duration
+	// ReusedConn reports whether any request reused a connection.
+	ReusedConn bool
+}
+
+// WithHTTPPhases records the phases of the HTTP requests made by every attempt
+// via net/http/httptrace into its AttemptReport, so that it can be told
+// whether hedges win because of connection setup or because of server time.
+// Thunks must use the context they are given for their requests.
+func WithHTTPPhases() Option {
+	return func(c *config) {
+		c.httpPhases = true
+	}
+}
+
+// httpPhases records the HTTPPhases of an attempt.
+type httpPhases struct {
+	mu     sync.Mutex
+	phases HTTPPhases
+
+	dnsStart, connectStart, tlsStart, wroteRequest time.Time
+}
+
+func (p *httpPhases) snapshot() *HTTPPhases {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	phases := p.phases
+	return &phases
+}
+
+// start marks the start of a phase.
+func (p *httpPhases) start(t *time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*t = time.Now()
+}
+
+// done adds the time since the given phase started to the given duration.
+func (p *httpPhases) done(d *time.Duration, start *time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !start.IsZero() {
+		*d += time.Since(*start)
+		*start = time.Time{}
+	}
+}
+
+func (p *httpPhases) withClientTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { p.start(&p.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { p.done(&p.phases.DNS, &p.dnsStart) },
+		ConnectStart:      func(string, string) { p.start(&p.connectStart) },
+		ConnectDone:       func(string, string, error) { p.done(&p.phases.Connect, &p.connectStart) },
+		TLSHandshakeStart: func() { p.start(&p.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { p.done(&p.phases.TLS, &p.tlsStart) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.phases.ReusedConn = p.phases.ReusedConn || info.Reused
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { p.start(&p.wroteRequest) },
+		GotFirstResponseByte: func() { p.done(&p.phases.TTFB, &p.wroteRequest) },
+	})
+}
@@ -0,0 +1,78 @@
+package speculatively
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithHTTPPhases(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		io.WriteString(w, "ok") //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{}}
+	thunk := func(ctx context.Context) (int, error) {
+		path := "/slow"
+		if IsHedge(ctx) {
+			path = "/fast"
+		}
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	e := NewExemplars(0, 1)
+	if _, err := Do(context.Background(), 20*time.Millisecond, thunk, WithMaxAttempts(2), WithHTTPPhases(), WithExemplars(e)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	attempts := e.Recent()[0].Attempts
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(attempts))
+	}
+	for _, a := range attempts {
+		if a.HTTP == nil {
+			t.Fatalf("expected HTTP phases for attempt %d", a.Index)
+		}
+		if a.HTTP.Connect <= 0 {
+			t.Errorf("expected connect time for attempt %d, got %s", a.Index, a.HTTP.Connect)
+		}
+	}
+	if ttfb := attempts[1].HTTP.TTFB; ttfb < 10*time.Millisecond || ttfb > time.Second {
+		t.Errorf("expected hedge TTFB ~10ms, got %s", ttfb)
+	}
+	if ttfb := attempts[0].HTTP.TTFB; ttfb != 0 {
+		t.Errorf("expected no TTFB for attempt still waiting for a response, got %s", ttfb)
+	}
+}
+
+func TestHTTPPhasesDisabled(t *testing.T) {
+	t.Parallel()
+
+	e := NewExemplars(0, 1)
+	if _, err := Do(context.Background(), time.Second, newSimpleTestThunk(1, nil, 0).call, WithExemplars(e)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if phases := e.Recent()[0].Attempts[0].HTTP; phases != nil {
+		t.Errorf("expected no HTTP phases, got %+v", phases)
+	}
+}
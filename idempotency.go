@@ -0,0 +1,41 @@
+package speculatively
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// WithIdempotencyKeys makes calls safe for speculative writes by generating
+// a unique idempotency key for every call, shared by all of its attempts,
+// which Thunks retrieve via IdempotencyKey and send along with their writes
+// so that the server can deduplicate them.
+//
+// If fence is not nil, it is called with the call's key and its winning
+// attempt once the call succeeds, e.g. to advance a fencing token so that
+// writes from losing attempts that are still in flight are rejected.  It is
+// called synchronously before the call returns.
+func WithIdempotencyKeys(fence func(key string, winner Attempt)) Option {
+	return func(c *config) {
+		c.idempotencyKeys = true
+		c.fence = fence
+	}
+}
+
+// IdempotencyKey returns the idempotency key of the call to which the given
+// context belongs, or false if the context does not belong to an attempt of
+// a call made with WithIdempotencyKeys.
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	info, ok := attemptFromContext(ctx)
+	if !ok || info.call.idempotencyKey == "" {
+		return "", false
+	}
+	return info.call.idempotencyKey, true
+}
+
+// newIdempotencyKey returns a random 128-bit key, hex encoded.
+func newIdempotencyKey() string {
+	var b [16]byte
+	rand.Read(b[:]) //nolint:errcheck
+	return hex.EncodeToString(b[:])
+}
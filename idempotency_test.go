@@ -0,0 +1,74 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithIdempotencyKeys(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		keys    []string
+		fenced  string
+		winners []Attempt
+	)
+	fence := func(key string, winner Attempt) {
+		mu.Lock()
+		defer mu.Unlock()
+		fenced = key
+		winners = append(winners, winner)
+	}
+	thunk := func(ctx context.Context) (int, error) {
+		key, ok := IdempotencyKey(ctx)
+		if !ok {
+			t.Errorf("expected idempotency key")
+		}
+		mu.Lock()
+		keys = append(keys, key)
+		mu.Unlock()
+		if !IsHedge(ctx) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return 1, nil
+	}
+
+	if _, err := Do(context.Background(), 10*time.Millisecond, thunk, WithIdempotencyKeys(fence), WithMaxAttempts(2)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 2 || keys[0] != keys[1] || len(keys[0]) != 32 {
+		t.Errorf("expected both attempts to share a 32 character key, got %q", keys)
+	}
+	if len(winners) != 1 || winners[0].Index != 1 || fenced != keys[0] {
+		t.Errorf("expected fence called once with key %q and attempt 1, got %q and %v", keys[0], fenced, winners)
+	}
+
+	// Each call has its own key
+	other, _ := Do(context.Background(), time.Second, func(ctx context.Context) (string, error) {
+		key, _ := IdempotencyKey(ctx)
+		return key, nil
+	}, WithIdempotencyKeys(nil))
+	if other == "" || other == keys[0] {
+		t.Errorf("expected a new key for another call, got %q", other)
+	}
+}
+
+func TestIdempotencyKeyDisabled(t *testing.T) {
+	t.Parallel()
+
+	if _, ok := IdempotencyKey(context.Background()); ok {
+		t.Errorf("expected no key outside of an attempt")
+	}
+	Do(context.Background(), time.Second, func(ctx context.Context) (int, error) { //nolint:errcheck
+		if _, ok := IdempotencyKey(ctx); ok {
+			t.Errorf("expected no key without WithIdempotencyKeys")
+		}
+		return 0, nil
+	})
+}
@@ -0,0 +1,61 @@
+package speculatively
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// InflightLimit caps the number of hedged attempts outstanding at once across
+// every call that shares it, e.g. all calls made through a Hedger or a group
+// of Hedgers, so that a latency spike on one dependency cannot cascade into an
+// explosion of goroutines and connections.  Hedges that would exceed the
+// limit are skipped, while the first attempt of each call always launches.
+//
+// An InflightLimit is safe for concurrent use.
+type InflightLimit struct {
+	watermark int64
+	inflight  int64
+}
+
+// NewInflightLimit creates an InflightLimit allowing up to watermark hedged
+// attempts to be outstanding at once.
+func NewInflightLimit(watermark int) *InflightLimit {
+	return &InflightLimit{watermark: int64(watermark)}
+}
+
+// WithInflightLimit skips hedges while the given InflightLimit is reached.
+func WithInflightLimit(l *InflightLimit) Option {
+	return func(c *config) {
+		c.inflight = l
+	}
+}
+
+// Inflight returns the number of hedged attempts currently outstanding.
+func (l *InflightLimit) Inflight() int {
+	return int(atomic.LoadInt64(&l.inflight))
+}
+
+func (l *InflightLimit) acquire() bool {
+	for {
+		n := atomic.LoadInt64(&l.inflight)
+		if n >= l.watermark {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&l.inflight, n, n+1) {
+			return true
+		}
+	}
+}
+
+func (l *InflightLimit) release() {
+	atomic.AddInt64(&l.inflight, -1)
+}
+
+// releasing wraps a Thunk so that the given release func is called once it
+// returns.
+func releasing[T any](thunk Thunk[T], release func()) Thunk[T] {
+	return func(ctx context.Context) (T, error) {
+		defer release()
+		return thunk(ctx)
+	}
+}
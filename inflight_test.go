@@ -0,0 +1,57 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithInflightLimit(t *testing.T) {
+	t.Parallel()
+
+	limit := NewInflightLimit(1)
+	release := make(chan struct{})
+
+	// Every attempt blocks until released, so the first call to hedge holds
+	// the only slot and every other call must skip its hedges
+	var (
+		attempts int64
+		wg       sync.WaitGroup
+	)
+	thunk := func(ctx context.Context) (int, error) {
+		atomic.AddInt64(&attempts, 1)
+		select {
+		case <-release:
+			return 1, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Do(context.Background(), 5*time.Millisecond, thunk, WithInflightLimit(limit), WithMaxAttempts(3)) //nolint:errcheck
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := limit.Inflight(); n != 1 {
+		t.Errorf("expected 1 inflight hedge, got %d", n)
+	}
+	if n := atomic.LoadInt64(&attempts); n != 4 {
+		t.Errorf("expected 3 first attempts and 1 hedge, got %d attempts", n)
+	}
+	close(release)
+	wg.Wait()
+
+	// Hedges release their slots once they exit
+	for deadline := time.Now().Add(time.Second); limit.Inflight() > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if n := limit.Inflight(); n != 0 {
+		t.Errorf("expected no inflight hedges, got %d", n)
+	}
+}
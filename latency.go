@@ -0,0 +1,43 @@
+package speculatively
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// InjectLatency wraps a Thunk so that the given fraction (between 0.0 and 1.0)
+// of its executions are artificially delayed by a random duration between lo
+// and hi before the underlying Thunk is called.
+//
+// This is intended for staging environments, to rehearse how a hedging
+// configuration behaves during a simulated latency regression.  The injected
+// delay respects context cancelation.
+func InjectLatency[T any](thunk Thunk[T], fraction float64, lo, hi time.Duration) Thunk[T] {
+	return func(ctx context.Context) (T, error) {
+		if fraction > 0 && rand.Float64() < fraction {
+			delay := lo
+			if hi > lo {
+				delay += time.Duration(rand.Int63n(int64(hi - lo)))
+			}
+			if err := sleep(ctx, delay); err != nil {
+				var zero T
+				return zero, err
+			}
+		}
+		return thunk(ctx)
+	}
+}
+
+// sleep waits for the given duration or until the context is canceled,
+// whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,65 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInjectLatency(t *testing.T) {
+	t.Parallel()
+
+	t.Run("all calls delayed", func(t *testing.T) {
+		t.Parallel()
+
+		thunk := newSimpleTestThunk(1, nil, 0)
+		delay := 25 * time.Millisecond
+
+		start := time.Now()
+		val, err := InjectLatency(thunk.call, 1, delay, delay)(context.Background())
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val != 1 {
+			t.Errorf("expected val = %d, got %d", 1, val)
+		}
+		if elapsed < delay {
+			t.Errorf("expected delay of at least %s, got %s", delay, elapsed)
+		}
+	})
+
+	t.Run("no calls delayed", func(t *testing.T) {
+		t.Parallel()
+
+		thunk := newSimpleTestThunk(1, nil, 0)
+		delay := time.Second
+
+		start := time.Now()
+		_, err := InjectLatency(thunk.call, 0, delay, delay)(context.Background())
+		elapsed := time.Since(start)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if elapsed >= delay {
+			t.Errorf("expected no delay, got %s", elapsed)
+		}
+	})
+
+	t.Run("delay respects context", func(t *testing.T) {
+		t.Parallel()
+
+		thunk := newSimpleTestThunk(1, nil, 0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := InjectLatency(thunk.call, 1, time.Second, 2*time.Second)(ctx)
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected err = %s, got %v", context.DeadlineExceeded, err)
+		}
+		if callCount := thunk.callCount(); callCount != 0 {
+			t.Errorf("expected Thunk not to run, got %d calls", callCount)
+		}
+	})
+}
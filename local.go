@@ -0,0 +1,112 @@
+package speculatively
+
+import (
+	"context"
+	"time"
+)
+
+// DoLocal races a local source of a result, e.g. a disk cache or an
+// embedded database, against a remote fetch of the same result, preferring
+// the remote result as long as it arrives within the given grace window.
+//
+// Both Thunks are executed immediately, in parallel.  A remote result that
+// arrives within grace is returned as soon as it does.  Once grace has
+// elapsed, whichever result arrives first is returned, including a local
+// result that was already waiting.  If either Thunk fails, the other's
+// result is returned as soon as it arrives, and if both fail the remote
+// error is returned.  A grace of zero or less returns whichever result
+// arrives first.
+//
+// The Thunk whose result is not returned is canceled, and its result is
+// passed to the cleanup func given via WithCleanup, if any, even if it
+// arrives after DoLocal has returned.
+//
+// Each Thunk is executed exactly once, so only the WithCleanup and WithClock
+// Options apply, and every other Option is ignored.  To hedge the remote
+// fetch, e.g. within a Budget, wrap it in a call to Do:
+//
+//	remote := func(ctx context.Context) (T, error) {
+//		return speculatively.Do(ctx, patience, fetch, opts...)
+//	}
+func DoLocal[T any](ctx context.Context, grace time.Duration, local, remote Thunk[T], opts ...Option) (T, error) {
+	cfg := newConfig(opts)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type sourced struct {
+		remote bool
+		val    T
+		err    error
+	}
+	results := make(chan sourced, 2)
+	for _, s := range []struct {
+		remote bool
+		thunk  Thunk[T]
+	}{{false, local}, {true, remote}} {
+		s := s
+		go func() {
+			val, err := s.thunk(ctx)
+			results <- sourced{s.remote, val, err}
+		}()
+	}
+
+	var graceOver <-chan time.Time
+	if grace > 0 {
+		ticker := cfg.newTicker(grace)
+		defer ticker.Stop()
+		graceOver = ticker.C()
+	}
+
+	pending := 2
+	var waiting *sourced // a local result waiting for grace to elapse
+	var localErr, remoteErr error
+	finish := func(r sourced) (T, error) {
+		// Clean up whichever result is discarded, now or once it arrives
+		if waiting != nil && waiting.remote != r.remote {
+			cfg.discard(waiting.val)
+		}
+		go func(pending int) {
+			for ; pending > 0; pending-- {
+				if late := <-results; late.err == nil {
+					cfg.discard(late.val)
+				}
+			}
+		}(pending)
+		return r.val, r.err
+	}
+	for {
+		select {
+		case r := <-results:
+			pending--
+			switch {
+			case r.remote && r.err == nil:
+				return finish(r)
+			case r.remote:
+				remoteErr = r.err
+				if waiting != nil {
+					return finish(*waiting)
+				}
+			case r.err == nil && (graceOver == nil || remoteErr != nil):
+				return finish(r)
+			case r.err == nil:
+				waiting = &r
+			default:
+				localErr = r.err
+			}
+			if localErr != nil && remoteErr != nil {
+				return finish(sourced{remote: true, err: remoteErr})
+			}
+		case <-graceOver:
+			if waiting != nil {
+				return finish(*waiting)
+			}
+			graceOver = nil
+		case <-ctx.Done():
+			if waiting != nil {
+				cfg.discard(waiting.val)
+				waiting = nil
+			}
+			return finish(sourced{err: ctx.Err()})
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoLocal(t *testing.T) {
+	t.Parallel()
+
+	var (
+		localErr  = errors.New("cache miss")
+		remoteErr = errors.New("remote unavailable")
+	)
+
+	testCases := map[string]struct {
+		local, remote *testThunk
+		grace         time.Duration
+		wantVal       int
+		wantErr       error
+		wantMaxDelay  time.Duration
+		wantMinDelay  time.Duration
+	}{
+		"remote within grace": {
+			local:        newSimpleTestThunk(1, nil, 0),
+			remote:       newSimpleTestThunk(2, nil, 20*time.Millisecond),
+			grace:        100 * time.Millisecond,
+			wantVal:      2,
+			wantMinDelay: 20 * time.Millisecond,
+			wantMaxDelay: 80 * time.Millisecond,
+		},
+		"remote after grace": {
+			local:        newSimpleTestThunk(1, nil, 0),
+			remote:       newSimpleTestThunk(2, nil, time.Second),
+			grace:        20 * time.Millisecond,
+			wantVal:      1,
+			wantMinDelay: 20 * time.Millisecond,
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+		"slow local after grace": {
+			local:        newSimpleTestThunk(1, nil, 50*time.Millisecond),
+			remote:       newSimpleTestThunk(2, nil, time.Second),
+			grace:        20 * time.Millisecond,
+			wantVal:      1,
+			wantMinDelay: 50 * time.Millisecond,
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+		"remote fails": {
+			local:        newSimpleTestThunk(1, nil, 0),
+			remote:       newSimpleTestThunk(0, remoteErr, 10*time.Millisecond),
+			grace:        time.Second,
+			wantVal:      1,
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+		"local fails": {
+			local:        newSimpleTestThunk(0, localErr, 0),
+			remote:       newSimpleTestThunk(2, nil, 50*time.Millisecond),
+			grace:        10 * time.Millisecond,
+			wantVal:      2,
+			wantMinDelay: 50 * time.Millisecond,
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+		"both fail": {
+			local:        newSimpleTestThunk(0, localErr, 0),
+			remote:       newSimpleTestThunk(0, remoteErr, 10*time.Millisecond),
+			grace:        time.Second,
+			wantErr:      remoteErr,
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+		"no grace": {
+			local:        newSimpleTestThunk(1, nil, 0),
+			remote:       newSimpleTestThunk(2, nil, time.Second),
+			wantVal:      1,
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.Now()
+			val, err := DoLocal(context.Background(), tc.grace, tc.local.call, tc.remote.call)
+			elapsed := time.Since(start)
+			if err != tc.wantErr {
+				t.Fatalf("expected err = %v, got %v", tc.wantErr, err)
+			}
+			if val != tc.wantVal {
+				t.Errorf("expected val = %d, got %d", tc.wantVal, val)
+			}
+			if elapsed < tc.wantMinDelay || elapsed > tc.wantMaxDelay {
+				t.Errorf("expected call to take between %s and %s, got %s", tc.wantMinDelay, tc.wantMaxDelay, elapsed)
+			}
+		})
+	}
+}
+
+func TestDoLocalCleanup(t *testing.T) {
+	t.Parallel()
+
+	var cleaned int64
+	cleanup := WithCleanup(func(val int) { atomic.StoreInt64(&cleaned, int64(val)) })
+
+	// The local result waiting for the remote one is discarded
+	remote := func(context.Context) (int, error) {
+		time.Sleep(20 * time.Millisecond)
+		return 2, nil
+	}
+	val, err := DoLocal(context.Background(), time.Second, newSimpleTestThunk(1, nil, 0).call, remote, cleanup)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 2 {
+		t.Errorf("expected val = %d, got %d", 2, val)
+	}
+	if got := atomic.LoadInt64(&cleaned); got != 1 {
+		t.Errorf("expected local result to be cleaned up, got %d", got)
+	}
+
+	// The remote result arriving after the call returned is discarded,
+	// even though it ignored cancelation
+	atomic.StoreInt64(&cleaned, 0)
+	val, err = DoLocal(context.Background(), 0, newSimpleTestThunk(1, nil, 0).call, remote, cleanup)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 1 {
+		t.Errorf("expected val = %d, got %d", 1, val)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt64(&cleaned); got != 2 {
+		t.Errorf("expected late remote result to be cleaned up, got %d", got)
+	}
+}
+
+func TestDoLocalContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := DoLocal(ctx, time.Second, newSimpleTestThunk(1, nil, time.Second).call, newSimpleTestThunk(2, nil, time.Second).call)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected err = %v, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,139 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithConcurrency caps the number of items processed at once by Map and its
+// variants, each of which may be running several attempts.  Values less
+// than 1 mean no limit, which is the default.
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithPartialResults makes Map and DoMap return the results of every item
+// that succeeded along with the errors of those that failed, rather than
+// failing as a whole if any item fails, e.g. for batch reads that can
+// tolerate missing a few keys.
+func WithPartialResults() Option {
+	return func(c *config) {
+		c.partialResults = true
+	}
+}
+
+// Map speculatively applies fn to every input, hedging each application as
+// Do does, and returns the outputs in the same order as the inputs.  At most
+// as many inputs as allowed by WithConcurrency are processed at once, and
+// the given Options otherwise apply to each input's call, e.g. so that every
+// call shares a Budget.
+//
+// The first error returned for any input cancels the processing of every
+// other input and is returned, unless WithPartialResults is set.  Then,
+// every input is processed even if others fail, and the outputs are returned
+// along with the errors of the failed inputs, by index, as ItemErrors, in
+// which case the outputs of the failed inputs are zero values.  Inputs not
+// processed before ctx is done fail with its error.
+func Map[I, O any](ctx context.Context, patience time.Duration, inputs []I, fn func(context.Context, I) (O, error), opts ...Option) ([]O, error) {
+	cfg := newConfig(opts)
+	if cfg.partialResults {
+		return mapPartial(ctx, cfg, patience, inputs, fn, opts)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outputs := make([]O, len(inputs))
+	var (
+		once     sync.Once
+		firstErr error
+	)
+	err := forEachInput(ctx, cfg, len(inputs), func(ctx context.Context, i int) {
+		out, err := Do(ctx, patience, func(ctx context.Context) (O, error) {
+			return fn(ctx, inputs[i])
+		}, opts...)
+		if err != nil {
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+			return
+		}
+		outputs[i] = out
+	})
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// mapPartial implements Map when WithPartialResults is set.
+func mapPartial[I, O any](ctx context.Context, cfg *config, patience time.Duration, inputs []I, fn func(context.Context, I) (O, error), opts []Option) ([]O, error) {
+	outputs := make([]O, len(inputs))
+	errs := make([]error, len(inputs))
+	processed := make([]bool, len(inputs))
+	err := forEachInput(ctx, cfg, len(inputs), func(ctx context.Context, i int) {
+		processed[i] = true
+		outputs[i], errs[i] = Do(ctx, patience, func(ctx context.Context) (O, error) {
+			return fn(ctx, inputs[i])
+		}, opts...)
+	})
+
+	var failed ItemErrors
+	for i := range inputs {
+		if !processed[i] {
+			errs[i] = err
+		}
+		if errs[i] != nil {
+			failed = append(failed, ItemError{Index: i, Err: errs[i]})
+		}
+	}
+	if len(failed) > 0 {
+		return outputs, failed
+	}
+	return outputs, nil
+}
+
+// forEachInput calls process with the index of each of n inputs, each from a
+// goroutine of its own, with at most as many calls running at once as
+// allowed by WithConcurrency, and waits for every call to return.  Each call
+// is passed the input's context, derived from ctx by WithDeadlineSplit, if
+// set.  If ctx is done before every input is processed, no further calls are
+// made and its error is returned.
+func forEachInput(ctx context.Context, cfg *config, n int, process func(ctx context.Context, i int)) error {
+	var sem chan struct{}
+	if cfg.concurrency > 0 {
+		sem = make(chan struct{}, cfg.concurrency)
+	}
+	split := newSplitter(ctx, cfg, n)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i := 0; i < n; i++ {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			ctx, cancel := split.item(ctx, i)
+			defer cancel()
+			process(ctx, i)
+		}(i)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	t.Parallel()
+
+	inputs := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	var running, peak, calls int64
+	outputs, err := Map(context.Background(), 10*time.Millisecond, inputs, func(ctx context.Context, in int) (int, error) {
+		n := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		// The first attempt for every input is slow, so that each one is
+		// hedged
+		delay := 5 * time.Millisecond
+		if !IsHedge(ctx) {
+			delay = time.Second
+		}
+		atomic.AddInt64(&calls, 1)
+		if err := sleep(ctx, delay); err != nil {
+			return 0, err
+		}
+		return in * 10, nil
+	}, WithConcurrency(2), WithMaxAttempts(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, in := range inputs {
+		if outputs[i] != in*10 {
+			t.Errorf("expected outputs[%d] = %d, got %d", i, in*10, outputs[i])
+		}
+	}
+	if got := atomic.LoadInt64(&calls); got != 2*int64(len(inputs)) {
+		t.Errorf("expected %d attempts, got %d", 2*len(inputs), got)
+	}
+	// Each of the 2 inputs processed at once runs up to 2 attempts
+	if got := atomic.LoadInt64(&peak); got > 4 {
+		t.Errorf("expected at most %d attempts running at once, got %d", 4, got)
+	}
+}
+
+func TestMapError(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	var completed int64
+	start := time.Now()
+	_, err := Map(context.Background(), time.Second, []int{1, 2, 3}, func(ctx context.Context, in int) (int, error) {
+		if in == 2 {
+			return 0, failed
+		}
+		if err := sleep(ctx, time.Second); err != nil {
+			return 0, err
+		}
+		atomic.AddInt64(&completed, 1)
+		return in, nil
+	})
+	if err != failed {
+		t.Fatalf("expected err = %v, got %v", failed, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected error to cancel other inputs, took %s", elapsed)
+	}
+	if got := atomic.LoadInt64(&completed); got != 0 {
+		t.Errorf("expected every other input to be canceled, %d completed", got)
+	}
+}
+
+func TestMapContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := Map(ctx, time.Second, []int{1, 2, 3}, func(ctx context.Context, in int) (int, error) {
+		return in, sleep(ctx, time.Second)
+	}, WithConcurrency(1))
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected err = %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestMapPartialResults(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	outputs, err := Map(context.Background(), time.Second, []int{1, 2, 3}, func(ctx context.Context, in int) (int, error) {
+		if in == 2 {
+			return 0, failed
+		}
+		if err := sleep(ctx, 10*time.Millisecond); err != nil {
+			return 0, err
+		}
+		return in * 10, nil
+	}, WithPartialResults())
+	var itemErrs ItemErrors
+	if !errors.As(err, &itemErrs) {
+		t.Fatalf("expected ItemErrors, got %v", err)
+	}
+	if len(itemErrs) != 1 || itemErrs[0].Index != 1 || itemErrs[0].Err != failed {
+		t.Errorf("expected only input 1 to fail, got %v", itemErrs)
+	}
+	if want := []int{10, 0, 30}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("expected outputs = %v, got %v", want, outputs)
+	}
+}
+
+func TestMapPartialResultsContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	outputs, err := Map(ctx, time.Second, []int{1, 2, 3}, func(ctx context.Context, in int) (int, error) {
+		if in == 1 {
+			return in, nil
+		}
+		return in, sleep(ctx, time.Second)
+	}, WithConcurrency(1), WithPartialResults())
+	var itemErrs ItemErrors
+	if !errors.As(err, &itemErrs) {
+		t.Fatalf("expected ItemErrors, got %v", err)
+	}
+	if len(itemErrs) != 2 {
+		t.Fatalf("expected inputs 1 and 2 to fail, got %v", itemErrs)
+	}
+	for i, e := range itemErrs {
+		if e.Index != i+1 || e.Err != context.DeadlineExceeded {
+			t.Errorf("expected input %d to fail with %v, got %v", i+1, context.DeadlineExceeded, e)
+		}
+	}
+	if outputs[0] != 1 {
+		t.Errorf("expected output of input 0 = 1, got %d", outputs[0])
+	}
+}
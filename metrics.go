@@ -0,0 +1,56 @@
+package speculatively
+
+import "time"
+
+// MetricsRecorder receives the events needed to record metrics describing
+// hedging activity, independent of any particular metrics system.
+//
+// Every method is called synchronously, from the call's or attempt's own
+// goroutine, so implementations must be safe for concurrent use and must not
+// block.  Implementations may embed NopMetricsRecorder to only record some
+// events.
+type MetricsRecorder interface {
+	// RecordAttempt is called when an attempt is launched.
+	RecordAttempt(a Attempt)
+	// RecordAttemptDone is called when an attempt's Thunk returns.
+	RecordAttemptDone(a Attempt, elapsed time.Duration, err error)
+	// RecordWinner is called with the attempt whose successful result is
+	// returned by the call.
+	RecordWinner(a Attempt)
+	// RecordSuppressedHedge is called every time a hedge is due but is not
+	// launched.
+	RecordSuppressedHedge(s Suppression)
+	// RecordTermination is called with how every attempt ended once the
+	// call ends.
+	RecordTermination(a Attempt, t Termination)
+}
+
+// NopMetricsRecorder is a MetricsRecorder that records nothing.
+type NopMetricsRecorder struct{}
+
+// RecordAttempt implements MetricsRecorder.
+func (NopMetricsRecorder) RecordAttempt(Attempt) {}
+
+// RecordAttemptDone implements MetricsRecorder.
+func (NopMetricsRecorder) RecordAttemptDone(Attempt, time.Duration, error) {}
+
+// RecordWinner implements MetricsRecorder.
+func (NopMetricsRecorder) RecordWinner(Attempt) {}
+
+// RecordSuppressedHedge implements MetricsRecorder.
+func (NopMetricsRecorder) RecordSuppressedHedge(Suppression) {}
+
+// RecordTermination implements MetricsRecorder.
+func (NopMetricsRecorder) RecordTermination(Attempt, Termination) {}
+
+// WithMetrics records the metrics of calls with the given MetricsRecorder.  It
+// may be given more than once, in which case every recorder is used.
+func WithMetrics(r MetricsRecorder) Option {
+	return withTelemetryHooks(Hooks{
+		OnLaunch:          r.RecordAttempt,
+		OnDone:            r.RecordAttemptDone,
+		OnWinner:          r.RecordWinner,
+		OnHedgeSuppressed: r.RecordSuppressedHedge,
+		OnTermination:     r.RecordTermination,
+	})
+}
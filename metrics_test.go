@@ -0,0 +1,53 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRecorder counts the events it records.
+type testRecorder struct {
+	NopMetricsRecorder
+
+	mu       sync.Mutex
+	attempts int
+	winners  []int
+}
+
+func (r *testRecorder) RecordAttempt(Attempt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+}
+
+func (r *testRecorder) RecordWinner(a Attempt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.winners = append(r.winners, a.Index)
+}
+
+func TestWithMetrics(t *testing.T) {
+	t.Parallel()
+
+	thunk := newTestThunk([]result[int]{{val: 1}, {val: 2}}, []time.Duration{time.Second, 5 * time.Millisecond})
+	rec := &testRecorder{}
+
+	val, err := Do(context.Background(), 10*time.Millisecond, thunk.call, WithMetrics(rec), WithMetrics(NopMetricsRecorder{}), WithMaxAttempts(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 2 {
+		t.Errorf("expected val = %d, got %d", 2, val)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", rec.attempts)
+	}
+	if len(rec.winners) != 1 || rec.winners[0] != 1 {
+		t.Errorf("expected winners = [1], got %v", rec.winners)
+	}
+}
@@ -0,0 +1,69 @@
+package speculatively
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Middleware wraps a Thunk with cross-cutting behavior, e.g. logging,
+// metrics, fault injection or timeouts.
+type Middleware[T any] func(Thunk[T]) Thunk[T]
+
+// Wrap wraps a Thunk with the given Middlewares.  The first Middleware is the
+// outermost, so that it sees each execution first and its result last.
+func Wrap[T any](thunk Thunk[T], middlewares ...Middleware[T]) Thunk[T] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		thunk = middlewares[i](thunk)
+	}
+	return thunk
+}
+
+// ObserveMiddleware calls fn with the elapsed time and error of every
+// execution of the wrapped Thunk, e.g. to log it or record metrics.
+func ObserveMiddleware[T any](fn func(ctx context.Context, elapsed time.Duration, err error)) Middleware[T] {
+	return func(thunk Thunk[T]) Thunk[T] {
+		return func(ctx context.Context) (T, error) {
+			start := time.Now()
+			val, err := thunk(ctx)
+			fn(ctx, time.Since(start), err)
+			return val, err
+		}
+	}
+}
+
+// ChaosMiddleware subjects executions of the wrapped Thunk to the given
+// Chaos.  See InjectChaos.
+func ChaosMiddleware[T any](c Chaos) Middleware[T] {
+	return func(thunk Thunk[T]) Thunk[T] {
+		return InjectChaos(thunk, c)
+	}
+}
+
+// PanicError is the error returned by a Thunk wrapped by RecoverMiddleware
+// that panicked.
+type PanicError struct {
+	// Value is the value the Thunk panicked with.
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("speculatively: thunk panicked: %v", e.Value)
+}
+
+// RecoverMiddleware turns panics in the wrapped Thunk into a *PanicError, so
+// that a panicking attempt fails like any other instead of crashing the
+// process from the attempt's goroutine.
+func RecoverMiddleware[T any]() Middleware[T] {
+	return func(thunk Thunk[T]) Thunk[T] {
+		return func(ctx context.Context) (val T, err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					var zero T
+					val, err = zero, &PanicError{Value: v}
+				}
+			}()
+			return thunk(ctx)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWrap(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	trace := func(name string) Middleware[int] {
+		return func(thunk Thunk[int]) Thunk[int] {
+			return func(ctx context.Context) (int, error) {
+				calls = append(calls, name+" before")
+				val, err := thunk(ctx)
+				calls = append(calls, name+" after")
+				return val, err
+			}
+		}
+	}
+	thunk := Wrap(func(context.Context) (int, error) {
+		calls = append(calls, "thunk")
+		return 1, nil
+	}, trace("outer"), trace("inner"))
+
+	val, err := thunk(context.Background())
+	if err != nil || val != 1 {
+		t.Fatalf("expected val = 1, got %d, %v", val, err)
+	}
+	want := []string{"outer before", "inner before", "thunk", "inner after", "outer after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+}
+
+func TestObserveMiddleware(t *testing.T) {
+	t.Parallel()
+
+	errBoom := errors.New("boom")
+	var (
+		observed time.Duration
+		gotErr   error
+	)
+	thunk := Wrap(func(context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 0, errBoom
+	}, ObserveMiddleware[int](func(_ context.Context, elapsed time.Duration, err error) {
+		observed, gotErr = elapsed, err
+	}))
+
+	thunk(context.Background()) //nolint:errcheck
+	if gotErr != errBoom {
+		t.Errorf("expected observed err = %v, got %v", errBoom, gotErr)
+	}
+	if observed < 10*time.Millisecond {
+		t.Errorf("expected observed elapsed time of at least 10ms, got %s", observed)
+	}
+}
+
+func TestChaosMiddleware(t *testing.T) {
+	t.Parallel()
+
+	thunk := Wrap(newSimpleTestThunk(1, nil, 0).call, ChaosMiddleware[int](Chaos{ErrorRate: 1}))
+	if _, err := thunk(context.Background()); err != ErrInjected {
+		t.Errorf("expected err = %v, got %v", ErrInjected, err)
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	t.Parallel()
+
+	thunk := Wrap(func(context.Context) (int, error) {
+		panic("boom")
+	}, RecoverMiddleware[int]())
+
+	_, err := Do(context.Background(), time.Second, thunk)
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected panic error, got %v", err)
+	}
+	if panicErr.Value != "boom" {
+		t.Errorf("expected panic value %q, got %v", "boom", panicErr.Value)
+	}
+}
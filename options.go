@@ -0,0 +1,114 @@
+package speculatively
+
+import (
+	"context"
+	"time"
+)
+
+// Option customizes the behavior of Do and its variants.
+type Option func(*config)
+
+// config holds the settings derived from a set of Options.
+type config struct {
+	maxAttempts int
+	budget      *Budget
+	retryable   func(error) bool
+	cleanup     func(interface{})
+	hooks       hookList
+	userHooks   hookList
+	tracker     *LatencyTracker
+	quantile    float64
+	staleness   time.Duration
+	stats       *stats
+	errorGate   *ErrorGate
+
+	adaptiveAttempts *AdaptiveAttempts
+	newCheckpoints   func() CheckpointStore
+
+	lowPriorityHedges bool
+	inflight          *InflightLimit
+	portfolio         *Portfolio
+	portfolioKey      string
+	canary            *Canary
+	traceName         string
+	profileKey        string
+	profileLabels     bool
+	latencyBuckets    []time.Duration
+	attemptContext    []func(context.Context, Attempt) context.Context
+	exemplars         *Exemplars
+	httpPhases        bool
+	sampler           *sampler
+	categorizer       Categorizer
+	runners           []Runner
+	idempotencyKeys   bool
+	fence             func(string, Attempt)
+	equal             func(a, b interface{}) bool
+	clock             Clock
+	semaphore         Semaphore
+	weight            int64
+	concurrency       int
+	firstResults      int
+	hedgePriority     int
+	queueHedges       bool
+	deadlineSplit     DeadlineSplit
+	partialResults    bool
+	resultKey         func(interface{}) interface{}
+	timestamped       func(interface{}) (Timestamped, bool)
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithMaxAttempts caps the total number of attempts (including the first) made
+// by a single call.  Values less than 1 mean no limit, which is the default.
+func WithMaxAttempts(n int) Option {
+	return func(c *config) {
+		c.maxAttempts = n
+	}
+}
+
+// WithBudget limits the hedged attempts launched by every call using this
+// option to those allowed by the given Budget.  A Budget is typically shared
+// across many calls.
+func WithBudget(b *Budget) Option {
+	return func(c *config) {
+		c.budget = b
+	}
+}
+
+// WithRetryable configures a func that reports whether an attempt's error is
+// retryable.  Rather than ending the call, a retryable error immediately
+// launches the next attempt, if any, and the call only fails with a retryable
+// error once every attempt has failed.  By default, the first result of any
+// attempt ends the call, even if it is an error.
+func WithRetryable(fn func(error) bool) Option {
+	return func(c *config) {
+		c.retryable = fn
+	}
+}
+
+// WithCleanup configures a func that is called with the value of every
+// successful attempt whose result is discarded, because another attempt won
+// or the call was canceled.  This is necessary when results hold resources,
+// e.g. to close losing connections or response bodies.
+func WithCleanup[T any](fn func(T)) Option {
+	return func(c *config) {
+		c.cleanup = func(val interface{}) {
+			if v, ok := val.(T); ok {
+				fn(v)
+			}
+		}
+	}
+}
+
+// discard cleans up a successful result that will not be returned.
+func (c *config) discard(val interface{}) {
+	if c.cleanup != nil {
+		c.cleanup(val)
+	}
+}
@@ -0,0 +1,125 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithMaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	thunk := newSimpleTestThunk(1, nil, 50*time.Millisecond)
+	patience := 5 * time.Millisecond
+
+	val, err := Do(context.Background(), patience, thunk.call, WithMaxAttempts(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 1 {
+		t.Errorf("expected val = %d, got %d", 1, val)
+	}
+	if callCount := thunk.callCount(); callCount != 2 {
+		t.Errorf("expected Thunk to run %d times, got %d", 2, callCount)
+	}
+}
+
+func TestWithBudget(t *testing.T) {
+	t.Parallel()
+
+	budget := NewBudget(0, 1)
+	patience := 5 * time.Millisecond
+
+	// The first call may spend the single banked hedge, but not more
+	thunk := newSimpleTestThunk(1, nil, 30*time.Millisecond)
+	if _, err := Do(context.Background(), patience, thunk.call, WithBudget(budget)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if callCount := thunk.callCount(); callCount != 2 {
+		t.Errorf("expected Thunk to run %d times, got %d", 2, callCount)
+	}
+
+	// The budget is now exhausted, so the second call may not hedge at all
+	thunk = newSimpleTestThunk(1, nil, 30*time.Millisecond)
+	if _, err := Do(context.Background(), patience, thunk.call, WithBudget(budget)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if callCount := thunk.callCount(); callCount != 1 {
+		t.Errorf("expected Thunk to run %d times, got %d", 1, callCount)
+	}
+}
+
+func TestWithRetryable(t *testing.T) {
+	t.Parallel()
+
+	t.Run("retryable error launches next attempt immediately", func(t *testing.T) {
+		t.Parallel()
+
+		results := []result[int]{
+			{val: 0, err: errors.New("retryable")},
+			{val: 2, err: nil},
+		}
+		thunk := newTestThunk(results, []time.Duration{0})
+
+		val, err := Do(context.Background(), time.Second, thunk.call, WithRetryable(func(error) bool { return true }))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val != 2 {
+			t.Errorf("expected val = %d, got %d", 2, val)
+		}
+	})
+
+	t.Run("last error returned once attempts are exhausted", func(t *testing.T) {
+		t.Parallel()
+
+		wantErr := errors.New("retryable")
+		thunk := newSimpleTestThunk(0, wantErr, 0)
+
+		_, err := Do(context.Background(), time.Second, thunk.call, WithMaxAttempts(3), WithRetryable(func(error) bool { return true }))
+		if err != wantErr {
+			t.Errorf("expected err = %s, got %v", wantErr, err)
+		}
+		if callCount := thunk.callCount(); callCount != 3 {
+			t.Errorf("expected Thunk to run %d times, got %d", 3, callCount)
+		}
+	})
+}
+
+func TestWithCleanup(t *testing.T) {
+	t.Parallel()
+
+	results := []result[int]{
+		{val: 1, err: nil},
+		{val: 2, err: nil},
+	}
+	delays := []time.Duration{
+		50 * time.Millisecond,
+		5 * time.Millisecond,
+	}
+	thunk := newTestThunk(results, delays)
+	cleaned := make(chan int, 2)
+
+	// The first attempt ignores cancelation, so its result is discarded
+	// after the second attempt wins
+	ignoreCancel := func(ctx context.Context) (int, error) {
+		return thunk.call(context.Background())
+	}
+	val, err := Do(context.Background(), 10*time.Millisecond, ignoreCancel, WithCleanup(func(v int) { cleaned <- v }))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 2 {
+		t.Errorf("expected val = %d, got %d", 2, val)
+	}
+
+	select {
+	case v := <-cleaned:
+		if v != 1 {
+			t.Errorf("expected losing value %d to be cleaned up, got %d", 1, v)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected losing value to be cleaned up")
+	}
+}
@@ -0,0 +1,94 @@
+package otelspeculatively
+
+import (
+	"context"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Attribute keys set on metrics.
+const (
+	NameKey        = attribute.Key("speculatively.name")
+	ReasonKey      = attribute.Key("speculatively.reason")
+	TerminationKey = attribute.Key("speculatively.termination")
+)
+
+// MetricsRecorder is a speculatively.MetricsRecorder recording OpenTelemetry
+// metrics, with every measurement attributed to a name.
+type MetricsRecorder struct {
+	name         attribute.KeyValue
+	attempts     metric.Int64Counter
+	hedges       metric.Int64Counter
+	hedgeWins    metric.Int64Counter
+	suppressed   metric.Int64Counter
+	terminations metric.Int64Counter
+	latency      metric.Float64Histogram
+}
+
+var _ speculatively.MetricsRecorder = (*MetricsRecorder)(nil)
+
+// NewMetricsRecorder creates a MetricsRecorder recording the metrics of calls
+// under the given name, using instruments created by the given Meter.
+func NewMetricsRecorder(meter metric.Meter, name string) (*MetricsRecorder, error) {
+	r := &MetricsRecorder{name: NameKey.String(name)}
+	var err error
+	if r.attempts, err = meter.Int64Counter("speculatively.attempts",
+		metric.WithDescription("Attempts launched, including hedges.")); err != nil {
+		return nil, err
+	}
+	if r.hedges, err = meter.Int64Counter("speculatively.hedges",
+		metric.WithDescription("Hedged attempts launched.")); err != nil {
+		return nil, err
+	}
+	if r.hedgeWins, err = meter.Int64Counter("speculatively.hedge_wins",
+		metric.WithDescription("Calls won by a hedged attempt.")); err != nil {
+		return nil, err
+	}
+	if r.suppressed, err = meter.Int64Counter("speculatively.hedges_suppressed",
+		metric.WithDescription("Hedges not launched when due, by reason.")); err != nil {
+		return nil, err
+	}
+	if r.terminations, err = meter.Int64Counter("speculatively.attempt.terminations",
+		metric.WithDescription("Attempts ended, by termination reason.")); err != nil {
+		return nil, err
+	}
+	if r.latency, err = meter.Float64Histogram("speculatively.attempt.duration",
+		metric.WithDescription("Duration of attempts, including hedges."),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RecordAttempt implements speculatively.MetricsRecorder.
+func (r *MetricsRecorder) RecordAttempt(a speculatively.Attempt) {
+	r.attempts.Add(context.Background(), 1, metric.WithAttributes(r.name))
+	if a.Index > 0 {
+		r.hedges.Add(context.Background(), 1, metric.WithAttributes(r.name))
+	}
+}
+
+// RecordAttemptDone implements speculatively.MetricsRecorder.
+func (r *MetricsRecorder) RecordAttemptDone(a speculatively.Attempt, elapsed time.Duration, _ error) {
+	r.latency.Record(context.Background(), elapsed.Seconds(), metric.WithAttributes(r.name, HedgeKey.Bool(a.Index > 0)))
+}
+
+// RecordWinner implements speculatively.MetricsRecorder.
+func (r *MetricsRecorder) RecordWinner(a speculatively.Attempt) {
+	if a.Index > 0 {
+		r.hedgeWins.Add(context.Background(), 1, metric.WithAttributes(r.name))
+	}
+}
+
+// RecordSuppressedHedge implements speculatively.MetricsRecorder.
+func (r *MetricsRecorder) RecordSuppressedHedge(s speculatively.Suppression) {
+	r.suppressed.Add(context.Background(), 1, metric.WithAttributes(r.name, ReasonKey.String(s.String())))
+}
+
+// RecordTermination implements speculatively.MetricsRecorder.
+func (r *MetricsRecorder) RecordTermination(_ speculatively.Attempt, t speculatively.Termination) {
+	r.terminations.Add(context.Background(), 1, metric.WithAttributes(r.name, TerminationKey.String(t.String())))
+}
@@ -0,0 +1,62 @@
+package otelspeculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricsRecorder(t *testing.T) {
+	t.Parallel()
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	rec, err := NewMetricsRecorder(meter, "lookup")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, err = speculatively.Do(context.Background(), 5*time.Millisecond, func(ctx context.Context) (int, error) {
+		if speculatively.IsHedge(ctx) {
+			return 2, nil
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, speculatively.WithMetrics(rec))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sums := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range sum.DataPoints {
+					if v, _ := dp.Attributes.Value(NameKey); v.AsString() != "lookup" {
+						t.Errorf("expected %s = %q, got %q", NameKey, "lookup", v.AsString())
+					}
+					sums[m.Name] += dp.Value
+				}
+			}
+		}
+	}
+	for name, want := range map[string]int64{
+		"speculatively.attempts":   2,
+		"speculatively.hedges":     1,
+		"speculatively.hedge_wins": 1,
+
+		"speculatively.attempt.terminations": 2,
+	} {
+		if got := sums[name]; got != want {
+			t.Errorf("expected %s = %d, got %d", name, want, got)
+		}
+	}
+}
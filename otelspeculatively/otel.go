@@ -0,0 +1,201 @@
+// Package otelspeculatively provides OpenTelemetry tracing and metrics for
+// speculatively.
+//
+// A span is created for every call, with a child span for every attempt, so
+// that hedged fan-out is visible in distributed traces.  MetricsRecorder
+// records metrics describing hedging activity.
+package otelspeculatively
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys set on attempt spans.
+const (
+	AttemptKey     = attribute.Key("speculatively.attempt")
+	HedgeKey       = attribute.Key("speculatively.hedge")
+	TargetKey      = attribute.Key("speculatively.target")
+	WonKey         = attribute.Key("speculatively.won")
+	CancelCauseKey = attribute.Key("speculatively.cancel_cause")
+)
+
+// Attribute keys set on call spans.
+const (
+	AttemptsKey = attribute.Key("speculatively.attempts")
+	WinnerKey   = attribute.Key("speculatively.winner")
+)
+
+// CancelCauseSuperseded is the cancelation cause recorded for attempts that
+// were canceled because another attempt won the call.
+const CancelCauseSuperseded = "superseded"
+
+// Do speculatively executes a Thunk like speculatively.Do, recording a span
+// named after the given name for the call, as a child of any span in the
+// given context, and a child span for every attempt.
+func Do[T any](ctx context.Context, tracer trace.Tracer, name string, patience time.Duration, thunk speculatively.Thunk[T], opts ...speculatively.Option) (T, error) {
+	ctx, call := Start(ctx, tracer, name)
+	val, err := speculatively.Do(ctx, patience, Thunk(call, thunk), append(opts[:len(opts):len(opts)], call.Option())...)
+	call.End(err)
+	return val, err
+}
+
+// Call traces a single call.  It is intended for tracing entry points other
+// than Do, e.g.:
+//
+//	ctx, call := otelspeculatively.Start(ctx, tracer, "lookup")
+//	val, err := speculatively.DoReplicas(ctx, patience, replicas, thunk, call.Option())
+//	call.End(err)
+//
+// where every thunk is wrapped via Thunk or ReplicaThunk.
+type Call struct {
+	tracer trace.Tracer
+	name   string
+	parent context.Context
+	span   trace.Span
+
+	mu       sync.Mutex
+	attempts int
+	winner   int
+	ended    bool
+	pending  []*attemptSpan
+}
+
+// Start starts a span for a call.  The returned context must be given to the
+// entry point executing the call, and Call.End must be called once it
+// returns.
+func Start(ctx context.Context, tracer trace.Tracer, name string) (context.Context, *Call) {
+	c := &Call{
+		tracer: tracer,
+		name:   name,
+		parent: ctx,
+		winner: -1,
+	}
+	ctx, c.span = tracer.Start(ctx, name)
+	return ctx, c
+}
+
+// Option returns an Option that must be given to the entry point executing
+// the call, so that the winning attempt can be identified.
+func (c *Call) Option() speculatively.Option {
+	return speculatively.WithHooks(speculatively.Hooks{
+		OnWinner: func(a speculatively.Attempt) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.winner = a.Index
+		},
+	})
+}
+
+// End ends the call's span, recording the given error, if any.  The spans of
+// attempts still running end once their thunks return.
+func (c *Call) End(err error) {
+	c.mu.Lock()
+	c.ended = true
+	pending := c.pending
+	c.pending = nil
+	c.span.SetAttributes(AttemptsKey.Int(c.attempts))
+	if c.winner >= 0 {
+		c.span.SetAttributes(WinnerKey.Int(c.winner))
+	}
+	c.mu.Unlock()
+
+	for _, a := range pending {
+		c.endAttempt(a)
+	}
+	if err != nil {
+		c.span.RecordError(err)
+		c.span.SetStatus(codes.Error, err.Error())
+	}
+	c.span.End()
+}
+
+// Thunk wraps a Thunk so that every attempt to execute it records a span as a
+// child of the call's span.
+func Thunk[T any](c *Call, thunk speculatively.Thunk[T]) speculatively.Thunk[T] {
+	return func(ctx context.Context) (T, error) {
+		ctx, a := c.startAttempt(ctx)
+		val, err := thunk(ctx)
+		a.err = err
+		a.canceled = ctx.Err()
+		c.finishAttempt(a)
+		return val, err
+	}
+}
+
+// ReplicaThunk wraps a ReplicaThunk like Thunk.
+func ReplicaThunk[R, T any](c *Call, thunk speculatively.ReplicaThunk[R, T]) speculatively.ReplicaThunk[R, T] {
+	return func(ctx context.Context, replica R) (T, error) {
+		return Thunk(c, func(ctx context.Context) (T, error) {
+			return thunk(ctx, replica)
+		})(ctx)
+	}
+}
+
+// attemptSpan is the span of an attempt, along with its outcome.
+type attemptSpan struct {
+	index    int
+	span     trace.Span
+	err      error
+	canceled error
+}
+
+func (c *Call) startAttempt(ctx context.Context) (context.Context, *attemptSpan) {
+	a := &attemptSpan{}
+	var attrs []attribute.KeyValue
+	if attempt, ok := speculatively.AttemptFromContext(ctx); ok {
+		a.index = attempt.Index
+		attrs = append(attrs, AttemptKey.Int(attempt.Index), HedgeKey.Bool(attempt.Index > 0))
+		if attempt.Target != nil {
+			attrs = append(attrs, TargetKey.String(fmt.Sprint(attempt.Target)))
+		}
+	}
+	ctx, a.span = c.tracer.Start(ctx, fmt.Sprintf("%s attempt %d", c.name, a.index), trace.WithAttributes(attrs...))
+
+	c.mu.Lock()
+	c.attempts++
+	c.mu.Unlock()
+	return ctx, a
+}
+
+// finishAttempt ends an attempt's span if the call has ended, or defers it
+// until the call ends otherwise, because whether the attempt won is not
+// known until then.
+func (c *Call) finishAttempt(a *attemptSpan) {
+	c.mu.Lock()
+	if !c.ended {
+		c.pending = append(c.pending, a)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	c.endAttempt(a)
+}
+
+func (c *Call) endAttempt(a *attemptSpan) {
+	c.mu.Lock()
+	won := a.index == c.winner
+	c.mu.Unlock()
+
+	a.span.SetAttributes(WonKey.Bool(won))
+	if !won && a.canceled != nil {
+		cause := CancelCauseSuperseded
+		if err := c.parent.Err(); err != nil {
+			cause = err.Error()
+		}
+		a.span.SetAttributes(CancelCauseKey.String(cause))
+	}
+	if a.err != nil && !errors.Is(a.err, a.canceled) {
+		a.span.RecordError(a.err)
+		a.span.SetStatus(codes.Error, a.err.Error())
+	}
+	a.span.End()
+}
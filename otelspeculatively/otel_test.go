@@ -0,0 +1,185 @@
+package otelspeculatively
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTracer() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	rec := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)), rec
+}
+
+// waitForSpans waits for the given number of spans to end.
+func waitForSpans(t *testing.T, rec *tracetest.SpanRecorder, n int) []sdktrace.ReadOnlySpan {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); len(rec.Ended()) < n && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	spans := rec.Ended()
+	if len(spans) != n {
+		t.Fatalf("expected %d spans, got %d", n, len(spans))
+	}
+	return spans
+}
+
+func findSpan(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name() == name {
+			return s
+		}
+	}
+	t.Fatalf("expected span %q", name)
+	return nil
+}
+
+func attr(s sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	tp, rec := newTracer()
+	tracer := tp.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	val, err := Do(ctx, tracer, "call", 10*time.Millisecond, func(ctx context.Context) (int, error) {
+		if speculatively.IsHedge(ctx) {
+			return 2, nil
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	parent.End()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 2 {
+		t.Errorf("expected val = %d, got %d", 2, val)
+	}
+
+	spans := waitForSpans(t, rec, 4)
+	call := findSpan(t, spans, "call")
+	if call.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected call span to be a child of the caller's span")
+	}
+	if v, _ := attr(call, AttemptsKey); v.AsInt64() != 2 {
+		t.Errorf("expected %s = %d, got %d", AttemptsKey, 2, v.AsInt64())
+	}
+	if v, _ := attr(call, WinnerKey); v.AsInt64() != 1 {
+		t.Errorf("expected %s = %d, got %d", WinnerKey, 1, v.AsInt64())
+	}
+
+	first := findSpan(t, spans, "call attempt 0")
+	hedge := findSpan(t, spans, "call attempt 1")
+	for _, s := range []sdktrace.ReadOnlySpan{first, hedge} {
+		if s.Parent().SpanID() != call.SpanContext().SpanID() {
+			t.Errorf("expected %q span to be a child of the call span", s.Name())
+		}
+	}
+	if v, _ := attr(first, WonKey); v.AsBool() {
+		t.Errorf("expected first attempt to lose")
+	}
+	if v, _ := attr(first, CancelCauseKey); v.AsString() != CancelCauseSuperseded {
+		t.Errorf("expected %s = %q, got %q", CancelCauseKey, CancelCauseSuperseded, v.AsString())
+	}
+	if first.Status().Code == codes.Error {
+		t.Errorf("expected superseded attempt not to be marked as an error")
+	}
+	if v, _ := attr(hedge, WonKey); !v.AsBool() {
+		t.Errorf("expected hedge to win")
+	}
+	if v, _ := attr(hedge, HedgeKey); !v.AsBool() {
+		t.Errorf("expected %s = true on hedge", HedgeKey)
+	}
+	if _, ok := attr(hedge, CancelCauseKey); ok {
+		t.Errorf("expected no %s on winner", CancelCauseKey)
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	t.Parallel()
+
+	tp, rec := newTracer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	_, err := Do(ctx, tp.Tracer("test"), "call", time.Second, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected err = %s, got %v", context.DeadlineExceeded, err)
+	}
+
+	spans := waitForSpans(t, rec, 2)
+	if call := findSpan(t, spans, "call"); call.Status().Code != codes.Error {
+		t.Errorf("expected call span to be marked as an error")
+	}
+	attempt := findSpan(t, spans, "call attempt 0")
+	if v, _ := attr(attempt, CancelCauseKey); v.AsString() != context.DeadlineExceeded.Error() {
+		t.Errorf("expected %s = %q, got %q", CancelCauseKey, context.DeadlineExceeded, v.AsString())
+	}
+}
+
+func TestDoSharedOptions(t *testing.T) {
+	t.Parallel()
+
+	tp, _ := newTracer()
+
+	// Options shared by concurrent calls must not be written to, even if
+	// their slice has spare capacity
+	opts := make([]speculatively.Option, 1, 2)
+	opts[0] = speculatively.WithMaxAttempts(1)
+	if _, err := Do(context.Background(), tp.Tracer("test"), "call", time.Second, func(ctx context.Context) (int, error) {
+		return 1, nil
+	}, opts...); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if opts[:2][1] != nil {
+		t.Errorf("expected Do not to write into the caller's options")
+	}
+}
+
+func TestReplicaThunk(t *testing.T) {
+	t.Parallel()
+
+	tp, rec := newTracer()
+
+	ctx, call := Start(context.Background(), tp.Tracer("test"), "call")
+	replicas := speculatively.Replicas[string]{List: []string{"a"}}
+	val, err := speculatively.DoReplicas(ctx, time.Second, replicas, ReplicaThunk(call, func(_ context.Context, replica string) (string, error) {
+		return replica, nil
+	}), call.Option())
+	call.End(err)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != "a" {
+		t.Errorf("expected val = %q, got %q", "a", val)
+	}
+
+	spans := waitForSpans(t, rec, 2)
+	attempt := findSpan(t, spans, "call attempt 0")
+	if v, _ := attr(attempt, TargetKey); v.AsString() != "a" {
+		t.Errorf("expected %s = %q, got %q", TargetKey, "a", v.AsString())
+	}
+	if v, _ := attr(attempt, WonKey); !v.AsBool() {
+		t.Errorf("expected attempt to win")
+	}
+}
@@ -0,0 +1,52 @@
+package speculatively
+
+import (
+	"context"
+	"time"
+)
+
+// Page is a page of items fetched from a paginated API.
+type Page[C, T any] struct {
+	Items []T
+
+	// Next is the cursor of the next page, if any.
+	Next C
+
+	// Last reports whether this is the last page.
+	Last bool
+}
+
+// DoPages iterates a paginated API, speculatively fetching each page as Do
+// does, starting with the page at the given cursor and following the cursor
+// of the next page returned by the winning attempt of each fetch, and passes
+// every item of every page to yield, in order.
+//
+// Every page shares the Budget given via WithBudget, so that long listings
+// cannot multiply the load on the API.  If none is given, the listing gets a
+// Budget of its own, allowing DefaultBudgetRatio hedges per page with up to
+// DefaultBudgetBurst banked.
+//
+// If fetching a page fails or yield returns an error, the listing stops and
+// that error is returned.
+func DoPages[C, T any](ctx context.Context, patience time.Duration, cursor C, fetch func(context.Context, C) (Page[C, T], error), yield func(T) error, opts ...Option) error {
+	if newConfig(opts).budget == nil {
+		opts = append(opts[:len(opts):len(opts)], WithBudget(NewBudget(DefaultBudgetRatio, DefaultBudgetBurst)))
+	}
+	for {
+		page, err := Do(ctx, patience, func(ctx context.Context) (Page[C, T], error) {
+			return fetch(ctx, cursor)
+		}, opts...)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			if err := yield(item); err != nil {
+				return err
+			}
+		}
+		if page.Last {
+			return nil
+		}
+		cursor = page.Next
+	}
+}
@@ -0,0 +1,100 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testPages returns a fetch func listing the ints 0 to n-1, 2 per page, with
+// cursors being the index of each page's first item.  The first attempt of
+// every page is slow.
+func testPages(n int, fetches *int64) func(context.Context, int) (Page[int, int], error) {
+	return func(ctx context.Context, cursor int) (Page[int, int], error) {
+		atomic.AddInt64(fetches, 1)
+		if !IsHedge(ctx) {
+			if err := sleep(ctx, time.Second); err != nil {
+				return Page[int, int]{}, err
+			}
+		}
+		var page Page[int, int]
+		for i := cursor; i < cursor+2 && i < n; i++ {
+			page.Items = append(page.Items, i)
+		}
+		page.Next = cursor + 2
+		page.Last = page.Next >= n
+		return page, nil
+	}
+}
+
+func TestDoPages(t *testing.T) {
+	t.Parallel()
+
+	var fetches int64
+	var got []int
+	start := time.Now()
+	err := DoPages(context.Background(), 10*time.Millisecond, 0, testPages(5, &fetches), func(item int) error {
+		got = append(got, item)
+		return nil
+	}, WithMaxAttempts(2), WithBudget(NewBudget(0, 3)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := []int{0, 1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected items %v, got %v", want, got)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected every page to be hedged, took %s", elapsed)
+	}
+	if got := atomic.LoadInt64(&fetches); got != 6 {
+		t.Errorf("expected %d fetches, got %d", 6, got)
+	}
+}
+
+func TestDoPagesSharesBudget(t *testing.T) {
+	t.Parallel()
+
+	// Only the first page can be hedged, so the second one waits for its
+	// slow first attempt
+	var fetches int64
+	start := time.Now()
+	err := DoPages(context.Background(), 10*time.Millisecond, 0, testPages(4, &fetches), func(int) error {
+		return nil
+	}, WithMaxAttempts(2), WithBudget(NewBudget(0, 1)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected second page not to be hedged, took %s", elapsed)
+	}
+	if got := atomic.LoadInt64(&fetches); got != 3 {
+		t.Errorf("expected %d fetches, got %d", 3, got)
+	}
+}
+
+func TestDoPagesErrors(t *testing.T) {
+	t.Parallel()
+
+	stop := errors.New("stop")
+	var fetches int64
+	err := DoPages(context.Background(), 10*time.Millisecond, 0, testPages(10, &fetches), func(item int) error {
+		if item == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("expected err = %v, got %v", stop, err)
+	}
+
+	failed := errors.New("failed")
+	err = DoPages(context.Background(), time.Second, "", func(context.Context, string) (Page[string, int], error) {
+		return Page[string, int]{}, failed
+	}, func(int) error { return nil })
+	if err != failed {
+		t.Errorf("expected err = %v, got %v", failed, err)
+	}
+}
@@ -0,0 +1,40 @@
+package speculatively
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+// Profiler label keys applied by WithProfileLabels.
+const (
+	LabelCall    = "speculatively.call"
+	LabelAttempt = "speculatively.attempt"
+	LabelHedge   = "speculatively.hedge"
+)
+
+// WithProfileLabels applies pprof labels identifying the call by the given key
+// and the attempt by its index to every attempt's goroutine, so that CPU and
+// goroutine profiles attribute work to specific hedges, e.g. to measure how
+// much profile weight comes from speculative execution.
+func WithProfileLabels(key string) Option {
+	return func(c *config) {
+		c.profileKey = key
+		c.profileLabels = true
+	}
+}
+
+// withProfileLabels calls fn with the attempt's labels applied if
+// WithProfileLabels is set.
+func (c *config) withProfileLabels(ctx context.Context, a Attempt, fn func(context.Context)) {
+	if !c.profileLabels {
+		fn(ctx)
+		return
+	}
+	labels := pprof.Labels(
+		LabelCall, c.profileKey,
+		LabelAttempt, strconv.Itoa(a.Index),
+		LabelHedge, strconv.FormatBool(a.Index > 0),
+	)
+	pprof.Do(ctx, labels, fn)
+}
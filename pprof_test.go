@@ -0,0 +1,44 @@
+package speculatively
+
+import (
+	"context"
+	"runtime/pprof"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithProfileLabels(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		labels = map[string]string{}
+	)
+	thunk := func(ctx context.Context) (int, error) {
+		attempt, _ := pprof.Label(ctx, LabelAttempt)
+		call, _ := pprof.Label(ctx, LabelCall)
+		hedge, _ := pprof.Label(ctx, LabelHedge)
+		mu.Lock()
+		labels[attempt] = call + "/" + hedge
+		mu.Unlock()
+		if IsHedge(ctx) {
+			return 2, nil
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+
+	if _, err := Do(context.Background(), 5*time.Millisecond, thunk, WithProfileLabels("lookup")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := labels["0"]; got != "lookup/false" {
+		t.Errorf("expected first attempt labels = %q, got %q", "lookup/false", got)
+	}
+	if got := labels["1"]; got != "lookup/true" {
+		t.Errorf("expected hedge labels = %q, got %q", "lookup/true", got)
+	}
+}
@@ -0,0 +1,146 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Prefetcher speculatively fetches results for keys ahead of demand, e.g.
+// the next page a user is likely to open, so that a later call for a
+// prefetched key adopts the fetch already in progress, or its result,
+// instead of starting from scratch.
+//
+// Prefetches are limited by Budget, if set, so that speculation on demand
+// that never materializes cannot multiply the load on a dependency, and
+// canceled once they go unclaimed for TTL.
+//
+// A Prefetcher is safe for concurrent use, and must not be copied after
+// first use.
+type Prefetcher[T any] struct {
+	// Patience is how long to wait for a fetch to complete before
+	// launching another attempt.
+	Patience time.Duration
+
+	// Options customize the hedging of every fetch, e.g. to share a
+	// Budget.  A result discarded because its prefetch went unclaimed is
+	// passed to the cleanup func given via WithCleanup, if any.
+	Options []Option
+
+	// Budget optionally limits prefetches: every call to Do deposits into
+	// it and every prefetch withdraws a whole token from it, so that a
+	// Budget created with a ratio of 0.5 allows one prefetch per 2 calls.
+	Budget *Budget
+
+	// TTL is how long a prefetch, or its result, is kept for a call to
+	// claim it before it is canceled or discarded.  If zero, prefetches
+	// are kept until claimed.
+	TTL time.Duration
+
+	pending map[string]*prefetch[T]
+	mu      sync.Mutex
+}
+
+// prefetch is a fetch started by Prefetch, whose result is available once
+// done is closed.
+type prefetch[T any] struct {
+	done   chan struct{}
+	val    T
+	err    error
+	cancel context.CancelFunc
+	expiry *time.Timer
+}
+
+// Prefetch starts speculatively executing a Thunk to fetch the result for the
+// given key in the background, as Do does, and reports whether it did.  A
+// fetch is not started if one is already pending for the key, or if the
+// Budget is exhausted.  The fetch outlives ctx, but keeps its values.
+func (p *Prefetcher[T]) Prefetch(ctx context.Context, key string, thunk Thunk[T]) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.pending[key]; ok {
+		return false
+	}
+	if p.Budget != nil && !p.Budget.withdraw() {
+		return false
+	}
+
+	fetchCtx, cancel := context.WithCancel(context.Background())
+	f := &prefetch[T]{done: make(chan struct{}), cancel: cancel}
+	go func() {
+		defer close(f.done)
+		f.val, f.err = Do(valuesFrom{Context: fetchCtx, values: ctx}, p.Patience, thunk, p.Options...)
+	}()
+	if p.TTL > 0 {
+		f.expiry = time.AfterFunc(p.TTL, func() { p.expire(key, f) })
+	}
+	if p.pending == nil {
+		p.pending = map[string]*prefetch[T]{}
+	}
+	p.pending[key] = f
+	return true
+}
+
+// Do returns the result of the prefetch pending for the given key, waiting
+// for it to complete if necessary, or speculatively executes a Thunk to fetch
+// it if there is none or the prefetch failed.  See Do for details.
+func (p *Prefetcher[T]) Do(ctx context.Context, key string, thunk Thunk[T]) (T, error) {
+	if p.Budget != nil {
+		p.Budget.deposit()
+	}
+	if f, ok := p.claim(key); ok {
+		select {
+		case <-f.done:
+			f.cancel()
+			if f.err == nil {
+				return f.val, nil
+			}
+		case <-ctx.Done():
+			p.abandon(f)
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+	return Do(ctx, p.Patience, thunk, p.Options...)
+}
+
+// claim removes the prefetch pending for the given key, if any, and returns
+// it.
+func (p *Prefetcher[T]) claim(key string) (*prefetch[T], bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f, ok := p.pending[key]
+	if !ok {
+		return nil, false
+	}
+	delete(p.pending, key)
+	if f.expiry != nil {
+		f.expiry.Stop()
+	}
+	return f, true
+}
+
+// expire abandons the given prefetch of the given key, unless it has been
+// claimed since.
+func (p *Prefetcher[T]) expire(key string, f *prefetch[T]) {
+	p.mu.Lock()
+	if p.pending[key] != f {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.pending, key)
+	p.mu.Unlock()
+	p.abandon(f)
+}
+
+// abandon cancels a prefetch whose result will not be returned, cleaning up
+// its result if it succeeded anyway.
+func (p *Prefetcher[T]) abandon(f *prefetch[T]) {
+	f.cancel()
+	go func() {
+		<-f.done
+		if f.err == nil {
+			newConfig(p.Options).discard(f.val)
+		}
+	}()
+}
@@ -0,0 +1,120 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrefetcher(t *testing.T) {
+	t.Parallel()
+
+	var calls int64
+	thunk := func(ctx context.Context) (int, error) {
+		atomic.AddInt64(&calls, 1)
+		if err := sleep(ctx, 50*time.Millisecond); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	p := Prefetcher[int]{Patience: time.Second}
+	if !p.Prefetch(context.Background(), "key", thunk) {
+		t.Fatalf("expected prefetch to start")
+	}
+	if p.Prefetch(context.Background(), "key", thunk) {
+		t.Errorf("expected duplicate prefetch not to start")
+	}
+
+	// The call adopts the prefetch in progress
+	time.Sleep(30 * time.Millisecond)
+	start := time.Now()
+	val, err := p.Do(context.Background(), "key", thunk)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 1 {
+		t.Errorf("expected val = %d, got %d", 1, val)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("expected call to adopt the prefetch, took %s", elapsed)
+	}
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Errorf("expected %d call, got %d", 1, got)
+	}
+
+	// The prefetch was claimed, so the next call starts from scratch
+	if _, err := p.Do(context.Background(), "key", thunk); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := atomic.LoadInt64(&calls); got != 2 {
+		t.Errorf("expected %d calls, got %d", 2, got)
+	}
+}
+
+func TestPrefetcherFailedPrefetch(t *testing.T) {
+	t.Parallel()
+
+	p := Prefetcher[int]{Patience: time.Second}
+	p.Prefetch(context.Background(), "key", newSimpleTestThunk(0, errors.New("failed"), 0).call)
+	time.Sleep(10 * time.Millisecond)
+	val, err := p.Do(context.Background(), "key", newSimpleTestThunk(2, nil, 0).call)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 2 {
+		t.Errorf("expected failed prefetch to be fetched again, got val = %d", val)
+	}
+}
+
+func TestPrefetcherTTL(t *testing.T) {
+	t.Parallel()
+
+	var cleaned int64
+	canceled := make(chan struct{})
+	p := Prefetcher[int]{
+		Patience: time.Second,
+		TTL:      20 * time.Millisecond,
+		Options:  []Option{WithCleanup(func(int) { atomic.AddInt64(&cleaned, 1) })},
+	}
+	p.Prefetch(context.Background(), "slow", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		close(canceled)
+		return 0, ctx.Err()
+	})
+	p.Prefetch(context.Background(), "fast", newSimpleTestThunk(1, nil, 0).call)
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatalf("expected unclaimed prefetch to be canceled")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt64(&cleaned); got != 1 {
+		t.Errorf("expected unclaimed result to be cleaned up, got %d cleanups", got)
+	}
+	if _, ok := p.claim("fast"); ok {
+		t.Errorf("expected unclaimed prefetch to be discarded")
+	}
+}
+
+func TestPrefetcherBudget(t *testing.T) {
+	t.Parallel()
+
+	p := Prefetcher[int]{Patience: time.Second, Budget: NewBudget(1, 1)}
+	thunk := newSimpleTestThunk(1, nil, 0).call
+	if !p.Prefetch(context.Background(), "a", thunk) {
+		t.Fatalf("expected first prefetch to start")
+	}
+	if p.Prefetch(context.Background(), "b", thunk) {
+		t.Errorf("expected prefetch beyond budget not to start")
+	}
+	if _, err := p.Do(context.Background(), "a", thunk); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !p.Prefetch(context.Background(), "b", thunk) {
+		t.Errorf("expected call to replenish the budget")
+	}
+}
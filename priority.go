@@ -0,0 +1,49 @@
+package speculatively
+
+import "context"
+
+// Priority marks how important the work done with a context is, so that
+// downstream clients and servers that understand the marker can queue or shed
+// less important work first under load.
+type Priority int
+
+// Priorities, in order of decreasing importance.
+const (
+	PriorityNormal Priority = iota
+	PriorityLow
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityNormal:
+		return "normal"
+	case PriorityLow:
+		return "low"
+	default:
+		return "unknown"
+	}
+}
+
+type priorityKey struct{}
+
+// ContextWithPriority returns a copy of ctx marked with the given Priority.
+func ContextWithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the Priority ctx is marked with, or
+// PriorityNormal if it is not marked.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// WithLowPriorityHedges marks the context of every hedged attempt (i.e. every
+// attempt after the first) with PriorityLow.
+func WithLowPriorityHedges() Option {
+	return func(c *config) {
+		c.lowPriorityHedges = true
+	}
+}
@@ -0,0 +1,49 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithLowPriorityHedges(t *testing.T) {
+	t.Parallel()
+
+	var (
+		priorities = map[int]Priority{}
+		mu         sync.Mutex
+	)
+	thunk := func(ctx context.Context) (int, error) {
+		a, _ := AttemptFromContext(ctx)
+		mu.Lock()
+		priorities[a.Index] = PriorityFromContext(ctx)
+		mu.Unlock()
+		return 0, sleep(ctx, 30*time.Millisecond)
+	}
+
+	if _, err := Do(context.Background(), 5*time.Millisecond, thunk, WithMaxAttempts(2), WithLowPriorityHedges()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if p := priorities[0]; p != PriorityNormal {
+		t.Errorf("expected first attempt to have %s priority, got %s", PriorityNormal, p)
+	}
+	if p := priorities[1]; p != PriorityLow {
+		t.Errorf("expected hedge to have %s priority, got %s", PriorityLow, p)
+	}
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	t.Parallel()
+
+	if p := PriorityFromContext(context.Background()); p != PriorityNormal {
+		t.Errorf("expected unmarked context to have %s priority, got %s", PriorityNormal, p)
+	}
+	ctx := ContextWithPriority(context.Background(), PriorityLow)
+	if p := PriorityFromContext(ctx); p != PriorityLow {
+		t.Errorf("expected %s priority, got %s", PriorityLow, p)
+	}
+}
@@ -0,0 +1,130 @@
+// Package promspeculatively exposes Prometheus metrics for speculatively.
+package promspeculatively
+
+import (
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector exposing counters and histograms that
+// describe hedging activity, labelled by name, so that standard dashboards
+// can be built for each Hedger or call site.
+type Collector struct {
+	attempts     *prometheus.CounterVec
+	hedges       *prometheus.CounterVec
+	hedgeWins    *prometheus.CounterVec
+	suppressed   *prometheus.CounterVec
+	terminations *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+}
+
+// NewCollector creates a Collector whose metrics are in the given namespace,
+// which may be empty.
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "speculatively",
+			Name:      "attempts_total",
+			Help:      "Attempts launched, including hedges.",
+		}, []string{"name"}),
+		hedges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "speculatively",
+			Name:      "hedges_total",
+			Help:      "Hedged attempts launched.",
+		}, []string{"name"}),
+		hedgeWins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "speculatively",
+			Name:      "hedge_wins_total",
+			Help:      "Calls won by a hedged attempt.",
+		}, []string{"name"}),
+		suppressed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "speculatively",
+			Name:      "hedges_suppressed_total",
+			Help:      "Hedges not launched when due, e.g. because the budget was exhausted, by reason.",
+		}, []string{"name", "reason"}),
+		terminations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "speculatively",
+			Name:      "attempt_terminations_total",
+			Help:      "Attempts ended, by termination reason.",
+		}, []string{"name", "termination"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "speculatively",
+			Name:      "attempt_duration_seconds",
+			Help:      "Duration of attempts, including hedges.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"name", "hedge"}),
+	}
+}
+
+// Option returns an Option recording the metrics of calls under the given
+// name.
+func (c *Collector) Option(name string) speculatively.Option {
+	return speculatively.WithMetrics(c.Recorder(name))
+}
+
+// Recorder returns a MetricsRecorder recording metrics under the given name.
+func (c *Collector) Recorder(name string) speculatively.MetricsRecorder {
+	return &recorder{c: c, name: name}
+}
+
+type recorder struct {
+	c    *Collector
+	name string
+}
+
+func (r *recorder) RecordAttempt(a speculatively.Attempt) {
+	r.c.attempts.WithLabelValues(r.name).Inc()
+	if a.Index > 0 {
+		r.c.hedges.WithLabelValues(r.name).Inc()
+	}
+}
+
+func (r *recorder) RecordAttemptDone(a speculatively.Attempt, elapsed time.Duration, _ error) {
+	hedge := "false"
+	if a.Index > 0 {
+		hedge = "true"
+	}
+	r.c.latency.WithLabelValues(r.name, hedge).Observe(elapsed.Seconds())
+}
+
+func (r *recorder) RecordWinner(a speculatively.Attempt) {
+	if a.Index > 0 {
+		r.c.hedgeWins.WithLabelValues(r.name).Inc()
+	}
+}
+
+func (r *recorder) RecordSuppressedHedge(s speculatively.Suppression) {
+	r.c.suppressed.WithLabelValues(r.name, s.String()).Inc()
+}
+
+func (r *recorder) RecordTermination(_ speculatively.Attempt, t speculatively.Termination) {
+	r.c.terminations.WithLabelValues(r.name, t.String()).Inc()
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.attempts.Describe(ch)
+	c.hedges.Describe(ch)
+	c.hedgeWins.Describe(ch)
+	c.suppressed.Describe(ch)
+	c.terminations.Describe(ch)
+	c.latency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.attempts.Collect(ch)
+	c.hedges.Collect(ch)
+	c.hedgeWins.Collect(ch)
+	c.suppressed.Collect(ch)
+	c.terminations.Collect(ch)
+	c.latency.Collect(ch)
+}
@@ -0,0 +1,74 @@
+package promspeculatively
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	c := NewCollector("test")
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	thunk := func(ctx context.Context) (int, error) {
+		if speculatively.IsHedge(ctx) {
+			return 2, nil
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	if _, err := speculatively.Do(context.Background(), 5*time.Millisecond, thunk, c.Option("lookup")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The budget allows a single hedge, so the third call's hedge is
+	// suppressed
+	budget := speculatively.NewBudget(0, 1)
+	if _, err := speculatively.Do(context.Background(), 5*time.Millisecond, thunk, speculatively.WithBudget(budget), c.Option("lookup")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := speculatively.Do(context.Background(), time.Millisecond, func(context.Context) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 1, nil
+	}, speculatively.WithBudget(budget), c.Option("lookup")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `
+# HELP test_speculatively_attempts_total Attempts launched, including hedges.
+# TYPE test_speculatively_attempts_total counter
+test_speculatively_attempts_total{name="lookup"} 5
+# HELP test_speculatively_hedge_wins_total Calls won by a hedged attempt.
+# TYPE test_speculatively_hedge_wins_total counter
+test_speculatively_hedge_wins_total{name="lookup"} 2
+# HELP test_speculatively_hedges_total Hedged attempts launched.
+# TYPE test_speculatively_hedges_total counter
+test_speculatively_hedges_total{name="lookup"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"test_speculatively_attempts_total",
+		"test_speculatively_hedges_total",
+		"test_speculatively_hedge_wins_total",
+	); err != nil {
+		t.Errorf("unexpected metrics: %s", err)
+	}
+	if n := testutil.ToFloat64(c.suppressed.WithLabelValues("lookup", "budget")); n < 1 {
+		t.Errorf("expected suppressed hedges to be counted, got %v", n)
+	}
+	if n := testutil.ToFloat64(c.terminations.WithLabelValues("lookup", "won")); n != 3 {
+		t.Errorf("expected 3 winning attempts, got %v", n)
+	}
+	if n := testutil.CollectAndCount(c.latency); n != 2 {
+		t.Errorf("expected latency histograms for first attempts and hedges, got %d", n)
+	}
+}
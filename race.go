@@ -0,0 +1,161 @@
+package speculatively
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DoRace speculatively executes a set of heterogeneous Thunks, e.g. alternate
+// algorithms or implementations computing the same result, launching one
+// after another in parallel and waiting for the given patience duration
+// between subsequent launches.  Each Thunk is executed at most once.
+//
+// Thunks are launched in the given order, unless a Portfolio is given via
+// WithPortfolio.  Only the first Thunk is launched in calls left out by a
+// Canary given via WithCanary.  See Do for details.
+func DoRace[T any](ctx context.Context, patience time.Duration, thunks []Thunk[T], opts ...Option) (T, error) {
+	if len(thunks) == 0 {
+		var zero T
+		return zero, ErrNoThunks
+	}
+
+	cfg := newConfig(opts)
+	order := make([]int, len(thunks))
+	for i := range order {
+		order[i] = i
+	}
+	if cfg.portfolio != nil {
+		order = cfg.portfolio.order(cfg.portfolioKey, len(thunks))
+	}
+	if cfg.canary != nil {
+		order = cfg.canary.order(order)
+	}
+
+	type raced struct {
+		variant int
+		val     T
+	}
+	if cleanup := cfg.cleanup; cleanup != nil {
+		// Clean up the values of discarded results, not their wrappers
+		cfg.cleanup = func(val interface{}) {
+			if r, ok := val.(raced); ok {
+				cleanup(r.val)
+			}
+		}
+	}
+	if equal := cfg.equal; equal != nil {
+		cfg.equal = func(a, b interface{}) bool {
+			return equal(a.(raced).val, b.(raced).val)
+		}
+	}
+	if key := cfg.resultKey; key != nil {
+		cfg.resultKey = func(val interface{}) interface{} {
+			return key(val.(raced).val)
+		}
+	}
+	if cfg.staleness > 0 {
+		cfg.timestamped = func(val interface{}) (Timestamped, bool) {
+			ts, ok := interface{}(val.(raced).val).(Timestamped)
+			return ts, ok
+		}
+	}
+	winner, err := run(ctx, patience, cfg, func(attempt int) (task[raced], bool) {
+		if attempt >= len(order) {
+			return task[raced]{}, false
+		}
+		variant := order[attempt]
+		return task[raced]{
+			thunk: func(ctx context.Context) (raced, error) {
+				if cfg.canary != nil {
+					cfg.canary.attempt(variant)
+				}
+				val, err := thunks[variant](ctx)
+				return raced{variant, val}, err
+			},
+			target: variant,
+		}, true
+	})
+	if err == nil && cfg.portfolio != nil {
+		cfg.portfolio.record(cfg.portfolioKey, winner.variant)
+	}
+	if err == nil && cfg.canary != nil {
+		cfg.canary.win(winner.variant)
+	}
+	return winner.val, err
+}
+
+// Portfolio remembers which of the Thunks raced by DoRace win, per key, and
+// biases future races toward historical winners by launching them first,
+// while still occasionally exploring the other variants.  This makes DoRace
+// a lightweight portfolio scheduler for "try algorithm A vs B" use cases.
+//
+// A Portfolio is safe for concurrent use.
+type Portfolio struct {
+	explore float64
+	wins    map[string][]int64
+	mu      sync.Mutex
+}
+
+// NewPortfolio creates a Portfolio that launches variants in a random order
+// for the given fraction (between 0.0 and 1.0) of races, to keep exploring,
+// and in order of historical wins otherwise.
+func NewPortfolio(explore float64) *Portfolio {
+	return &Portfolio{
+		explore: explore,
+		wins:    map[string][]int64{},
+	}
+}
+
+// WithPortfolio orders the Thunks raced by DoRace according to the given
+// Portfolio's memory of winners for the given key, and records the winner.
+func WithPortfolio(p *Portfolio, key string) Option {
+	return func(c *config) {
+		c.portfolio = p
+		c.portfolioKey = key
+	}
+}
+
+// Wins returns the number of races won by each variant for the given key,
+// indexed by the position of its Thunk.
+func (p *Portfolio) Wins(key string) []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int64(nil), p.wins[key]...)
+}
+
+// order returns the order in which n variants should be launched.
+func (p *Portfolio) order(key string, n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if rand.Float64() < p.explore {
+		rand.Shuffle(n, func(i, j int) { order[i], order[j] = order[j], order[i] })
+		return order
+	}
+	wins := p.Wins(key)
+	winsOf := func(variant int) int64 {
+		if variant < len(wins) {
+			return wins[variant]
+		}
+		return 0
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return winsOf(order[i]) > winsOf(order[j])
+	})
+	return order
+}
+
+func (p *Portfolio) record(key string, variant int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	wins := p.wins[key]
+	for len(wins) <= variant {
+		wins = append(wins, 0)
+	}
+	wins[variant]++
+	p.wins[key] = wins
+}
@@ -0,0 +1,139 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDoRace(t *testing.T) {
+	t.Parallel()
+
+	slow := newSimpleTestThunk(1, nil, time.Second)
+	fast := newSimpleTestThunk(2, nil, 5*time.Millisecond)
+
+	val, err := DoRace(context.Background(), 10*time.Millisecond, []Thunk[int]{slow.call, fast.call})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 2 {
+		t.Errorf("expected val = %d, got %d", 2, val)
+	}
+	if slow.callCount() != 1 || fast.callCount() != 1 {
+		t.Errorf("expected each thunk to run once, got %d and %d", slow.callCount(), fast.callCount())
+	}
+
+	if _, err := DoRace[int](context.Background(), time.Second, nil); err != ErrNoThunks {
+		t.Errorf("expected err = %s, got %v", ErrNoThunks, err)
+	}
+}
+
+func TestPortfolio(t *testing.T) {
+	t.Parallel()
+
+	p := NewPortfolio(0)
+	slow := newSimpleTestThunk(1, nil, 200*time.Millisecond)
+	fast := newSimpleTestThunk(2, nil, 5*time.Millisecond)
+	thunks := []Thunk[int]{slow.call, fast.call}
+
+	// The first race launches the slow variant first, but the fast variant
+	// wins and is launched first in every subsequent race
+	for i := 0; i < 3; i++ {
+		val, err := DoRace(context.Background(), 10*time.Millisecond, thunks, WithPortfolio(p, "key"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val != 2 {
+			t.Errorf("expected val = %d, got %d", 2, val)
+		}
+	}
+	if n := slow.callCount(); n != 1 {
+		t.Errorf("expected slow variant to run only in the first race, got %d calls", n)
+	}
+	if wins := p.Wins("key"); len(wins) != 2 || wins[0] != 0 || wins[1] != 3 {
+		t.Errorf("expected wins = [0 3], got %v", wins)
+	}
+	if wins := p.Wins("other"); len(wins) != 0 {
+		t.Errorf("expected no wins for other key, got %v", wins)
+	}
+}
+
+func TestPortfolioExplores(t *testing.T) {
+	t.Parallel()
+
+	p := NewPortfolio(1)
+	p.record("key", 0)
+
+	firsts := map[int]int{}
+	for i := 0; i < 200; i++ {
+		firsts[p.order("key", 2)[0]]++
+	}
+	if firsts[1] == 0 {
+		t.Errorf("expected exploration to launch losing variant first sometimes, got %v", firsts)
+	}
+}
+
+func TestDoRaceCleanup(t *testing.T) {
+	t.Parallel()
+
+	discarded := make(chan int, 2)
+	thunks := []Thunk[int]{
+		func(ctx context.Context) (int, error) {
+			time.Sleep(50 * time.Millisecond)
+			return 1, nil
+		},
+		func(ctx context.Context) (int, error) { return 2, nil },
+	}
+
+	val, err := DoRace(context.Background(), 10*time.Millisecond, thunks, WithCleanup(func(v int) { discarded <- v }))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 2 {
+		t.Errorf("expected val = %d, got %d", 2, val)
+	}
+	select {
+	case v := <-discarded:
+		if v != 1 {
+			t.Errorf("expected discarded val = %d, got %d", 1, v)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected losing result to be cleaned up")
+	}
+}
+
+func TestDoRaceWithMaxStaleness(t *testing.T) {
+	t.Parallel()
+
+	variant := func(id int, age time.Duration) Thunk[timestamped] {
+		return func(context.Context) (timestamped, error) {
+			return timestamped{id: id, age: age, ts: time.Now().Add(-age)}, nil
+		}
+	}
+
+	t.Run("stale result races the next variant", func(t *testing.T) {
+		t.Parallel()
+
+		thunks := []Thunk[timestamped]{variant(0, time.Hour), variant(1, time.Second)}
+		val, err := DoRace(context.Background(), time.Hour, thunks, WithMaxStaleness(time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val.id != 1 {
+			t.Errorf("expected fresh result from variant 1, got %+v", val)
+		}
+	})
+
+	t.Run("freshest stale result returned when variants exhausted", func(t *testing.T) {
+		t.Parallel()
+
+		thunks := []Thunk[timestamped]{variant(0, 2*time.Hour), variant(1, time.Hour), variant(2, 3*time.Hour)}
+		val, err := DoRace(context.Background(), time.Hour, thunks, WithMaxStaleness(time.Minute))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val.id != 1 {
+			t.Errorf("expected freshest stale result from variant 1, got %+v", val)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package speculatively
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// DoReadCloser speculatively executes a set of Thunks that each open a
+// stream of the same data, e.g. a download from one of several mirrors, in
+// the manner of DoRace, and returns the first stream to be opened.
+//
+// Unlike the result of other calls, the winning stream is not canceled when
+// DoReadCloser returns, so that it can be consumed afterwards: the context
+// passed to its Thunk is only canceled once the stream is closed or ctx is
+// done.  Every other stream is closed and the context of its Thunk canceled,
+// aborting its transfer, as soon as the winner is known.
+func DoReadCloser(ctx context.Context, patience time.Duration, thunks []Thunk[io.ReadCloser], opts ...Option) (io.ReadCloser, error) {
+	detached := make([]Thunk[io.ReadCloser], len(thunks))
+	for i, thunk := range thunks {
+		detached[i] = detach(ctx, thunk)
+	}
+	opts = append(opts[:len(opts):len(opts)], WithCleanup(func(r io.ReadCloser) { r.Close() }))
+	return DoRace(ctx, patience, detached, opts...)
+}
+
+// detach wraps a Thunk that opens a stream so that the stream outlives the
+// attempt that opened it.  The Thunk is canceled along with its attempt until
+// the stream is opened, after which only closing the stream or ctx being done
+// cancels it.
+func detach(ctx context.Context, thunk Thunk[io.ReadCloser]) Thunk[io.ReadCloser] {
+	return func(attemptCtx context.Context) (io.ReadCloser, error) {
+		streamCtx, keep, cancel := Detach(ctx, attemptCtx)
+		r, err := thunk(valuesFrom{Context: streamCtx, values: attemptCtx})
+		keep()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return CancelOnClose(r, cancel), nil
+	}
+}
+
+// Detach returns a context derived from ctx, rather than from attemptCtx,
+// the context of an attempt, for work whose result must outlive the attempt,
+// e.g. a response whose body is read after the call returns.  The returned
+// context is canceled along with attemptCtx until keep is called once the
+// result exists, after which only calling cancel or ctx being done cancels
+// it.  Cancel must be called once the result is no longer used, or right
+// away if the work fails:
+//
+//	reqCtx, keep, cancel := speculatively.Detach(ctx, attemptCtx)
+//	resp, err := client.Do(req.WithContext(reqCtx))
+//	keep()
+//	if err != nil {
+//		cancel()
+//		return nil, err
+//	}
+//	resp.Body = speculatively.CancelOnClose(resp.Body, cancel)
+func Detach(ctx, attemptCtx context.Context) (detached context.Context, keep func(), cancel context.CancelFunc) {
+	detached, cancel = context.WithCancel(ctx)
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-attemptCtx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+	var once sync.Once
+	keep = func() {
+		once.Do(func() {
+			close(stop)
+			<-stopped
+		})
+	}
+	return detached, keep, cancel
+}
+
+// CancelOnClose returns a ReadCloser that reads from r and calls cancel once
+// it is closed, e.g. to release the context returned by Detach along with
+// the stream read with it.
+func CancelOnClose(r io.ReadCloser, cancel context.CancelFunc) io.ReadCloser {
+	return &cancelingReadCloser{ReadCloser: r, cancel: cancel}
+}
+
+// valuesFrom is a context that is canceled along with its embedded Context
+// but holds the values of another, so that detached thunks can still inspect
+// their attempt, e.g. via IsHedge.
+type valuesFrom struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesFrom) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+// cancelingReadCloser cancels the context of the thunk that opened it once
+// it is closed.
+type cancelingReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelingReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
@@ -0,0 +1,143 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testStream is a stream that can only be read while the context of the
+// thunk that opened it is not done.
+type testStream struct {
+	ctx    context.Context
+	r      io.Reader
+	closed *int64
+}
+
+func (s *testStream) Read(p []byte) (int, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.r.Read(p)
+}
+
+func (s *testStream) Close() error {
+	atomic.AddInt64(s.closed, 1)
+	return nil
+}
+
+func TestDoReadCloser(t *testing.T) {
+	t.Parallel()
+
+	var (
+		closed   [2]int64
+		canceled = make(chan struct{}, 1)
+		hedged   int64
+	)
+	open := func(i int, delay time.Duration) Thunk[io.ReadCloser] {
+		return func(ctx context.Context) (io.ReadCloser, error) {
+			if IsHedge(ctx) {
+				atomic.AddInt64(&hedged, 1)
+			}
+			time.Sleep(delay)
+			go func() {
+				<-ctx.Done()
+				if i == 0 {
+					canceled <- struct{}{}
+				}
+			}()
+			return &testStream{ctx: ctx, r: strings.NewReader("mirror"), closed: &closed[i]}, nil
+		}
+	}
+
+	r, err := DoReadCloser(context.Background(), 10*time.Millisecond, []Thunk[io.ReadCloser]{
+		open(0, 100*time.Millisecond),
+		open(1, 0),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The losing stream is closed and its transfer canceled
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Errorf("expected losing stream to be canceled")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt64(&closed[0]); got != 1 {
+		t.Errorf("expected losing stream to be closed once, got %d", got)
+	}
+
+	// The winning stream is still readable after the call returned
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected read error: %s", err)
+	}
+	if string(b) != "mirror" {
+		t.Errorf("expected %q, got %q", "mirror", b)
+	}
+	if got := atomic.LoadInt64(&hedged); got != 1 {
+		t.Errorf("expected hedge to see its attempt in context, got %d hedges", got)
+	}
+	if got := atomic.LoadInt64(&closed[1]); got != 0 {
+		t.Errorf("expected winning stream to be left open, got %d closes", got)
+	}
+	r.Close()
+	if got := atomic.LoadInt64(&closed[1]); got != 1 {
+		t.Errorf("expected winning stream to be closed once, got %d", got)
+	}
+}
+
+func TestDoReadCloserError(t *testing.T) {
+	t.Parallel()
+
+	errOpen := errors.New("open failed")
+	_, err := DoReadCloser(context.Background(), time.Second, []Thunk[io.ReadCloser]{
+		func(ctx context.Context) (io.ReadCloser, error) { return nil, errOpen },
+	})
+	if err != errOpen {
+		t.Errorf("expected err = %s, got %v", errOpen, err)
+	}
+}
+
+func TestDetach(t *testing.T) {
+	t.Parallel()
+
+	t.Run("canceled along with attempt until kept", func(t *testing.T) {
+		t.Parallel()
+
+		attemptCtx, cancelAttempt := context.WithCancel(context.Background())
+		detached, keep, cancel := Detach(context.Background(), attemptCtx)
+		defer cancel()
+		cancelAttempt()
+		select {
+		case <-detached.Done():
+		case <-time.After(time.Second):
+			t.Fatalf("expected detached context to be canceled along with attempt")
+		}
+		keep()
+	})
+
+	t.Run("outlives attempt once kept", func(t *testing.T) {
+		t.Parallel()
+
+		attemptCtx, cancelAttempt := context.WithCancel(context.Background())
+		detached, keep, cancel := Detach(context.Background(), attemptCtx)
+		keep()
+		cancelAttempt()
+		if err := detached.Err(); err != nil {
+			t.Fatalf("expected kept context to outlive attempt, got %s", err)
+		}
+
+		r := CancelOnClose(io.NopCloser(strings.NewReader("")), cancel)
+		r.Close()
+		if err := detached.Err(); err != context.Canceled {
+			t.Errorf("expected err = %s after close, got %v", context.Canceled, err)
+		}
+	})
+}
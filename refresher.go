@@ -0,0 +1,130 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotRefreshed is returned by Refresher.Get until the value has been
+// fetched once.
+var ErrNotRefreshed = errors.New("speculatively: not refreshed yet")
+
+// TooStaleError is returned by Refresher.Get when the latest value is older
+// than its MaxAge, along with the error of the latest refresh, if any.
+type TooStaleError struct {
+	Age, MaxAge time.Duration
+	Err         error
+}
+
+func (e *TooStaleError) Error() string {
+	msg := fmt.Sprintf("speculatively: value refreshed %s ago, more than %s", e.Age, e.MaxAge)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *TooStaleError) Unwrap() error {
+	return e.Err
+}
+
+// Refresher keeps a value, e.g. configuration or a feed, up to date by
+// periodically fetching it again, speculatively executing a Thunk as Do
+// does, so that readers always get the latest value right away.
+//
+// Run a Refresher in a goroutine of its own, and read its value via Get:
+//
+//	r := &speculatively.Refresher[Config]{Interval: time.Minute, MaxAge: 5 * time.Minute, Patience: time.Second}
+//	go r.Run(ctx, fetchConfig)
+//	<-r.Ready()
+//	cfg, err := r.Get()
+//
+// Failed refreshes keep the previous value, until it is older than MaxAge.
+// A Refresher is safe for concurrent use, and must not be copied after
+// first use.
+type Refresher[T any] struct {
+	// Interval is how long to wait between the end of a refresh and the
+	// start of the next.  It must be positive.
+	Interval time.Duration
+
+	// MaxAge optionally bounds the staleness of the value returned by Get.
+	// If zero, the latest value is returned however old it is.
+	MaxAge time.Duration
+
+	// Patience is how long to wait for a refresh to complete before
+	// launching another attempt.
+	Patience time.Duration
+
+	// Options customize the hedging of every refresh, e.g. to share a
+	// Budget.
+	Options []Option
+
+	val       T
+	refreshed time.Time
+	err       error
+	ready     chan struct{}
+	once      sync.Once
+	mu        sync.Mutex
+}
+
+// Run fetches the value right away and then every Interval, until ctx is
+// done.
+func (r *Refresher[T]) Run(ctx context.Context, thunk Thunk[T]) {
+	cfg := newConfig(r.Options)
+	for {
+		val, err := Do(ctx, r.Patience, thunk, r.Options...)
+		if ctx.Err() != nil {
+			return
+		}
+		r.mu.Lock()
+		r.err = err
+		if err == nil {
+			r.val, r.refreshed = val, cfg.now()
+		}
+		r.mu.Unlock()
+		if err == nil {
+			r.once.Do(func() { close(r.readyChan()) })
+		}
+
+		if err := sleep(ctx, r.Interval); err != nil {
+			return
+		}
+	}
+}
+
+// Ready returns a channel that is closed once the value has been fetched
+// once.
+func (r *Refresher[T]) Ready() <-chan struct{} {
+	return r.readyChan()
+}
+
+// Get returns the latest value, or ErrNotRefreshed if it has never been
+// fetched, or a TooStaleError if it is older than MaxAge.
+func (r *Refresher[T]) Get() (T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var zero T
+	if r.refreshed.IsZero() {
+		if r.err != nil {
+			return zero, r.err
+		}
+		return zero, ErrNotRefreshed
+	}
+	if age := newConfig(r.Options).since(r.refreshed); r.MaxAge > 0 && age > r.MaxAge {
+		return zero, &TooStaleError{Age: age, MaxAge: r.MaxAge, Err: r.err}
+	}
+	return r.val, nil
+}
+
+// readyChan returns the channel closed once the value has been fetched.
+func (r *Refresher[T]) readyChan() chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ready == nil {
+		r.ready = make(chan struct{})
+	}
+	return r.ready
+}
@@ -0,0 +1,72 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefresher(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	var version, failing int64
+	thunk := func(ctx context.Context) (int64, error) {
+		if atomic.LoadInt64(&failing) == 1 {
+			return 0, failed
+		}
+		// The first attempt of every refresh is slow, so that each one is
+		// hedged
+		if !IsHedge(ctx) {
+			if err := sleep(ctx, time.Second); err != nil {
+				return 0, err
+			}
+		}
+		return atomic.AddInt64(&version, 1), nil
+	}
+
+	r := &Refresher[int64]{
+		Interval: 10 * time.Millisecond,
+		MaxAge:   100 * time.Millisecond,
+		Patience: 5 * time.Millisecond,
+	}
+	if _, err := r.Get(); err != ErrNotRefreshed {
+		t.Errorf("expected err = %v, got %v", ErrNotRefreshed, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, thunk)
+	select {
+	case <-r.Ready():
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("expected value to be fetched")
+	}
+	first, err := r.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	latest, err := r.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if latest <= first {
+		t.Errorf("expected value to be refreshed, got %d after %d", latest, first)
+	}
+
+	// Failed refreshes keep the previous value until it is too old
+	atomic.StoreInt64(&failing, 1)
+	time.Sleep(20 * time.Millisecond)
+	if _, err := r.Get(); err != nil {
+		t.Errorf("expected previous value, got err = %v", err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	var stale *TooStaleError
+	if _, err := r.Get(); !errors.As(err, &stale) || !errors.Is(err, failed) {
+		t.Errorf("expected TooStaleError wrapping %v, got %v", failed, err)
+	}
+}
@@ -0,0 +1,183 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoReplicas is returned by DoReplicas when given an empty set of replicas.
+var ErrNoReplicas = errors.New("speculatively: no replicas")
+
+// ErrNoThunks is returned by DoRace when given no Thunks.
+var ErrNoThunks = errors.New("speculatively: no thunks")
+
+// ReplicaThunk is a computation to be speculatively executed against one of a
+// set of interchangeable replicas (e.g. backend hosts).
+type ReplicaThunk[R, T any] func(context.Context, R) (T, error)
+
+// Replicas describes the set of replicas raced by DoReplicas.
+type Replicas[R any] struct {
+	// List of replicas, in order of preference.
+	List []R
+
+	// Healthy optionally reports whether a replica is currently healthy.
+	// Unhealthy replicas are skipped, so that attempts are spent only on
+	// healthy replicas.  If no replicas are healthy, all of them are tried as
+	// a last resort.
+	Healthy func(R) bool
+
+	// Weight optionally assigns each replica a relative weight, so that
+	// attempts are distributed across replicas proportionally (e.g. 70/30
+	// between a near and a far region).  When set, the order in which
+	// replicas are tried is randomized according to their weights rather
+	// than following List.  Replicas with a weight of zero or less are tried
+	// last.
+	Weight func(R) float64
+
+	// Discovery optionally supplies the list of replicas as of each call,
+	// e.g. from DNS SRV records or a service registry, so that the set of
+	// replicas follows endpoints as they come and go.  When set, it
+	// replaces List.
+	Discovery Discovery[R]
+}
+
+// Discovery supplies the current list of replicas, in order of preference.
+type Discovery[R any] interface {
+	Replicas(ctx context.Context) ([]R, error)
+}
+
+// DiscoveryFunc adapts a func to the Discovery interface.
+type DiscoveryFunc[R any] func(ctx context.Context) ([]R, error)
+
+// Replicas calls fn.
+func (fn DiscoveryFunc[R]) Replicas(ctx context.Context) ([]R, error) {
+	return fn(ctx)
+}
+
+// WatchedReplicas is a Discovery that holds the most recent list of replicas
+// received from a watch channel, e.g. one fed by a service registry's watch
+// API.
+//
+// A WatchedReplicas is safe for concurrent use.
+type WatchedReplicas[R any] struct {
+	mu   sync.Mutex
+	list []R
+}
+
+// WatchReplicas creates a WatchedReplicas holding the given initial list of
+// replicas, and replacing it with every list received from updates until
+// the channel is closed.
+func WatchReplicas[R any](initial []R, updates <-chan []R) *WatchedReplicas[R] {
+	w := &WatchedReplicas[R]{list: initial}
+	go func() {
+		for list := range updates {
+			w.mu.Lock()
+			w.list = list
+			w.mu.Unlock()
+		}
+	}()
+	return w
+}
+
+// Replicas implements Discovery.
+func (w *WatchedReplicas[R]) Replicas(context.Context) ([]R, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.list, nil
+}
+
+// discover returns a copy of r listing the replicas supplied by its
+// Discovery, if any.
+func (r Replicas[R]) discover(ctx context.Context) (Replicas[R], error) {
+	if r.Discovery == nil {
+		return r, nil
+	}
+	list, err := r.Discovery.Replicas(ctx)
+	if err != nil {
+		return r, err
+	}
+	r.List = list
+	return r, nil
+}
+
+// candidates returns the replicas to be tried, in order.
+func (r Replicas[R]) candidates() []R {
+	candidates := r.List
+	if r.Healthy != nil {
+		healthy := make([]R, 0, len(r.List))
+		for _, replica := range r.List {
+			if r.Healthy(replica) {
+				healthy = append(healthy, replica)
+			}
+		}
+		if len(healthy) > 0 {
+			candidates = healthy
+		}
+	}
+	if r.Weight != nil {
+		candidates = weightedOrder(candidates, r.Weight)
+	}
+	return candidates
+}
+
+// weightedOrder returns a copy of the given replicas in a random order where
+// each replica's chance of coming before the others is proportional to its
+// weight, using the Efraimidis-Spirakis weighted sampling algorithm.
+func weightedOrder[R any](replicas []R, weight func(R) float64) []R {
+	type keyed struct {
+		replica R
+		key     float64
+	}
+	keys := make([]keyed, len(replicas))
+	for i, replica := range replicas {
+		key := math.Inf(-1)
+		if w := weight(replica); w > 0 {
+			key = math.Pow(rand.Float64(), 1/w)
+		}
+		keys[i] = keyed{replica, key}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].key > keys[j].key
+	})
+	ordered := make([]R, len(keys))
+	for i, k := range keys {
+		ordered[i] = k.replica
+	}
+	return ordered
+}
+
+// DoReplicas speculatively executes a ReplicaThunk against one replica after
+// another in parallel, waiting for the given patience duration between
+// subsequent executions.  Each replica is tried at most once.
+//
+// Note that for DoReplicas to respect context cancelations, the given
+// ReplicaThunk must respect them.
+func DoReplicas[R, T any](ctx context.Context, patience time.Duration, replicas Replicas[R], thunk ReplicaThunk[R, T], opts ...Option) (T, error) {
+	replicas, err := replicas.discover(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	candidates := replicas.candidates()
+	if len(candidates) == 0 {
+		var zero T
+		return zero, ErrNoReplicas
+	}
+	return run(ctx, patience, newConfig(opts), func(attempt int) (task[T], bool) {
+		if attempt >= len(candidates) {
+			return task[T]{}, false
+		}
+		replica := candidates[attempt]
+		return task[T]{
+			thunk: func(ctx context.Context) (T, error) {
+				return thunk(ctx, replica)
+			},
+			target: replica,
+		}, true
+	})
+}
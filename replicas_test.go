@@ -0,0 +1,196 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// replicaRecorder records which replicas a ReplicaThunk was executed against.
+type replicaRecorder struct {
+	delays map[string]time.Duration
+	calls  []string
+	mu     sync.Mutex
+}
+
+func (r *replicaRecorder) call(ctx context.Context, replica string) (string, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, replica)
+	d := r.delays[replica]
+	r.mu.Unlock()
+
+	if err := sleep(ctx, d); err != nil {
+		return "", err
+	}
+	return replica, nil
+}
+
+func (r *replicaRecorder) called() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func TestDoReplicas(t *testing.T) {
+	t.Parallel()
+
+	t.Run("hedges to next replica", func(t *testing.T) {
+		t.Parallel()
+
+		rec := &replicaRecorder{delays: map[string]time.Duration{
+			"a": time.Second,
+			"b": 5 * time.Millisecond,
+		}}
+		replicas := Replicas[string]{List: []string{"a", "b"}}
+
+		val, err := DoReplicas(context.Background(), 10*time.Millisecond, replicas, rec.call)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val != "b" {
+			t.Errorf("expected val = %q, got %q", "b", val)
+		}
+	})
+
+	t.Run("each replica tried at most once", func(t *testing.T) {
+		t.Parallel()
+
+		rec := &replicaRecorder{delays: map[string]time.Duration{
+			"a": 50 * time.Millisecond,
+		}}
+		replicas := Replicas[string]{List: []string{"a"}}
+
+		val, err := DoReplicas(context.Background(), 5*time.Millisecond, replicas, rec.call)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val != "a" {
+			t.Errorf("expected val = %q, got %q", "a", val)
+		}
+		if calls := rec.called(); len(calls) != 1 {
+			t.Errorf("expected 1 call, got %v", calls)
+		}
+	})
+
+	t.Run("unhealthy replicas skipped", func(t *testing.T) {
+		t.Parallel()
+
+		rec := &replicaRecorder{delays: map[string]time.Duration{
+			"a": 50 * time.Millisecond,
+			"b": 50 * time.Millisecond,
+			"c": 50 * time.Millisecond,
+		}}
+		replicas := Replicas[string]{
+			List:    []string{"a", "b", "c"},
+			Healthy: func(r string) bool { return r != "a" },
+		}
+
+		val, err := DoReplicas(context.Background(), 10*time.Millisecond, replicas, rec.call)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val != "b" {
+			t.Errorf("expected val = %q, got %q", "b", val)
+		}
+		for _, replica := range rec.called() {
+			if replica == "a" {
+				t.Errorf("unhealthy replica %q should not have been called", replica)
+			}
+		}
+	})
+
+	t.Run("all unhealthy falls back to all replicas", func(t *testing.T) {
+		t.Parallel()
+
+		rec := &replicaRecorder{}
+		replicas := Replicas[string]{
+			List:    []string{"a", "b"},
+			Healthy: func(string) bool { return false },
+		}
+
+		val, err := DoReplicas(context.Background(), 10*time.Millisecond, replicas, rec.call)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if val != "a" {
+			t.Errorf("expected val = %q, got %q", "a", val)
+		}
+	})
+
+	t.Run("no replicas", func(t *testing.T) {
+		t.Parallel()
+
+		rec := &replicaRecorder{}
+		_, err := DoReplicas(context.Background(), 10*time.Millisecond, Replicas[string]{}, rec.call)
+		if err != ErrNoReplicas {
+			t.Errorf("expected err = %s, got %v", ErrNoReplicas, err)
+		}
+	})
+}
+
+func TestWeightedReplicas(t *testing.T) {
+	t.Parallel()
+
+	weights := map[string]float64{"near": 70, "far": 30, "disabled": 0}
+	replicas := Replicas[string]{
+		List:   []string{"disabled", "far", "near"},
+		Weight: func(r string) float64 { return weights[r] },
+	}
+
+	const iterations = 2000
+	firsts := map[string]int{}
+	for i := 0; i < iterations; i++ {
+		candidates := replicas.candidates()
+		if len(candidates) != 3 {
+			t.Fatalf("expected 3 candidates, got %v", candidates)
+		}
+		if last := candidates[2]; last != "disabled" {
+			t.Fatalf("expected zero-weight replica to be tried last, got %v", candidates)
+		}
+		firsts[candidates[0]]++
+	}
+
+	if share := float64(firsts["near"]) / iterations; share < 0.6 || share > 0.8 {
+		t.Errorf("expected near replica to be tried first ~70%% of the time, got %.1f%%", share*100)
+	}
+	if replicas.List[0] != "disabled" {
+		t.Errorf("expected replica list not to be modified, got %v", replicas.List)
+	}
+}
+
+func TestReplicasDiscovery(t *testing.T) {
+	t.Parallel()
+
+	updates := make(chan []string)
+	watched := WatchReplicas([]string{"a"}, updates)
+	replicas := Replicas[string]{List: []string{"static"}, Discovery: watched}
+	thunk := func(_ context.Context, replica string) (string, error) {
+		return replica, nil
+	}
+
+	got, err := DoReplicas(context.Background(), time.Second, replicas, thunk)
+	if err != nil || got != "a" {
+		t.Errorf("expected discovered replica %q, got %q, %v", "a", got, err)
+	}
+
+	// Later calls follow updates to the replica set, once received
+	updates <- []string{"b", "c"}
+	updates <- []string{"c"}
+	updates <- []string{"c"}
+	got, err = DoReplicas(context.Background(), time.Second, replicas, thunk)
+	if err != nil || got != "c" {
+		t.Errorf("expected updated replica %q, got %q, %v", "c", got, err)
+	}
+	close(updates)
+
+	// Discovery errors fail the call
+	errDiscovery := errors.New("registry unavailable")
+	replicas.Discovery = DiscoveryFunc[string](func(context.Context) ([]string, error) {
+		return nil, errDiscovery
+	})
+	if _, err := DoReplicas(context.Background(), time.Second, replicas, thunk); err != errDiscovery {
+		t.Errorf("expected err = %v, got %v", errDiscovery, err)
+	}
+}
@@ -0,0 +1,74 @@
+package speculatively
+
+import "time"
+
+// Report describes how a single call unfolded.
+type Report struct {
+	// Start is the time the call was made.
+	Start time.Time
+	// Duration is the time the call took to return.
+	Duration time.Duration
+	// Winner is the index of the attempt whose successful result was
+	// returned, or -1 if the call failed.
+	Winner int
+	// Err is the error returned by the call, if any.
+	Err error
+	// Attempts describes every attempt launched, ordered by index, followed
+	// by the hedge that was due but suppressed when the call ended, if any.
+	Attempts []AttemptReport
+}
+
+// AttemptReport describes a single attempt within a Report.
+type AttemptReport struct {
+	Attempt
+	// Done reports whether the attempt returned before the call ended.  If
+	// not, the attempt was still running and Elapsed is the time it had run
+	// for when the call ended.
+	Done bool
+	// Elapsed is the time the attempt took to return.
+	Elapsed time.Duration
+	// Err is the error the attempt returned, if any.
+	Err error
+	// Termination is how the attempt ended.
+	Termination Termination
+	// Suppression is the reason the attempt was not launched, if its
+	// Termination is TerminationSuppressed.
+	Suppression Suppression
+	// HTTP holds the phases of the attempt's HTTP requests if WithHTTPPhases
+	// is set, or nil.
+	HTTP *HTTPPhases
+}
+
+// report builds a Report of the call, which ended at the given time.  See
+// call.end for the other arguments.
+func (c *call[T]) report(end time.Time, winner, failed int, err error) Report {
+	r := Report{
+		Start:    c.start,
+		Duration: end.Sub(c.start),
+		Winner:   winner,
+		Err:      err,
+		Attempts: make([]AttemptReport, len(c.attempts)),
+	}
+	for i, a := range c.attempts {
+		ar := AttemptReport{Attempt: a, Termination: c.termination(i, winner, failed)}
+		if elapsed, ok := c.delivered[i]; ok {
+			ar.Done = true
+			ar.Elapsed = elapsed
+			ar.Err = c.errs[i]
+		} else {
+			ar.Elapsed = end.Sub(a.Start)
+		}
+		if i < len(c.phases) {
+			ar.HTTP = c.phases[i].snapshot()
+		}
+		r.Attempts[i] = ar
+	}
+	if s := c.suppressed; s != nil && s.attempt.Index == len(c.attempts) {
+		r.Attempts = append(r.Attempts, AttemptReport{
+			Attempt:     s.attempt,
+			Termination: TerminationSuppressed,
+			Suppression: s.reason,
+		})
+	}
+	return r
+}
@@ -0,0 +1,69 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("hedge wins", func(t *testing.T) {
+		t.Parallel()
+
+		fail := errors.New("fail")
+		thunk := newTestThunk([]result[int]{{err: fail}, {val: 2}, {val: 3}}, []time.Duration{5 * time.Millisecond, 20 * time.Millisecond, time.Second})
+		e := NewExemplars(0, 1)
+
+		if _, err := Do(context.Background(), 10*time.Millisecond, thunk.call, WithRetryable(func(error) bool { return true }), WithMaxAttempts(3), WithExemplars(e)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		reports := e.Recent()
+		if len(reports) != 1 {
+			t.Fatalf("expected 1 report, got %d", len(reports))
+		}
+		r := reports[0]
+		if r.Winner != 1 || r.Err != nil {
+			t.Errorf("expected attempt 1 to win, got winner %d and err %v", r.Winner, r.Err)
+		}
+		if len(r.Attempts) != 3 {
+			t.Fatalf("expected 3 attempts, got %d", len(r.Attempts))
+		}
+		if a := r.Attempts[0]; !a.Done || a.Err != fail {
+			t.Errorf("expected attempt 0 to fail, got %+v", a)
+		}
+		if a := r.Attempts[1]; !a.Done || a.Err != nil || a.Elapsed < 20*time.Millisecond {
+			t.Errorf("expected attempt 1 to succeed after ~20ms, got %+v", a)
+		}
+		if a := r.Attempts[2]; a.Done || a.Index != 2 {
+			t.Errorf("expected attempt 2 to still be running, got %+v", a)
+		}
+		if r.Duration < r.Attempts[1].Elapsed {
+			t.Errorf("expected call duration of at least %s, got %s", r.Attempts[1].Elapsed, r.Duration)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+
+		thunk := newSimpleTestThunk(1, nil, time.Second)
+		e := NewExemplars(0, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		if _, err := Do(ctx, time.Second, thunk.call, WithExemplars(e)); err == nil {
+			t.Fatalf("expected error")
+		}
+
+		r := e.Recent()[0]
+		if r.Winner != -1 || r.Err != context.DeadlineExceeded {
+			t.Errorf("expected no winner and err = %s, got %d and %v", context.DeadlineExceeded, r.Winner, r.Err)
+		}
+		if len(r.Attempts) != 1 || r.Attempts[0].Done {
+			t.Errorf("expected a single running attempt, got %+v", r.Attempts)
+		}
+	})
+}
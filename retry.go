@@ -0,0 +1,86 @@
+package speculatively
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Retry is a sequential retry policy for RetryMiddleware.
+type Retry struct {
+	// Attempts is the maximum number of times the wrapped Thunk is
+	// executed, including the first.  Values less than 1 mean a single
+	// execution.
+	Attempts int
+
+	// Backoff returns how long to wait before the given retry, starting
+	// at 1 for the first retry, e.g. a func returned by
+	// ExponentialBackoff.  If nil, retries are immediate.
+	Backoff func(retry int) time.Duration
+
+	// Retryable reports whether an error is transient and worth retrying.
+	// If nil, IsTransient is used.
+	Retryable func(error) bool
+}
+
+// RetryMiddleware retries executions of the wrapped Thunk that fail with a
+// transient error according to the given policy, returning the last error
+// once retries are exhausted.  Backoff delays respect context cancelation.
+//
+// Retrying and hedging complement each other: used to wrap the Thunk given to
+// Do, each attempt retries its own transient failures while Do hedges slow
+// attempts, so the two layers should be sized together, e.g. by keeping
+// retries few and quick within a generous patience.
+func RetryMiddleware[T any](r Retry) Middleware[T] {
+	retryable := r.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	return func(thunk Thunk[T]) Thunk[T] {
+		return func(ctx context.Context) (T, error) {
+			for retry := 1; ; retry++ {
+				val, err := thunk(ctx)
+				if err == nil || retry >= r.Attempts || !retryable(err) {
+					return val, err
+				}
+				if r.Backoff != nil {
+					if err := sleep(ctx, r.Backoff(retry)); err != nil {
+						return val, err
+					}
+				} else if ctx.Err() != nil {
+					return val, ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+// IsTransient reports whether an error is likely to be transient, i.e. a
+// timeout or connection failure as categorized by CategorizeError.
+func IsTransient(err error) bool {
+	switch CategorizeError(err) {
+	case ErrorTimeout, ErrorConnection:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExponentialBackoff returns a backoff func for Retry that waits a random
+// duration of up to base doubled for every retry, capped at limit, i.e. with
+// "full jitter".
+func ExponentialBackoff(base, limit time.Duration) func(retry int) time.Duration {
+	return func(retry int) time.Duration {
+		d := base
+		for i := 1; i < retry && d < limit; i++ {
+			d *= 2
+		}
+		if d > limit {
+			d = limit
+		}
+		if d <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(int64(d)) + 1)
+	}
+}
@@ -0,0 +1,105 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRetryMiddleware(t *testing.T) {
+	t.Parallel()
+
+	errPermanent := errors.New("permanent")
+	testCases := map[string]struct {
+		errs      []error
+		retry     Retry
+		wantCalls int
+		wantErr   error
+	}{
+		"success": {
+			retry:     Retry{Attempts: 3},
+			wantCalls: 1,
+		},
+		"transient failures": {
+			errs:      []error{io.ErrUnexpectedEOF, context.DeadlineExceeded},
+			retry:     Retry{Attempts: 3, Backoff: ExponentialBackoff(time.Millisecond, 5*time.Millisecond)},
+			wantCalls: 3,
+		},
+		"retries exhausted": {
+			errs:      []error{io.ErrUnexpectedEOF, io.ErrUnexpectedEOF, io.ErrUnexpectedEOF},
+			retry:     Retry{Attempts: 2},
+			wantCalls: 2,
+			wantErr:   io.ErrUnexpectedEOF,
+		},
+		"permanent failure": {
+			errs:      []error{errPermanent},
+			retry:     Retry{Attempts: 3},
+			wantCalls: 1,
+			wantErr:   errPermanent,
+		},
+		"custom classifier": {
+			errs:      []error{errPermanent},
+			retry:     Retry{Attempts: 3, Retryable: func(err error) bool { return err == errPermanent }},
+			wantCalls: 2,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := 0
+			thunk := Wrap(func(context.Context) (int, error) {
+				calls++
+				if calls <= len(tc.errs) {
+					return 0, tc.errs[calls-1]
+				}
+				return 1, nil
+			}, RetryMiddleware[int](tc.retry))
+
+			val, err := thunk(context.Background())
+			if err != tc.wantErr {
+				t.Errorf("expected err = %v, got %v", tc.wantErr, err)
+			}
+			if err == nil && val != 1 {
+				t.Errorf("expected val = %d, got %d", 1, val)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("expected %d calls, got %d", tc.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestRetryMiddlewareBackoffRespectsContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	thunk := Wrap(func(context.Context) (int, error) {
+		return 0, io.ErrUnexpectedEOF
+	}, RetryMiddleware[int](Retry{Attempts: 3, Backoff: func(int) time.Duration { return time.Hour }}))
+
+	start := time.Now()
+	if _, err := thunk(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected err = %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected backoff to stop with the context, took %s", elapsed)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	for retry, limit := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 10: 50 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if d := backoff(retry); d <= 0 || d > limit {
+				t.Fatalf("expected backoff for retry %d in (0, %s], got %s", retry, limit, d)
+			}
+		}
+	}
+}
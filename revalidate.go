@@ -0,0 +1,155 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RevalidatingCache caches the results of speculative executions by key,
+// serving them with stale-while-revalidate semantics:
+//
+//   - a result younger than Fresh is returned right away;
+//   - a result older than Fresh but younger than Fresh + Stale is returned
+//     right away too, while a hedged refresh runs in the background;
+//   - otherwise, the call blocks on a hedged fetch.
+//
+// Concurrent fetches and refreshes of the same key share a single
+// execution, as with Coalescer.  Failed refreshes are ignored, so that the
+// stale result keeps being served until it expires.
+//
+// Only successful results are cached.  Results are kept in memory, and
+// evicted as soon as they expire, i.e. Fresh + Stale after they were
+// fetched, unless a Cache is set to share them across processes.
+//
+// A RevalidatingCache is safe for concurrent use, and must not be copied
+// after first use.
+type RevalidatingCache[T any] struct {
+	// Fresh is how long a result is returned without being refreshed.
+	Fresh time.Duration
+
+	// Stale is how long a result keeps being returned, once it is no
+	// longer fresh, while it is refreshed in the background.
+	Stale time.Duration
+
+	// Patience is how long to wait for a fetch to complete before
+	// launching another attempt.
+	Patience time.Duration
+
+	// Options customize the hedging of every fetch, e.g. to share a
+	// Budget.
+	Options []Option
+
+	// Cache optionally stores results instead of process memory, e.g. to
+	// share them across instances of a service.  Errors reading from the
+	// Cache are treated as misses, and errors writing to it are ignored.
+	Cache Cache
+
+	// Codec serializes results stored in Cache.  If its funcs are nil,
+	// results are serialized by JSONCodec.
+	Codec Codec[T]
+
+	entries map[string]*memoryEntry[T]
+	flights Coalescer[T]
+	mu      sync.Mutex
+}
+
+// cached is a result along with the time it was fetched.
+type cached[T any] struct {
+	val     T
+	fetched time.Time
+}
+
+// memoryEntry is a result cached in memory, along with the timer that evicts
+// it once it expires.
+type memoryEntry[T any] struct {
+	cached[T]
+	expiry *time.Timer
+}
+
+// Do returns the result cached for the given key, refreshing it in the
+// background if it is stale, or speculatively executes a Thunk to fetch it
+// if it is missing or expired.  See Do for details.
+func (c *RevalidatingCache[T]) Do(ctx context.Context, key string, thunk Thunk[T]) (T, error) {
+	if entry, ok := c.load(ctx, key); ok {
+		switch age := time.Since(entry.fetched); {
+		case age < c.Fresh:
+			return entry.val, nil
+		case age < c.Fresh+c.Stale:
+			// The refresh outlives this call, but keeps its values
+			go c.fetch(valuesFrom{Context: context.Background(), values: ctx}, key, thunk)
+			return entry.val, nil
+		}
+	}
+	return c.fetch(ctx, key, thunk)
+}
+
+// fetch speculatively executes a Thunk, shared with any fetch of the same key
+// already in progress, and caches its result.
+func (c *RevalidatingCache[T]) fetch(ctx context.Context, key string, thunk Thunk[T]) (T, error) {
+	val, err := c.flights.Do(ctx, key, c.Patience, thunk, c.Options...)
+	if err != nil {
+		return val, err
+	}
+	c.store(ctx, key, cached[T]{val: val, fetched: time.Now()})
+	return val, nil
+}
+
+// load returns the entry cached for the given key, if any.
+func (c *RevalidatingCache[T]) load(ctx context.Context, key string) (cached[T], bool) {
+	if c.Cache == nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		entry, ok := c.entries[key]
+		if !ok {
+			return cached[T]{}, false
+		}
+		return entry.cached, true
+	}
+	data, ok, err := c.Cache.Get(ctx, key)
+	if err != nil || !ok {
+		return cached[T]{}, false
+	}
+	entry, err := c.codec().decode(data)
+	return entry, err == nil
+}
+
+// store caches the given entry under the given key.
+func (c *RevalidatingCache[T]) store(ctx context.Context, key string, entry cached[T]) {
+	if c.Cache == nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.entries == nil {
+			c.entries = map[string]*memoryEntry[T]{}
+		}
+		if old, ok := c.entries[key]; ok {
+			old.expiry.Stop()
+		}
+		e := &memoryEntry[T]{cached: entry}
+		e.expiry = time.AfterFunc(c.Fresh+c.Stale, func() { c.evict(key, e) })
+		c.entries[key] = e
+		return
+	}
+	data, err := c.codec().encode(entry)
+	if err != nil {
+		return
+	}
+	c.Cache.Set(ctx, key, data, c.Fresh+c.Stale) //nolint:errcheck
+}
+
+// evict removes the given entry cached in memory for the given key, unless it
+// has been replaced since.
+func (c *RevalidatingCache[T]) evict(key string, e *memoryEntry[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[key] == e {
+		delete(c.entries, key)
+	}
+}
+
+func (c *RevalidatingCache[T]) codec() Codec[T] {
+	if c.Codec.Marshal == nil || c.Codec.Unmarshal == nil {
+		return JSONCodec[T]()
+	}
+	return c.Codec
+}
@@ -0,0 +1,117 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRevalidatingCache(t *testing.T) {
+	t.Parallel()
+
+	c := &RevalidatingCache[int64]{
+		Fresh:    50 * time.Millisecond,
+		Stale:    100 * time.Millisecond,
+		Patience: time.Second,
+	}
+	var fetches int64
+	refreshed := make(chan struct{}, 10)
+	thunk := func(ctx context.Context) (int64, error) {
+		n := atomic.AddInt64(&fetches, 1)
+		refreshed <- struct{}{}
+		return n, nil
+	}
+	get := func() int64 {
+		t.Helper()
+		got, err := c.Do(context.Background(), "key", thunk)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return got
+	}
+
+	// A miss blocks on a fetch, after which the result is fresh
+	if got := get(); got != 1 {
+		t.Errorf("expected fetched result 1, got %d", got)
+	}
+	<-refreshed
+	if got := get(); got != 1 {
+		t.Errorf("expected fresh result 1, got %d", got)
+	}
+
+	// A stale result is returned while it is refreshed in the background
+	time.Sleep(60 * time.Millisecond)
+	if got := get(); got != 1 {
+		t.Errorf("expected stale result 1, got %d", got)
+	}
+	<-refreshed
+	time.Sleep(10 * time.Millisecond)
+	if got := get(); got != 2 {
+		t.Errorf("expected refreshed result 2, got %d", got)
+	}
+
+	// An expired result blocks on a fetch
+	time.Sleep(200 * time.Millisecond)
+	if got := get(); got != 3 {
+		t.Errorf("expected fetched result 3, got %d", got)
+	}
+}
+
+func TestRevalidatingCacheEviction(t *testing.T) {
+	t.Parallel()
+
+	c := &RevalidatingCache[int]{
+		Fresh:    10 * time.Millisecond,
+		Stale:    10 * time.Millisecond,
+		Patience: time.Second,
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if _, err := c.Do(context.Background(), key, func(ctx context.Context) (int, error) { return i, nil }); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// Expired results are evicted even if they are never requested again
+	size := func() int {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.entries)
+	}
+	for deadline := time.Now().Add(time.Second); size() > 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := size(); n != 0 {
+		t.Errorf("expected expired results to be evicted, %d remain", n)
+	}
+}
+
+func TestRevalidatingCacheErrors(t *testing.T) {
+	t.Parallel()
+
+	c := &RevalidatingCache[int]{Fresh: time.Millisecond, Stale: time.Hour, Patience: time.Second}
+	wantErr := errors.New("unavailable")
+	fail := func(context.Context) (int, error) { return 0, wantErr }
+
+	// Errors are not cached
+	if _, err := c.Do(context.Background(), "key", fail); err != wantErr {
+		t.Errorf("expected error %v, got %v", wantErr, err)
+	}
+	got, err := c.Do(context.Background(), "key", func(context.Context) (int, error) { return 1, nil })
+	if err != nil || got != 1 {
+		t.Fatalf("expected result 1, got %d, %v", got, err)
+	}
+
+	// A failed refresh keeps the stale result
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		got, err := c.Do(context.Background(), "key", fail)
+		if err != nil || got != 1 {
+			t.Errorf("expected stale result 1, got %d, %v", got, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
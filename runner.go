@@ -0,0 +1,54 @@
+package speculatively
+
+import "context"
+
+// Runner runs the attempts of calls somewhere other than in a goroutine of
+// their own, e.g. on a bounded pool of workers, through a job queue, or on a
+// sidecar or remote worker.  Do and its variants still decide when each
+// attempt is launched, which result wins, and when the others are canceled,
+// while the Runner decides where and when each attempt actually executes.
+//
+// A Runner that dispatches work off-process typically attaches the worker it
+// selects to the attempt's context, e.g. via context.WithValue, for the Thunk
+// to send its request to.
+type Runner interface {
+	// Run arranges for run to be called, exactly once, with the given
+	// context or one derived from it, to execute the given attempt.  It is
+	// called synchronously as each attempt is launched, so it must not
+	// block, e.g. waiting for run to return.
+	//
+	// The context is canceled once the attempt is no longer needed, e.g.
+	// because another attempt won the call, which a Runner may use to
+	// abort work dispatched elsewhere.  An attempt whose context is done by
+	// the time run is called returns as soon as its Thunk respects the
+	// cancelation.
+	Run(ctx context.Context, attempt Attempt, run func(context.Context))
+}
+
+// RunnerFunc adapts a func to the Runner interface.
+type RunnerFunc func(ctx context.Context, attempt Attempt, run func(context.Context))
+
+// Run calls fn.
+func (fn RunnerFunc) Run(ctx context.Context, attempt Attempt, run func(context.Context)) {
+	fn(ctx, attempt, run)
+}
+
+// WithRunners runs attempts on the given Runners rather than in goroutines
+// of their own.  Attempts are assigned to Runners in turn, so that e.g. given
+// two Runners, the first attempt of each call runs on the first Runner and
+// its first hedge on the second.
+func WithRunners(runners ...Runner) Option {
+	return func(c *config) {
+		c.runners = runners
+	}
+}
+
+// start executes fn for the given attempt on the Runner assigned to it, or
+// in a new goroutine if there is none.
+func (c *config) start(ctx context.Context, a Attempt, fn func(context.Context)) {
+	if len(c.runners) == 0 {
+		go fn(ctx)
+		return
+	}
+	c.runners[a.Index%len(c.runners)].Run(ctx, a, fn)
+}
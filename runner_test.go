@@ -0,0 +1,109 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type workerKey struct{}
+
+// testRunner runs every attempt in a goroutine, tagging its context with the
+// runner's name, and records the attempts it ran and whether they were
+// canceled.
+type testRunner struct {
+	name string
+
+	mu       sync.Mutex
+	attempts []int
+	canceled []int
+}
+
+func (r *testRunner) Run(ctx context.Context, a Attempt, run func(context.Context)) {
+	r.mu.Lock()
+	r.attempts = append(r.attempts, a.Index)
+	r.mu.Unlock()
+	go func() {
+		run(context.WithValue(ctx, workerKey{}, r.name))
+		if ctx.Err() != nil {
+			r.mu.Lock()
+			r.canceled = append(r.canceled, a.Index)
+			r.mu.Unlock()
+		}
+	}()
+}
+
+func (r *testRunner) ran() ([]int, []int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.attempts...), append([]int(nil), r.canceled...)
+}
+
+func TestWithRunners(t *testing.T) {
+	t.Parallel()
+
+	primary, secondary := &testRunner{name: "primary"}, &testRunner{name: "secondary"}
+	done := make(chan struct{})
+	got, err := Do(context.Background(), 10*time.Millisecond, func(ctx context.Context) (string, error) {
+		worker, _ := ctx.Value(workerKey{}).(string)
+		if worker == "primary" {
+			<-ctx.Done()
+			close(done)
+			return "", ctx.Err()
+		}
+		return worker, nil
+	}, WithRunners(primary, secondary), WithMaxAttempts(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != "secondary" {
+		t.Errorf("expected result from secondary runner, got %q", got)
+	}
+
+	// The losing attempt is canceled on its runner
+	<-done
+	time.Sleep(10 * time.Millisecond)
+	if attempts, canceled := primary.ran(); len(attempts) != 1 || attempts[0] != 0 || len(canceled) != 1 {
+		t.Errorf("expected primary runner to run canceled attempt 0, got attempts %v, canceled %v", attempts, canceled)
+	}
+	if attempts, canceled := secondary.ran(); len(attempts) != 1 || attempts[0] != 1 || len(canceled) != 0 {
+		t.Errorf("expected secondary runner to run attempt 1, got attempts %v, canceled %v", attempts, canceled)
+	}
+}
+
+func TestWithRunnersAssignsInTurn(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		runners []int
+	)
+	runner := func(i int) Runner {
+		return RunnerFunc(func(ctx context.Context, a Attempt, run func(context.Context)) {
+			mu.Lock()
+			runners = append(runners, i)
+			mu.Unlock()
+			go run(ctx)
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	Do(ctx, time.Millisecond, func(ctx context.Context) (int, error) { //nolint:errcheck
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, WithRunners(runner(0), runner(1)), WithMaxAttempts(3))
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []int{0, 1, 0}
+	if len(runners) != len(want) {
+		t.Fatalf("expected runners %v, got %v", want, runners)
+	}
+	for i := range want {
+		if runners[i] != want[i] {
+			t.Errorf("expected runners %v, got %v", want, runners)
+			break
+		}
+	}
+}
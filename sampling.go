@@ -0,0 +1,42 @@
+package speculatively
+
+import "sync/atomic"
+
+// WithTelemetrySampling bounds the overhead of detailed telemetry for services
+// making very many calls, by only recording 1 in every n calls in detail.
+// Calls that are not sampled skip MetricsRecorders, loggers, Exemplars,
+// runtime traces, profiler labels and HTTP phases, while the cheap counters
+// behind Hedger stats and Hooks registered via WithHooks apply to every call.
+//
+// Sampling is deterministic, so the returned Option must be created once and
+// shared by every call, e.g. via a Hedger.
+func WithTelemetrySampling(n int) Option {
+	s := &sampler{n: int64(n)}
+	return func(c *config) {
+		c.sampler = s
+	}
+}
+
+// sampler selects 1 in every n calls.
+type sampler struct {
+	n     int64
+	count int64
+}
+
+func (s *sampler) sample() bool {
+	if s.n <= 1 {
+		return true
+	}
+	return atomic.AddInt64(&s.count, 1)%s.n == 1
+}
+
+// unsampled returns a copy of c with detailed telemetry disabled.
+func (c *config) unsampled() *config {
+	cfg := *c
+	cfg.hooks = cfg.userHooks
+	cfg.exemplars = nil
+	cfg.traceName = ""
+	cfg.profileLabels = false
+	cfg.httpPhases = false
+	return &cfg
+}
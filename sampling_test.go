@@ -0,0 +1,78 @@
+package speculatively
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithTelemetrySampling(t *testing.T) {
+	t.Parallel()
+
+	var metrics, hooks int64
+	rec := &countingRecorder{attempts: &metrics}
+	e := NewExemplars(0, 100)
+	h := NewHedger(time.Second,
+		WithTelemetrySampling(10),
+		WithMetrics(rec),
+		WithExemplars(e),
+		WithHooks(Hooks{OnLaunch: func(Attempt) { atomic.AddInt64(&hooks, 1) }}),
+	)
+
+	thunk := newSimpleTestThunk(1, nil, 0)
+	for i := 0; i < 30; i++ {
+		if _, err := DoWith(context.Background(), h, thunk.call); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if n := atomic.LoadInt64(&metrics); n != 3 {
+		t.Errorf("expected 3 calls recorded by metrics, got %d", n)
+	}
+	if n := len(e.Recent()); n != 3 {
+		t.Errorf("expected 3 exemplars, got %d", n)
+	}
+	if n := atomic.LoadInt64(&hooks); n != 30 {
+		t.Errorf("expected hooks to see all 30 calls, got %d", n)
+	}
+	if n := h.Stats().Calls; n != 30 {
+		t.Errorf("expected stats to count all 30 calls, got %d", n)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int64{-1, 0, 1} {
+		s := &sampler{n: n}
+		for i := 0; i < 3; i++ {
+			if !s.sample() {
+				t.Errorf("expected every call to be sampled with n = %d", n)
+			}
+		}
+	}
+
+	s := &sampler{n: 3}
+	var got []bool
+	for i := 0; i < 6; i++ {
+		got = append(got, s.sample())
+	}
+	want := []bool{true, false, false, true, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected samples %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+// countingRecorder counts the attempts it records.
+type countingRecorder struct {
+	NopMetricsRecorder
+	attempts *int64
+}
+
+func (r *countingRecorder) RecordAttempt(Attempt) {
+	atomic.AddInt64(r.attempts, 1)
+}
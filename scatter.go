@@ -0,0 +1,53 @@
+package speculatively
+
+import (
+	"context"
+	"time"
+)
+
+// DoScatter fans a request out to every one of a set of shards, e.g. the
+// partitions of a sharded store, speculatively executing fn against each
+// shard as Do does, and gathers their results in the same order as the
+// shards.  At most as many shards as allowed by WithConcurrency are called at
+// once, and the given Options otherwise apply to each shard's call.
+//
+// Each shard's call, including all of its attempts, is bounded by
+// shardTimeout, if positive, and the whole fan-out by the deadline of ctx, if
+// any, so that a slow shard cannot hold up the others' results.  The results
+// of every shard that succeeded are returned even if others failed, along
+// with the errors of the failed shards, by index, as ItemErrors.
+func DoScatter[S, T any](ctx context.Context, patience, shardTimeout time.Duration, shards []S, fn ReplicaThunk[S, T], opts ...Option) ([]T, error) {
+	results := make([]T, len(shards))
+	errs := make([]error, len(shards))
+	called := make([]bool, len(shards))
+	err := forEachInput(ctx, newConfig(opts), len(shards), func(ctx context.Context, i int) {
+		called[i] = true
+		if shardTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, shardTimeout)
+			defer cancel()
+		}
+		results[i], errs[i] = Do(ctx, patience, func(ctx context.Context) (T, error) {
+			return fn(ctx, shards[i])
+		}, opts...)
+	})
+	if err != nil {
+		// Shards not called before ctx was done fail with its error
+		for i := range errs {
+			if !called[i] {
+				errs[i] = err
+			}
+		}
+	}
+
+	var failed ItemErrors
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, ItemError{Index: i, Err: err})
+		}
+	}
+	if len(failed) > 0 {
+		return results, failed
+	}
+	return results, nil
+}
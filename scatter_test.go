@@ -0,0 +1,76 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDoScatter(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	// Each shard's first attempt is slow, so that every shard is hedged,
+	// except for shard 2, which hangs, and shard 3, which fails
+	fn := func(ctx context.Context, shard int) (int, error) {
+		switch {
+		case shard == 2:
+			<-ctx.Done()
+			return 0, ctx.Err()
+		case shard == 3:
+			return 0, failed
+		case !IsHedge(ctx):
+			if err := sleep(ctx, time.Second); err != nil {
+				return 0, err
+			}
+		}
+		return shard * 10, nil
+	}
+
+	start := time.Now()
+	results, err := DoScatter(context.Background(), 10*time.Millisecond, 50*time.Millisecond, []int{0, 1, 2, 3}, fn, WithMaxAttempts(2))
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected hanging shard to time out, took %s", elapsed)
+	}
+	if want := []int{0, 10, 0, 0}; !reflect.DeepEqual(results, want) {
+		t.Errorf("expected results %v, got %v", want, results)
+	}
+	var shardErrs ItemErrors
+	if !errors.As(err, &shardErrs) {
+		t.Fatalf("expected ItemErrors, got %v", err)
+	}
+	if len(shardErrs) != 2 ||
+		shardErrs[0].Index != 2 || shardErrs[0].Err != context.DeadlineExceeded ||
+		shardErrs[1].Index != 3 || shardErrs[1].Err != failed {
+		t.Errorf("expected shard 2 to time out and shard 3 to fail, got %v", shardErrs)
+	}
+}
+
+func TestDoScatterDeadline(t *testing.T) {
+	t.Parallel()
+
+	// Only one shard is called at once, so that the overall deadline
+	// expires before the last shard is called
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results, err := DoScatter(ctx, time.Second, 0, []time.Duration{0, time.Second, 0}, func(ctx context.Context, delay time.Duration) (string, error) {
+		return "ok", sleep(ctx, delay)
+	}, WithConcurrency(1))
+	if want := []string{"ok", "", ""}; !reflect.DeepEqual(results, want) {
+		t.Errorf("expected results %v, got %v", want, results)
+	}
+	var shardErrs ItemErrors
+	if !errors.As(err, &shardErrs) {
+		t.Fatalf("expected ItemErrors, got %v", err)
+	}
+	if len(shardErrs) != 2 || shardErrs[0].Index != 1 || shardErrs[1].Index != 2 {
+		t.Errorf("expected shards 1 and 2 to miss the deadline, got %v", shardErrs)
+	}
+	for _, e := range shardErrs {
+		if e.Err != context.DeadlineExceeded {
+			t.Errorf("expected shard %d err = %v, got %v", e.Index, context.DeadlineExceeded, e.Err)
+		}
+	}
+}
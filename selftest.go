@@ -0,0 +1,156 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// selfTestCalls is the number of synthetic calls made by Hedger.SelfTest.
+const selfTestCalls = 50
+
+// SelfTestReport describes the outcome of Hedger.SelfTest.
+type SelfTestReport struct {
+	// Calls is the number of synthetic calls made.
+	Calls int
+	// Attempts is the total number of attempts launched across all calls.
+	Attempts int
+	// Hedges is the number of attempts launched after the first attempt of
+	// each call.
+	Hedges int
+	// MaxAttemptsPerCall is the largest number of attempts made by a single
+	// call.
+	MaxAttemptsPerCall int
+	// Leaked is the number of attempts still running once the test finished
+	// waiting for canceled attempts to exit.
+	Leaked int
+	// Violations describes every policy invariant that did not hold.
+	Violations []string
+}
+
+// OK reports whether every policy invariant held.
+func (r SelfTestReport) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// SelfTest exercises h's policy against synthetic thunks with known latencies
+// and verifies that its invariants hold: budgets are respected, max attempts
+// are honored and canceled attempts do not leak.  It is useful as a startup
+// check or in the CI of services using speculatively.
+//
+// SelfTest runs against copies of the Budget and AdaptiveAttempts used by h,
+// and leaves every other state h shares with real calls untouched, e.g. its
+// hooks, metrics, LatencyTracker and ErrorGate.  It takes between one and
+// ten patience durations to run, depending on how much hedging h allows.  An
+// error is returned if any invariant was violated or if ctx was canceled
+// before the test completed.
+func (h *Hedger) SelfTest(ctx context.Context) (SelfTestReport, error) {
+	return h.selfTest(ctx, sleep)
+}
+
+// selfTest implements SelfTest, with each synthetic attempt waiting out its
+// latency via the given sleep func.
+func (h *Hedger) selfTest(ctx context.Context, sleep func(context.Context, time.Duration) error) (SelfTestReport, error) {
+	cfg := newConfig(h.opts).selfTest()
+	patience := h.patience
+	if patience <= 0 {
+		patience = time.Millisecond
+	}
+
+	var (
+		inflight int64
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		report   = SelfTestReport{Calls: selfTestCalls}
+		callErr  error
+	)
+	for i := 0; i < selfTestCalls; i++ {
+		// Every fifth call is slow enough to trigger hedging, while hedges
+		// and all other calls finish well within the patience duration.
+		slow := i%5 == 0
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var attempts int64
+			_, err := run(ctx, patience, cfg, func(int) (task[int], bool) {
+				return task[int]{thunk: func(ctx context.Context) (int, error) {
+					atomic.AddInt64(&inflight, 1)
+					defer atomic.AddInt64(&inflight, -1)
+					latency := patience / 2
+					if atomic.AddInt64(&attempts, 1) == 1 && slow {
+						latency = 10 * patience
+					}
+					return 0, sleep(ctx, latency)
+				}}, true
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			n := int(atomic.LoadInt64(&attempts))
+			report.Attempts += n
+			report.Hedges += n - 1
+			if n > report.MaxAttemptsPerCall {
+				report.MaxAttemptsPerCall = n
+			}
+			if err != nil && callErr == nil {
+				callErr = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Give canceled attempts a chance to observe cancelation and exit.  This
+	// waits on the system clock even under WithClock, since the attempts are
+	// real goroutines and a fake clock may never reach the deadline.
+	deadline := time.Now().Add(10 * patience)
+	for atomic.LoadInt64(&inflight) > 0 && time.Now().Before(deadline) {
+		time.Sleep(patience / 10)
+	}
+	report.Leaked = int(atomic.LoadInt64(&inflight))
+
+	if ctx.Err() != nil {
+		return report, ctx.Err()
+	}
+	if callErr != nil {
+		report.Violations = append(report.Violations, fmt.Sprintf("call failed: %s", callErr))
+	}
+	if limit := cfg.attemptLimit(); limit > 0 && report.MaxAttemptsPerCall > limit {
+		report.Violations = append(report.Violations, fmt.Sprintf("max attempts exceeded: %d > %d", report.MaxAttemptsPerCall, limit))
+	}
+	if b := cfg.budget; b != nil {
+		if allowed := int(b.burst + b.ratio*float64(report.Calls)); report.Hedges > allowed {
+			report.Violations = append(report.Violations, fmt.Sprintf("budget exceeded: %d hedges > %d allowed", report.Hedges, allowed))
+		}
+	}
+	if report.Leaked > 0 {
+		report.Violations = append(report.Violations, fmt.Sprintf("%d attempts leaked", report.Leaked))
+	}
+	if !report.OK() {
+		return report, errors.New("speculatively: self test failed: " + strings.Join(report.Violations, "; "))
+	}
+	return report, nil
+}
+
+// selfTest returns a config with the same policy as c, but none of the state
+// c shares with real calls, so that synthetic calls neither draw from it nor
+// feed into it.
+func (c *config) selfTest() *config {
+	cfg := &config{
+		maxAttempts:       c.maxAttempts,
+		lowPriorityHedges: c.lowPriorityHedges,
+		clock:             c.clock,
+		hedgePriority:     c.hedgePriority,
+		queueHedges:       c.queueHedges,
+	}
+	if c.budget != nil {
+		cfg.budget = c.budget.clone()
+	}
+	if c.adaptiveAttempts != nil {
+		cfg.adaptiveAttempts = c.adaptiveAttempts.clone()
+	}
+	return cfg
+}
@@ -0,0 +1,39 @@
+package speculatively
+
+import "context"
+
+// Semaphore is a weighted semaphore, like semaphore.Weighted from
+// golang.org/x/sync, which implements it.
+type Semaphore interface {
+	Acquire(ctx context.Context, n int64) error
+	TryAcquire(n int64) bool
+	Release(n int64)
+}
+
+// WithSemaphore makes every attempt hold the given weight of the given
+// Semaphore while it runs, so that calls share a concurrency budget in
+// proportion to their cost, e.g. with heavy queries weighing more than cheap
+// ones.
+//
+// The first attempt of each call waits for its weight to be available, as
+// do attempts replacing those that failed with a retryable error, while
+// hedges are suppressed if their weight is not available right away.
+func WithSemaphore(sem Semaphore, weight int64) Option {
+	return func(c *config) {
+		c.semaphore = sem
+		c.weight = weight
+	}
+}
+
+// acquiring wraps a Thunk so that it waits for the given weight of the given
+// Semaphore before running, and releases it once done.
+func acquiring[T any](thunk Thunk[T], sem Semaphore, weight int64) Thunk[T] {
+	return func(ctx context.Context) (T, error) {
+		if err := sem.Acquire(ctx, weight); err != nil {
+			var zero T
+			return zero, err
+		}
+		defer sem.Release(weight)
+		return thunk(ctx)
+	}
+}
@@ -0,0 +1,129 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSemaphore is a minimal weighted semaphore, polling for capacity when
+// acquiring.
+type testSemaphore struct {
+	mu   sync.Mutex
+	size int64
+	used int64
+}
+
+func (s *testSemaphore) Acquire(ctx context.Context, n int64) error {
+	for !s.TryAcquire(n) {
+		if err := sleep(ctx, time.Millisecond); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *testSemaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.used+n > s.size {
+		return false
+	}
+	s.used += n
+	return true
+}
+
+func (s *testSemaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used -= n
+}
+
+func (s *testSemaphore) inUse() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.used
+}
+
+func TestWithSemaphore(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		size           int64
+		weight         int64
+		wantSuppressed bool
+	}{
+		"hedge admitted":   {size: 4, weight: 2},
+		"hedge suppressed": {size: 3, weight: 2, wantSuppressed: true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sem := &testSemaphore{size: tc.size}
+			var suppressed []Suppression
+			var mu sync.Mutex
+			val, err := Do(context.Background(), 10*time.Millisecond, newSimpleTestThunk(1, nil, 50*time.Millisecond).call,
+				WithSemaphore(sem, tc.weight),
+				WithMaxAttempts(2),
+				WithHooks(Hooks{OnHedgeSuppressed: func(s Suppression) {
+					mu.Lock()
+					defer mu.Unlock()
+					suppressed = append(suppressed, s)
+				}}),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if val != 1 {
+				t.Errorf("expected val = %d, got %d", 1, val)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got := len(suppressed) > 0; got != tc.wantSuppressed {
+				t.Errorf("expected suppressed = %v, got %v", tc.wantSuppressed, suppressed)
+			}
+			for _, s := range suppressed {
+				if s != SuppressedBySemaphore {
+					t.Errorf("expected suppression = %v, got %v", SuppressedBySemaphore, s)
+				}
+			}
+
+			// Every weight is released once the attempts exit
+			time.Sleep(100 * time.Millisecond)
+			if n := sem.inUse(); n != 0 {
+				t.Errorf("expected weight in use = 0, got %d", n)
+			}
+		})
+	}
+}
+
+func TestWithSemaphoreFirstAttemptWaits(t *testing.T) {
+	t.Parallel()
+
+	sem := &testSemaphore{size: 2, used: 2}
+	time.AfterFunc(20*time.Millisecond, func() { sem.Release(2) })
+
+	start := time.Now()
+	val, err := Do(context.Background(), time.Second, newSimpleTestThunk(1, nil, 0).call, WithSemaphore(sem, 2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 1 {
+		t.Errorf("expected val = %d, got %d", 1, val)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected first attempt to wait for its weight, returned after %s", elapsed)
+	}
+
+	// Waiting for weight respects the call's context
+	sem.TryAcquire(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := Do(ctx, time.Second, newSimpleTestThunk(1, nil, 0).call, WithSemaphore(sem, 2)); err != context.DeadlineExceeded {
+		t.Errorf("expected err = %v, got %v", context.DeadlineExceeded, err)
+	}
+}
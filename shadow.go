@@ -0,0 +1,126 @@
+package speculatively
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// Shadow describes a candidate implementation to be dark-launched alongside
+// a control implementation by DoShadow.
+type Shadow[T any] struct {
+	// Candidate computes the same result as the control, e.g. via a new
+	// code path or backend.  Its result is never returned.
+	Candidate Thunk[T]
+
+	// Compare reports whether the control and candidate results match.  If
+	// nil, reflect.DeepEqual is used.
+	Compare func(control, candidate T) bool
+
+	// Report is called with the outcome of every comparison, from a
+	// goroutine of its own, once both the control and the candidate have
+	// finished.
+	Report func(ShadowResult[T])
+
+	// Timeout bounds the candidate's execution, which otherwise outlives
+	// the call so that slow candidates can still be compared.  If zero,
+	// the candidate is only bounded by its Thunk.
+	Timeout time.Duration
+}
+
+// ShadowResult is the outcome of comparing a control and a candidate.
+type ShadowResult[T any] struct {
+	Control, Candidate               T
+	ControlErr, CandidateErr         error
+	ControlElapsed, CandidateElapsed time.Duration
+
+	// Match reports whether both succeeded with matching results, or both
+	// failed.
+	Match bool
+}
+
+// DoShadow speculatively executes a control Thunk, whose result is
+// returned, while simultaneously executing the candidate Thunk of the given
+// Shadow in the same manner, so that the candidate can be compared to the
+// control in production without affecting callers, in the manner of GitHub's
+// Scientist.  See Do for details.
+//
+// The control and the candidate are each hedged according to the given
+// patience and Options, except that the candidate neither draws from nor
+// feeds into any state shared with other calls, e.g. a Budget,
+// LatencyTracker, Hooks or metrics, so that it cannot affect the control.
+// The candidate's successful result is discarded as set by WithCleanup once
+// it has been reported.
+//
+// The call returns as soon as the control's result is known, without waiting
+// for the candidate, whose context keeps the values of ctx but is not
+// canceled along with it.
+func DoShadow[T any](ctx context.Context, patience time.Duration, control Thunk[T], shadow Shadow[T], opts ...Option) (T, error) {
+	cfg := newConfig(opts)
+	shadowCfg := cfg.shadow()
+	candidateCtx, cancel := context.Background(), context.CancelFunc(func() {})
+	if shadow.Timeout > 0 {
+		candidateCtx, cancel = context.WithTimeout(candidateCtx, shadow.Timeout)
+	}
+	candidate := make(chan ShadowResult[T], 1)
+	go func() {
+		defer cancel()
+		start := shadowCfg.now()
+		val, err := run(valuesFrom{Context: candidateCtx, values: ctx}, patience, shadowCfg, func(int) (task[T], bool) {
+			return task[T]{thunk: shadow.Candidate}, true
+		})
+		candidate <- ShadowResult[T]{Candidate: val, CandidateErr: err, CandidateElapsed: shadowCfg.since(start)}
+	}()
+
+	start := cfg.now()
+	val, err := run(ctx, patience, cfg, func(int) (task[T], bool) {
+		return task[T]{thunk: control}, true
+	})
+	elapsed := cfg.since(start)
+
+	go func() {
+		r := <-candidate
+		r.Control, r.ControlErr, r.ControlElapsed = val, err, elapsed
+		r.Match = shadow.match(r)
+		if shadow.Report != nil {
+			shadow.Report(r)
+		}
+		if r.CandidateErr == nil {
+			shadowCfg.discard(r.Candidate)
+		}
+	}()
+	return val, err
+}
+
+// shadow returns a copy of c for the candidate of DoShadow, without any of
+// the state c shares with other calls.
+func (c *config) shadow() *config {
+	cfg := *c
+	cfg.budget = nil
+	cfg.tracker = nil
+	cfg.stats = nil
+	cfg.hooks = nil
+	cfg.userHooks = nil
+	cfg.errorGate = nil
+	cfg.adaptiveAttempts = nil
+	cfg.inflight = nil
+	cfg.portfolio = nil
+	cfg.canary = nil
+	cfg.exemplars = nil
+	cfg.sampler = nil
+	cfg.semaphore = nil
+	cfg.fence = nil
+	cfg.equal = nil
+	cfg.resultKey = nil
+	return &cfg
+}
+
+func (s Shadow[T]) match(r ShadowResult[T]) bool {
+	if r.ControlErr != nil || r.CandidateErr != nil {
+		return r.ControlErr != nil && r.CandidateErr != nil
+	}
+	if s.Compare == nil {
+		return reflect.DeepEqual(r.Control, r.Candidate)
+	}
+	return s.Compare(r.Control, r.Candidate)
+}
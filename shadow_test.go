@@ -0,0 +1,150 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoShadow(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		control, candidate Thunk[string]
+		compare            func(control, candidate string) bool
+		wantMatch          bool
+	}{
+		"matching results": {
+			control:   func(context.Context) (string, error) { return "result", nil },
+			candidate: func(context.Context) (string, error) { return "result", nil },
+			wantMatch: true,
+		},
+		"mismatched results": {
+			control:   func(context.Context) (string, error) { return "result", nil },
+			candidate: func(context.Context) (string, error) { return "other", nil },
+		},
+		"custom comparator": {
+			control:   func(context.Context) (string, error) { return "result", nil },
+			candidate: func(context.Context) (string, error) { return "RESULT", nil },
+			compare:   strings.EqualFold,
+			wantMatch: true,
+		},
+		"candidate fails": {
+			control:   func(context.Context) (string, error) { return "result", nil },
+			candidate: func(context.Context) (string, error) { return "", errors.New("boom") },
+		},
+		"slow candidate": {
+			control: func(context.Context) (string, error) { return "result", nil },
+			candidate: func(ctx context.Context) (string, error) {
+				select {
+				case <-time.After(50 * time.Millisecond):
+					return "result", nil
+				case <-ctx.Done():
+					return "", ctx.Err()
+				}
+			},
+			wantMatch: true,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			reports := make(chan ShadowResult[string], 1)
+			ctx, cancel := context.WithCancel(context.Background())
+			got, err := DoShadow(ctx, time.Second, tc.control, Shadow[string]{
+				Candidate: tc.candidate,
+				Compare:   tc.compare,
+				Report:    func(r ShadowResult[string]) { reports <- r },
+			})
+			// The candidate outlives the call's context
+			cancel()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != "result" {
+				t.Errorf("expected control result %q, got %q", "result", got)
+			}
+			r := <-reports
+			if r.Match != tc.wantMatch {
+				t.Errorf("expected match = %v, got %v (candidate %q, %v)", tc.wantMatch, r.Match, r.Candidate, r.CandidateErr)
+			}
+			if r.Control != "result" {
+				t.Errorf("expected reported control %q, got %q", "result", r.Control)
+			}
+		})
+	}
+}
+
+func TestDoShadowTimeout(t *testing.T) {
+	t.Parallel()
+
+	reports := make(chan ShadowResult[int], 1)
+	DoShadow(context.Background(), time.Second, func(context.Context) (int, error) { //nolint:errcheck
+		return 1, nil
+	}, Shadow[int]{
+		Candidate: func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		},
+		Report:  func(r ShadowResult[int]) { reports <- r },
+		Timeout: 10 * time.Millisecond,
+	})
+	select {
+	case r := <-reports:
+		if r.CandidateErr != context.DeadlineExceeded || r.Match {
+			t.Errorf("expected mismatch due to candidate timeout, got %v, match = %v", r.CandidateErr, r.Match)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected candidate to time out")
+	}
+}
+
+func TestDoShadowIsolated(t *testing.T) {
+	t.Parallel()
+
+	budget := NewBudget(0, 1)
+	var launched int64
+	cleaned := make(chan string, 2)
+	reported := make(chan struct{})
+	slow := func(val string) Thunk[string] {
+		return func(ctx context.Context) (string, error) {
+			if IsHedge(ctx) {
+				return val, nil
+			}
+			return val, sleep(ctx, 30*time.Millisecond)
+		}
+	}
+	_, err := DoShadow(context.Background(), 5*time.Millisecond, slow("control"), Shadow[string]{
+		Candidate: slow("candidate"),
+		Report:    func(ShadowResult[string]) { close(reported) },
+	},
+		WithBudget(budget),
+		WithCleanup(func(v string) { cleaned <- v }),
+		WithHooks(Hooks{OnLaunch: func(Attempt) { atomic.AddInt64(&launched, 1) }}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	<-reported
+
+	// Only the control draws from the budget and calls hooks
+	if n := atomic.LoadInt64(&launched); n != 2 {
+		t.Errorf("expected 2 launches of the control, got %d", n)
+	}
+	if remaining := budget.Remaining(); remaining != 0 {
+		t.Errorf("expected only the control to draw from the budget, %v remaining", remaining)
+	}
+	select {
+	case v := <-cleaned:
+		if v != "candidate" {
+			t.Errorf("expected candidate result to be cleaned up, got %q", v)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected candidate result to be cleaned up")
+	}
+}
@@ -0,0 +1,86 @@
+package speculatively
+
+import (
+	"math/rand"
+	"time"
+)
+
+// SimulationResult is the predicted behavior of a policy reported by
+// Simulate.
+type SimulationResult struct {
+	// Calls is the number of simulated calls.
+	Calls int
+	// P50, P95 and P99 are the predicted call latency quantiles.
+	P50, P95, P99 time.Duration
+	// ExtraLoad is the number of hedges launched as a fraction of calls.
+	ExtraLoad float64
+}
+
+// Simulate predicts the behavior of h's policy by simulating the given number
+// of sequential calls whose attempts have latencies drawn independently from
+// the given func, e.g. one returned by SampleLatencies.  This allows hedging
+// configurations to be evaluated before enabling them in production.
+//
+// The simulation honors h's patience and max attempts (including adaptive
+// ones, as of the start of the simulation) and budget, without drawing from
+// h's actual budget.  It assumes that attempts never fail.  If calls is zero
+// or negative, nothing is simulated and the zero SimulationResult is
+// returned.
+func Simulate(h *Hedger, latency func() time.Duration, calls int) SimulationResult {
+	if calls <= 0 {
+		return SimulationResult{}
+	}
+	cfg := newConfig(h.opts)
+	if cfg.budget != nil {
+		cfg.budget = cfg.budget.clone()
+	}
+	patience := cfg.patience(h.patience)
+	maxAttempts := cfg.attemptLimit()
+
+	latencies := make([]time.Duration, calls)
+	hedges := 0
+	for i := range latencies {
+		if cfg.budget != nil {
+			cfg.budget.deposit()
+		}
+		done := latency()
+		for attempt := 1; maxAttempts <= 0 || attempt < maxAttempts; attempt++ {
+			start := time.Duration(attempt) * patience
+			if patience <= 0 || start >= done {
+				break
+			}
+			if cfg.budget != nil && !cfg.budget.withdraw() {
+				// A suppressed hedge may be launched at a later tick, once
+				// the budget has been replenished by other calls, which a
+				// sequential simulation cannot capture.
+				break
+			}
+			hedges++
+			if end := start + latency(); end < done {
+				done = end
+			}
+		}
+		latencies[i] = done
+	}
+
+	result := SimulationResult{Calls: calls}
+	d := newDistribution(latencies)
+	result.P50 = d.quantile(0.5)
+	result.P95 = d.quantile(0.95)
+	result.P99 = d.quantile(0.99)
+	result.ExtraLoad = float64(hedges) / float64(calls)
+	return result
+}
+
+// SampleLatencies returns a func that draws latencies at random from the
+// given samples, e.g. those recorded by a LatencyTracker, for use with
+// Simulate.  It panics if samples is empty, since there is nothing to draw
+// from, e.g. if the LatencyTracker has not recorded anything yet.
+func SampleLatencies(samples []time.Duration) func() time.Duration {
+	if len(samples) == 0 {
+		panic("speculatively: SampleLatencies called with no samples")
+	}
+	return func() time.Duration {
+		return samples[rand.Intn(len(samples))]
+	}
+}
@@ -0,0 +1,93 @@
+package speculatively
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	t.Parallel()
+
+	// Every tenth attempt is slow
+	newLatency := func() func() time.Duration {
+		n := 0
+		return func() time.Duration {
+			n++
+			if n%10 == 0 {
+				return time.Second
+			}
+			return 10 * time.Millisecond
+		}
+	}
+
+	t.Run("no hedging", func(t *testing.T) {
+		t.Parallel()
+
+		result := Simulate(NewHedger(time.Minute), newLatency(), 1000)
+		if result.Calls != 1000 {
+			t.Errorf("expected 1000 calls, got %d", result.Calls)
+		}
+		if result.P50 != 10*time.Millisecond || result.P95 != time.Second {
+			t.Errorf("expected unhedged latencies, got %+v", result)
+		}
+		if result.ExtraLoad != 0 {
+			t.Errorf("expected no extra load, got %v", result.ExtraLoad)
+		}
+	})
+
+	t.Run("hedging cuts the tail", func(t *testing.T) {
+		t.Parallel()
+
+		result := Simulate(NewHedger(20*time.Millisecond, WithMaxAttempts(2)), newLatency(), 1000)
+		if result.P99 != 30*time.Millisecond {
+			t.Errorf("expected p99 = %s, got %s", 30*time.Millisecond, result.P99)
+		}
+		if result.ExtraLoad < 0.09 || result.ExtraLoad > 0.12 {
+			t.Errorf("expected ~10%% extra load, got %v", result.ExtraLoad)
+		}
+	})
+
+	t.Run("budget limits extra load", func(t *testing.T) {
+		t.Parallel()
+
+		budget := NewBudget(0.05, 1)
+		result := Simulate(NewHedger(20*time.Millisecond, WithBudget(budget)), newLatency(), 1000)
+		if result.ExtraLoad > 0.051 {
+			t.Errorf("expected at most ~5%% extra load, got %v", result.ExtraLoad)
+		}
+		if remaining := budget.Remaining(); remaining != 1 {
+			t.Errorf("expected simulation not to draw from real budget, %v remaining", remaining)
+		}
+	})
+
+	t.Run("samples", func(t *testing.T) {
+		t.Parallel()
+
+		samples := []time.Duration{5 * time.Millisecond}
+		result := Simulate(NewHedger(time.Millisecond), SampleLatencies(samples), 10)
+		if result.P99 != 5*time.Millisecond {
+			t.Errorf("expected p99 = %s, got %s", 5*time.Millisecond, result.P99)
+		}
+	})
+
+	t.Run("no calls", func(t *testing.T) {
+		t.Parallel()
+
+		for _, calls := range []int{0, -1} {
+			if result := Simulate(NewHedger(time.Millisecond), SampleLatencies([]time.Duration{time.Millisecond}), calls); result != (SimulationResult{}) {
+				t.Errorf("expected zero result for %d calls, got %+v", calls, result)
+			}
+		}
+	})
+
+	t.Run("no samples", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected SampleLatencies to panic without samples")
+			}
+		}()
+		SampleLatencies(nil)
+	})
+}
@@ -0,0 +1,106 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Deduplicator is a singleflight-style deduplication layer, as implemented by
+// singleflight.Group from golang.org/x/sync.
+type Deduplicator interface {
+	Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool)
+}
+
+// DoShared speculatively executes a Thunk through the given Deduplicator, so
+// that concurrent calls with the same key share a single hedged execution via
+// the caller's existing deduplication layer rather than competing with it.
+// Likewise, calls made directly through the Deduplicator with the same key
+// share their results with DoShared.  See Do for details.
+//
+// As with any singleflight-style layer, the shared execution runs with the
+// context of the call that started it.  A result of the wrong type, e.g. one
+// produced by an unrelated caller sharing the key, yields a *TypeError.
+func DoShared[T any](ctx context.Context, group Deduplicator, key string, patience time.Duration, thunk Thunk[T], opts ...Option) (T, error) {
+	val, err, _ := group.Do(key, func() (interface{}, error) {
+		return Do(ctx, patience, thunk, opts...)
+	})
+	return As[T](val, err)
+}
+
+// Coalescer shares a single speculative execution among concurrent calls
+// with the same key, like singleflight.Group from golang.org/x/sync, so that
+// a stampede of identical calls launches the attempts of one call rather than
+// multiplying hedges by the number of callers.
+//
+// Unlike with DoShared, the shared execution is not tied to the context of
+// the call that started it: it is only canceled once every call waiting for
+// it has given up, and a call that gives up returns right away.  Values of
+// the starting call's context remain visible to the shared Thunk.
+//
+// Since every waiting call receives the same result, Coalescer is not suited
+// to results that must be released by their caller, e.g. an io.ReadCloser.
+//
+// The zero value is ready to use.  A Coalescer is safe for concurrent use.
+type Coalescer[T any] struct {
+	flights map[string]*flight[T]
+	mu      sync.Mutex
+}
+
+// flight is a shared execution in progress.
+type flight[T any] struct {
+	done    chan struct{}
+	val     T
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Do speculatively executes a Thunk, or waits for the result of the
+// execution already in progress for the same key, if any.  See Do for
+// details.
+func (c *Coalescer[T]) Do(ctx context.Context, key string, patience time.Duration, thunk Thunk[T], opts ...Option) (T, error) {
+	c.mu.Lock()
+	if c.flights == nil {
+		c.flights = map[string]*flight[T]{}
+	}
+	f, ok := c.flights[key]
+	if !ok {
+		sharedCtx, cancel := context.WithCancel(context.Background())
+		f = &flight[T]{done: make(chan struct{}), cancel: cancel}
+		c.flights[key] = f
+		go func() {
+			f.val, f.err = Do(valuesFrom{Context: sharedCtx, values: ctx}, patience, thunk, opts...)
+			c.mu.Lock()
+			c.land(key, f)
+			c.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		if f.waiters--; f.waiters == 0 {
+			// Nobody is left to receive the result, so later calls must
+			// start afresh
+			c.land(key, f)
+		}
+		c.mu.Unlock()
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// land ends the given flight and forgets it, unless it has already been
+// replaced.  It must be called with c.mu held.
+func (c *Coalescer[T]) land(key string, f *flight[T]) {
+	f.cancel()
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+}
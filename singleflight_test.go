@@ -0,0 +1,183 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testGroup is a minimal singleflight.Group lookalike.
+type testGroup struct {
+	calls map[string]*testGroupCall
+	mu    sync.Mutex
+}
+
+type testGroupCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+func (g *testGroup) Do(key string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*testGroupCall{}
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &testGroupCall{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = fn()
+	c.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	return c.val, c.err, false
+}
+
+func TestDoShared(t *testing.T) {
+	t.Parallel()
+
+	t.Run("concurrent calls share one execution", func(t *testing.T) {
+		t.Parallel()
+
+		group := &testGroup{}
+		thunk := newSimpleTestThunk(1, nil, 50*time.Millisecond)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				val, err := DoShared(context.Background(), group, "key", time.Second, thunk.call)
+				if err != nil || val != 1 {
+					t.Errorf("expected val = 1, got %d, %v", val, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if callCount := thunk.callCount(); callCount != 1 {
+			t.Errorf("expected Thunk to run once, got %d", callCount)
+		}
+	})
+
+	t.Run("results shared with direct callers", func(t *testing.T) {
+		t.Parallel()
+
+		group := &testGroup{}
+		release := make(chan struct{})
+		go group.Do("key", func() (interface{}, error) { //nolint:errcheck
+			<-release
+			return "not an int", nil
+		})
+		time.Sleep(10 * time.Millisecond)
+
+		errc := make(chan error, 1)
+		go func() {
+			_, err := DoShared(context.Background(), group, "key", time.Second, newSimpleTestThunk(1, nil, 0).call)
+			errc <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+
+		if _, ok := (<-errc).(*TypeError); !ok {
+			t.Errorf("expected *TypeError from mismatched shared result")
+		}
+	})
+}
+
+func TestCoalescer(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c        Coalescer[int]
+		attempts int64
+		wg       sync.WaitGroup
+	)
+	release := make(chan struct{})
+	thunk := func(ctx context.Context) (int, error) {
+		atomic.AddInt64(&attempts, 1)
+		select {
+		case <-release:
+			return 42, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	// Every concurrent call shares the attempts of a single execution
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := c.Do(context.Background(), "key", 10*time.Millisecond, thunk, WithMaxAttempts(2))
+			if err != nil || got != 42 {
+				t.Errorf("expected result 42, got %d, %v", got, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt64(&attempts); n != 2 {
+		t.Errorf("expected 2 attempts shared by every call, got %d", n)
+	}
+}
+
+func TestCoalescerCancelation(t *testing.T) {
+	t.Parallel()
+
+	var c Coalescer[int]
+	canceled := make(chan struct{})
+	thunk := func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		close(canceled)
+		return 0, ctx.Err()
+	}
+
+	// The shared execution survives the call that started it giving up,
+	// as long as another call is still waiting for it
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := c.Do(first, "key", time.Hour, thunk)
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		_, err := c.Do(second, "key", time.Hour, thunk)
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cancelFirst()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("expected first call to be canceled, got %v", err)
+	}
+	select {
+	case <-canceled:
+		t.Fatalf("expected shared execution to keep running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelSecond()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("expected second call to be canceled, got %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatalf("expected shared execution to be canceled once every call gave up")
+	}
+}
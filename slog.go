@@ -0,0 +1,60 @@
+//go:build go1.21
+
+package speculatively
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Attribute keys used by WithLogger.
+const (
+	LogKeyAttempt = "attempt"
+	LogKeyHedge   = "hedge"
+	LogKeyTarget  = "target"
+	LogKeyElapsed = "elapsed"
+	LogKeyError   = "error"
+	LogKeyReason  = "reason"
+)
+
+// WithLogger logs structured events with the given Logger as calls progress.
+// Attempts being launched and completing and first attempts winning are
+// logged at debug level, while hedges winning or being suppressed are logged
+// at info level.
+func WithLogger(l *slog.Logger) Option {
+	return withTelemetryHooks(Hooks{
+		OnLaunch: func(a Attempt) {
+			l.LogAttrs(context.Background(), slog.LevelDebug, "speculatively: attempt launched", attemptAttrs(a)...)
+		},
+		OnDone: func(a Attempt, elapsed time.Duration, err error) {
+			attrs := append(attemptAttrs(a), slog.Duration(LogKeyElapsed, elapsed))
+			if err != nil {
+				attrs = append(attrs, slog.String(LogKeyError, err.Error()))
+			}
+			l.LogAttrs(context.Background(), slog.LevelDebug, "speculatively: attempt done", attrs...)
+		},
+		OnWinner: func(a Attempt) {
+			level := slog.LevelDebug
+			if a.Index > 0 {
+				level = slog.LevelInfo
+			}
+			l.LogAttrs(context.Background(), level, "speculatively: winner chosen", attemptAttrs(a)...)
+		},
+		OnHedgeSuppressed: func(s Suppression) {
+			l.LogAttrs(context.Background(), slog.LevelInfo, "speculatively: hedge suppressed", slog.String(LogKeyReason, s.String()))
+		},
+	})
+}
+
+func attemptAttrs(a Attempt) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Int(LogKeyAttempt, a.Index),
+		slog.Bool(LogKeyHedge, a.Index > 0),
+	}
+	if a.Target != nil {
+		attrs = append(attrs, slog.String(LogKeyTarget, fmt.Sprint(a.Target)))
+	}
+	return attrs
+}
@@ -0,0 +1,100 @@
+//go:build go1.21
+
+package speculatively
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines(t *testing.T) []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var lines []map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b.buf.Bytes()))
+	for dec.More() {
+		var line map[string]interface{}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("debug", func(t *testing.T) {
+		t.Parallel()
+
+		buf := &syncBuffer{}
+		logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		thunk := newTestThunk([]result[int]{{val: 1}, {val: 2}}, []time.Duration{time.Second, 5 * time.Millisecond})
+
+		if _, err := Do(context.Background(), 10*time.Millisecond, thunk.call, WithLogger(logger), WithMaxAttempts(2)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var winner map[string]interface{}
+		launched := 0
+		for _, line := range buf.lines(t) {
+			switch line["msg"] {
+			case "speculatively: attempt launched":
+				launched++
+			case "speculatively: winner chosen":
+				winner = line
+			}
+		}
+		if launched != 2 {
+			t.Errorf("expected 2 attempts launched, got %d", launched)
+		}
+		if winner == nil {
+			t.Fatalf("expected winner to be logged")
+		}
+		if winner[LogKeyAttempt] != float64(1) || winner[LogKeyHedge] != true || winner["level"] != "INFO" {
+			t.Errorf("expected hedge winner logged at info, got %v", winner)
+		}
+	})
+
+	t.Run("info", func(t *testing.T) {
+		t.Parallel()
+
+		buf := &syncBuffer{}
+		logger := slog.New(slog.NewJSONHandler(buf, nil))
+		thunk := newSimpleTestThunk(1, nil, 30*time.Millisecond)
+		budget := NewBudget(0, 1)
+		budget.withdraw()
+
+		if _, err := Do(context.Background(), 10*time.Millisecond, thunk.call, WithBudget(budget), WithLogger(logger)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		lines := buf.lines(t)
+		if len(lines) == 0 {
+			t.Fatalf("expected suppressed hedges to be logged")
+		}
+		for _, line := range lines {
+			if line["msg"] != "speculatively: hedge suppressed" || line[LogKeyReason] != "budget" {
+				t.Errorf("expected only suppressed hedges to be logged, got %v", line)
+			}
+		}
+	})
+}
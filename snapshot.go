@@ -0,0 +1,142 @@
+package speculatively
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// CallSnapshot describes a call made through a Hedger that is in progress,
+// or that has returned but still has losing attempts running.
+type CallSnapshot struct {
+	// Start is the time the call was made.
+	Start time.Time
+	// Elapsed is the time since the call was made.
+	Elapsed time.Duration
+	// Done reports whether the call has returned.
+	Done bool
+	// Attempts are the call's attempts that are still running, ordered by
+	// index.
+	Attempts []AttemptSnapshot
+}
+
+// AttemptSnapshot describes a running attempt.
+type AttemptSnapshot struct {
+	Attempt
+	// Elapsed is the time since the attempt was launched.
+	Elapsed time.Duration
+}
+
+// Snapshot lists the calls currently running through h, ordered by start
+// time, so that hedge pileups during a latency incident can be seen at a
+// glance.  Start times and elapsed durations follow the Clock set via
+// WithClock, if any.
+func (h *Hedger) Snapshot() []CallSnapshot {
+	return h.stats.live.snapshot(newConfig(h.opts).now())
+}
+
+// liveCalls tracks the calls running through a Hedger.
+type liveCalls struct {
+	mu    sync.Mutex
+	calls map[*liveCall]struct{}
+}
+
+func (l *liveCalls) begin(start time.Time) *liveCall {
+	c := &liveCall{
+		calls:    l,
+		start:    start,
+		attempts: map[int]Attempt{},
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.calls == nil {
+		l.calls = map[*liveCall]struct{}{}
+	}
+	l.calls[c] = struct{}{}
+	return c
+}
+
+func (l *liveCalls) remove(c *liveCall) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.calls, c)
+}
+
+func (l *liveCalls) snapshot(now time.Time) []CallSnapshot {
+	l.mu.Lock()
+	calls := make([]*liveCall, 0, len(l.calls))
+	for c := range l.calls {
+		calls = append(calls, c)
+	}
+	l.mu.Unlock()
+
+	snapshots := make([]CallSnapshot, 0, len(calls))
+	for _, c := range calls {
+		snapshots = append(snapshots, c.snapshot(now))
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Start.Before(snapshots[j].Start) })
+	return snapshots
+}
+
+// liveCall tracks the running attempts of a call.  A nil *liveCall tracks
+// nothing.
+type liveCall struct {
+	calls *liveCalls
+	start time.Time
+
+	mu       sync.Mutex
+	done     bool
+	attempts map[int]Attempt
+}
+
+func (c *liveCall) launch(a Attempt) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempts[a.Index] = a
+}
+
+func (c *liveCall) exit(a Attempt) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.attempts, a.Index)
+	c.removeIfFinished()
+}
+
+func (c *liveCall) end() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.done = true
+	c.removeIfFinished()
+}
+
+// removeIfFinished stops tracking the call once it has returned and every
+// attempt has exited.  It must be called with c.mu held.
+func (c *liveCall) removeIfFinished() {
+	if c.done && len(c.attempts) == 0 {
+		c.calls.remove(c)
+	}
+}
+
+func (c *liveCall) snapshot(now time.Time) CallSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := CallSnapshot{
+		Start:   c.start,
+		Elapsed: now.Sub(c.start),
+		Done:    c.done,
+	}
+	for _, a := range c.attempts {
+		s.Attempts = append(s.Attempts, AttemptSnapshot{Attempt: a, Elapsed: now.Sub(a.Start)})
+	}
+	sort.Slice(s.Attempts, func(i, j int) bool { return s.Attempts[i].Index < s.Attempts[j].Index })
+	return s
+}
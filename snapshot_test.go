@@ -0,0 +1,120 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	h := NewHedger(5*time.Millisecond, WithMaxAttempts(2))
+	if calls := h.Snapshot(); len(calls) != 0 {
+		t.Fatalf("expected no calls, got %+v", calls)
+	}
+
+	release := make(chan struct{})
+	thunk := func(ctx context.Context) (int, error) {
+		if IsHedge(ctx) {
+			return 2, nil
+		}
+		// The first attempt ignores cancelation until released
+		<-release
+		return 1, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		DoWith(ctx, h, func(ctx context.Context) (int, error) { //nolint:errcheck
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+	}()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if calls := h.Snapshot(); len(calls) == 1 && len(calls[0].Attempts) == 2 {
+			break
+		}
+	}
+
+	if _, err := DoWith(context.Background(), h, thunk); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// One call is still running both attempts, while the other has
+	// returned but its first attempt is still running
+	calls := h.Snapshot()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %+v", calls)
+	}
+	var running, done CallSnapshot
+	for _, c := range calls {
+		if c.Done {
+			done = c
+		} else {
+			running = c
+		}
+	}
+	if len(running.Attempts) != 2 || running.Attempts[0].Index != 0 || running.Attempts[1].Index != 1 {
+		t.Errorf("expected running call to have attempts [0 1], got %+v", running.Attempts)
+	}
+	if len(done.Attempts) != 1 || done.Attempts[0].Index != 0 {
+		t.Errorf("expected returned call to have attempt 0 still running, got %+v", done.Attempts)
+	}
+	if a := done.Attempts[0]; a.Elapsed < 5*time.Millisecond || a.Elapsed > done.Elapsed {
+		t.Errorf("expected attempt elapsed between 5ms and %s, got %s", done.Elapsed, a.Elapsed)
+	}
+
+	close(release)
+	cancel()
+	<-blocked
+	for deadline := time.Now().Add(time.Second); len(h.Snapshot()) > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if calls := h.Snapshot(); len(calls) != 0 {
+		t.Errorf("expected no calls once every attempt exits, got %+v", calls)
+	}
+}
+
+func TestSnapshotWithClock(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	h := NewHedger(time.Hour, WithClock(frozenClock{now}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		DoWith(ctx, h, func(ctx context.Context) (int, error) { //nolint:errcheck
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	var calls []CallSnapshot
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if calls = h.Snapshot(); len(calls) == 1 && len(calls[0].Attempts) == 1 {
+			break
+		}
+	}
+	if len(calls) != 1 || len(calls[0].Attempts) != 1 {
+		t.Fatalf("expected 1 call with 1 attempt, got %+v", calls)
+	}
+
+	// The clock never moves, so nothing has taken any time
+	c, a := calls[0], calls[0].Attempts[0]
+	if !c.Start.Equal(now) || c.Elapsed != 0 {
+		t.Errorf("expected call start %s and elapsed 0, got %s and %s", now, c.Start, c.Elapsed)
+	}
+	if !a.Start.Equal(now) || a.Elapsed != 0 {
+		t.Errorf("expected attempt start %s and elapsed 0, got %s and %s", now, a.Start, a.Elapsed)
+	}
+}
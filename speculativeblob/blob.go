@@ -0,0 +1,65 @@
+/*
+Package speculativeblob provides speculative execution helpers for object
+stores such as S3, GCS or Azure Blob Storage.
+*/
+package speculativeblob
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Bucket reads objects from a single region or bucket of an object store.
+type Bucket interface {
+	// Get returns a stream of the object stored under the given key.  The
+	// transfer must be aborted when ctx is done.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// BucketFunc adapts a func to the Bucket interface.
+type BucketFunc func(ctx context.Context, key string) (io.ReadCloser, error)
+
+// Get calls fn.
+func (fn BucketFunc) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return fn(ctx, key)
+}
+
+// Store hedges object reads across buckets holding replicas of the same
+// objects, e.g. the same bucket replicated to several regions.
+//
+// Each object is requested from the first bucket immediately, and from each
+// subsequent bucket after waiting for Patience, or as soon as a previous
+// request fails.  The first successfully opened object stream is returned
+// and the other transfers are aborted.
+type Store struct {
+	// Buckets are the buckets to read from, in order of preference, e.g.
+	// the primary region followed by its secondaries.
+	Buckets []Bucket
+
+	// Patience is how long to wait for a bucket to start returning an
+	// object before requesting it from the next bucket.
+	Patience time.Duration
+
+	// Options customize the hedging of every read, e.g. to share a Budget.
+	Options []speculatively.Option
+}
+
+// Get returns a stream of the object stored under the given key, from
+// whichever bucket starts returning it first.  The stream remains readable
+// once Get has returned, until it is closed or ctx is done.
+func (s *Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	thunks := make([]speculatively.Thunk[io.ReadCloser], len(s.Buckets))
+	for i, bucket := range s.Buckets {
+		bucket := bucket
+		thunks[i] = func(ctx context.Context) (io.ReadCloser, error) {
+			return bucket.Get(ctx, key)
+		}
+	}
+	opts := append([]speculatively.Option{
+		speculatively.WithRetryable(func(error) bool { return true }),
+	}, s.Options...)
+	return speculatively.DoReadCloser(ctx, s.Patience, thunks, opts...)
+}
@@ -0,0 +1,134 @@
+package speculativeblob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testBucket returns the contents of every object as a stream that can only
+// be read while the context of its request is not done.
+type testBucket struct {
+	region  string
+	delay   time.Duration
+	err     error
+	aborted int64
+	closed  int64
+}
+
+func (b *testBucket) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	select {
+	case <-time.After(b.delay):
+	case <-ctx.Done():
+		atomic.AddInt64(&b.aborted, 1)
+		return nil, ctx.Err()
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &testStream{ctx: ctx, r: strings.NewReader(b.region + "/" + key), bucket: b}, nil
+}
+
+type testStream struct {
+	ctx    context.Context
+	r      io.Reader
+	bucket *testBucket
+}
+
+func (s *testStream) Read(p []byte) (int, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.r.Read(p)
+}
+
+func (s *testStream) Close() error {
+	atomic.AddInt64(&s.bucket.closed, 1)
+	return nil
+}
+
+func TestStoreGet(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		primary, secondary *testBucket
+		want               string
+		wantAborted        int64
+	}{
+		"primary wins": {
+			primary:   &testBucket{region: "us-east-1"},
+			secondary: &testBucket{region: "us-west-2"},
+			want:      "us-east-1/key",
+		},
+		"secondary wins": {
+			primary:     &testBucket{region: "us-east-1", delay: time.Second},
+			secondary:   &testBucket{region: "us-west-2"},
+			want:        "us-west-2/key",
+			wantAborted: 1,
+		},
+		"primary fails": {
+			primary:   &testBucket{region: "us-east-1", err: errors.New("throttled")},
+			secondary: &testBucket{region: "us-west-2"},
+			want:      "us-west-2/key",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Store{
+				Buckets:  []Bucket{tc.primary, tc.secondary},
+				Patience: 20 * time.Millisecond,
+			}
+			body, err := s.Get(context.Background(), "key")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// The winning stream must still be readable once the other
+			// transfer has been aborted
+			time.Sleep(50 * time.Millisecond)
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("unexpected read error: %s", err)
+			}
+			body.Close()
+			if string(got) != tc.want {
+				t.Errorf("expected object %q, got %q", tc.want, got)
+			}
+			if aborted := atomic.LoadInt64(&tc.primary.aborted); aborted != tc.wantAborted {
+				t.Errorf("expected %d aborted transfers, got %d", tc.wantAborted, aborted)
+			}
+		})
+	}
+}
+
+func TestStoreClosesLosers(t *testing.T) {
+	t.Parallel()
+
+	// Both buckets return a stream, but the primary's arrives after the
+	// secondary's has won
+	primary := &testBucket{region: "us-east-1"}
+	slow := BucketFunc(func(ctx context.Context, key string) (io.ReadCloser, error) {
+		time.Sleep(100 * time.Millisecond)
+		return primary.Get(context.Background(), key)
+	})
+	secondary := &testBucket{region: "us-west-2"}
+	s := &Store{Buckets: []Bucket{slow, secondary}, Patience: 10 * time.Millisecond}
+
+	body, err := s.Get(context.Background(), "key")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer body.Close()
+
+	time.Sleep(200 * time.Millisecond)
+	if closed := atomic.LoadInt64(&primary.closed); closed != 1 {
+		t.Errorf("expected losing stream to be closed, got %d closes", closed)
+	}
+}
@@ -0,0 +1,107 @@
+package speculativeblob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Layered reads objects that may be stored both locally, e.g. in an
+// artifact cache on disk, and remotely, returning the contents from
+// whichever source provides a valid copy first.
+//
+// Each object is read from Local immediately, and fetched from Remote after
+// waiting for Patience, or as soon as the local read fails, e.g. because the
+// object is not cached.  Every copy is checked by Validate before it can
+// win, so that a stale or corrupt local copy is passed over for the remote
+// one.
+type Layered struct {
+	// Local holds local copies of objects, by key, e.g. os.DirFS of a cache
+	// directory.
+	Local fs.FS
+
+	// Remote is the bucket holding every object.
+	Remote Bucket
+
+	// Patience is how long to wait for the local copy of an object before
+	// fetching it from Remote too.  A short Patience races the two sources
+	// almost from the start, while a longer one avoids network transfers
+	// unless the disk is slow.
+	Patience time.Duration
+
+	// Validate optionally checks every copy of an object, e.g. against its
+	// expected checksum, returning an error to reject it.
+	Validate func(key string, data []byte) error
+
+	// Options customize the racing of every read, e.g. to share a Budget.
+	Options []speculatively.Option
+}
+
+// ValidationError is the error returned for a copy of an object rejected by
+// Layered.Validate.
+type ValidationError struct {
+	Key    string
+	Remote bool
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	source := "local"
+	if e.Remote {
+		source = "remote"
+	}
+	return fmt.Sprintf("speculativeblob: invalid %s copy of %s: %s", source, e.Key, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Read returns the contents of the object stored under the given key.  If
+// neither source provides a valid copy, the error of the last one to fail is
+// returned.
+func (l *Layered) Read(ctx context.Context, key string) ([]byte, error) {
+	local := func(context.Context) ([]byte, error) {
+		data, err := fs.ReadFile(l.Local, key)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.validate(key, data, false); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	remote := func(ctx context.Context) ([]byte, error) {
+		r, err := l.Remote.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.validate(key, data, true); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	opts := append([]speculatively.Option{
+		speculatively.WithRetryable(func(error) bool { return true }),
+	}, l.Options...)
+	return speculatively.DoRace(ctx, l.Patience, []speculatively.Thunk[[]byte]{local, remote}, opts...)
+}
+
+func (l *Layered) validate(key string, data []byte, remote bool) error {
+	if l.Validate == nil {
+		return nil
+	}
+	if err := l.Validate(key, data); err != nil {
+		return &ValidationError{Key: key, Remote: remote, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package speculativeblob
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// slowFS delays every file it opens.
+type slowFS struct {
+	fs.FS
+	delay time.Duration
+}
+
+func (s slowFS) Open(name string) (fs.File, error) {
+	time.Sleep(s.delay)
+	return s.FS.Open(name)
+}
+
+func TestLayeredRead(t *testing.T) {
+	t.Parallel()
+
+	validate := func(key string, data []byte) error {
+		if !strings.HasSuffix(string(data), key) {
+			return errors.New("checksum mismatch")
+		}
+		return nil
+	}
+
+	testCases := map[string]struct {
+		local  fs.FS
+		remote *testBucket
+		want   string
+	}{
+		"local copy": {
+			local:  fstest.MapFS{"key": {Data: []byte("disk/key")}},
+			remote: &testBucket{region: "remote"},
+			want:   "disk/key",
+		},
+		"slow disk": {
+			local:  slowFS{fstest.MapFS{"key": {Data: []byte("disk/key")}}, time.Second},
+			remote: &testBucket{region: "remote"},
+			want:   "remote/key",
+		},
+		"not cached": {
+			local:  fstest.MapFS{},
+			remote: &testBucket{region: "remote", delay: 20 * time.Millisecond},
+			want:   "remote/key",
+		},
+		"corrupt local copy": {
+			local:  fstest.MapFS{"key": {Data: []byte("corrupted")}},
+			remote: &testBucket{region: "remote", delay: 20 * time.Millisecond},
+			want:   "remote/key",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			l := &Layered{
+				Local:    tc.local,
+				Remote:   tc.remote,
+				Patience: 50 * time.Millisecond,
+				Validate: validate,
+			}
+			start := time.Now()
+			got, err := l.Read(context.Background(), "key")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("expected contents %q, got %q", tc.want, got)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("expected slow source to be raced, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestLayeredReadInvalid(t *testing.T) {
+	t.Parallel()
+
+	mismatch := errors.New("checksum mismatch")
+	l := &Layered{
+		Local:    fstest.MapFS{},
+		Remote:   &testBucket{region: "remote"},
+		Patience: 10 * time.Millisecond,
+		Validate: func(string, []byte) error { return mismatch },
+	}
+	_, err := l.Read(context.Background(), "key")
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if !invalid.Remote || invalid.Key != "key" || !errors.Is(err, mismatch) {
+		t.Errorf("expected invalid remote copy of key, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package speculativeblob
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Target stages uploads to a single backend, e.g. a region or bucket of an
+// object store, so that they only become visible once committed, e.g. via a
+// multipart upload or a write to a temporary key.
+type Target interface {
+	// Upload stages the given body under the given key.  The transfer must
+	// be aborted when ctx is done.
+	Upload(ctx context.Context, key string, body io.Reader) (Staged, error)
+}
+
+// TargetFunc adapts a func to the Target interface.
+type TargetFunc func(ctx context.Context, key string, body io.Reader) (Staged, error)
+
+// Upload calls fn.
+func (fn TargetFunc) Upload(ctx context.Context, key string, body io.Reader) (Staged, error) {
+	return fn(ctx, key, body)
+}
+
+// Staged is an upload that has been transferred but not yet made visible.
+type Staged interface {
+	// Commit makes the upload visible.
+	Commit(ctx context.Context) error
+	// Abort discards the upload.
+	Abort(ctx context.Context) error
+}
+
+// Uploader hedges uploads across targets that can each hold an object, e.g.
+// artifact stores in several regions, where the object only needs to land
+// in one of them.
+//
+// Each object is uploaded to the first target immediately, and to each
+// subsequent target after waiting for Patience, or as soon as a previous
+// upload fails.  The first upload to be staged is committed, and every other
+// upload is aborted: those still in progress are canceled, and those staged
+// too late are discarded via Abort.
+type Uploader struct {
+	// Targets are the targets to upload to, in order of preference.
+	Targets []Target
+
+	// Patience is how long to wait for an upload to be staged before
+	// starting it on the next target.
+	Patience time.Duration
+
+	// Options customize the hedging of every upload, e.g. to share a
+	// Budget.
+	Options []speculatively.Option
+}
+
+// Upload uploads size bytes read from body under the given key, and commits
+// the upload to whichever target stages it first.  Each target reads body
+// independently, so it must support concurrent reads, as e.g. *os.File and
+// *bytes.Reader do.
+//
+// If the commit fails, the staged upload is aborted and the error returned.
+// No other target is tried, since every other upload has been discarded by
+// then.
+func (u *Uploader) Upload(ctx context.Context, key string, body io.ReaderAt, size int64) error {
+	thunks := make([]speculatively.Thunk[Staged], len(u.Targets))
+	for i, target := range u.Targets {
+		target := target
+		thunks[i] = func(ctx context.Context) (Staged, error) {
+			return target.Upload(ctx, key, io.NewSectionReader(body, 0, size))
+		}
+	}
+	opts := append([]speculatively.Option{
+		speculatively.WithRetryable(func(error) bool { return true }),
+	}, u.Options...)
+	// Losers are aborted after the call has ended, so their abort cannot be
+	// canceled along with it
+	opts = append(opts, speculatively.WithCleanup(func(s Staged) {
+		if s != nil {
+			s.Abort(context.Background()) //nolint:errcheck
+		}
+	}))
+
+	staged, err := speculatively.DoRace(ctx, u.Patience, thunks, opts...)
+	if err != nil {
+		return err
+	}
+	if err := staged.Commit(ctx); err != nil {
+		// Abort even if ctx is done, since a failed commit would otherwise
+		// leave the upload staged
+		staged.Abort(context.Background()) //nolint:errcheck
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package speculativeblob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testTarget stages uploads in memory after a delay, recording what happens
+// to them.
+type testTarget struct {
+	delay     time.Duration
+	err       error
+	commitErr error
+
+	mu        sync.Mutex
+	canceled  bool
+	committed []byte
+	aborted   bool
+}
+
+func (t *testTarget) Upload(ctx context.Context, key string, body io.Reader) (Staged, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-time.After(t.delay):
+	case <-ctx.Done():
+		t.mu.Lock()
+		t.canceled = true
+		t.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &testStaged{target: t, data: data}, nil
+}
+
+func (t *testTarget) state() (canceled bool, committed []byte, aborted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.canceled, t.committed, t.aborted
+}
+
+type testStaged struct {
+	target *testTarget
+	data   []byte
+}
+
+func (s *testStaged) Commit(context.Context) error {
+	s.target.mu.Lock()
+	defer s.target.mu.Unlock()
+	if s.target.commitErr != nil {
+		return s.target.commitErr
+	}
+	s.target.committed = s.data
+	return nil
+}
+
+func (s *testStaged) Abort(context.Context) error {
+	s.target.mu.Lock()
+	defer s.target.mu.Unlock()
+	s.target.aborted = true
+	return nil
+}
+
+func TestUploader(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte("artifact")
+	testCases := map[string]struct {
+		primary, secondary  *testTarget
+		wantPrimaryCommit   bool
+		wantPrimaryCanceled bool
+	}{
+		"primary wins": {
+			primary:           &testTarget{},
+			secondary:         &testTarget{},
+			wantPrimaryCommit: true,
+		},
+		"secondary wins": {
+			primary:             &testTarget{delay: time.Second},
+			secondary:           &testTarget{},
+			wantPrimaryCanceled: true,
+		},
+		"primary fails": {
+			primary:   &testTarget{err: errors.New("quota exceeded")},
+			secondary: &testTarget{},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			u := &Uploader{Targets: []Target{tc.primary, tc.secondary}, Patience: 20 * time.Millisecond}
+			if err := u.Upload(context.Background(), "key", bytes.NewReader(payload), int64(len(payload))); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			time.Sleep(20 * time.Millisecond)
+
+			winner, loser := tc.secondary, tc.primary
+			if tc.wantPrimaryCommit {
+				winner, loser = tc.primary, tc.secondary
+			}
+			if _, committed, _ := winner.state(); !bytes.Equal(committed, payload) {
+				t.Errorf("expected winner to commit %q, got %q", payload, committed)
+			}
+			if _, committed, _ := loser.state(); committed != nil {
+				t.Errorf("expected loser not to commit, got %q", committed)
+			}
+			if canceled, _, _ := tc.primary.state(); canceled != tc.wantPrimaryCanceled {
+				t.Errorf("expected primary canceled = %v, got %v", tc.wantPrimaryCanceled, canceled)
+			}
+		})
+	}
+}
+
+func TestUploaderAbortsLateUploads(t *testing.T) {
+	t.Parallel()
+
+	// The primary ignores cancelation, so its upload is staged after the
+	// secondary's has been committed
+	primary := &testTarget{}
+	slow := TargetFunc(func(ctx context.Context, key string, body io.Reader) (Staged, error) {
+		time.Sleep(50 * time.Millisecond)
+		return primary.Upload(context.Background(), key, body)
+	})
+	secondary := &testTarget{}
+	u := &Uploader{Targets: []Target{slow, secondary}, Patience: 10 * time.Millisecond}
+
+	payload := []byte("artifact")
+	if err := u.Upload(context.Background(), "key", bytes.NewReader(payload), int64(len(payload))); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, committed, aborted := primary.state(); committed != nil || !aborted {
+		t.Errorf("expected late upload to be aborted, got committed %q, aborted = %v", committed, aborted)
+	}
+	if _, committed, _ := secondary.state(); !bytes.Equal(committed, payload) {
+		t.Errorf("expected secondary to commit %q, got %q", payload, committed)
+	}
+}
+
+func TestUploaderCommitFails(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("commit failed")
+	primary := &testTarget{commitErr: failed}
+	secondary := &testTarget{delay: time.Second}
+	u := &Uploader{Targets: []Target{primary, secondary}, Patience: 20 * time.Millisecond}
+
+	payload := []byte("artifact")
+	if err := u.Upload(context.Background(), "key", bytes.NewReader(payload), int64(len(payload))); err != failed {
+		t.Fatalf("expected err = %v, got %v", failed, err)
+	}
+	if _, committed, aborted := primary.state(); committed != nil || !aborted {
+		t.Errorf("expected failed commit to be aborted, got committed %q, aborted = %v", committed, aborted)
+	}
+	if _, committed, _ := secondary.state(); committed != nil {
+		t.Errorf("expected no fallback to the secondary, got committed %q", committed)
+	}
+}
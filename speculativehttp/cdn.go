@@ -0,0 +1,164 @@
+package speculativehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// ErrNotFetchable is returned by CDNs.Fetch for requests other than GET or
+// HEAD requests without a body.
+var ErrNotFetchable = errors.New("speculativehttp: only GET and HEAD requests without a body can be fetched")
+
+// CDNs fetches assets served by several CDNs, e.g. a primary CDN fronting an
+// origin and alternate CDNs shielding the same origin.
+//
+// Each request is sent to its own URL's host, the primary CDN, immediately,
+// and to each alternate CDN after waiting for Patience, or as soon as a
+// previous request fails.  The first successful response is returned, and
+// every other request is canceled and the body of its response closed, which
+// tears down its connection rather than waiting for the rest of the asset to
+// be transferred.
+//
+// The winning response remains usable after Fetch returns: its body may be
+// read until it is closed or the request's context is done.
+//
+// Responses to Range requests must either be partial responses covering the
+// requested range, or full responses from CDNs ignoring the Range header,
+// which are returned as is.  Partial responses starting at another offset
+// than requested, e.g. from a CDN serving a stale object of another size,
+// fail with a RangeError so that the next CDN is tried.
+type CDNs struct {
+	// Alternates are the hosts, e.g. "assets.cdn-b.example.com", of the
+	// alternate CDNs, in order of preference.
+	Alternates []string
+
+	// Client is used to send requests.  If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Patience is how long to wait for a response before sending the
+	// request to the next CDN.
+	Patience time.Duration
+
+	// Options customize the hedging of every request, e.g. to share a
+	// Budget.
+	Options []speculatively.Option
+}
+
+// RangeError is the error returned for a partial response that does not
+// cover the requested range.
+type RangeError struct {
+	URL          string
+	Range        string
+	ContentRange string
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("speculativehttp: content range %q does not match requested range %q for %s", e.ContentRange, e.Range, e.URL)
+}
+
+// Fetch sends the given GET or HEAD request to the primary CDN and then to
+// each alternate CDN until one responds successfully, with a 2xx or 304
+// status.  If no CDN does, the error of the last request to fail is
+// returned.
+func (c *CDNs) Fetch(req *http.Request) (*http.Response, error) {
+	if (req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodHead) ||
+		(req.Body != nil && req.Body != http.NoBody) {
+		return nil, ErrNotFetchable
+	}
+	hosts := append([]string{req.URL.Host}, c.Alternates...)
+	thunks := make([]speculatively.Thunk[*http.Response], len(hosts))
+	for i, host := range hosts {
+		i, host := i, host
+		thunks[i] = func(ctx context.Context) (*http.Response, error) {
+			return c.fetch(ctx, req, host, i == 0)
+		}
+	}
+	opts := append([]speculatively.Option{
+		speculatively.WithRetryable(func(error) bool { return true }),
+		speculatively.WithCleanup(func(resp *http.Response) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+		}),
+	}, c.Options...)
+	return speculatively.DoRace(req.Context(), c.Patience, thunks, opts...)
+}
+
+// fetch sends a copy of req to the given host.  The request is detached
+// from ctx via speculatively.Detach once a response is received, after which
+// only closing its body or the request's own context being done cancels it.
+func (c *CDNs) fetch(ctx context.Context, req *http.Request, host string, primary bool) (*http.Response, error) {
+	fetchCtx, keep, cancel := speculatively.Detach(req.Context(), ctx)
+
+	clone := req.Clone(fetchCtx)
+	clone.URL.Host = host
+	if !primary {
+		// Address each alternate CDN by its own host
+		clone.Host = ""
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(clone)
+	keep()
+	if err == nil {
+		err = checkFetched(clone, resp)
+		if err != nil {
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = speculatively.CancelOnClose(resp.Body, cancel)
+	return resp, nil
+}
+
+// checkFetched returns an error if resp is not a successful response to req.
+func checkFetched(req *http.Request, resp *http.Response) error {
+	url := req.URL.String()
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return &StatusError{URL: url, StatusCode: resp.StatusCode}
+	case resp.StatusCode != http.StatusPartialContent:
+		return nil
+	}
+	requested := req.Header.Get("Range")
+	served := resp.Header.Get("Content-Range")
+	if want, ok := rangeStart(requested, "bytes="); ok {
+		if got, ok := rangeStart(served, "bytes "); !ok || got != want {
+			return &RangeError{URL: url, Range: requested, ContentRange: served}
+		}
+	}
+	return nil
+}
+
+// rangeStart returns the offset of the first byte of a single range given by
+// a Range or Content-Range header, whose unit is given by prefix, if it is
+// not a suffix range.
+func rangeStart(header, prefix string) (int64, bool) {
+	if !strings.HasPrefix(header, prefix) || strings.Contains(header, ",") {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(strings.TrimPrefix(header, prefix), "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,177 @@
+package speculativehttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCDN returns the host of a server that serves the given contents for
+// every path, honoring Range requests, after the given delay.  The handler
+// may override the response by writing it itself.
+func newCDN(t *testing.T, contents string, delay time.Duration, handler http.HandlerFunc) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		if handler != nil {
+			handler(w, r)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(contents))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return u.Host
+}
+
+func TestCDNsFetch(t *testing.T) {
+	t.Parallel()
+
+	const contents = "0123456789"
+
+	testCases := map[string]struct {
+		primary     func(t *testing.T) string
+		rangeHeader string
+		want        string
+		wantStatus  int
+	}{
+		"primary": {
+			primary:    func(t *testing.T) string { return newCDN(t, contents, 0, nil) },
+			want:       contents,
+			wantStatus: http.StatusOK,
+		},
+		"slow primary": {
+			primary:    func(t *testing.T) string { return newCDN(t, contents, 5*time.Second, nil) },
+			want:       contents,
+			wantStatus: http.StatusOK,
+		},
+		"failed primary": {
+			primary: func(t *testing.T) string {
+				return newCDN(t, contents, 0, func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusBadGateway)
+				})
+			},
+			want:       contents,
+			wantStatus: http.StatusOK,
+		},
+		"range": {
+			primary:     func(t *testing.T) string { return newCDN(t, contents, 0, nil) },
+			rangeHeader: "bytes=2-4",
+			want:        "234",
+			wantStatus:  http.StatusPartialContent,
+		},
+		"primary serves wrong range": {
+			primary: func(t *testing.T) string {
+				return newCDN(t, contents, 0, func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Range", "bytes 0-2/10")
+					w.WriteHeader(http.StatusPartialContent)
+					w.Write([]byte("012"))
+				})
+			},
+			rangeHeader: "bytes=2-4",
+			want:        "234",
+			wantStatus:  http.StatusPartialContent,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &CDNs{
+				Alternates: []string{newCDN(t, contents, 20*time.Millisecond, nil)},
+				Patience:   50 * time.Millisecond,
+			}
+			req, _ := http.NewRequest(http.MethodGet, "http://"+tc.primary(t)+"/asset.js", nil)
+			if tc.rangeHeader != "" {
+				req.Header.Set("Range", tc.rangeHeader)
+			}
+			start := time.Now()
+			resp, err := c.Fetch(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer resp.Body.Close()
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("expected slow CDN to be hedged, took %s", elapsed)
+			}
+
+			// The winning response must still be readable once the other
+			// request has been canceled
+			time.Sleep(50 * time.Millisecond)
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("unexpected read error: %s", err)
+			}
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("expected status %d, got %d", tc.wantStatus, resp.StatusCode)
+			}
+			if string(body) != tc.want {
+				t.Errorf("expected body %q, got %q", tc.want, body)
+			}
+		})
+	}
+}
+
+func TestCDNsFetchErrors(t *testing.T) {
+	t.Parallel()
+
+	post, _ := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("body"))
+	if _, err := (&CDNs{Patience: time.Millisecond}).Fetch(post); err != ErrNotFetchable {
+		t.Errorf("expected err = %v, got %v", ErrNotFetchable, err)
+	}
+
+	wrongRange := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-2/10")
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	c := &CDNs{Patience: 10 * time.Millisecond}
+	req, _ := http.NewRequest(http.MethodGet, "http://"+newCDN(t, "", 0, wrongRange)+"/asset.js", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	_, err := c.Fetch(req)
+	var rangeErr *RangeError
+	if !errors.As(err, &rangeErr) {
+		t.Fatalf("expected RangeError, got %v", err)
+	}
+	if rangeErr.Range != "bytes=2-4" || rangeErr.ContentRange != "bytes 0-2/10" {
+		t.Errorf("expected range %q served as %q, got %+v", "bytes=2-4", "bytes 0-2/10", rangeErr)
+	}
+}
+
+func TestRangeStart(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		header, prefix string
+		want           int64
+		wantOK         bool
+	}{
+		"range":         {header: "bytes=100-199", prefix: "bytes=", want: 100, wantOK: true},
+		"open range":    {header: "bytes=100-", prefix: "bytes=", want: 100, wantOK: true},
+		"content range": {header: "bytes 100-199/1000", prefix: "bytes ", want: 100, wantOK: true},
+		"suffix range":  {header: "bytes=-500", prefix: "bytes="},
+		"multiple":      {header: "bytes=0-1,5-6", prefix: "bytes="},
+		"other unit":    {header: "items=0-1", prefix: "bytes="},
+		"empty":         {prefix: "bytes="},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := rangeStart(tc.header, tc.prefix)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("expected rangeStart(%q) = %d, %v, got %d, %v", tc.header, tc.want, tc.wantOK, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package speculativehttp
+
+import (
+	"context"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is the header that marks a request as safe to send
+// more than once, regardless of its method.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotentKey struct{}
+
+// ContextWithIdempotent returns a copy of ctx that marks requests made with
+// it as safe to send more than once, so that RoundTripper hedges them
+// regardless of their method.
+func ContextWithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// idempotent reports whether req may be hedged without risking side effects
+// being applied more than once.  Requests with an idempotent method as
+// defined by RFC 9110, with an Idempotency-Key header or whose context is
+// marked by ContextWithIdempotent qualify.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if req.Header.Get(IdempotencyKeyHeader) != "" {
+		return true
+	}
+	ok, _ := req.Context().Value(idempotentKey{}).(bool)
+	return ok
+}
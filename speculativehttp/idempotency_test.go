@@ -0,0 +1,95 @@
+package speculativehttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+func TestIdempotent(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method string
+		header string
+		marked bool
+		want   bool
+	}{
+		"GET":                    {method: "GET", want: true},
+		"default method":         {method: "", want: true},
+		"HEAD":                   {method: "HEAD", want: true},
+		"OPTIONS":                {method: "OPTIONS", want: true},
+		"TRACE":                  {method: "TRACE", want: true},
+		"PUT":                    {method: "PUT", want: true},
+		"DELETE":                 {method: "DELETE", want: true},
+		"POST":                   {method: "POST", want: false},
+		"PATCH":                  {method: "PATCH", want: false},
+		"POST with key":          {method: "POST", header: "abc123", want: true},
+		"POST marked idempotent": {method: "POST", marked: true, want: true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tc.marked {
+				ctx = ContextWithIdempotent(ctx)
+			}
+			req, err := http.NewRequestWithContext(ctx, tc.method, "http://example.com", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %s", err)
+			}
+			req.Method = tc.method
+			if tc.header != "" {
+				req.Header.Set(IdempotencyKeyHeader, tc.header)
+			}
+			if got := idempotent(req); got != tc.want {
+				t.Errorf("expected idempotent = %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRoundTripperIdempotency(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		key          string
+		wantAttempts int64
+	}{
+		"POST is not hedged":      {wantAttempts: 1},
+		"POST with key is hedged": {key: "abc123", wantAttempts: 2},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, count, _ := newTestServer(t)
+			rt := &RoundTripper{
+				Patience: 25 * time.Millisecond,
+				Options:  []speculatively.Option{speculatively.WithMaxAttempts(2)},
+			}
+			req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("payload"))
+			if tc.key != "" {
+				req.Header.Set(IdempotencyKeyHeader, tc.key)
+			}
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if got := atomic.LoadInt64(count); got != tc.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.wantAttempts, got)
+			}
+		})
+	}
+}
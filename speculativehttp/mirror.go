@@ -0,0 +1,110 @@
+package speculativehttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Mirrors downloads files that are published, byte for byte, on several
+// mirrors, e.g. release artifacts or package archives.
+//
+// Each file is downloaded from the first mirror immediately, and from each
+// subsequent mirror after waiting for Patience, or as soon as a previous
+// download fails.  Every download is verified against the file's checksum
+// before it can win, so that a corrupt or tampered copy on one mirror is
+// skipped in favor of the next good one.  The first verified download is
+// returned and all other downloads are canceled.
+type Mirrors struct {
+	// URLs are the base URLs of the mirrors, in order of preference.
+	URLs []string
+
+	// Client is used to download files.  If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Patience is how long to wait for a download to complete before
+	// starting it from the next mirror.
+	Patience time.Duration
+
+	// Options customize the hedging of every download, e.g. to share a
+	// Budget.
+	Options []speculatively.Option
+}
+
+// ChecksumError is the error returned for a download whose contents do not
+// match the expected checksum.
+type ChecksumError struct {
+	URL       string
+	Got, Want []byte
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("speculativehttp: checksum mismatch for %s: got %x, want %x", e.URL, e.Got, e.Want)
+}
+
+// StatusError is the error returned for a download that fails with a non-2xx
+// response.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("speculativehttp: unexpected status %d for %s", e.StatusCode, e.URL)
+}
+
+// Download returns the contents of the file at the given path, relative to
+// the base URL of each mirror, once they have been verified to hash to sum
+// using a hash created by newHash, e.g. sha256.New.  If no mirror returns a
+// verified copy, the error of the last download to fail is returned.
+func (m *Mirrors) Download(ctx context.Context, path string, newHash func() hash.Hash, sum []byte) ([]byte, error) {
+	thunks := make([]speculatively.Thunk[[]byte], len(m.URLs))
+	for i, base := range m.URLs {
+		url := strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+		thunks[i] = func(ctx context.Context) ([]byte, error) {
+			return m.download(ctx, url, newHash, sum)
+		}
+	}
+	opts := append([]speculatively.Option{
+		speculatively.WithRetryable(func(error) bool { return true }),
+	}, m.Options...)
+	return speculatively.DoRace(ctx, m.Patience, thunks, opts...)
+}
+
+// download fetches and verifies a single copy of a file.
+func (m *Mirrors) download(ctx context.Context, url string, newHash func() hash.Hash, sum []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	h := newHash()
+	body, err := io.ReadAll(io.TeeReader(resp.Body, h))
+	if err != nil {
+		return nil, err
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, sum) {
+		return nil, &ChecksumError{URL: url, Got: got, Want: sum}
+	}
+	return body, nil
+}
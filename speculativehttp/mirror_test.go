@@ -0,0 +1,118 @@
+package speculativehttp
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newMirror returns a server that serves the given contents for every path,
+// after the given delay, or fails with the given status if it is not zero.
+func newMirror(t *testing.T, contents string, delay time.Duration, status int) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(contents))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestMirrorsDownload(t *testing.T) {
+	t.Parallel()
+
+	const contents = "release artifact"
+	sum := sha256.Sum256([]byte(contents))
+
+	testCases := map[string]struct {
+		mirrors      func(t *testing.T) []string
+		wantErr      error
+		wantMaxDelay time.Duration
+	}{
+		"first mirror": {
+			mirrors: func(t *testing.T) []string {
+				return []string{newMirror(t, contents, 0, 0), newMirror(t, contents, 0, 0)}
+			},
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+		"slow first mirror": {
+			mirrors: func(t *testing.T) []string {
+				return []string{newMirror(t, contents, 5*time.Second, 0), newMirror(t, contents, 0, 0)}
+			},
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+		"corrupt first mirror": {
+			mirrors: func(t *testing.T) []string {
+				return []string{newMirror(t, "corrupted", 0, 0), newMirror(t, contents, 0, 0)}
+			},
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+		"failing first mirror": {
+			mirrors: func(t *testing.T) []string {
+				return []string{newMirror(t, contents, 0, http.StatusNotFound), newMirror(t, contents, 0, 0)}
+			},
+			wantMaxDelay: 500 * time.Millisecond,
+		},
+		"every mirror corrupt": {
+			mirrors: func(t *testing.T) []string {
+				return []string{newMirror(t, "corrupted", 0, 0), newMirror(t, "tampered", 0, 0)}
+			},
+			wantErr: &ChecksumError{},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &Mirrors{URLs: tc.mirrors(t), Patience: 50 * time.Millisecond}
+			start := time.Now()
+			got, err := m.Download(context.Background(), "/releases/v1.tar.gz", sha256.New, sum[:])
+			if tc.wantErr != nil {
+				var checksumErr *ChecksumError
+				if !errors.As(err, &checksumErr) {
+					t.Fatalf("expected checksum error, got %v", err)
+				}
+				if string(checksumErr.Want) != string(sum[:]) {
+					t.Errorf("expected checksum error to want %x, got %x", sum, checksumErr.Want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(got) != contents {
+				t.Errorf("expected contents %q, got %q", contents, got)
+			}
+			if elapsed := time.Since(start); elapsed > tc.wantMaxDelay {
+				t.Errorf("expected download within %s, took %s", tc.wantMaxDelay, elapsed)
+			}
+		})
+	}
+}
+
+func TestMirrorsDownloadStatusError(t *testing.T) {
+	t.Parallel()
+
+	m := &Mirrors{URLs: []string{newMirror(t, "", 0, http.StatusServiceUnavailable)}, Patience: time.Second}
+	_, err := m.Download(context.Background(), "file", sha256.New, nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected status error, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status code %d, got %d", http.StatusServiceUnavailable, statusErr.StatusCode)
+	}
+}
@@ -0,0 +1,70 @@
+package speculativehttp
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Policy overrides how RoundTripper hedges the requests matching its
+// Pattern, since a single transport often fronts many backends with very
+// different latency profiles.
+type Policy struct {
+	// Pattern matches requests by host and, optionally, path prefix, e.g.
+	// "api.example.com" or "api.example.com/search/".  A host starting with
+	// "*." matches any subdomain, and a host with a port only matches
+	// requests to that port.  A pattern starting with "/" matches the path
+	// prefix on any host.
+	Pattern string
+
+	// Patience overrides RoundTripper.Patience, if non-zero.
+	Patience time.Duration
+
+	// Options are applied after RoundTripper.Options, e.g. to set a
+	// different max attempts or Budget.
+	Options []speculatively.Option
+}
+
+// matches reports whether p applies to req.
+func (p Policy) matches(req *http.Request) bool {
+	host, path := p.Pattern, "/"
+	if i := strings.Index(host, "/"); i >= 0 {
+		host, path = host[:i], host[i:]
+	}
+	if !strings.HasPrefix(req.URL.Path, path) && !(path == "/" && req.URL.Path == "") {
+		return false
+	}
+	if host == "" {
+		return true
+	}
+
+	reqHost := req.URL.Hostname()
+	if strings.Contains(host, ":") {
+		reqHost = req.URL.Host
+	}
+	reqHost = strings.ToLower(reqHost)
+	host = strings.ToLower(host)
+	if strings.HasPrefix(host, "*.") {
+		return strings.HasSuffix(reqHost, host[1:])
+	}
+	return reqHost == host
+}
+
+// policy returns the patience and options with which to hedge req, as set by
+// the first of rt's Policies that matches it, if any.
+func (rt *RoundTripper) policy(req *http.Request) (time.Duration, []speculatively.Option) {
+	patience, opts := rt.Patience, rt.Options
+	for _, p := range rt.Policies {
+		if !p.matches(req) {
+			continue
+		}
+		if p.Patience != 0 {
+			patience = p.Patience
+		}
+		opts = append(opts[:len(opts):len(opts)], p.Options...)
+		break
+	}
+	return patience, opts
+}
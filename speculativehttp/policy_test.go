@@ -0,0 +1,113 @@
+package speculativehttp
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+func TestPolicyMatches(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		pattern string
+		url     string
+		want    bool
+	}{
+		"host":                  {"api.example.com", "http://api.example.com/foo", true},
+		"host case insensitive": {"API.example.com", "http://api.EXAMPLE.com/foo", true},
+		"host with any port":    {"api.example.com", "http://api.example.com:8080/foo", true},
+		"other host":            {"api.example.com", "http://www.example.com/foo", false},
+		"host and port":         {"api.example.com:8080", "http://api.example.com:8080/foo", true},
+		"other port":            {"api.example.com:8080", "http://api.example.com:9090/foo", false},
+		"wildcard subdomain":    {"*.example.com", "http://api.example.com/foo", true},
+		"wildcard nested":       {"*.example.com", "http://a.b.example.com/foo", true},
+		"wildcard bare domain":  {"*.example.com", "http://example.com/foo", false},
+		"path prefix":           {"api.example.com/search/", "http://api.example.com/search/q", true},
+		"other path":            {"api.example.com/search/", "http://api.example.com/users/1", false},
+		"path on any host":      {"/search/", "http://www.example.com/search/q", true},
+		"empty path":            {"api.example.com", "http://api.example.com", true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %s", err)
+			}
+			if got := (Policy{Pattern: tc.pattern}).matches(req); got != tc.want {
+				t.Errorf("expected %q matches %q = %v, got %v", tc.pattern, tc.url, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRoundTripperPolicies(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		path         string
+		wantAttempts int64
+	}{
+		"default policy":  {path: "/fast", wantAttempts: 2},
+		"matching policy": {path: "/slow/1", wantAttempts: 1},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, count, _ := newTestServer(t)
+			rt := &RoundTripper{
+				Patience: 25 * time.Millisecond,
+				Options:  []speculatively.Option{speculatively.WithMaxAttempts(2)},
+				Policies: []Policy{
+					{Pattern: "/other/", Patience: time.Millisecond},
+					{Pattern: "/slow/", Patience: 10 * time.Second},
+				},
+			}
+			req, _ := http.NewRequest("GET", srv.URL+tc.path, nil)
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if got := atomic.LoadInt64(count); got != tc.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.wantAttempts, got)
+			}
+		})
+	}
+}
+
+func TestRoundTripperPolicyOptions(t *testing.T) {
+	t.Parallel()
+
+	budget := speculatively.NewBudget(0, 0)
+	rt := &RoundTripper{
+		Patience: time.Millisecond,
+		Options:  []speculatively.Option{speculatively.WithMaxAttempts(3)},
+		Policies: []Policy{
+			{Pattern: "api.example.com", Options: []speculatively.Option{speculatively.WithBudget(budget)}},
+		},
+	}
+
+	req, _ := http.NewRequest("GET", "http://api.example.com/", nil)
+	patience, opts := rt.policy(req)
+	if patience != rt.Patience {
+		t.Errorf("expected default patience %s, got %s", rt.Patience, patience)
+	}
+	if len(opts) != 2 {
+		t.Errorf("expected 2 options, got %d", len(opts))
+	}
+
+	req, _ = http.NewRequest("GET", "http://www.example.com/", nil)
+	if _, opts := rt.policy(req); len(opts) != 1 {
+		t.Errorf("expected 1 option, got %d", len(opts))
+	}
+}
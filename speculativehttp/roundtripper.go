@@ -0,0 +1,181 @@
+/*
+Package speculativehttp provides speculative execution helpers for HTTP
+clients.
+*/
+package speculativehttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// RoundTripper is an http.RoundTripper that hedges outgoing requests.
+//
+// Each request is sent immediately, and sent again in parallel every time
+// Patience elapses without a response, up to the limits set by Options.  The
+// first response (or error) is returned, and the bodies of all other
+// responses are closed.
+//
+// The winning response remains usable after RoundTrip returns: its body may
+// be read until it is closed or the request's context is done.
+//
+// Only requests that are safe to send more than once are hedged: those with
+// an idempotent method (GET, HEAD, OPTIONS, TRACE, PUT or DELETE), those with
+// an Idempotency-Key header, and those whose context is marked by
+// ContextWithIdempotent.  Other requests are sent once, without hedging.
+//
+// Requests with a body are only hedged if their GetBody func is set, since a
+// body cannot otherwise be sent more than once, or if the body is small
+// enough to be buffered in memory as allowed by MaxBodyBuffer.  Requests
+// created with http.NewRequest from common in-memory readers have GetBody
+// set.  Other requests with a body are sent once, without hedging.
+type RoundTripper struct {
+	// Transport is used to send each attempt.  If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Transports optionally race each request over several transports, e.g.
+	// an HTTP/3 transport followed by an HTTP/2 one, to smooth over
+	// networks where one protocol is degraded.  Successive attempts use
+	// one transport after another, wrapping around, and the first to
+	// return response headers wins.  If set, Transport is ignored.
+	Transports []http.RoundTripper
+
+	// Patience is how long to wait for a response before sending the
+	// request again.
+	Patience time.Duration
+
+	// Options customize the hedging of every request, e.g. to cap the
+	// number of attempts or share a Budget.
+	Options []speculatively.Option
+
+	// MaxBodyBuffer is the size, in bytes, of the largest request body
+	// that is buffered in memory so that a request whose GetBody func is
+	// not set can be hedged.  If zero, bodies are never buffered.
+	MaxBodyBuffer int64
+
+	// Policies override Patience and Options for the requests they match.
+	// The first matching Policy applies, and requests matching none of them
+	// are hedged according to Patience and Options alone.
+	Policies []Policy
+
+	// Stall optionally hedges requests as soon as an attempt stalls, in
+	// addition to whenever Patience elapses.
+	Stall StallTimeouts
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	replayable := false
+	if idempotent(req) {
+		var err error
+		req, replayable, err = rt.replayable(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	patience, policyOpts := rt.policy(req)
+	opts := append([]speculatively.Option{
+		speculatively.WithCleanup(func(resp *http.Response) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+		}),
+	}, policyOpts...)
+	if !replayable {
+		opts = append(opts, speculatively.WithMaxAttempts(1))
+	}
+	return speculatively.Do(req.Context(), patience, func(ctx context.Context) (*http.Response, error) {
+		return rt.send(ctx, req)
+	}, opts...)
+}
+
+// send sends a single attempt of the given request.  The attempt is canceled
+// along with ctx until a response is received, after which only closing its
+// body or the request's own context being done cancels it, so that the
+// winning response can still be read once the call has returned.
+func (rt *RoundTripper) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	attemptCtx, keep, cancel := speculatively.Detach(req.Context(), ctx)
+
+	if rt.Stall.enabled() {
+		var unwatch func()
+		attemptCtx, unwatch = rt.Stall.watch(attemptCtx, ctx)
+		defer unwatch()
+	}
+
+	var resp *http.Response
+	clone, err := rt.clone(ctx, attemptCtx, req)
+	if err == nil {
+		resp, err = rt.transport(ctx).RoundTrip(clone)
+	}
+	keep()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = speculatively.CancelOnClose(resp.Body, cancel)
+	return resp, nil
+}
+
+// clone returns a copy of req to be sent by the attempt running with ctx,
+// with its own body if it is a hedge.
+func (rt *RoundTripper) clone(ctx, attemptCtx context.Context, req *http.Request) (*http.Request, error) {
+	clone := req.Clone(attemptCtx)
+	if a, ok := speculatively.AttemptFromContext(ctx); ok && a.Index > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// transport returns the transport to be used by the attempt running with ctx.
+func (rt *RoundTripper) transport(ctx context.Context) http.RoundTripper {
+	if len(rt.Transports) > 0 {
+		a, _ := speculatively.AttemptFromContext(ctx)
+		return rt.Transports[a.Index%len(rt.Transports)]
+	}
+	if rt.Transport != nil {
+		return rt.Transport
+	}
+	return http.DefaultTransport
+}
+
+// replayable returns req, or a copy of it whose body has been buffered, and
+// reports whether the returned request may be sent more than once.
+func (rt *RoundTripper) replayable(req *http.Request) (*http.Request, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return req, true, nil
+	}
+	if rt.MaxBodyBuffer <= 0 || req.ContentLength > rt.MaxBodyBuffer {
+		return req, false, nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(req.Body, rt.MaxBodyBuffer+1))
+	if err != nil {
+		req.Body.Close()
+		return nil, false, err
+	}
+	clone := req.Clone(req.Context())
+	if int64(len(buf)) > rt.MaxBodyBuffer {
+		// Too large to buffer, so send what was read followed by the rest
+		clone.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+		return clone, false, nil
+	}
+	req.Body.Close()
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	clone.Body, _ = clone.GetBody()
+	return clone, true, nil
+}
@@ -0,0 +1,240 @@
+package speculativehttp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// newTestServer returns a server that echoes each request's body, after
+// stalling the first request until it is canceled.
+func newTestServer(t *testing.T) (*httptest.Server, *int64, chan struct{}) {
+	t.Helper()
+	var count int64
+	canceled := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt64(&count, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				canceled <- struct{}{}
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &count, canceled
+}
+
+func TestRoundTripper(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		body          func() io.Reader
+		maxBodyBuffer int64
+		wantAttempts  int64
+		wantResponse  string
+		wantCanceled  bool
+		wantMinLength time.Duration
+	}{
+		"no body": {
+			body:         func() io.Reader { return nil },
+			wantAttempts: 2,
+			wantCanceled: true,
+		},
+		"replayable body": {
+			body:         func() io.Reader { return strings.NewReader("payload") },
+			wantAttempts: 2,
+			wantResponse: "payload",
+			wantCanceled: true,
+		},
+		"non-replayable body": {
+			body:          func() io.Reader { return io.MultiReader(strings.NewReader("payload")) },
+			wantAttempts:  1,
+			wantResponse:  "payload",
+			wantMinLength: time.Second,
+		},
+		"buffered body": {
+			body:          func() io.Reader { return io.MultiReader(strings.NewReader("payload")) },
+			maxBodyBuffer: 64,
+			wantAttempts:  2,
+			wantResponse:  "payload",
+			wantCanceled:  true,
+		},
+		"body too large to buffer": {
+			body:          func() io.Reader { return io.MultiReader(strings.NewReader("payload")) },
+			maxBodyBuffer: 3,
+			wantAttempts:  1,
+			wantResponse:  "payload",
+			wantMinLength: time.Second,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, count, canceled := newTestServer(t)
+			client := &http.Client{
+				Transport: &RoundTripper{
+					Patience:      25 * time.Millisecond,
+					Options:       []speculatively.Option{speculatively.WithMaxAttempts(2)},
+					MaxBodyBuffer: tc.maxBodyBuffer,
+				},
+			}
+
+			req, err := http.NewRequest("PUT", srv.URL, tc.body())
+			if err != nil {
+				t.Fatalf("failed to create request: %s", err)
+			}
+			start := time.Now()
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer resp.Body.Close()
+			elapsed := time.Since(start)
+
+			// The winning response must still be readable once the call's
+			// other attempts have been canceled
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read response body: %s", err)
+			}
+			if string(body) != tc.wantResponse {
+				t.Errorf("expected response %q, got %q", tc.wantResponse, body)
+			}
+			if elapsed < tc.wantMinLength {
+				t.Errorf("expected request to take at least %s, got %s", tc.wantMinLength, elapsed)
+			}
+			if got := atomic.LoadInt64(count); got != tc.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.wantAttempts, got)
+			}
+			if tc.wantCanceled {
+				select {
+				case <-canceled:
+				case <-time.After(time.Second):
+					t.Errorf("expected losing attempt to be canceled")
+				}
+			}
+		})
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed *int64
+}
+
+func (r closeRecorder) Close() error {
+	atomic.AddInt64(r.closed, 1)
+	return nil
+}
+
+type transportFunc func(*http.Request) (*http.Response, error)
+
+func (fn transportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestRoundTripperClosesLosers(t *testing.T) {
+	t.Parallel()
+
+	var attempts, closed int64
+	transport := transportFunc(func(req *http.Request) (*http.Response, error) {
+		n := atomic.AddInt64(&attempts, 1)
+		if n == 1 {
+			// The first attempt responds late, after ignoring cancelation
+			time.Sleep(100 * time.Millisecond)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       closeRecorder{bytes.NewReader(nil), &closed},
+			Request:    req,
+		}, nil
+	})
+
+	rt := &RoundTripper{
+		Transport: transport,
+		Patience:  10 * time.Millisecond,
+		Options:   []speculatively.Option{speculatively.WithMaxAttempts(2)},
+	}
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if got := atomic.LoadInt64(&closed); got != 1 {
+		t.Errorf("expected losing response to be closed, got %d closes", got)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt64(&closed); got != 2 {
+		t.Errorf("expected 2 closes, got %d", got)
+	}
+}
+
+func TestRoundTripperTransports(t *testing.T) {
+	t.Parallel()
+
+	// protocol returns a transport that responds with its name after the
+	// given delay, unless canceled first
+	protocol := func(name string, delay time.Duration) http.RoundTripper {
+		return transportFunc(func(req *http.Request) (*http.Response, error) {
+			select {
+			case <-time.After(delay):
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Proto:      name,
+				Body:       io.NopCloser(strings.NewReader(name)),
+				Request:    req,
+			}, nil
+		})
+	}
+
+	testCases := map[string]struct {
+		h3, h2 time.Duration
+		want   string
+	}{
+		"preferred protocol":          {h3: 0, h2: 0, want: "HTTP/3.0"},
+		"degraded preferred protocol": {h3: time.Second, h2: 0, want: "HTTP/2.0"},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rt := &RoundTripper{
+				Transports: []http.RoundTripper{protocol("HTTP/3.0", tc.h3), protocol("HTTP/2.0", tc.h2)},
+				Patience:   10 * time.Millisecond,
+				Options:    []speculatively.Option{speculatively.WithMaxAttempts(2)},
+			}
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			start := time.Now()
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer resp.Body.Close()
+			if resp.Proto != tc.want {
+				t.Errorf("expected response over %s, got %s", tc.want, resp.Proto)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("expected degraded protocol to be raced, took %s", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package speculativehttp
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// StallTimeouts hedge a request as soon as one of its attempts spends too
+// long in a single phase of the request, as observed via httptrace, rather
+// than only once patience has elapsed.  This targets hedges at attempts that
+// are genuinely stuck, e.g. on a slow DNS server or an unresponsive backend,
+// while leaving attempts that are merely transferring a large response
+// alone.
+//
+// A zero timeout disables detection of stalls in the corresponding phase.
+type StallTimeouts struct {
+	// DNS is how long to wait for a DNS lookup to complete.
+	DNS time.Duration
+
+	// Connect is how long to wait for a new connection to be established.
+	Connect time.Duration
+
+	// TLS is how long to wait for a TLS handshake to complete.
+	TLS time.Duration
+
+	// FirstByte is how long to wait for the first byte of the response
+	// once the request has been written.
+	FirstByte time.Duration
+}
+
+func (s StallTimeouts) enabled() bool {
+	return s.DNS > 0 || s.Connect > 0 || s.TLS > 0 || s.FirstByte > 0
+}
+
+// watch returns a copy of ctx that traces the request sent with it and hedges
+// the given attempt when the request stalls, along with a func to stop
+// watching for stalls.
+func (s StallTimeouts) watch(ctx, attempt context.Context) (context.Context, func()) {
+	w := &stallWatcher{attempt: attempt, timers: map[string]*time.Timer{}}
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { w.start("dns", s.DNS) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { w.stop("dns") },
+		ConnectStart:         func(string, string) { w.start("connect", s.Connect) },
+		ConnectDone:          func(string, string, error) { w.stop("connect") },
+		TLSHandshakeStart:    func() { w.start("tls", s.TLS) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { w.stop("tls") },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { w.start("first_byte", s.FirstByte) },
+		GotFirstResponseByte: func() { w.stop("first_byte") },
+	}
+	return httptrace.WithClientTrace(ctx, trace), w.stopAll
+}
+
+// stallWatcher tracks the phases of an attempt that are in progress.
+type stallWatcher struct {
+	attempt context.Context
+	timers  map[string]*time.Timer
+	stopped bool
+	mu      sync.Mutex
+}
+
+func (w *stallWatcher) start(phase string, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return
+	}
+	if t, ok := w.timers[phase]; ok {
+		t.Stop()
+	}
+	w.timers[phase] = time.AfterFunc(timeout, func() {
+		speculatively.HedgeNow(w.attempt)
+	})
+}
+
+func (w *stallWatcher) stop(phase string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if t, ok := w.timers[phase]; ok {
+		t.Stop()
+		delete(w.timers, phase)
+	}
+}
+
+func (w *stallWatcher) stopAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	for phase, t := range w.timers {
+		t.Stop()
+		delete(w.timers, phase)
+	}
+}
@@ -0,0 +1,80 @@
+package speculativehttp
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+func TestRoundTripperStall(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		stall        StallTimeouts
+		wantAttempts int64
+		wantFast     bool
+	}{
+		"first byte stall": {
+			stall:        StallTimeouts{FirstByte: 20 * time.Millisecond},
+			wantAttempts: 2,
+			wantFast:     true,
+		},
+		"stalls ignored": {
+			wantAttempts: 1,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, count, _ := newTestServer(t)
+			rt := &RoundTripper{
+				Patience: 10 * time.Second,
+				Options:  []speculatively.Option{speculatively.WithMaxAttempts(2)},
+				Stall:    tc.stall,
+			}
+			req, _ := http.NewRequest("GET", srv.URL, nil)
+			start := time.Now()
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			elapsed := time.Since(start)
+
+			if got := atomic.LoadInt64(count); got != tc.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.wantAttempts, got)
+			}
+			if fast := elapsed < 500*time.Millisecond; fast != tc.wantFast {
+				t.Errorf("expected fast = %v, took %s", tc.wantFast, elapsed)
+			}
+		})
+	}
+}
+
+func TestStallWatcher(t *testing.T) {
+	t.Parallel()
+
+	w := &stallWatcher{timers: map[string]*time.Timer{}}
+	w.start("dns", 0)
+	if len(w.timers) != 0 {
+		t.Errorf("expected zero timeout to be ignored")
+	}
+	w.start("dns", time.Hour)
+	w.start("connect", time.Hour)
+	w.stop("dns")
+	if len(w.timers) != 1 {
+		t.Errorf("expected 1 phase in progress, got %d", len(w.timers))
+	}
+	w.stopAll()
+	w.start("tls", time.Hour)
+	if len(w.timers) != 0 {
+		t.Errorf("expected no phases in progress once stopped, got %d", len(w.timers))
+	}
+}
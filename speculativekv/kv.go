@@ -0,0 +1,146 @@
+/*
+Package speculativekv provides speculative execution helpers for reading from
+strongly consistent key-value stores such as etcd or Consul, so that
+control-plane code can tolerate one slow server without blowing its deadline.
+
+It depends only on the small Getter interface, which is satisfied by wrapping
+one client per endpoint.  Each Getter must perform reads that are
+linearizable on its own, e.g. etcd's default reads, which go through the
+leader, or Consul's consistent reads.  For example, with etcd's clientv3:
+
+	getter := speculativekv.GetterFunc(func(ctx context.Context, key string) (speculativekv.Entry, error) {
+		resp, err := cli.Get(ctx, key)
+		if err != nil {
+			return speculativekv.Entry{}, err
+		}
+		e := speculativekv.Entry{Revision: resp.Header.Revision}
+		if len(resp.Kvs) > 0 {
+			e.Value, e.Found = resp.Kvs[0].Value, true
+		}
+		return e, nil
+	})
+
+Or with Consul's api package:
+
+	getter := speculativekv.GetterFunc(func(ctx context.Context, key string) (speculativekv.Entry, error) {
+		q := &api.QueryOptions{RequireConsistent: true}
+		pair, meta, err := client.KV().Get(key, q.WithContext(ctx))
+		if err != nil {
+			return speculativekv.Entry{}, err
+		}
+		e := speculativekv.Entry{Revision: int64(meta.LastIndex)}
+		if pair != nil {
+			e.Value, e.Found = pair.Value, true
+		}
+		return e, nil
+	})
+*/
+package speculativekv
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Entry is the result of reading a key.
+type Entry struct {
+	// Value is the value stored under the key, if Found.
+	Value []byte
+
+	// Found reports whether the key exists.
+	Found bool
+
+	// Revision is the revision of the store as of the read, e.g. the
+	// revision in etcd's response header or Consul's X-Consul-Index, or 0
+	// if unknown.
+	Revision int64
+}
+
+// Getter reads keys through a single endpoint of a key-value store.
+type Getter interface {
+	Get(ctx context.Context, key string) (Entry, error)
+}
+
+// GetterFunc adapts a func to the Getter interface.
+type GetterFunc func(ctx context.Context, key string) (Entry, error)
+
+// Get calls fn.
+func (fn GetterFunc) Get(ctx context.Context, key string) (Entry, error) {
+	return fn(ctx, key)
+}
+
+// StaleError is returned by an attempt whose read was as of an older
+// revision than a previous read made through the same Client.
+type StaleError struct {
+	Revision    int64
+	MinRevision int64
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("speculativekv: read at revision %d, older than revision %d already observed", e.Revision, e.MinRevision)
+}
+
+// Client hedges reads across the endpoints of a key-value store.
+//
+// Each key is read through the first endpoint immediately, and through each
+// subsequent endpoint after waiting for Patience, or as soon as a previous
+// read fails.  The first successful read is returned and every other read is
+// canceled.
+//
+// Reads never go back in time: a read as of an older revision than one
+// already returned by the Client is rejected with a StaleError, and the next
+// endpoint tried instead, so that a lagging server can't undo an update the
+// caller has already observed.
+type Client struct {
+	// Endpoints are the endpoints to read through, in order of preference,
+	// e.g. the current leader followed by the other servers.
+	Endpoints []Getter
+
+	// Patience is how long to wait for a read to complete before reading
+	// through the next endpoint.
+	Patience time.Duration
+
+	// Options customize the hedging of every read, e.g. to share a Budget.
+	Options []speculatively.Option
+
+	// revision is the highest revision returned so far
+	revision int64
+}
+
+// Get reads the given key through whichever endpoint answers first.
+func (c *Client) Get(ctx context.Context, key string) (Entry, error) {
+	opts := append([]speculatively.Option{
+		speculatively.WithRetryable(func(error) bool { return true }),
+	}, c.Options...)
+	replicas := speculatively.Replicas[Getter]{List: c.Endpoints}
+	e, err := speculatively.DoReplicas(ctx, c.Patience, replicas, func(ctx context.Context, endpoint Getter) (Entry, error) {
+		e, err := endpoint.Get(ctx, key)
+		if err != nil {
+			return Entry{}, err
+		}
+		if floor := atomic.LoadInt64(&c.revision); e.Revision < floor {
+			return Entry{}, &StaleError{Revision: e.Revision, MinRevision: floor}
+		}
+		return e, nil
+	}, opts...)
+	if err != nil {
+		return Entry{}, err
+	}
+	c.observe(e.Revision)
+	return e, nil
+}
+
+// observe records the given revision as returned, if it is the highest so
+// far.
+func (c *Client) observe(revision int64) {
+	for {
+		seen := atomic.LoadInt64(&c.revision)
+		if revision <= seen || atomic.CompareAndSwapInt64(&c.revision, seen, revision) {
+			return
+		}
+	}
+}
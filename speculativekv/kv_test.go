@@ -0,0 +1,120 @@
+package speculativekv
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// testEndpoint returns the same entry for every key after the given delay.
+type testEndpoint struct {
+	entry Entry
+	delay time.Duration
+	err   error
+	reads int64
+}
+
+func (e *testEndpoint) Get(ctx context.Context, key string) (Entry, error) {
+	atomic.AddInt64(&e.reads, 1)
+	select {
+	case <-time.After(e.delay):
+	case <-ctx.Done():
+		return Entry{}, ctx.Err()
+	}
+	if e.err != nil {
+		return Entry{}, e.err
+	}
+	return e.entry, nil
+}
+
+func TestClientGet(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		leader, follower *testEndpoint
+		want             string
+	}{
+		"leader wins": {
+			leader:   &testEndpoint{entry: Entry{Value: []byte("leader"), Found: true, Revision: 2}},
+			follower: &testEndpoint{entry: Entry{Value: []byte("follower"), Found: true, Revision: 2}},
+			want:     "leader",
+		},
+		"slow leader": {
+			leader:   &testEndpoint{entry: Entry{Value: []byte("leader"), Found: true, Revision: 2}, delay: time.Second},
+			follower: &testEndpoint{entry: Entry{Value: []byte("follower"), Found: true, Revision: 2}},
+			want:     "follower",
+		},
+		"failed leader": {
+			leader:   &testEndpoint{err: errors.New("connection refused")},
+			follower: &testEndpoint{entry: Entry{Value: []byte("follower"), Found: true, Revision: 2}},
+			want:     "follower",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Client{
+				Endpoints: []Getter{tc.leader, tc.follower},
+				Patience:  10 * time.Millisecond,
+			}
+			start := time.Now()
+			e, err := c.Get(context.Background(), "key")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(e.Value) != tc.want {
+				t.Errorf("expected value %q, got %q", tc.want, e.Value)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("expected slow endpoint to be hedged, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestClientRejectsStaleReads(t *testing.T) {
+	t.Parallel()
+
+	leader := &testEndpoint{entry: Entry{Value: []byte("new"), Found: true, Revision: 5}}
+	lagging := &testEndpoint{entry: Entry{Value: []byte("old"), Found: true, Revision: 3}}
+	c := &Client{Endpoints: []Getter{leader}, Patience: 10 * time.Millisecond}
+	if _, err := c.Get(context.Background(), "key"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// A lagging endpoint answering first is passed over for one that has
+	// caught up
+	c.Endpoints = []Getter{lagging, &testEndpoint{entry: leader.entry, delay: 20 * time.Millisecond}}
+	e, err := c.Get(context.Background(), "key")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(e.Value) != "new" {
+		t.Errorf("expected value %q, got %q", "new", e.Value)
+	}
+
+	c.Endpoints = []Getter{lagging}
+	_, err = c.Get(context.Background(), "key")
+	var stale *StaleError
+	if !errors.As(err, &stale) {
+		t.Fatalf("expected StaleError, got %v", err)
+	}
+	if stale.Revision != 3 || stale.MinRevision != 5 {
+		t.Errorf("expected revision 3 older than 5, got %d older than %d", stale.Revision, stale.MinRevision)
+	}
+}
+
+func TestClientNoEndpoints(t *testing.T) {
+	t.Parallel()
+
+	c := &Client{Patience: 10 * time.Millisecond}
+	if _, err := c.Get(context.Background(), "key"); err != speculatively.ErrNoReplicas {
+		t.Errorf("expected err = %v, got %v", speculatively.ErrNoReplicas, err)
+	}
+}
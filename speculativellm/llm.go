@@ -0,0 +1,113 @@
+/*
+Package speculativellm provides speculative execution helpers for inference
+requests, e.g. LLM completions, whose latency is extremely long-tailed.
+
+It races the same request across several model providers or regions, each
+wrapped in the small Provider interface, which can be satisfied by any
+client library.  For example, with two regions of the same API:
+
+	racer := &speculativellm.Racer[Prompt, string]{
+		Providers: []speculativellm.Provider[Prompt, string]{
+			speculativellm.ProviderFunc[Prompt, string](usEast.Complete),
+			speculativellm.ProviderFunc[Prompt, string](euWest.Complete),
+		},
+		Patience: 2 * time.Second,
+	}
+	completion, err := racer.Complete(ctx, prompt)
+*/
+package speculativellm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Provider completes requests, e.g. through a single model provider or
+// region.
+type Provider[Req, Resp any] interface {
+	Complete(ctx context.Context, req Req) (Resp, error)
+}
+
+// ProviderFunc adapts a func to the Provider interface.
+type ProviderFunc[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// Complete calls fn.
+func (fn ProviderFunc[Req, Resp]) Complete(ctx context.Context, req Req) (Resp, error) {
+	return fn(ctx, req)
+}
+
+// RejectedError is returned by an attempt whose completion was rejected by
+// the Racer's Accept func.
+type RejectedError struct {
+	Provider int
+	Err      error
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("speculativellm: completion from provider %d rejected: %s", e.Provider, e.Err)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// Racer races requests across Providers.
+//
+// Each request is sent to the first provider immediately, and to each
+// subsequent provider after waiting for Patience, or as soon as a previous
+// request fails or its completion is rejected.  The first acceptable
+// completion is returned and every other request is canceled.
+type Racer[Req, Resp any] struct {
+	// Providers are the providers to send requests to, in order of
+	// preference, e.g. the cheapest first.
+	Providers []Provider[Req, Resp]
+
+	// Patience is how long to wait for a completion before sending a
+	// request to the next provider.
+	Patience time.Duration
+
+	// Options customize the racing of every request, e.g. to share a
+	// Budget or learn which provider is fastest via WithPortfolio.
+	Options []speculatively.Option
+
+	// Accept optionally checks every completion, e.g. that it parses as
+	// the expected JSON, returning an error to reject it.
+	Accept func(Resp) error
+
+	// Cost is optionally called with the outcome of every request sent to a
+	// provider, given by its index in Providers, including requests
+	// canceled because another provider won, from the request's own
+	// goroutine, e.g. to account for the tokens billed by each provider.
+	Cost func(provider int, resp Resp, err error)
+}
+
+// Complete sends the given request to one provider after another until one
+// returns an acceptable completion.
+func (r *Racer[Req, Resp]) Complete(ctx context.Context, req Req) (Resp, error) {
+	thunks := make([]speculatively.Thunk[Resp], len(r.Providers))
+	for i, provider := range r.Providers {
+		i, provider := i, provider
+		thunks[i] = func(ctx context.Context) (Resp, error) {
+			resp, err := provider.Complete(ctx, req)
+			if r.Cost != nil {
+				r.Cost(i, resp, err)
+			}
+			if err != nil {
+				return resp, err
+			}
+			if r.Accept != nil {
+				if err := r.Accept(resp); err != nil {
+					return resp, &RejectedError{Provider: i, Err: err}
+				}
+			}
+			return resp, nil
+		}
+	}
+	opts := append([]speculatively.Option{
+		speculatively.WithRetryable(func(error) bool { return true }),
+	}, r.Options...)
+	return speculatively.DoRace(ctx, r.Patience, thunks, opts...)
+}
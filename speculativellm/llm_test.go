@@ -0,0 +1,144 @@
+package speculativellm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testProvider answers every prompt with its name after the given delay.
+type testProvider struct {
+	name  string
+	delay time.Duration
+	err   error
+}
+
+func (p *testProvider) Complete(ctx context.Context, prompt string) (string, error) {
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if p.err != nil {
+		return "", p.err
+	}
+	return p.name + ": " + prompt, nil
+}
+
+func TestRacerComplete(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		primary, secondary *testProvider
+		accept             func(string) error
+		want               string
+	}{
+		"primary wins": {
+			primary:   &testProvider{name: "primary"},
+			secondary: &testProvider{name: "secondary"},
+			want:      "primary: hello",
+		},
+		"slow primary": {
+			primary:   &testProvider{name: "primary", delay: time.Second},
+			secondary: &testProvider{name: "secondary"},
+			want:      "secondary: hello",
+		},
+		"failed primary": {
+			primary:   &testProvider{name: "primary", err: errors.New("overloaded")},
+			secondary: &testProvider{name: "secondary", delay: 20 * time.Millisecond},
+			want:      "secondary: hello",
+		},
+		"rejected primary": {
+			primary:   &testProvider{name: "primary"},
+			secondary: &testProvider{name: "secondary", delay: 20 * time.Millisecond},
+			accept: func(s string) error {
+				if strings.HasPrefix(s, "primary") {
+					return errors.New("malformed")
+				}
+				return nil
+			},
+			want: "secondary: hello",
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &Racer[string, string]{
+				Providers: []Provider[string, string]{tc.primary, tc.secondary},
+				Patience:  50 * time.Millisecond,
+				Accept:    tc.accept,
+			}
+			start := time.Now()
+			got, err := r.Complete(context.Background(), "hello")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected completion %q, got %q", tc.want, got)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("expected slow provider to be raced, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestRacerCost(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	costs := map[int]error{}
+	done := make(chan struct{}, 2)
+	r := &Racer[string, string]{
+		Providers: []Provider[string, string]{
+			&testProvider{name: "primary", delay: time.Second},
+			&testProvider{name: "secondary"},
+		},
+		Patience: 10 * time.Millisecond,
+		Cost: func(provider int, _ string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			costs[provider] = err
+			done <- struct{}{}
+		},
+	}
+	if _, err := r.Complete(context.Background(), "hello"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The canceled request to the primary is accounted for too
+	<-done
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	if err := costs[0]; err != context.Canceled {
+		t.Errorf("expected primary cost with err = %v, got %v", context.Canceled, err)
+	}
+	if err, ok := costs[1]; !ok || err != nil {
+		t.Errorf("expected secondary cost with err = nil, got %v (ok = %v)", err, ok)
+	}
+}
+
+func TestRacerAllRejected(t *testing.T) {
+	t.Parallel()
+
+	malformed := errors.New("malformed")
+	r := &Racer[string, string]{
+		Providers: []Provider[string, string]{&testProvider{name: "primary"}},
+		Patience:  10 * time.Millisecond,
+		Accept:    func(string) error { return malformed },
+	}
+	_, err := r.Complete(context.Background(), "hello")
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("expected RejectedError, got %v", err)
+	}
+	if rejected.Provider != 0 || !errors.Is(err, malformed) {
+		t.Errorf("expected provider 0 rejected with %v, got %v", malformed, err)
+	}
+}
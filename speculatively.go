@@ -6,6 +6,8 @@ package speculatively
 
 import (
 	"context"
+	"runtime/trace"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,39 +19,441 @@ type Thunk[T any] func(context.Context) (T, error)
 //
 // Note that for Do to respect context cancelations, the given Thunk must
 // respect them.
-func Do[T any](ctx context.Context, patience time.Duration, thunk Thunk[T]) (T, error) {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+func Do[T any](ctx context.Context, patience time.Duration, thunk Thunk[T], opts ...Option) (T, error) {
+	return run(ctx, patience, newConfig(opts), func(int) (task[T], bool) {
+		return task[T]{thunk: thunk}, true
+	})
+}
+
+// task is a Thunk to be executed as an attempt, along with the target it will
+// be executed against, if any.
+type task[T any] struct {
+	thunk  Thunk[T]
+	target interface{}
 
-	out := make(chan result[T])
-	go runThunk(ctx, thunk, out)
+	// admitted reports whether the thunk already holds its weight of the
+	// call's Semaphore, if any
+	admitted bool
+}
+
+// run implements the scheduling shared by Do and its variants.  The next func
+// is called with the index of each attempt to be launched and returns the
+// task to execute, or false if no further attempts should be launched.
+func run[T any](ctx context.Context, patience time.Duration, cfg *config, next func(attempt int) (task[T], bool)) (T, error) {
+	c, end := startCall(ctx, cfg, next)
+	defer end()
+	ctx, cfg = c.ctx, c.cfg
+	if t, ok := c.peek(); ok {
+		c.launch(t)
+	}
 
-	ticker := time.NewTicker(patience)
+	every := cfg.patience(patience)
+	ticker := cfg.newTicker(every)
 	defer ticker.Stop()
 
-	for step := 1; ; step++ {
+	for {
 		select {
-		case r := <-out:
-			return r.val, r.err
+		case r := <-c.out:
+			delete(c.running, r.attempt)
+			c.delivered[r.attempt] = r.elapsed
+			c.errs[r.attempt] = r.err
+			switch {
+			case r.err == nil && cfg.isStale(r.val):
+				// Keep the freshest stale result as a fallback while racing
+				// for a fresh one
+				c.keep(r)
+				if c.replace() {
+					continue
+				}
+				r = *c.stale
+			case r.err != nil && cfg.retryable != nil && cfg.retryable(r.err):
+				if c.replace() {
+					continue
+				}
+			}
+			return c.finish(r)
 		case <-ctx.Done():
+			c.canceled = true
+			if c.stale != nil {
+				return c.finish(*c.stale)
+			}
+			c.account(-1)
+			c.end(-1, -1, ctx.Err())
 			var zero T
 			return zero, ctx.Err()
-		case <-ticker.C:
-			go runThunk(ctx, thunk, out)
+		case tick := <-ticker.C():
+			if c.info.holding(tick) {
+				// The attempts are waiting on the consumer, and the
+				// release of their hold restarts the wait
+				continue
+			}
+			if !c.hedge() {
+				ticker.Stop()
+			}
+		case <-c.info.hedgeNow:
+			// Restart the wait for the next hedge from now
+			if c.hedge() {
+				ticker.Reset(every)
+			} else {
+				ticker.Stop()
+			}
+		case <-c.info.progress:
+			ticker.Reset(every)
+		case <-c.grants():
+			// A token was granted to the queued hedge, which restarts the
+			// wait for the next one
+			if c.grant() {
+				ticker.Reset(every)
+			} else {
+				ticker.Stop()
+			}
+		}
+	}
+}
+
+// startCall begins a call, whose attempts are given by next, and returns it
+// along with a func to be called once the call ends.
+func startCall[T any](ctx context.Context, cfg *config, next func(attempt int) (task[T], bool)) (*call[T], func()) {
+	if cfg.sampler != nil && !cfg.sampler.sample() {
+		cfg = cfg.unsampled()
+	}
+	var task *trace.Task
+	if cfg.traceName != "" {
+		ctx, task = trace.NewTask(ctx, cfg.traceName)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+
+	if cfg.budget != nil {
+		cfg.budget.deposit()
+	}
+	live := cfg.stats.call(cfg.now())
+
+	c := &call[T]{
+		ctx:       ctx,
+		cfg:       cfg,
+		next:      next,
+		out:       make(chan result[T]),
+		running:   map[int]bool{},
+		delivered: map[int]time.Duration{},
+		errs:      map[int]error{},
+		start:     cfg.now(),
+		id:        atomic.AddUint64(&callSeq, 1),
+		info: &callInfo{
+			cfg:         cfg,
+			maxAttempts: cfg.attemptLimit(),
+			live:        live,
+			hedgeNow:    make(chan struct{}, 1),
+			progress:    make(chan struct{}, 1),
+		},
+	}
+	if cfg.newCheckpoints != nil {
+		c.info.checkpoints = cfg.newCheckpoints()
+	}
+	if cfg.idempotencyKeys {
+		c.info.idempotencyKey = newIdempotencyKey()
+	}
+	return c, func() {
+		// Give up the call's place in its Budget's queue of hedges, if
+		// any, and record when the call ends, before its remaining
+		// attempts are canceled
+		if c.waiter != nil {
+			cfg.budget.dequeue(c.waiter)
+		}
+		atomic.StoreInt64(&c.info.ended, cfg.now().UnixNano())
+		live.end()
+		cancel()
+		if task != nil {
+			task.End()
+		}
+	}
+}
+
+// grant launches the queued hedge to which a token was just granted, unless
+// it is suppressed for another reason, and reports whether there may be
+// further attempts to launch.
+func (c *call[T]) grant() bool {
+	c.waiter, c.granted = nil, true
+	more := c.hedge()
+	if c.granted {
+		c.granted = false
+		c.cfg.budget.refund()
+	}
+	return more
+}
+
+// hedge launches the next attempt, unless it is suppressed, and reports
+// whether there may be further attempts to launch.
+func (c *call[T]) hedge() bool {
+	cfg := c.cfg
+	t, ok := c.peek()
+	if !ok {
+		return false
+	}
+	if cfg.errorGate != nil && !cfg.errorGate.openAt(cfg.now()) {
+		c.suppress(t, SuppressedByErrorGate)
+		return true
+	}
+	if cfg.inflight != nil {
+		if !cfg.inflight.acquire() {
+			c.suppress(t, SuppressedByInflightLimit)
+			return true
+		}
+		t.thunk = releasing(t.thunk, cfg.inflight.release)
+	}
+	if cfg.semaphore != nil {
+		if !cfg.semaphore.TryAcquire(cfg.weight) {
+			if cfg.inflight != nil {
+				cfg.inflight.release()
+			}
+			c.suppress(t, SuppressedBySemaphore)
+			return true
+		}
+		weight := cfg.weight
+		t.thunk = releasing(t.thunk, func() { cfg.semaphore.Release(weight) })
+		t.admitted = true
+	}
+	if cfg.budget != nil && !c.granted && !cfg.budget.withdraw() {
+		if cfg.inflight != nil {
+			cfg.inflight.release()
+		}
+		if t.admitted {
+			cfg.semaphore.Release(cfg.weight)
+		}
+		c.suppress(t, SuppressedByBudget)
+		if cfg.queueHedges && c.waiter == nil {
+			deadline, _ := c.ctx.Deadline()
+			c.waiter = cfg.budget.enqueue(cfg.hedgePriority, deadline)
+		}
+		return true
+	}
+	c.granted = false
+	c.launch(t)
+	return true
+}
+
+// grants returns the channel on which a token is granted to the call's
+// queued hedge, or nil if no hedge is queued.
+func (c *call[T]) grants() <-chan struct{} {
+	if c.waiter == nil {
+		return nil
+	}
+	return c.waiter.granted
+}
+
+// callSeq is the ID of the most recent call.
+var callSeq uint64
+
+// call holds the state of a single invocation of run.
+type call[T any] struct {
+	ctx      context.Context
+	cfg      *config
+	next     func(attempt int) (task[T], bool)
+	out      chan result[T]
+	attempts []Attempt
+	running  map[int]bool
+	stale    *result[T]
+	info     *callInfo
+
+	// delivered holds the elapsed time and errs the error of every attempt
+	// whose result was received, by attempt index
+	delivered map[int]time.Duration
+	errs      map[int]error
+
+	start time.Time
+	id    uint64
+
+	// canceled reports whether the call ended because its context was
+	// done, and suppressed holds the last hedge that was due but not
+	// launched, if any
+	canceled   bool
+	suppressed *suppressedHedge
+
+	// waiter is the call's hedge queued in its Budget by
+	// WithHedgePriority, if any, and granted reports whether a token was
+	// granted to it and not yet used
+	waiter  *hedgeWaiter
+	granted bool
+
+	// phases holds the HTTP phases of every attempt, by attempt index, if
+	// WithHTTPPhases is set
+	phases []*httpPhases
+}
+
+// peek returns the next task to launch, if any.
+func (c *call[T]) peek() (task[T], bool) {
+	if limit := c.info.maxAttempts; limit > 0 && len(c.attempts) >= limit {
+		return task[T]{}, false
+	}
+	return c.next(len(c.attempts))
+}
+
+func (c *call[T]) launch(t task[T]) {
+	if c.cfg.semaphore != nil && !t.admitted {
+		t.thunk = acquiring(t.thunk, c.cfg.semaphore, c.cfg.weight)
+	}
+	a := Attempt{
+		Index:  len(c.attempts),
+		Target: t.target,
+		Start:  c.cfg.now(),
+		Call:   c.id,
+	}
+	c.attempts = append(c.attempts, a)
+	c.running[a.Index] = true
+	atomic.AddInt64(&c.info.launched, 1)
+	ctx := withAttempt(c.ctx, &attemptInfo{attempt: a, call: c.info})
+	if c.cfg.lowPriorityHedges && a.Index > 0 {
+		ctx = ContextWithPriority(ctx, PriorityLow)
+	}
+	if c.cfg.httpPhases {
+		p := &httpPhases{}
+		c.phases = append(c.phases, p)
+		ctx = p.withClientTrace(ctx)
+	}
+	for _, fn := range c.cfg.attemptContext {
+		ctx = fn(ctx, a)
+	}
+	c.cfg.stats.launch(a)
+	c.info.live.launch(a)
+	c.cfg.hooks.launch(a)
+	c.cfg.traceLogf(ctx, "attempt %d launched", a.Index)
+	c.cfg.start(ctx, a, func(ctx context.Context) {
+		runThunk(ctx, c.cfg, a, t.thunk, c.out, c.info)
+	})
+}
+
+// replace replaces an attempt whose result was rejected by launching the next
+// attempt right away, and reports whether the call should keep waiting for
+// further results.
+func (c *call[T]) replace() bool {
+	if t, ok := c.peek(); ok {
+		c.launch(t)
+		return true
+	}
+	return len(c.running) > 0
+}
+
+// keep holds on to a stale result if it is fresher than any previous stale
+// result, cleaning up whichever result is discarded.
+func (c *call[T]) keep(r result[T]) {
+	if c.stale == nil {
+		c.stale = &r
+		return
+	}
+	if c.cfg.fresher(r.val, c.stale.val) {
+		c.cfg.discard(c.stale.val)
+		c.stale = &r
+		return
+	}
+	c.cfg.discard(r.val)
+}
+
+// finish ends the call with the given result.
+func (c *call[T]) finish(r result[T]) (T, error) {
+	if r.err != nil && c.stale != nil {
+		r = *c.stale
+	}
+	if c.stale != nil && r.attempt != c.stale.attempt {
+		c.cfg.discard(c.stale.val)
+	}
+	c.account(r.attempt)
+	if r.err == nil {
+		if c.cfg.adaptiveAttempts != nil {
+			c.cfg.adaptiveAttempts.record(r.attempt)
+		}
+		c.cfg.stats.win(c.attempts[r.attempt], r.elapsed)
+		c.cfg.hooks.winner(c.attempts[r.attempt])
+		if c.cfg.equal != nil {
+			c.info.won.Store(wonResult{attempt: c.attempts[r.attempt], val: r.val})
+		}
+		if c.cfg.fence != nil {
+			c.cfg.fence(c.info.idempotencyKey, c.attempts[r.attempt])
+		}
+		c.cfg.traceLogf(c.ctx, "attempt %d won", r.attempt)
+	}
+	for i := range c.running {
+		c.cfg.hooks.loser(c.attempts[i])
+	}
+	if r.err == nil {
+		c.end(r.attempt, -1, nil)
+	} else {
+		c.end(-1, r.attempt, r.err)
+	}
+	return r.val, r.err
+}
+
+// end records the outcome of the call, given the index of the winning
+// attempt or of the attempt whose error was returned, or -1, and the error
+// returned.
+func (c *call[T]) end(winner, failed int, err error) {
+	if c.cfg.hooks.hasTermination() {
+		for i, a := range c.attempts {
+			c.cfg.hooks.terminated(a, c.termination(i, winner, failed))
+		}
+		if s := c.suppressed; s != nil && s.attempt.Index == len(c.attempts) {
+			c.cfg.hooks.terminated(s.attempt, TerminationSuppressed)
+		}
+	}
+	if e := c.cfg.exemplars; e != nil {
+		now := c.cfg.now()
+		if e.slow(now.Sub(c.start)) {
+			e.record(c.report(now, winner, failed, err))
+		}
+	}
+}
+
+// account records every received result other than the winner's as wasted
+// work.  Attempts still running are accounted for by runThunk once they exit.
+func (c *call[T]) account(winner int) {
+	for i, elapsed := range c.delivered {
+		if i != winner {
+			c.cfg.stats.waste(c.attempts[i], elapsed)
 		}
 	}
 }
 
 type result[T any] struct {
-	val T
-	err error
+	val     T
+	err     error
+	attempt int
+	elapsed time.Duration
 }
 
-func runThunk[T any](ctx context.Context, thunk Thunk[T], out chan result[T]) {
-	var r result[T]
-	r.val, r.err = thunk(ctx)
+func runThunk[T any](ctx context.Context, cfg *config, a Attempt, thunk Thunk[T], out chan result[T], info *callInfo) {
+	defer info.live.exit(a)
+	r := result[T]{attempt: a.Index}
+	cfg.withProfileLabels(ctx, a, func(ctx context.Context) {
+		cfg.traceRegion(ctx, a, func() {
+			r.val, r.err = thunk(ctx)
+		})
+	})
+	r.elapsed = cfg.since(a.Start)
+	if ended := atomic.LoadInt64(&info.ended); ended != 0 {
+		// The attempt was still running when its call ended
+		lag := cfg.since(time.Unix(0, ended))
+		cfg.stats.loserExit(lag)
+		cfg.hooks.loserExit(a, lag)
+	}
+	cfg.hooks.done(a, r.elapsed, r.err)
+	if r.err != nil {
+		cfg.hooks.failed(cfg, a, r.err)
+	}
+	if r.err == nil && cfg.tracker != nil {
+		cfg.tracker.Record(r.elapsed)
+	}
+	// Attempts canceled because the call ended say nothing about the health
+	// of the dependency
+	if cfg.errorGate != nil && ctx.Err() == nil {
+		cfg.errorGate.recordAt(cfg.now(), r.err != nil)
+	}
 	select {
 	case out <- r:
-	default:
+	case <-ctx.Done():
+		cfg.stats.waste(a, r.elapsed)
+		if r.err == nil {
+			cfg.stats.late()
+			cfg.hooks.late(a, r.elapsed)
+			cfg.checkConsistency(info, a, r.val)
+			cfg.discard(r.val)
+		}
 	}
 }
@@ -0,0 +1,172 @@
+/*
+Package speculativelytest provides helpers to unit test hedging
+configurations deterministically, without real sleeps: a fake Clock that
+drives the launch of hedges, scripted Thunks whose latencies and results are
+set in advance, and assertions about the attempts they observed.
+
+A typical test runs a call in the background and advances the clock to
+launch hedges:
+
+	clock := speculativelytest.NewClock(time.Time{})
+	script := speculativelytest.NewScript(clock,
+		speculativelytest.Step[string]{Latency: time.Hour},
+		speculativelytest.Step[string]{Latency: 10 * time.Millisecond, Val: "hedge"},
+	)
+	go speculatively.Do(ctx, 50*time.Millisecond, script.Thunk, speculatively.WithClock(clock))
+	clock.BlockUntil(2)                  // the ticker and the first attempt
+	clock.Advance(50 * time.Millisecond) // launches the hedge
+	clock.BlockUntil(3)                  // ... and the hedge
+	clock.Advance(10 * time.Millisecond) // completes the hedge
+	script.AssertAttempts(t, 2)
+	script.AssertCanceled(t, 0)
+*/
+package speculativelytest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Clock is a fake speculatively.Clock whose time only moves when advanced.
+//
+// A Clock is safe for concurrent use.
+type Clock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	timers  []*timer
+}
+
+// timer is a pending timer or ticker.
+type timer struct {
+	c        chan time.Time
+	deadline time.Time
+	period   time.Duration
+}
+
+// NewClock creates a Clock set to the given time.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now implements speculatively.Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker implements speculatively.Clock.
+func (c *Clock) NewTicker(d time.Duration) speculatively.Ticker {
+	t := &timer{c: make(chan time.Time, 1), period: d}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schedule(t, c.now.Add(d))
+	return &ticker{clock: c, t: t}
+}
+
+// After returns a channel that receives the time once the clock has been
+// advanced by d, like time.After.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	ch, _ := c.timer(d)
+	return ch
+}
+
+// timer returns a channel that receives the time once the clock has been
+// advanced by d, and a func that cancels the timer.
+func (c *Clock) timer(d time.Duration) (<-chan time.Time, func()) {
+	t := &timer{c: make(chan time.Time, 1)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schedule(t, c.now.Add(d))
+	return t.c, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.stop(t)
+	}
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker due
+// along the way in order.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].deadline.After(end) {
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		c.now = t.deadline
+		select {
+		case t.c <- c.now:
+		default:
+			// Like time.Ticker, drop ticks for slow receivers
+		}
+		if t.period > 0 {
+			c.schedule(t, t.deadline.Add(t.period))
+		}
+	}
+	c.now = end
+	c.changed.Broadcast()
+}
+
+// BlockUntil waits until at least n timers and tickers are pending, e.g.
+// until a call has started its ticker and a scripted attempt is waiting for
+// its latency to elapse, so that advancing the clock fires them.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+// schedule adds a timer to fire at the given deadline.  It must be called
+// with c.mu held.
+func (c *Clock) schedule(t *timer, deadline time.Time) {
+	t.deadline = deadline
+	c.timers = append(c.timers, t)
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+	c.changed.Broadcast()
+}
+
+// stop removes a timer.  It must be called with c.mu held.
+func (c *Clock) stop(t *timer) {
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.changed.Broadcast()
+			return
+		}
+	}
+}
+
+// ticker is a speculatively.Ticker driven by a Clock.
+type ticker struct {
+	clock *Clock
+	t     *timer
+}
+
+func (t *ticker) C() <-chan time.Time {
+	return t.t.c
+}
+
+func (t *ticker) Reset(d time.Duration) {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.clock.stop(t.t)
+	t.t.period = d
+	t.clock.schedule(t.t, t.clock.now.Add(d))
+}
+
+func (t *ticker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.clock.stop(t.t)
+}
@@ -0,0 +1,77 @@
+package speculativelytest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	t.Parallel()
+
+	start := time.Unix(1000, 0)
+	c := NewClock(start)
+	after := c.After(30 * time.Millisecond)
+	ticker := c.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	c.Advance(20 * time.Millisecond)
+	select {
+	case now := <-ticker.C():
+		if !now.Equal(start.Add(20 * time.Millisecond)) {
+			t.Errorf("expected tick at %s, got %s", start.Add(20*time.Millisecond), now)
+		}
+	default:
+		t.Fatalf("expected ticker to tick")
+	}
+	select {
+	case <-after:
+		t.Fatalf("expected timer not to fire yet")
+	default:
+	}
+
+	c.Advance(25 * time.Millisecond)
+	select {
+	case now := <-after:
+		if !now.Equal(start.Add(30 * time.Millisecond)) {
+			t.Errorf("expected timer to fire at %s, got %s", start.Add(30*time.Millisecond), now)
+		}
+	default:
+		t.Fatalf("expected timer to fire")
+	}
+	select {
+	case <-ticker.C():
+	default:
+		t.Fatalf("expected ticker to tick again")
+	}
+	if now := c.Now(); !now.Equal(start.Add(45 * time.Millisecond)) {
+		t.Errorf("expected now = %s, got %s", start.Add(45*time.Millisecond), now)
+	}
+
+	// Resetting restarts the period from now
+	ticker.Reset(time.Second)
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatalf("expected reset ticker not to tick yet")
+	default:
+	}
+}
+
+func TestClockBlockUntil(t *testing.T) {
+	t.Parallel()
+
+	c := NewClock(time.Time{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.BlockUntil(2)
+	}()
+	c.After(time.Second)
+	select {
+	case <-done:
+		t.Fatalf("expected BlockUntil to wait for a second timer")
+	case <-time.After(10 * time.Millisecond):
+	}
+	c.After(time.Second)
+	<-done
+}
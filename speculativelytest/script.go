@@ -0,0 +1,139 @@
+package speculativelytest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Step is the scripted outcome of an attempt.
+type Step[T any] struct {
+	// Latency is how long the attempt takes, according to the Script's
+	// Clock, before returning Val and Err.
+	Latency time.Duration
+
+	// Val and Err are the result of the attempt.
+	Val T
+	Err error
+
+	// Hang makes the attempt wait until its context is done, ignoring
+	// Latency, Val and Err.  Unlike a long Latency, a hanging attempt does
+	// not count towards Clock.BlockUntil.
+	Hang bool
+}
+
+// Script is a Thunk whose attempts follow scripted Steps, and which records
+// how each attempt ended.
+//
+// A Script is safe for concurrent use.
+type Script[T any] struct {
+	clock *Clock
+	steps []Step[T]
+
+	mu       sync.Mutex
+	attempts int
+	canceled []int
+	wg       sync.WaitGroup
+}
+
+// NewScript creates a Script whose nth attempt follows the nth Step, and
+// whose further attempts follow the last Step.  Latencies elapse according
+// to the given Clock, or in real time if it is nil.
+func NewScript[T any](clock *Clock, steps ...Step[T]) *Script[T] {
+	return &Script[T]{clock: clock, steps: steps}
+}
+
+// Thunk executes the Step of the attempt to which ctx belongs.  Use it as a
+// speculatively.Thunk, e.g. via the method value script.Thunk.
+func (s *Script[T]) Thunk(ctx context.Context) (T, error) {
+	s.mu.Lock()
+	index := s.attempts
+	s.attempts++
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+
+	if a, ok := speculatively.AttemptFromContext(ctx); ok {
+		index = a.Index
+	}
+	var step Step[T]
+	if len(s.steps) > 0 {
+		step = s.steps[len(s.steps)-1]
+		if index < len(s.steps) {
+			step = s.steps[index]
+		}
+	}
+
+	var elapsed <-chan time.Time
+	switch {
+	case step.Hang:
+	case s.clock != nil:
+		var stop func()
+		elapsed, stop = s.clock.timer(step.Latency)
+		defer stop()
+	default:
+		timer := time.NewTimer(step.Latency)
+		defer timer.Stop()
+		elapsed = timer.C
+	}
+	select {
+	case <-elapsed:
+		return step.Val, step.Err
+	case <-ctx.Done():
+		s.mu.Lock()
+		s.canceled = append(s.canceled, index)
+		s.mu.Unlock()
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Attempts returns the number of attempts executed so far.
+func (s *Script[T]) Attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts
+}
+
+// Wait waits for every attempt executed so far to return.
+func (s *Script[T]) Wait() {
+	s.wg.Wait()
+}
+
+// Canceled returns the indexes of the attempts that were canceled, once
+// every attempt executed so far has returned.
+func (s *Script[T]) Canceled() []int {
+	s.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.canceled...)
+}
+
+// AssertAttempts fails the test unless exactly n attempts were executed.
+func (s *Script[T]) AssertAttempts(t testing.TB, n int) {
+	t.Helper()
+	if got := s.Attempts(); got != n {
+		t.Errorf("expected %d attempts, got %d", n, got)
+	}
+}
+
+// AssertCanceled fails the test unless exactly the attempts with the given
+// indexes were canceled, once every attempt has returned.
+func (s *Script[T]) AssertCanceled(t testing.TB, indexes ...int) {
+	t.Helper()
+	got := s.Canceled()
+	want := map[int]bool{}
+	for _, i := range indexes {
+		want[i] = true
+	}
+	match := len(got) == len(want)
+	for _, i := range got {
+		match = match && want[i]
+	}
+	if !match {
+		t.Errorf("expected canceled attempts %v, got %v", indexes, got)
+	}
+}
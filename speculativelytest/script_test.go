@@ -0,0 +1,75 @@
+package speculativelytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+func TestScript(t *testing.T) {
+	t.Parallel()
+
+	clock := NewClock(time.Time{})
+	script := NewScript(clock,
+		Step[string]{Latency: time.Hour},
+		Step[string]{Latency: 10 * time.Millisecond, Val: "hedge"},
+	)
+
+	type result struct {
+		val string
+		err error
+	}
+	results := make(chan result)
+	go func() {
+		val, err := speculatively.Do(context.Background(), 50*time.Millisecond, script.Thunk,
+			speculatively.WithClock(clock), speculatively.WithMaxAttempts(3))
+		results <- result{val, err}
+	}()
+
+	clock.BlockUntil(2)
+	clock.Advance(50 * time.Millisecond)
+	clock.BlockUntil(3)
+	clock.Advance(10 * time.Millisecond)
+
+	r := <-results
+	if r.err != nil {
+		t.Fatalf("unexpected error: %s", r.err)
+	}
+	if r.val != "hedge" {
+		t.Errorf("expected val = %q, got %q", "hedge", r.val)
+	}
+	script.AssertAttempts(t, 2)
+	script.AssertCanceled(t, 0)
+}
+
+func TestScriptRealTime(t *testing.T) {
+	t.Parallel()
+
+	errBoom := errors.New("boom")
+	script := NewScript(nil, Step[int]{Err: errBoom}, Step[int]{Val: 2})
+
+	// Without a Clock, attempts beyond the script repeat the last Step
+	for i, want := range []int{0, 2, 2} {
+		val, err := script.Thunk(context.Background())
+		if val != want || (i == 0) != (err == errBoom) {
+			t.Errorf("expected attempt %d to return %d, got %d, %v", i, want, val, err)
+		}
+	}
+	script.AssertAttempts(t, 3)
+	script.AssertCanceled(t)
+}
+
+func TestScriptHang(t *testing.T) {
+	t.Parallel()
+
+	script := NewScript(NewClock(time.Time{}), Step[int]{Hang: true})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := script.Thunk(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected err = %v, got %v", context.DeadlineExceeded, err)
+	}
+	script.AssertCanceled(t, 0)
+}
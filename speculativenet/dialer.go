@@ -0,0 +1,147 @@
+/*
+Package speculativenet provides speculative execution helpers for network
+connections.
+*/
+package speculativenet
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Dialer races connection attempts over multiple network paths, identified by
+// the local address each attempt binds to (e.g. to race a VPN interface
+// against a direct route on a multi-homed host).
+//
+// The first path is dialed immediately, and each subsequent path is dialed
+// after waiting for Patience, or as soon as a previous attempt fails.  The
+// first established connection is returned and all others are closed.
+//
+// The path that won most recently is remembered and tried first on
+// subsequent dials.
+//
+// DialAny races connection attempts across multiple addresses of the same
+// target instead.
+type Dialer struct {
+	// Dialer is used to make each connection attempt, with its LocalAddr
+	// replaced by the path being tried.  If nil, a zero net.Dialer is used.
+	Dialer *net.Dialer
+
+	// Patience is how long to wait for an attempt to connect before dialing
+	// the next path.
+	Patience time.Duration
+
+	// LocalAddrs are the local addresses to dial from, in order of
+	// preference.  A nil address lets the system choose.  If empty, only the
+	// system's default path is used.
+	LocalAddrs []net.Addr
+
+	// OnWin is optionally called with the local address of the path that
+	// won each dial.
+	OnWin func(net.Addr)
+
+	preferred    net.Addr
+	hasPreferred bool
+	mu           sync.Mutex
+}
+
+// Preferred returns the local address of the path that won the most recent
+// dial, which is nil if the system chose it.  The boolean is false if no dial
+// has succeeded yet.
+func (d *Dialer) Preferred() (net.Addr, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.preferred, d.hasPreferred
+}
+
+// DialContext connects to the address on the named network, racing
+// connection attempts across d's paths.  See net.Dialer.DialContext for the
+// meaning of network and address.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	type dialed struct {
+		conn net.Conn
+		path net.Addr
+	}
+
+	replicas := speculatively.Replicas[net.Addr]{List: d.paths()}
+	winner, err := speculatively.DoReplicas(ctx, d.Patience, replicas, func(ctx context.Context, path net.Addr) (dialed, error) {
+		conn, err := d.dial(ctx, network, address, path)
+		return dialed{conn, path}, err
+	},
+		speculatively.WithRetryable(func(error) bool { return true }),
+		speculatively.WithCleanup(func(d dialed) { d.conn.Close() }),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.preferred = winner.path
+	d.hasPreferred = true
+	d.mu.Unlock()
+	if d.OnWin != nil {
+		d.OnWin(winner.path)
+	}
+	return winner.conn, nil
+}
+
+// DialAny connects to any of the given addresses on the named network, e.g.
+// the addresses a host name resolves to, racing connection attempts across
+// them in the style of Happy Eyeballs (RFC 8305).  The first address is
+// dialed immediately, and each subsequent address is dialed after waiting
+// for Patience, or as soon as a previous attempt fails.  The first
+// established connection is returned and all others are closed.
+//
+// Every address is dialed from the preferred path, i.e. the first of
+// LocalAddrs unless another path won a previous dial.
+func (d *Dialer) DialAny(ctx context.Context, network string, addresses ...string) (net.Conn, error) {
+	path := d.paths()[0]
+	replicas := speculatively.Replicas[string]{List: addresses}
+	return speculatively.DoReplicas(ctx, d.Patience, replicas, func(ctx context.Context, address string) (net.Conn, error) {
+		return d.dial(ctx, network, address, path)
+	},
+		speculatively.WithRetryable(func(error) bool { return true }),
+		speculatively.WithCleanup(func(conn net.Conn) { conn.Close() }),
+	)
+}
+
+// dial makes a single connection attempt from the given path.
+func (d *Dialer) dial(ctx context.Context, network, address string, path net.Addr) (net.Conn, error) {
+	var dialer net.Dialer
+	if d.Dialer != nil {
+		dialer = *d.Dialer
+	}
+	dialer.LocalAddr = path
+	return dialer.DialContext(ctx, network, address)
+}
+
+// paths returns the local addresses to dial from, with the preferred path
+// first.
+func (d *Dialer) paths() []net.Addr {
+	if len(d.LocalAddrs) == 0 {
+		return []net.Addr{nil}
+	}
+	preferred, ok := d.Preferred()
+	if !ok {
+		return d.LocalAddrs
+	}
+	paths := make([]net.Addr, 0, len(d.LocalAddrs))
+	paths = append(paths, preferred)
+	for _, path := range d.LocalAddrs {
+		if !sameAddr(path, preferred) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func sameAddr(a, b net.Addr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Network() == b.Network() && a.String() == b.String()
+}
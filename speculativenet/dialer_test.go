@@ -0,0 +1,179 @@
+package speculativenet
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newListener(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return ln
+}
+
+func TestDialerPaths(t *testing.T) {
+	t.Parallel()
+
+	ln := newListener(t)
+
+	// A local address of the wrong type fails immediately, so the working
+	// path must be tried next rather than failing the dial
+	broken := &net.UDPAddr{IP: net.ParseIP("127.0.0.1")}
+	working := &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}
+
+	var won net.Addr
+	d := &Dialer{
+		Patience:   time.Second,
+		LocalAddrs: []net.Addr{broken, working},
+		OnWin:      func(addr net.Addr) { won = addr },
+	}
+
+	conn, err := d.DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conn.Close()
+
+	if won != working {
+		t.Errorf("expected winning path %s, got %s", working, won)
+	}
+	if preferred, ok := d.Preferred(); !ok || preferred != working {
+		t.Errorf("expected preferred path %s, got %s", working, preferred)
+	}
+	if paths := d.paths(); len(paths) != 2 || paths[0] != working || paths[1] != broken {
+		t.Errorf("expected preferred path to be tried first, got %v", paths)
+	}
+}
+
+func TestDialerSystemPathWins(t *testing.T) {
+	t.Parallel()
+
+	ln := newListener(t)
+
+	// The nil path lets the system choose, and must be remembered like any
+	// other path when it wins
+	broken := &net.UDPAddr{IP: net.ParseIP("127.0.0.1")}
+	d := &Dialer{
+		Patience:   time.Second,
+		LocalAddrs: []net.Addr{broken, nil},
+	}
+
+	if _, ok := d.Preferred(); ok {
+		t.Errorf("expected no preferred path before the first dial")
+	}
+
+	conn, err := d.DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conn.Close()
+
+	if preferred, ok := d.Preferred(); !ok || preferred != nil {
+		t.Errorf("expected preferred path to be the system's, got %v (ok = %v)", preferred, ok)
+	}
+	if paths := d.paths(); len(paths) != 2 || paths[0] != nil || paths[1] != broken {
+		t.Errorf("expected system path to be tried first, got %v", paths)
+	}
+}
+
+func TestDialerDefaultPath(t *testing.T) {
+	t.Parallel()
+
+	ln := newListener(t)
+	d := &Dialer{Patience: time.Second}
+
+	conn, err := d.DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conn.Close()
+}
+
+func TestDialerAllPathsFail(t *testing.T) {
+	t.Parallel()
+
+	ln := newListener(t)
+	d := &Dialer{
+		Patience: time.Second,
+		LocalAddrs: []net.Addr{
+			&net.UDPAddr{IP: net.ParseIP("127.0.0.1")},
+			&net.UnixAddr{Name: "broken", Net: "unix"},
+		},
+	}
+
+	if _, err := d.DialContext(context.Background(), "tcp", ln.Addr().String()); err == nil {
+		t.Fatalf("expected error when all paths fail")
+	}
+}
+
+func TestDialerDialAny(t *testing.T) {
+	t.Parallel()
+
+	slow, fast := newListener(t), newListener(t)
+	d := &Dialer{
+		Dialer: &net.Dialer{
+			// Stall connections to the slow listener
+			Control: func(_, address string, _ syscall.RawConn) error {
+				if address == slow.Addr().String() {
+					time.Sleep(time.Second)
+				}
+				return nil
+			},
+		},
+		Patience: 20 * time.Millisecond,
+	}
+
+	start := time.Now()
+	conn, err := d.DialAny(context.Background(), "tcp", slow.Addr().String(), fast.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer conn.Close()
+	if got := conn.RemoteAddr().String(); got != fast.Addr().String() {
+		t.Errorf("expected connection to %s, got %s", fast.Addr(), got)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected fast address to be dialed after patience, took %s", elapsed)
+	}
+}
+
+func TestDialerDialAnyFailover(t *testing.T) {
+	t.Parallel()
+
+	// Nothing listens on a closed listener's address, so dialing it fails
+	// right away and the next address must be tried without waiting
+	closed := newListener(t)
+	closed.Close()
+	ln := newListener(t)
+	d := &Dialer{Patience: time.Second}
+
+	start := time.Now()
+	conn, err := d.DialAny(context.Background(), "tcp", closed.Addr().String(), ln.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected failed dial to be retried right away, took %s", elapsed)
+	}
+
+	if _, err := d.DialAny(context.Background(), "tcp", closed.Addr().String()); err == nil {
+		t.Errorf("expected error when all addresses fail")
+	}
+}
@@ -0,0 +1,84 @@
+package speculativenet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Resolver races DNS lookups across multiple resolvers, e.g. the system's
+// resolver and a few custom DNS servers, since the tail latency of DNS
+// lookups is often dominated by a single slow or lossy server.
+//
+// The first resolver is queried immediately, and each subsequent resolver
+// after waiting for Patience, or as soon as a previous lookup fails.  The
+// first answer is returned and all other lookups are canceled.  A host that
+// does not exist is an answer, so it ends the lookup right away.
+type Resolver struct {
+	// Resolvers are the resolvers to query, in order of preference.  A nil
+	// resolver is net.DefaultResolver.  If empty, only net.DefaultResolver
+	// is used.
+	Resolvers []*net.Resolver
+
+	// Patience is how long to wait for a resolver to answer before querying
+	// the next one.
+	Patience time.Duration
+
+	// Options customize the hedging of every lookup, e.g. to share a Budget.
+	Options []speculatively.Option
+}
+
+// DNSServer returns a resolver that sends its queries to the DNS server at
+// the given address, e.g. "1.1.1.1:53", rather than to the servers
+// configured on the system.
+func DNSServer(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// LookupHost looks up the given host, returning a slice of its addresses.
+// See net.Resolver.LookupHost.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return lookup(ctx, r, func(ctx context.Context, resolver *net.Resolver) ([]string, error) {
+		return resolver.LookupHost(ctx, host)
+	})
+}
+
+// LookupIPAddr looks up the given host, returning its IPv4 and IPv6
+// addresses.  See net.Resolver.LookupIPAddr.
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return lookup(ctx, r, func(ctx context.Context, resolver *net.Resolver) ([]net.IPAddr, error) {
+		return resolver.LookupIPAddr(ctx, host)
+	})
+}
+
+func lookup[T any](ctx context.Context, r *Resolver, thunk speculatively.ReplicaThunk[*net.Resolver, T]) (T, error) {
+	resolvers := r.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = []*net.Resolver{nil}
+	}
+	opts := append([]speculatively.Option{
+		speculatively.WithRetryable(func(err error) bool { return !notFound(err) }),
+	}, r.Options...)
+	replicas := speculatively.Replicas[*net.Resolver]{List: resolvers}
+	return speculatively.DoReplicas(ctx, r.Patience, replicas, func(ctx context.Context, resolver *net.Resolver) (T, error) {
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		return thunk(ctx, resolver)
+	}, opts...)
+}
+
+// notFound reports whether err means that the host does not exist.
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,169 @@
+package speculativenet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestResolver returns a resolver whose queries are answered in memory,
+// after the given delay, with the given IPv4 address or, if ip is nil, with
+// NXDOMAIN.  It also returns the number of queries it received.
+func newTestResolver(ip net.IP, delay time.Duration) (*net.Resolver, *int64) {
+	var queries int64
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go serveDNS(server, ip, delay, &queries)
+			return client, nil
+		},
+	}, &queries
+}
+
+// serveDNS answers DNS queries sent over conn with TCP framing.
+func serveDNS(conn net.Conn, ip net.IP, delay time.Duration, queries *int64) {
+	defer conn.Close()
+	for {
+		var size uint16
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		query := make([]byte, size)
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+		atomic.AddInt64(queries, 1)
+		time.Sleep(delay)
+
+		// The question follows the 12 byte header and is made of the
+		// queried name, its type and its class
+		end := 12
+		for query[end] != 0 {
+			end += int(query[end]) + 1
+		}
+		end += 5
+		qtype := binary.BigEndian.Uint16(query[end-4:])
+
+		resp := append([]byte{}, query[:end]...)
+		binary.BigEndian.PutUint16(resp[2:], 0x8180)
+		binary.BigEndian.PutUint16(resp[6:], 0)  // answers
+		binary.BigEndian.PutUint16(resp[10:], 0) // additional records
+		switch {
+		case ip == nil:
+			binary.BigEndian.PutUint16(resp[2:], 0x8183)
+		case qtype == 1:
+			binary.BigEndian.PutUint16(resp[6:], 1)
+			resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+			resp = append(resp, ip.To4()...)
+		}
+		if err := binary.Write(conn, binary.BigEndian, uint16(len(resp))); err != nil {
+			return
+		}
+		if _, err := conn.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	t.Parallel()
+
+	slowIP, fastIP := net.IPv4(192, 0, 2, 1), net.IPv4(192, 0, 2, 2)
+	testCases := map[string]struct {
+		first, second     net.IP
+		firstDelay        time.Duration
+		wantIP            net.IP
+		wantNotFound      bool
+		wantSecondQueries bool
+	}{
+		"first resolver answers": {
+			first:  slowIP,
+			second: fastIP,
+			wantIP: slowIP,
+		},
+		"slow first resolver": {
+			first:             slowIP,
+			second:            fastIP,
+			firstDelay:        time.Second,
+			wantIP:            fastIP,
+			wantSecondQueries: true,
+		},
+		"not found is an answer": {
+			first:        nil,
+			second:       fastIP,
+			wantNotFound: true,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			first, _ := newTestResolver(tc.first, tc.firstDelay)
+			second, secondQueries := newTestResolver(tc.second, 0)
+			r := &Resolver{
+				Resolvers: []*net.Resolver{first, second},
+				Patience:  50 * time.Millisecond,
+			}
+
+			addrs, err := r.LookupIPAddr(context.Background(), "speculatively.test.")
+			if tc.wantNotFound {
+				if !notFound(err) {
+					t.Fatalf("expected not found error, got %v", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if len(addrs) != 1 || !addrs[0].IP.Equal(tc.wantIP) {
+					t.Errorf("expected addrs [%s], got %v", tc.wantIP, addrs)
+				}
+			}
+			if got := atomic.LoadInt64(secondQueries) > 0; got != tc.wantSecondQueries {
+				t.Errorf("expected second resolver queried = %v, got %v", tc.wantSecondQueries, got)
+			}
+		})
+	}
+}
+
+func TestResolverLookupHost(t *testing.T) {
+	t.Parallel()
+
+	broken := &net.Resolver{
+		PreferGo: true,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("broken")
+		},
+	}
+	working, _ := newTestResolver(net.IPv4(192, 0, 2, 3), 0)
+
+	// A failing resolver is skipped right away rather than failing the
+	// lookup
+	r := &Resolver{Resolvers: []*net.Resolver{broken, working}, Patience: time.Second}
+	start := time.Now()
+	hosts, err := r.LookupHost(context.Background(), "speculatively.test.")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(hosts) != 1 || hosts[0] != "192.0.2.3" {
+		t.Errorf("expected hosts [192.0.2.3], got %v", hosts)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected failed lookup to be retried right away, took %s", elapsed)
+	}
+}
+
+func TestDNSServer(t *testing.T) {
+	t.Parallel()
+
+	r := DNSServer("127.0.0.1:0")
+	if !r.PreferGo || r.Dial == nil {
+		t.Errorf("expected resolver using custom dial func")
+	}
+}
@@ -0,0 +1,122 @@
+package speculativenet
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SRV discovers replicas from DNS SRV records, as the "host:port" addresses
+// of the targets they advertise, for use as the Discovery of
+// speculatively.Replicas.  Addresses are listed in the order of preference
+// established by net.Resolver.LookupSRV, i.e. by priority and randomized by
+// weight.
+//
+// The addresses are cached for TTL, and the last addresses discovered keep
+// being returned if a later lookup fails.
+type SRV struct {
+	// Resolver is used for lookups.  If nil, net.DefaultResolver is used.
+	Resolver *net.Resolver
+
+	// Service, Proto and Name identify the records to look up, as given to
+	// net.Resolver.LookupSRV, e.g. "http", "tcp" and "example.com".
+	Service, Proto, Name string
+
+	// TTL is how long discovered addresses are reused before being looked
+	// up again.  If zero, every call looks them up again.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	addrs   []string
+	expires time.Time
+	lookup  *srvLookup
+}
+
+// srvLookup is a lookup in progress, shared by every call waiting for it.
+type srvLookup struct {
+	done    chan struct{}
+	addrs   []string
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Replicas implements speculatively.Discovery.
+//
+// Concurrent calls share a single lookup, which is not tied to the context
+// of the call that started it: it is only canceled once every call waiting
+// for it has given up, and a call that gives up returns right away.
+func (s *SRV) Replicas(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	if s.addrs != nil && time.Now().Before(s.expires) {
+		defer s.mu.Unlock()
+		return append([]string(nil), s.addrs...), nil
+	}
+	l := s.lookup
+	if l == nil {
+		lookupCtx, cancel := context.WithCancel(context.Background())
+		l = &srvLookup{done: make(chan struct{}), cancel: cancel}
+		s.lookup = l
+		go s.refresh(lookupCtx, l)
+	}
+	l.waiters++
+	s.mu.Unlock()
+
+	select {
+	case <-l.done:
+		if l.err != nil {
+			return nil, l.err
+		}
+		return append([]string(nil), l.addrs...), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if l.waiters--; l.waiters == 0 {
+			// Nobody is left to receive the addresses, so later calls
+			// must look them up afresh
+			s.land(l)
+		}
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// refresh performs the given lookup and caches the addresses it discovers.
+// If it fails, the last addresses discovered, if any, are its result.
+func (s *SRV) refresh(ctx context.Context, l *srvLookup) {
+	resolver := s.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, records, err := resolver.LookupSRV(ctx, s.Service, s.Proto, s.Name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer close(l.done)
+	s.land(l)
+	if err != nil {
+		if s.addrs != nil {
+			l.addrs = s.addrs
+			return
+		}
+		l.err = err
+		return
+	}
+	addrs := make([]string, len(records))
+	for i, r := range records {
+		addrs[i] = net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port)))
+	}
+	s.addrs, s.expires = addrs, time.Now().Add(s.TTL)
+	l.addrs = addrs
+}
+
+// land ends the given lookup and forgets it, unless it has already been
+// replaced.  It must be called with s.mu held.
+func (s *SRV) land(l *srvLookup) {
+	l.cancel()
+	if s.lookup == l {
+		s.lookup = nil
+	}
+}
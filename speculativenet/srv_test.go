@@ -0,0 +1,183 @@
+package speculativenet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSRVResolver returns a resolver that answers every SRV query in memory
+// with a record for each of the given ports, all targeting
+// "host.speculatively.test.", or fails if fail is set.  It also returns the
+// number of queries it received.
+func newSRVResolver(ports []uint16, fail *int32) (*net.Resolver, *int64) {
+	var queries int64
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			if atomic.LoadInt32(fail) != 0 {
+				return nil, errors.New("unreachable")
+			}
+			client, server := net.Pipe()
+			go serveSRV(server, ports, &queries)
+			return client, nil
+		},
+	}, &queries
+}
+
+// serveSRV answers SRV queries sent over conn with TCP framing.
+func serveSRV(conn net.Conn, ports []uint16, queries *int64) {
+	defer conn.Close()
+	var target []byte
+	for _, label := range []string{"host", "speculatively", "test"} {
+		target = append(append(target, byte(len(label))), label...)
+	}
+	target = append(target, 0)
+	for {
+		var size uint16
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		query := make([]byte, size)
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+		atomic.AddInt64(queries, 1)
+
+		end := 12
+		for query[end] != 0 {
+			end += int(query[end]) + 1
+		}
+		end += 5
+
+		resp := append([]byte{}, query[:end]...)
+		binary.BigEndian.PutUint16(resp[2:], 0x8180)
+		binary.BigEndian.PutUint16(resp[6:], uint16(len(ports)))
+		binary.BigEndian.PutUint16(resp[10:], 0)
+		for _, port := range ports {
+			resp = append(resp, 0xc0, 12, 0, 33, 0, 1, 0, 0, 0, 60)
+			rdlength := 6 + len(target)
+			resp = append(resp, byte(rdlength>>8), byte(rdlength))
+			resp = append(resp, 0, 10, 0, 10, byte(port>>8), byte(port))
+			resp = append(resp, target...)
+		}
+		if err := binary.Write(conn, binary.BigEndian, uint16(len(resp))); err != nil {
+			return
+		}
+		if _, err := conn.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func TestSRV(t *testing.T) {
+	t.Parallel()
+
+	var fail int32
+	resolver, queries := newSRVResolver([]uint16{8080}, &fail)
+	srv := &SRV{
+		Resolver: resolver,
+		Service:  "http",
+		Proto:    "tcp",
+		Name:     "speculatively.test.",
+		TTL:      20 * time.Millisecond,
+	}
+
+	want := []string{"host.speculatively.test:8080"}
+	for i := 0; i < 2; i++ {
+		addrs, err := srv.Replicas(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(addrs, want) {
+			t.Errorf("expected addrs %v, got %v", want, addrs)
+		}
+	}
+	if n := atomic.LoadInt64(queries); n != 1 {
+		t.Errorf("expected cached addrs to be reused, got %d queries", n)
+	}
+
+	// Once expired, the last addrs discovered outlive failed lookups
+	time.Sleep(30 * time.Millisecond)
+	atomic.StoreInt32(&fail, 1)
+	addrs, err := srv.Replicas(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(addrs, want) {
+		t.Errorf("expected stale addrs %v, got %v", want, addrs)
+	}
+}
+
+func TestSRVError(t *testing.T) {
+	t.Parallel()
+
+	fail := int32(1)
+	resolver, _ := newSRVResolver(nil, &fail)
+	srv := &SRV{Resolver: resolver, Service: "http", Proto: "tcp", Name: "speculatively.test."}
+	if _, err := srv.Replicas(context.Background()); err == nil {
+		t.Errorf("expected lookup error")
+	}
+}
+
+func TestSRVSlowLookup(t *testing.T) {
+	t.Parallel()
+
+	var fail int32
+	fast, _ := newSRVResolver([]uint16{8080}, &fail)
+	release := make(chan struct{})
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			return fast.Dial(ctx, network, address)
+		},
+	}
+	srv := &SRV{
+		Resolver: resolver,
+		Service:  "http",
+		Proto:    "tcp",
+		Name:     "speculatively.test.",
+		TTL:      time.Minute,
+	}
+
+	waiting := make(chan error, 1)
+	go func() {
+		_, err := srv.Replicas(context.Background())
+		waiting <- err
+	}()
+
+	// A call that gives up returns right away, without waiting for the slow
+	// lookup it shares
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := srv.Replicas(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected err = %s, got %v", context.DeadlineExceeded, err)
+	}
+
+	close(release)
+	if err := <-waiting; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Callers get their own copy of the cached addrs
+	want := []string{"host.speculatively.test:8080"}
+	addrs, err := srv.Replicas(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	addrs[0] = "changed"
+	if addrs, _ := srv.Replicas(context.Background()); !reflect.DeepEqual(addrs, want) {
+		t.Errorf("expected addrs %v, got %v", want, addrs)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Package speculativeredis provides speculative execution helpers for Redis
+clients.
+
+It depends only on the small Doer interface, which can be satisfied by any
+Redis client library.  For example, with go-redis:
+
+	doer := speculativeredis.DoerFunc(func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return rdb.Do(ctx, args...).Result()
+	})
+*/
+package speculativeredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// ErrNoCommand is returned by Client.Do when called without a command.
+var ErrNoCommand = errors.New("speculativeredis: no command")
+
+// Doer executes a single Redis command, given as its name followed by its
+// arguments, and returns its reply.
+type Doer interface {
+	Do(ctx context.Context, args ...interface{}) (interface{}, error)
+}
+
+// DoerFunc adapts a func to the Doer interface.
+type DoerFunc func(ctx context.Context, args ...interface{}) (interface{}, error)
+
+// Do calls fn.
+func (fn DoerFunc) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return fn(ctx, args...)
+}
+
+// ReadCommands are the read-only commands hedged by default.
+var ReadCommands = map[string]bool{
+	"EXISTS":        true,
+	"GET":           true,
+	"GETRANGE":      true,
+	"HEXISTS":       true,
+	"HGET":          true,
+	"HGETALL":       true,
+	"HKEYS":         true,
+	"HLEN":          true,
+	"HMGET":         true,
+	"HVALS":         true,
+	"LINDEX":        true,
+	"LLEN":          true,
+	"LRANGE":        true,
+	"MGET":          true,
+	"PTTL":          true,
+	"SCARD":         true,
+	"SISMEMBER":     true,
+	"SMEMBERS":      true,
+	"SMISMEMBER":    true,
+	"STRLEN":        true,
+	"TTL":           true,
+	"TYPE":          true,
+	"ZCARD":         true,
+	"ZCOUNT":        true,
+	"ZRANGE":        true,
+	"ZRANGEBYSCORE": true,
+	"ZRANK":         true,
+	"ZREVRANGE":     true,
+	"ZREVRANK":      true,
+	"ZSCORE":        true,
+}
+
+// Client hedges read commands across interchangeable Redis clients, e.g.
+// the replicas of a cluster or duplicate connections to the same server, to
+// shave tail latency during failovers and other blips.
+//
+// Each hedged command is sent to the first replica immediately, and to each
+// subsequent replica after waiting for Patience.  The first reply (or error)
+// is returned.  Commands that are not hedged are sent once, to the first
+// replica.
+type Client struct {
+	// Replicas are the clients to send commands to, in order of
+	// preference.  The first one receives every command that is not
+	// hedged.
+	Replicas []Doer
+
+	// Patience is how long to wait for a reply before sending a command to
+	// the next replica.
+	Patience time.Duration
+
+	// Options customize the hedging of every command, e.g. to share a
+	// Budget.
+	Options []speculatively.Option
+
+	// Commands are the names, in upper case, of the commands to hedge.  If
+	// nil, ReadCommands are hedged.
+	Commands map[string]bool
+}
+
+// Do executes a command, hedging it if it is one of c's Commands.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, ErrNoCommand
+	}
+	if len(c.Replicas) == 0 {
+		return nil, speculatively.ErrNoReplicas
+	}
+	if !c.hedged(args[0]) {
+		return c.Replicas[0].Do(ctx, args...)
+	}
+	replicas := speculatively.Replicas[Doer]{List: c.Replicas}
+	return speculatively.DoReplicas(ctx, c.Patience, replicas, func(ctx context.Context, replica Doer) (interface{}, error) {
+		return replica.Do(ctx, args...)
+	}, c.Options...)
+}
+
+// hedged reports whether the named command is hedged.
+func (c *Client) hedged(name interface{}) bool {
+	commands := c.Commands
+	if commands == nil {
+		commands = ReadCommands
+	}
+	return commands[strings.ToUpper(fmt.Sprint(name))]
+}
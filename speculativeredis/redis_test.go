@@ -0,0 +1,118 @@
+package speculativeredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// testReplica replies to every command with its name after the given delay.
+type testReplica struct {
+	name     string
+	delay    time.Duration
+	commands int64
+}
+
+func (r *testReplica) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	atomic.AddInt64(&r.commands, 1)
+	select {
+	case <-time.After(r.delay):
+		return fmt.Sprintf("%s: %v", r.name, args), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		args         []interface{}
+		commands     map[string]bool
+		want         string
+		wantCommands []int64
+	}{
+		"read command is hedged": {
+			args:         []interface{}{"GET", "key"},
+			want:         "replica: [GET key]",
+			wantCommands: []int64{1, 1},
+		},
+		"command name is case insensitive": {
+			args:         []interface{}{"get", "key"},
+			want:         "replica: [get key]",
+			wantCommands: []int64{1, 1},
+		},
+		"write command is not hedged": {
+			args:         []interface{}{"SET", "key", "value"},
+			want:         "primary: [SET key value]",
+			wantCommands: []int64{1, 0},
+		},
+		"opted in command is hedged": {
+			args:         []interface{}{"EVALSHA", "abc", 0},
+			commands:     map[string]bool{"EVALSHA": true},
+			want:         "replica: [EVALSHA abc 0]",
+			wantCommands: []int64{1, 1},
+		},
+		"default command not opted in": {
+			args:         []interface{}{"GET", "key"},
+			commands:     map[string]bool{"EVALSHA": true},
+			want:         "primary: [GET key]",
+			wantCommands: []int64{1, 0},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			primary := &testReplica{name: "primary", delay: 200 * time.Millisecond}
+			replica := &testReplica{name: "replica"}
+			c := &Client{
+				Replicas: []Doer{primary, replica},
+				Patience: 10 * time.Millisecond,
+				Commands: tc.commands,
+			}
+			got, err := c.Do(context.Background(), tc.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected reply %q, got %q", tc.want, got)
+			}
+			for i, r := range []*testReplica{primary, replica} {
+				if got := atomic.LoadInt64(&r.commands); got != tc.wantCommands[i] {
+					t.Errorf("expected %d commands sent to %s, got %d", tc.wantCommands[i], r.name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	t.Parallel()
+
+	c := &Client{}
+	if _, err := c.Do(context.Background(), "GET", "key"); !errors.Is(err, speculatively.ErrNoReplicas) {
+		t.Errorf("expected ErrNoReplicas, got %v", err)
+	}
+	c.Replicas = []Doer{&testReplica{}}
+	if _, err := c.Do(context.Background()); !errors.Is(err, ErrNoCommand) {
+		t.Errorf("expected ErrNoCommand, got %v", err)
+	}
+}
+
+func TestDoerFunc(t *testing.T) {
+	t.Parallel()
+
+	var d Doer = DoerFunc(func(_ context.Context, args ...interface{}) (interface{}, error) {
+		return len(args), nil
+	})
+	if got, _ := d.Do(context.Background(), "GET", "key"); got != 2 {
+		t.Errorf("expected 2, got %v", got)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Package speculativesearch provides speculative execution helpers for
+Elasticsearch and OpenSearch clients, whose search tail latency is often
+dominated by a single hot shard or node.
+
+Its Transport plugs into any client that accepts a custom
+http.RoundTripper, e.g. with go-elasticsearch:
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{"http://es-1:9200"},
+		Transport: &speculativesearch.Transport{
+			Nodes:    []string{"es-1:9200", "es-2:9200", "es-3:9200"},
+			Patience: 100 * time.Millisecond,
+		},
+	})
+*/
+package speculativesearch
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"github.com/mccutchen/speculatively/speculativehttp"
+)
+
+// ReadEndpoints are the endpoints hedged by default, which search or read
+// documents without side effects.
+var ReadEndpoints = map[string]bool{
+	"_count":      true,
+	"_field_caps": true,
+	"_mget":       true,
+	"_msearch":    true,
+	"_search":     true,
+}
+
+// Transport is an http.RoundTripper that hedges read-only search requests
+// across the coordinating nodes of a cluster, or across copies of the
+// shards they target by varying the preference of each request.
+//
+// Each search request is sent immediately, and sent again in parallel every
+// time Patience elapses without a response, up to the limits set by Options.
+// The first response (or error) is returned, and every other request is
+// canceled.  Other requests are sent once, as is.
+type Transport struct {
+	// Transport is used to send each request.  If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// Nodes optionally are the hosts, e.g. "es-2:9200", of the
+	// coordinating nodes to send successive attempts of each search
+	// request to, wrapping around.  If empty, every attempt is sent to the
+	// request's own host.
+	Nodes []string
+
+	// Preferences optionally are the values of the preference parameter
+	// of successive attempts of each search request, wrapping around, e.g.
+	// distinct custom strings so that each attempt is routed to other
+	// copies of the targeted shards.  If empty, the preference of each
+	// request is left as is.
+	Preferences []string
+
+	// Patience is how long to wait for a response before sending a search
+	// request again.
+	Patience time.Duration
+
+	// Options customize the hedging of every search request, e.g. to cap
+	// the number of attempts or share a Budget.
+	Options []speculatively.Option
+
+	// MaxBodyBuffer is the size, in bytes, of the largest request body
+	// that is buffered in memory so that a search request whose GetBody
+	// func is not set can be hedged, as with speculativehttp.RoundTripper.
+	MaxBodyBuffer int64
+
+	// Endpoints are the endpoints to hedge, e.g. "_search", given by the
+	// first path segment of each request starting with an underscore.  If
+	// nil, ReadEndpoints are hedged.  Scroll requests are never hedged,
+	// since every one of them advances the scroll.
+	Endpoints map[string]bool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.hedged(req) {
+		return t.transport().RoundTrip(req)
+	}
+	routes := len(t.Nodes)
+	if len(t.Preferences) > routes {
+		routes = len(t.Preferences)
+	}
+	if routes == 0 {
+		routes = 1
+	}
+	transports := make([]http.RoundTripper, routes)
+	for i := range transports {
+		r := route{transport: t.transport()}
+		if len(t.Nodes) > 0 {
+			r.node = t.Nodes[i%len(t.Nodes)]
+		}
+		if len(t.Preferences) > 0 {
+			r.preference = t.Preferences[i%len(t.Preferences)]
+		}
+		transports[i] = r
+	}
+	rt := &speculativehttp.RoundTripper{
+		Transports:    transports,
+		Patience:      t.Patience,
+		Options:       t.Options,
+		MaxBodyBuffer: t.MaxBodyBuffer,
+	}
+	// Search requests are sent with POST, but are safe to send more than
+	// once
+	return rt.RoundTrip(req.WithContext(speculativehttp.ContextWithIdempotent(req.Context())))
+}
+
+// hedged reports whether req is a search request to be hedged.
+func (t *Transport) hedged(req *http.Request) bool {
+	if req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodPost {
+		return false
+	}
+	endpoints := t.Endpoints
+	if endpoints == nil {
+		endpoints = ReadEndpoints
+	}
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, s := range segments {
+		if !strings.HasPrefix(s, "_") {
+			continue
+		}
+		if i+1 < len(segments) && segments[i+1] == "scroll" {
+			return false
+		}
+		return endpoints[s]
+	}
+	return false
+}
+
+func (t *Transport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+// route sends requests to a given node, with a given preference, if set.
+type route struct {
+	transport  http.RoundTripper
+	node       string
+	preference string
+}
+
+func (r route) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.node == "" && r.preference == "" {
+		return r.transport.RoundTrip(req)
+	}
+	// req is already a copy made for this attempt
+	if r.node != "" {
+		req.URL.Host, req.Host = r.node, ""
+	}
+	if r.preference != "" {
+		q := req.URL.Query()
+		q.Set("preference", r.preference)
+		req.URL.RawQuery = q.Encode()
+	}
+	return r.transport.RoundTrip(req)
+}
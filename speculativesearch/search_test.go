@@ -0,0 +1,141 @@
+package speculativesearch
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// newNode returns the host of a server that echoes the preference and body
+// of every request after the given delay.
+func newNode(t *testing.T, name string, delay time.Duration, requests *int64) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(requests, 1)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", name, r.URL.Query().Get("preference"), body)
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return u.Host
+}
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method, path string
+		preferences  []string
+		want         string
+		wantRequests int64
+	}{
+		"search is hedged": {
+			method:       http.MethodPost,
+			path:         "/logs/_search",
+			want:         "fast  {}",
+			wantRequests: 2,
+		},
+		"search with preferences": {
+			method:       http.MethodPost,
+			path:         "/logs/_search",
+			preferences:  []string{"a", "b"},
+			want:         "fast b {}",
+			wantRequests: 2,
+		},
+		"scroll is not hedged": {
+			method:       http.MethodPost,
+			path:         "/_search/scroll",
+			want:         "slow  {}",
+			wantRequests: 1,
+		},
+		"write is not hedged": {
+			method:       http.MethodPost,
+			path:         "/logs/_doc",
+			want:         "slow  {}",
+			wantRequests: 1,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var requests int64
+			slow := newNode(t, "slow", 200*time.Millisecond, &requests)
+			fast := newNode(t, "fast", 0, &requests)
+			client := &http.Client{
+				Transport: &Transport{
+					Nodes:       []string{slow, fast},
+					Preferences: tc.preferences,
+					Patience:    20 * time.Millisecond,
+					Options:     []speculatively.Option{speculatively.WithMaxAttempts(2)},
+				},
+			}
+			req, _ := http.NewRequest(tc.method, "http://"+slow+tc.path, strings.NewReader("{}"))
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read response body: %s", err)
+			}
+			if string(body) != tc.want {
+				t.Errorf("expected response %q, got %q", tc.want, body)
+			}
+			if got := atomic.LoadInt64(&requests); got != tc.wantRequests {
+				t.Errorf("expected %d requests, got %d", tc.wantRequests, got)
+			}
+		})
+	}
+}
+
+func TestTransportHedged(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		method, path string
+		endpoints    map[string]bool
+		want         bool
+	}{
+		"search":               {method: "GET", path: "/logs/_search", want: true},
+		"search all indexes":   {method: "POST", path: "/_search", want: true},
+		"search template":      {method: "POST", path: "/logs/_search/template", want: true},
+		"multi search":         {method: "POST", path: "/_msearch", want: true},
+		"count":                {method: "GET", path: "/logs/_count", want: true},
+		"multi get":            {method: "POST", path: "/logs/_mget", want: true},
+		"scroll":               {method: "POST", path: "/_search/scroll", want: false},
+		"index document":       {method: "POST", path: "/logs/_doc", want: false},
+		"delete by query":      {method: "POST", path: "/logs/_delete_by_query", want: false},
+		"delete index":         {method: "DELETE", path: "/logs", want: false},
+		"no endpoint":          {method: "GET", path: "/logs", want: false},
+		"custom endpoint":      {method: "GET", path: "/logs/_doc/1", endpoints: map[string]bool{"_doc": true}, want: true},
+		"default not opted in": {method: "GET", path: "/logs/_search", endpoints: map[string]bool{"_doc": true}, want: false},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, _ := http.NewRequest(tc.method, "http://es:9200"+tc.path, nil)
+			tr := &Transport{Endpoints: tc.endpoints}
+			if got := tr.hedged(req); got != tc.want {
+				t.Errorf("expected hedged(%s %s) = %v, got %v", tc.method, tc.path, tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package speculativesql
+
+import (
+	"strings"
+	"unicode"
+)
+
+// readStatements are the keywords that may start a read-only statement.
+var readStatements = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"SHOW":     true,
+	"EXPLAIN":  true,
+	"DESCRIBE": true,
+	"VALUES":   true,
+	"TABLE":    true,
+}
+
+// writeKeywords are the keywords whose presence anywhere in a statement
+// suggests that it modifies data or takes locks, e.g. a data-modifying CTE,
+// SELECT ... INTO or SELECT ... FOR UPDATE.
+var writeKeywords = map[string]bool{
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"UPSERT":   true,
+	"REPLACE":  true,
+	"INTO":     true,
+	"CREATE":   true,
+	"ALTER":    true,
+	"DROP":     true,
+	"TRUNCATE": true,
+	"GRANT":    true,
+	"REVOKE":   true,
+	"CALL":     true,
+	"LOCK":     true,
+	"SHARE":    true,
+	"ANALYZE":  true,
+}
+
+// readOnly reports whether query is obviously a read-only statement, judging
+// by its keywords while ignoring comments and quoted strings and identifiers.
+// It errs on the side of reporting false.
+func readOnly(query string) bool {
+	words := keywords(query)
+	if len(words) == 0 || !readStatements[words[0]] {
+		return false
+	}
+	for _, w := range words[1:] {
+		if writeKeywords[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// keywords returns the unquoted words of query, in upper case.
+func keywords(query string) []string {
+	var (
+		words []string
+		word  strings.Builder
+	)
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToUpper(word.String()))
+			word.Reset()
+		}
+	}
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			flush()
+			i += skipUntil(query[i:], "\n")
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			flush()
+			i += 2 + skipUntil(query[i+2:], "*/")
+		case c == '\'' || c == '"' || c == '`':
+			flush()
+			i += 1 + skipUntil(query[i+1:], string(c))
+		case c == '_' || unicode.IsLetter(rune(c)) || (word.Len() > 0 && unicode.IsDigit(rune(c))):
+			word.WriteByte(c)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// skipUntil returns the index of the last byte of the first occurrence of
+// end in s, or the index of the last byte of s if end does not occur.
+func skipUntil(s, end string) int {
+	if i := strings.Index(s, end); i >= 0 {
+		return i + len(end) - 1
+	}
+	return len(s) - 1
+}
@@ -0,0 +1,44 @@
+package speculativesql
+
+import "testing"
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		query string
+		want  bool
+	}{
+		"select":                {"SELECT * FROM users WHERE id = $1", true},
+		"lower case select":     {"select id from users", true},
+		"leading comment":       {"-- fetch users\nSELECT * FROM users", true},
+		"leading block comment": {"/* fetch */ SELECT * FROM users", true},
+		"cte":                   {"WITH u AS (SELECT * FROM users) SELECT * FROM u", true},
+		"keyword in string":     {"SELECT * FROM logs WHERE msg = 'DELETE FROM users'", true},
+		"keyword in identifier": {`SELECT "update" FROM t`, true},
+		"keyword in comment":    {"SELECT 1 /* then UPDATE */", true},
+		"column name prefix":    {"SELECT updated_at FROM users", true},
+		"explain":               {"EXPLAIN SELECT 1", true},
+		"insert":                {"INSERT INTO users (name) VALUES ($1)", false},
+		"update":                {"UPDATE users SET name = $1", false},
+		"delete":                {"DELETE FROM users", false},
+		"returning":             {"INSERT INTO users DEFAULT VALUES RETURNING id", false},
+		"modifying cte":         {"WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", false},
+		"select into":           {"SELECT * INTO backup FROM users", false},
+		"for update":            {"SELECT * FROM users FOR UPDATE", false},
+		"for share":             {"SELECT * FROM users FOR SHARE", false},
+		"ddl":                   {"CREATE TABLE t (id int)", false},
+		"empty":                 {"", false},
+		"only comment":          {"-- SELECT", false},
+		"unterminated string":   {"SELECT 'abc", true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := readOnly(tc.query); got != tc.want {
+				t.Errorf("expected readOnly(%q) = %v, got %v", tc.query, tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Package speculativesql provides speculative execution helpers for
+database/sql.
+*/
+package speculativesql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Queryer is a database handle that can run queries, such as *sql.DB,
+// *sql.Conn or *sql.Tx.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// DB races read queries across interchangeable database handles, e.g. a
+// primary and its read replicas, for tail-tolerant read paths.
+//
+// Each query is sent to the first handle immediately, and to each
+// subsequent handle after waiting for Patience, or as soon as a previous
+// attempt fails.  The first successful Rows are returned and all other
+// queries are canceled.
+//
+// Since running a statement more than once changes its meaning inside a
+// transaction, or if it modifies data, queries are only hedged if they are
+// read-only and none of the handles is a *sql.Tx.  Other queries are run
+// once, on the first handle.
+type DB struct {
+	// DBs are the handles to query, in order of preference, e.g. the
+	// primary followed by its replicas.
+	DBs []Queryer
+
+	// Patience is how long to wait for a query to return before sending it
+	// to the next handle.
+	Patience time.Duration
+
+	// Options customize the hedging of every query, e.g. to share a Budget.
+	Options []speculatively.Option
+}
+
+// Rows are the result of a query run by DB.  They must be closed, like
+// sql.Rows, which also releases the query's context.
+type Rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+// Close closes the Rows and releases the query's context.
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.cancel()
+	return err
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT, on
+// each of db's handles in turn until one of them succeeds.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	opts := append([]speculatively.Option{
+		speculatively.WithRetryable(func(error) bool { return true }),
+		speculatively.WithCleanup(func(r *Rows) { r.Close() }),
+	}, db.Options...)
+	if !db.hedgeable(query) {
+		opts = append(opts, speculatively.WithMaxAttempts(1))
+	}
+	replicas := speculatively.Replicas[Queryer]{List: db.DBs}
+	return speculatively.DoReplicas(ctx, db.Patience, replicas, func(attemptCtx context.Context, handle Queryer) (*Rows, error) {
+		return queryHandle(ctx, attemptCtx, handle, query, args)
+	}, opts...)
+}
+
+// queryHandle runs a query on a single handle.  The query is canceled along
+// with attemptCtx until it returns, after which only closing its Rows or ctx
+// being done cancels it, so that the winning Rows can still be read once
+// every other attempt has been canceled.
+func queryHandle(ctx, attemptCtx context.Context, handle Queryer, query string, args []interface{}) (*Rows, error) {
+	queryCtx, keep, cancel := speculatively.Detach(ctx, attemptCtx)
+
+	rows, err := handle.QueryContext(queryCtx, query, args...)
+	keep()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &Rows{Rows: rows, cancel: cancel}, nil
+}
+
+// hedgeable reports whether query may be run more than once.
+func (db *DB) hedgeable(query string) bool {
+	for _, handle := range db.DBs {
+		if _, ok := handle.(*sql.Tx); ok {
+			return false
+		}
+	}
+	return readOnly(query)
+}
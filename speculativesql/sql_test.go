@@ -0,0 +1,210 @@
+package speculativesql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// testDB is a database/sql driver whose queries return a single row holding
+// the database's name after the given delay.
+type testDB struct {
+	name     string
+	delay    time.Duration
+	err      error
+	queries  int64
+	canceled int64
+}
+
+func (d *testDB) Connect(context.Context) (driver.Conn, error) { return testConn{d}, nil }
+func (d *testDB) Driver() driver.Driver                        { return nil }
+
+type testConn struct{ db *testDB }
+
+func (c testConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (c testConn) Close() error                        { return nil }
+func (c testConn) Begin() (driver.Tx, error)           { return testTx{}, nil }
+
+type testTx struct{}
+
+func (testTx) Commit() error   { return nil }
+func (testTx) Rollback() error { return nil }
+
+func (c testConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	atomic.AddInt64(&c.db.queries, 1)
+	select {
+	case <-time.After(c.db.delay):
+	case <-ctx.Done():
+		atomic.AddInt64(&c.db.canceled, 1)
+		return nil, ctx.Err()
+	}
+	if c.db.err != nil {
+		return nil, c.db.err
+	}
+	return &testRows{values: []string{c.db.name, query}}, nil
+}
+
+type testRows struct {
+	values []string
+}
+
+func (r *testRows) Columns() []string { return []string{"value"} }
+func (r *testRows) Close() error      { return nil }
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func newTestDB(t *testing.T, name string, delay time.Duration, err error) (*sql.DB, *testDB) {
+	t.Helper()
+	d := &testDB{name: name, delay: delay, err: err}
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestQueryContext(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		delays       []time.Duration
+		errs         []error
+		wantWinner   string
+		wantCanceled []int64
+	}{
+		"primary wins": {
+			delays:       []time.Duration{0, 0},
+			errs:         []error{nil, nil},
+			wantWinner:   "db0",
+			wantCanceled: []int64{0, 0},
+		},
+		"replica wins": {
+			delays:       []time.Duration{time.Second, 0},
+			errs:         []error{nil, nil},
+			wantWinner:   "db1",
+			wantCanceled: []int64{1, 0},
+		},
+		"primary fails": {
+			delays:       []time.Duration{0, 0},
+			errs:         []error{errors.New("down"), nil},
+			wantWinner:   "db1",
+			wantCanceled: []int64{0, 0},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				dbs   []Queryer
+				fakes []*testDB
+			)
+			for i := range tc.delays {
+				db, fake := newTestDB(t, fmt.Sprintf("db%d", i), tc.delays[i], tc.errs[i])
+				dbs, fakes = append(dbs, db), append(fakes, fake)
+			}
+			db := &DB{DBs: dbs, Patience: 25 * time.Millisecond}
+
+			rows, err := db.QueryContext(context.Background(), "SELECT 1")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer rows.Close()
+
+			// The winning rows must still be readable once the other
+			// queries have been canceled
+			time.Sleep(50 * time.Millisecond)
+			var got []string
+			for rows.Next() {
+				var v string
+				if err := rows.Scan(&v); err != nil {
+					t.Fatalf("unexpected scan error: %s", err)
+				}
+				got = append(got, v)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("unexpected rows error: %s", err)
+			}
+			if len(got) != 2 || got[0] != tc.wantWinner || got[1] != "SELECT 1" {
+				t.Errorf("expected rows from %s, got %q", tc.wantWinner, got)
+			}
+			for i, want := range tc.wantCanceled {
+				if got := atomic.LoadInt64(&fakes[i].canceled); got != want {
+					t.Errorf("expected %d canceled queries on db%d, got %d", want, i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryContextAllFail(t *testing.T) {
+	t.Parallel()
+
+	errDown := errors.New("down")
+	db0, _ := newTestDB(t, "db0", 0, errDown)
+	db1, _ := newTestDB(t, "db1", 0, errDown)
+	db := &DB{
+		DBs:      []Queryer{db0, db1},
+		Patience: time.Second,
+		Options:  []speculatively.Option{speculatively.WithMaxAttempts(2)},
+	}
+	if _, err := db.QueryContext(context.Background(), "SELECT 1"); !errors.Is(err, errDown) {
+		t.Errorf("expected error %v, got %v", errDown, err)
+	}
+}
+
+func TestQueryContextSingleExecution(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		query string
+		inTx  bool
+	}{
+		"mutating statement": {query: "UPDATE users SET name = 'x' RETURNING id"},
+		"transaction":        {query: "SELECT 1", inTx: true},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			db0, fake0 := newTestDB(t, "db0", 100*time.Millisecond, nil)
+			db1, fake1 := newTestDB(t, "db1", 0, nil)
+			var primary Queryer = db0
+			if tc.inTx {
+				tx, err := db0.Begin()
+				if err != nil {
+					t.Fatalf("failed to begin transaction: %s", err)
+				}
+				defer tx.Rollback() //nolint:errcheck
+				primary = tx
+			}
+			db := &DB{DBs: []Queryer{primary, db1}, Patience: 10 * time.Millisecond}
+
+			rows, err := db.QueryContext(context.Background(), tc.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			rows.Close()
+			if got := atomic.LoadInt64(&fake0.queries); got != 1 {
+				t.Errorf("expected 1 query on primary, got %d", got)
+			}
+			if got := atomic.LoadInt64(&fake1.queries); got != 0 {
+				t.Errorf("expected no queries on replica, got %d", got)
+			}
+		})
+	}
+}
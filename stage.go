@@ -0,0 +1,92 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of processing a single element of a pipeline by
+// Stage.
+type Result[I, O any] struct {
+	Input I
+	Val   O
+	Err   error
+}
+
+// Stage is a pipeline stage that speculatively processes every element
+// received from in, executing the Thunk returned for it by newThunk as Do
+// does, and sends each element's Result to out, in the order the elements
+// were received.  It returns once in is closed and every Result has been
+// sent, or once ctx is done, and closes out before returning.  It is
+// typically run in a goroutine of its own:
+//
+//	go speculatively.Stage(ctx, patience, lines, parsed, func(line string) speculatively.Thunk[Record] {
+//		return func(ctx context.Context) (Record, error) {
+//			return parse(ctx, line)
+//		}
+//	})
+//
+// At most as many elements as allowed by WithConcurrency are processed or
+// waiting for earlier elements to be sent at once, and the given Options
+// otherwise apply to each element's call.  Results not yet sent when ctx is
+// done are dropped.
+func Stage[I, O any](ctx context.Context, patience time.Duration, in <-chan I, out chan<- Result[I, O], newThunk func(I) Thunk[O], opts ...Option) {
+	defer close(out)
+
+	var sem chan struct{}
+	if limit := newConfig(opts).concurrency; limit > 0 {
+		sem = make(chan struct{}, limit)
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Each element waits for the previous one to be sent before sending
+	// its own Result, so that Results are sent in order
+	prev := make(chan struct{})
+	close(prev)
+	for {
+		var input I
+		select {
+		case v, ok := <-in:
+			if !ok {
+				return
+			}
+			input = v
+		case <-ctx.Done():
+			return
+		}
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		sent := make(chan struct{})
+		wg.Add(1)
+		go func(input I, prev <-chan struct{}, sent chan<- struct{}) {
+			defer wg.Done()
+			defer close(sent)
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			r := Result[I, O]{Input: input}
+			r.Val, r.Err = Do(ctx, patience, newThunk(input), opts...)
+			select {
+			case <-prev:
+			case <-ctx.Done():
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case out <- r:
+			case <-ctx.Done():
+			}
+		}(input, prev, sent)
+		prev = sent
+	}
+}
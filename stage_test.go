@@ -0,0 +1,108 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStage(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	var running, peak int64
+	newThunk := func(n int) Thunk[string] {
+		return func(ctx context.Context) (string, error) {
+			cur := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if cur <= p || atomic.CompareAndSwapInt64(&peak, p, cur) {
+					break
+				}
+			}
+			// Earlier elements take longer, and the first attempt for
+			// every element is slow, so that each one is hedged
+			delay := time.Duration(10-n) * time.Millisecond
+			if !IsHedge(ctx) {
+				delay = time.Second
+			}
+			if err := sleep(ctx, delay); err != nil {
+				return "", err
+			}
+			if n == 3 {
+				return "", failed
+			}
+			return strconv.Itoa(n), nil
+		}
+	}
+
+	in := make(chan int)
+	out := make(chan Result[int, string])
+	go Stage(context.Background(), 10*time.Millisecond, in, out, newThunk, WithConcurrency(3), WithMaxAttempts(2))
+	go func() {
+		defer close(in)
+		for n := 0; n < 8; n++ {
+			in <- n
+		}
+	}()
+
+	start := time.Now()
+	var got []Result[int, string]
+	for r := range out {
+		got = append(got, r)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected every element to be hedged, took %s", elapsed)
+	}
+	if len(got) != 8 {
+		t.Fatalf("expected %d results, got %d", 8, len(got))
+	}
+	for n, r := range got {
+		if r.Input != n {
+			t.Errorf("expected result %d for input %d, got input %d", n, n, r.Input)
+		}
+		switch {
+		case n == 3 && r.Err != failed:
+			t.Errorf("expected err = %v for input %d, got %v", failed, n, r.Err)
+		case n != 3 && (r.Val != strconv.Itoa(n) || r.Err != nil):
+			t.Errorf("expected val = %q for input %d, got %q, %v", strconv.Itoa(n), n, r.Val, r.Err)
+		}
+	}
+	// Each of the 3 elements processed at once runs up to 2 attempts
+	if got := atomic.LoadInt64(&peak); got > 6 {
+		t.Errorf("expected at most %d attempts running at once, got %d", 6, got)
+	}
+}
+
+func TestStageContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := make(chan Result[int, int])
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Stage(ctx, time.Second, in, out, func(n int) Thunk[int] {
+			return newSimpleTestThunk(n, nil, 0).call
+		})
+	}()
+
+	in <- 1
+	if r := <-out; r.Val != 1 {
+		t.Errorf("expected val = %d, got %d", 1, r.Val)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected stage to return once its context is done")
+	}
+	if _, ok := <-out; ok {
+		t.Errorf("expected out to be closed")
+	}
+}
@@ -0,0 +1,198 @@
+package speculatively
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the activity of a Hedger.
+type Stats struct {
+	// Calls is the number of calls made.
+	Calls int64
+	// Attempts is the number of attempts launched, including hedges.
+	Attempts int64
+	// Hedges is the number of hedged attempts launched, i.e. every attempt
+	// after the first of its call.
+	Hedges int64
+	// Wins is the number of calls that succeeded.
+	Wins int64
+	// HedgeWins is the number of calls won by a hedged attempt.
+	HedgeWins int64
+
+	// WastedAttempts is the number of attempts that were launched but did
+	// not produce the result of their call, because they lost the race, were
+	// canceled or had their result rejected.
+	WastedAttempts int64
+	// WastedDuration is the cumulative time spent running wasted attempts.
+	WastedDuration time.Duration
+	// LateResults is the number of wasted attempts that returned a
+	// successful result after their call had already ended.
+	LateResults int64
+	// Divergences is the number of late results that differed from the
+	// result of their call, if WithConsistencyCheck is set.
+	Divergences int64
+
+	// LoserExits is the number of attempts still running when their call
+	// ended, which took LoserExitDuration in total and LoserExitMax at most
+	// to observe cancelation and exit.
+	LoserExits        int64
+	LoserExitDuration time.Duration
+	LoserExitMax      time.Duration
+
+	// Budgeted reports whether the Hedger's hedges are limited by a Budget,
+	// in which case BudgetRemaining is the number of hedges it currently
+	// allows.
+	Budgeted        bool
+	BudgetRemaining float64
+
+	// Latency holds histograms of the latency of attempts if enabled via
+	// WithLatencyHistograms, or nil.
+	Latency *LatencyHistograms
+}
+
+// HedgeWinRate returns the fraction of hedges that won their call, or 0 if
+// no hedges were launched.
+func (s Stats) HedgeWinRate() float64 {
+	if s.Hedges == 0 {
+		return 0
+	}
+	return float64(s.HedgeWins) / float64(s.Hedges)
+}
+
+// AttemptsPerCall returns the average number of attempts launched per call,
+// or 0 if no calls were made.
+func (s Stats) AttemptsPerCall() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Attempts) / float64(s.Calls)
+}
+
+// Stats returns a snapshot of the activity of every call made through h.
+func (h *Hedger) Stats() Stats {
+	s := Stats{
+		Calls:          atomic.LoadInt64(&h.stats.calls),
+		Attempts:       atomic.LoadInt64(&h.stats.attempts),
+		Hedges:         atomic.LoadInt64(&h.stats.hedges),
+		Wins:           atomic.LoadInt64(&h.stats.wins),
+		HedgeWins:      atomic.LoadInt64(&h.stats.hedgeWins),
+		WastedAttempts: atomic.LoadInt64(&h.stats.wastedAttempts),
+		WastedDuration: time.Duration(atomic.LoadInt64(&h.stats.wastedNanos)),
+		LateResults:    atomic.LoadInt64(&h.stats.lateResults),
+		Divergences:    atomic.LoadInt64(&h.stats.divergences),
+
+		LoserExits:        atomic.LoadInt64(&h.stats.loserExits),
+		LoserExitDuration: time.Duration(atomic.LoadInt64(&h.stats.loserExitNanos)),
+		LoserExitMax:      time.Duration(atomic.LoadInt64(&h.stats.loserExitMaxNanos)),
+	}
+	if b := newConfig(h.opts).budget; b != nil {
+		s.Budgeted = true
+		s.BudgetRemaining = b.Remaining()
+	}
+	if h.stats.latency != nil {
+		s.Latency = h.stats.latency.snapshot()
+	}
+	return s
+}
+
+// stats accumulates the activity of calls made through a Hedger.  A nil
+// *stats discards everything.
+type stats struct {
+	calls          int64
+	attempts       int64
+	hedges         int64
+	wins           int64
+	hedgeWins      int64
+	wastedAttempts int64
+	wastedNanos    int64
+	lateResults    int64
+	divergences    int64
+
+	loserExits        int64
+	loserExitNanos    int64
+	loserExitMaxNanos int64
+
+	latency *latencyHistograms
+	live    liveCalls
+}
+
+func withStats(s *stats) Option {
+	return func(c *config) {
+		c.stats = s
+	}
+}
+
+// call records a new call starting at the given time, returning a liveCall
+// tracking its attempts.
+func (s *stats) call(start time.Time) *liveCall {
+	if s == nil {
+		return nil
+	}
+	atomic.AddInt64(&s.calls, 1)
+	return s.live.begin(start)
+}
+
+func (s *stats) launch(a Attempt) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.attempts, 1)
+	if a.Index > 0 {
+		atomic.AddInt64(&s.hedges, 1)
+	}
+}
+
+func (s *stats) win(a Attempt, elapsed time.Duration) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.wins, 1)
+	if a.Index > 0 {
+		atomic.AddInt64(&s.hedgeWins, 1)
+	}
+	if s.latency != nil {
+		s.latency.observe(a, elapsed, true)
+	}
+}
+
+func (s *stats) late() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.lateResults, 1)
+}
+
+func (s *stats) diverged() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.divergences, 1)
+}
+
+func (s *stats) loserExit(lag time.Duration) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.loserExits, 1)
+	atomic.AddInt64(&s.loserExitNanos, int64(lag))
+	for {
+		prev := atomic.LoadInt64(&s.loserExitMaxNanos)
+		if int64(lag) <= prev || atomic.CompareAndSwapInt64(&s.loserExitMaxNanos, prev, int64(lag)) {
+			break
+		}
+	}
+	if s.latency != nil {
+		s.latency.loserExits.observe(lag)
+	}
+}
+
+func (s *stats) waste(a Attempt, elapsed time.Duration) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.wastedAttempts, 1)
+	atomic.AddInt64(&s.wastedNanos, int64(elapsed))
+	if s.latency != nil {
+		s.latency.observe(a, elapsed, false)
+	}
+}
@@ -0,0 +1,137 @@
+package speculatively
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWastedWork(t *testing.T) {
+	t.Parallel()
+
+	results := []result[int]{
+		{val: 1, err: nil},
+		{val: 2, err: nil},
+	}
+	delays := []time.Duration{
+		time.Second,
+		5 * time.Millisecond,
+	}
+	thunk := newTestThunk(results, delays)
+	h := NewHedger(20*time.Millisecond, WithMaxAttempts(2))
+
+	if _, err := DoWith(context.Background(), h, thunk.call); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The losing attempt is accounted for once it observes cancelation
+	var stats Stats
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if stats = h.Stats(); stats.WastedAttempts > 0 {
+			break
+		}
+	}
+	if stats.WastedAttempts != 1 {
+		t.Fatalf("expected 1 wasted attempt, got %d", stats.WastedAttempts)
+	}
+	if stats.WastedDuration < 20*time.Millisecond || stats.WastedDuration > time.Second {
+		t.Errorf("expected wasted duration ~25ms, got %s", stats.WastedDuration)
+	}
+}
+
+func TestWastedWorkRejectedResults(t *testing.T) {
+	t.Parallel()
+
+	thunk := newSimpleTestThunk(0, context.DeadlineExceeded, 0)
+	h := NewHedger(time.Second, WithMaxAttempts(3), WithRetryable(func(error) bool { return true }))
+
+	if _, err := DoWith(context.Background(), h, thunk.call); err == nil {
+		t.Fatalf("expected error")
+	}
+	// The final error is the call's result, so only the two retried
+	// attempts were wasted
+	if stats := h.Stats(); stats.WastedAttempts != 2 {
+		t.Errorf("expected 2 wasted attempts, got %d", stats.WastedAttempts)
+	}
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	h := NewHedger(10*time.Millisecond, WithBudget(NewBudget(0.5, 3)), WithMaxAttempts(2))
+
+	if s := h.Stats(); s.Calls != 0 || s.AttemptsPerCall() != 0 || s.HedgeWinRate() != 0 {
+		t.Errorf("expected empty stats, got %+v", s)
+	}
+
+	slow := newTestThunk([]result[int]{{val: 1}, {val: 2}}, []time.Duration{time.Second, 5 * time.Millisecond})
+	if _, err := DoWith(context.Background(), h, slow.call); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	fast := newSimpleTestThunk(1, nil, 0)
+	for i := 0; i < 3; i++ {
+		if _, err := DoWith(context.Background(), h, fast.call); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	s := h.Stats()
+	if s.Calls != 4 || s.Attempts != 5 || s.Hedges != 1 || s.Wins != 4 || s.HedgeWins != 1 {
+		t.Errorf("expected 4 calls, 5 attempts, 1 hedge, 4 wins and 1 hedge win, got %+v", s)
+	}
+	if rate := s.HedgeWinRate(); rate != 1 {
+		t.Errorf("expected hedge win rate = 1, got %v", rate)
+	}
+	if avg := s.AttemptsPerCall(); avg != 1.25 {
+		t.Errorf("expected attempts per call = 1.25, got %v", avg)
+	}
+	// The single hedge was paid back by the deposits of later calls
+	if !s.Budgeted || s.BudgetRemaining != 3 {
+		t.Errorf("expected budget remaining = 3, got %+v", s)
+	}
+
+	if s := NewHedger(time.Second).Stats(); s.Budgeted {
+		t.Errorf("expected hedger without budget not to be budgeted")
+	}
+}
+
+func TestLoserExits(t *testing.T) {
+	t.Parallel()
+
+	// The first attempt takes 20ms to notice that it lost
+	thunk := func(ctx context.Context) (int, error) {
+		if IsHedge(ctx) {
+			return 2, nil
+		}
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		return 0, ctx.Err()
+	}
+	exits := make(chan time.Duration, 1)
+	hooks := Hooks{OnLoserExit: func(_ Attempt, lag time.Duration) { exits <- lag }}
+	h := NewHedger(5*time.Millisecond, WithHooks(hooks), WithLatencyHistograms())
+
+	if _, err := DoWith(context.Background(), h, thunk); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	select {
+	case lag := <-exits:
+		if lag < 20*time.Millisecond || lag > time.Second {
+			t.Errorf("expected loser exit lag ~20ms, got %s", lag)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected OnLoserExit to be called")
+	}
+
+	s := h.Stats()
+	if s.LoserExits != 1 {
+		t.Errorf("expected 1 loser exit, got %d", s.LoserExits)
+	}
+	if s.LoserExitMax < 20*time.Millisecond || s.LoserExitDuration != s.LoserExitMax {
+		t.Errorf("expected loser exit max = duration ~20ms, got %s and %s", s.LoserExitMax, s.LoserExitDuration)
+	}
+	if n := s.Latency.LoserExits.Count(); n != 1 {
+		t.Errorf("expected 1 loser exit in histogram, got %d", n)
+	}
+}
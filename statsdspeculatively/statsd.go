@@ -0,0 +1,133 @@
+// Package statsdspeculatively emits speculatively metrics over StatsD or
+// DogStatsD.
+package statsdspeculatively
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// Flavor is the StatsD protocol dialect to emit.
+type Flavor int
+
+// Flavors.
+const (
+	// StatsD emits plain StatsD, which does not support tags, so tags are
+	// appended to metric names instead, e.g. "hedges_suppressed.budget".
+	StatsD Flavor = iota
+	// DogStatsD emits DogStatsD, with tags.
+	DogStatsD
+)
+
+// Recorder is a speculatively.MetricsRecorder emitting counters and timers
+// describing hedging activity, each as its own datagram:
+//
+//   - attempts: attempts launched, including hedges
+//   - hedges: hedged attempts launched
+//   - hedge_wins: calls won by a hedged attempt
+//   - hedges_suppressed: hedges not launched when due, tagged by reason
+//   - attempt.duration: duration of attempts, tagged by whether they are hedges
+//   - attempt.terminations: attempts ended, tagged by termination
+//
+// Write errors are ignored, as is customary for StatsD.
+type Recorder struct {
+	w      io.Writer
+	flavor Flavor
+	prefix string
+	tags   []string
+	mu     sync.Mutex
+}
+
+var _ speculatively.MetricsRecorder = (*Recorder)(nil)
+
+// New creates a Recorder writing metrics of the given Flavor to w, with names
+// prefixed by the given prefix, e.g. "myservice.lookup.".  The given tags,
+// e.g. "env:prod", are added to every metric emitted with DogStatsD.
+func New(w io.Writer, flavor Flavor, prefix string, tags ...string) *Recorder {
+	return &Recorder{
+		w:      w,
+		flavor: flavor,
+		prefix: prefix,
+		tags:   tags,
+	}
+}
+
+// Dial creates a Recorder emitting metrics to the StatsD server at the given
+// UDP address.  See New for the other arguments.
+func Dial(addr string, flavor Flavor, prefix string, tags ...string) (*Recorder, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return New(conn, flavor, prefix, tags...), nil
+}
+
+// RecordAttempt implements speculatively.MetricsRecorder.
+func (r *Recorder) RecordAttempt(a speculatively.Attempt) {
+	r.emit("attempts", "1", "c")
+	if a.Index > 0 {
+		r.emit("hedges", "1", "c")
+	}
+}
+
+// RecordAttemptDone implements speculatively.MetricsRecorder.
+func (r *Recorder) RecordAttemptDone(a speculatively.Attempt, elapsed time.Duration, _ error) {
+	ms := strconv.FormatFloat(float64(elapsed)/float64(time.Millisecond), 'f', -1, 64)
+	r.emit("attempt.duration", ms, "ms", "hedge", strconv.FormatBool(a.Index > 0))
+}
+
+// RecordWinner implements speculatively.MetricsRecorder.
+func (r *Recorder) RecordWinner(a speculatively.Attempt) {
+	if a.Index > 0 {
+		r.emit("hedge_wins", "1", "c")
+	}
+}
+
+// RecordSuppressedHedge implements speculatively.MetricsRecorder.
+func (r *Recorder) RecordSuppressedHedge(s speculatively.Suppression) {
+	r.emit("hedges_suppressed", "1", "c", "reason", s.String())
+}
+
+// RecordTermination implements speculatively.MetricsRecorder.
+func (r *Recorder) RecordTermination(_ speculatively.Attempt, t speculatively.Termination) {
+	r.emit("attempt.terminations", "1", "c", "termination", t.String())
+}
+
+// emit writes a single metric, with the given tag keys and values.
+func (r *Recorder) emit(name, value, typ string, tags ...string) {
+	var b strings.Builder
+	b.WriteString(r.prefix)
+	b.WriteString(name)
+	if r.flavor == StatsD {
+		for i := 1; i < len(tags); i += 2 {
+			b.WriteString(".")
+			b.WriteString(tags[i])
+		}
+	}
+	b.WriteString(":")
+	b.WriteString(value)
+	b.WriteString("|")
+	b.WriteString(typ)
+	if r.flavor == DogStatsD && len(r.tags)+len(tags) > 0 {
+		b.WriteString("|#")
+		b.WriteString(strings.Join(r.tags, ","))
+		for i := 0; i+1 < len(tags); i += 2 {
+			if i > 0 || len(r.tags) > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(tags[i])
+			b.WriteString(":")
+			b.WriteString(tags[i+1])
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.w.Write([]byte(b.String())) //nolint:errcheck
+}
@@ -0,0 +1,126 @@
+package statsdspeculatively
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+)
+
+// datagrams records every write as a separate datagram.
+type datagrams struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (d *datagrams) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.msgs = append(d.msgs, string(p))
+	return len(p), nil
+}
+
+func (d *datagrams) get() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	msgs := append([]string(nil), d.msgs...)
+	sort.Strings(msgs)
+	return msgs
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		flavor Flavor
+		tags   []string
+		want   []string
+	}{
+		"statsd": {
+			flavor: StatsD,
+			want: []string{
+				"svc.attempt.terminations.suppressed:1|c",
+				"svc.attempt.terminations.won:1|c",
+				"svc.attempts:1|c",
+				"svc.hedges_suppressed.inflight_limit:1|c",
+			},
+		},
+		"dogstatsd": {
+			flavor: DogStatsD,
+			tags:   []string{"env:test"},
+			want: []string{
+				"svc.attempt.terminations:1|c|#env:test,termination:suppressed",
+				"svc.attempt.terminations:1|c|#env:test,termination:won",
+				"svc.attempts:1|c|#env:test",
+				"svc.hedges_suppressed:1|c|#env:test,reason:inflight_limit",
+			},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			d := &datagrams{}
+			rec := New(d, tc.flavor, "svc.", tc.tags...)
+			// No hedges may be inflight, so the single hedge due is suppressed
+			limit := speculatively.NewInflightLimit(0)
+			_, err := speculatively.Do(context.Background(), 5*time.Millisecond, func(context.Context) (int, error) {
+				time.Sleep(15 * time.Millisecond)
+				return 1, nil
+			}, speculatively.WithInflightLimit(limit), speculatively.WithMaxAttempts(2), speculatively.WithMetrics(rec))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var got []string
+			for _, msg := range d.get() {
+				if strings.Contains(msg, "attempt.duration") {
+					if !strings.Contains(msg, "|ms") {
+						t.Errorf("expected timer, got %q", msg)
+					}
+					continue
+				}
+				// The hedge may be suppressed more than once
+				if len(got) > 0 && got[len(got)-1] == msg {
+					continue
+				}
+				got = append(got, msg)
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("expected datagrams:\n%s\ngot:\n%s", strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestDial(t *testing.T) {
+	t.Parallel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer conn.Close()
+
+	rec, err := Dial(conn.LocalAddr().String(), DogStatsD, "svc.")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rec.RecordAttempt(speculatively.Attempt{Index: 1})
+
+	buf := make([]byte, 512)
+	conn.SetReadDeadline(time.Now().Add(time.Second)) //nolint:errcheck
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := string(buf[:n]); got != "svc.attempts:1|c" {
+		t.Errorf("expected datagram %q, got %q", "svc.attempts:1|c", got)
+	}
+}
@@ -0,0 +1,274 @@
+package speculatively
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Producer produces a stream of items, e.g. the chunks of a long download or
+// the messages of a server-streaming RPC, by passing each of them to emit in
+// order, starting with the item at the given offset, i.e. after the items
+// already delivered by other attempts.  Producers that cannot resume from an
+// offset must read the stream from the beginning and skip that many items
+// before emitting the rest.
+//
+// Emit returns an error, which the Producer should return, if the stream
+// must stop.  A Producer consuming a channel may be written as:
+//
+//	func(ctx context.Context, offset int, emit func(Item) error) error {
+//		for item := range open(ctx, offset) {
+//			if err := emit(item); err != nil {
+//				return err
+//			}
+//		}
+//		return ctx.Err()
+//	}
+type Producer[T any] func(ctx context.Context, offset int, emit func(T) error) error
+
+// DoStream speculatively executes a Producer, passing every item of its
+// stream to yield exactly once and in order, and returns once the stream is
+// complete.
+//
+// Unlike with Do, a replacement Producer is only launched once the stream
+// stalls, i.e. when no item has been delivered for the given patience
+// duration, rather than once patience has elapsed since the call began.
+// Every running Producer then races to deliver the next item, so that the
+// stream splices over to whichever is faster, and the others are canceled
+// once one of them completes the stream.
+//
+// Yield is never called concurrently, and time spent in it does not count as
+// a stall.  If it returns an error, the stream stops and that error is
+// returned.  See Do for details.
+func DoStream[T any](ctx context.Context, patience time.Duration, produce Producer[T], yield func(T) error, opts ...Option) error {
+	var (
+		mu        sync.Mutex
+		delivered int
+	)
+	_, err := Do(ctx, patience, func(ctx context.Context) (struct{}, error) {
+		mu.Lock()
+		offset := delivered
+		mu.Unlock()
+
+		position := offset
+		err := produce(ctx, offset, func(item T) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if position++; position <= delivered {
+				// Another attempt already delivered this item
+				return nil
+			}
+			// Time spent waiting for the consumer does not mean the
+			// attempt is stalled
+			release := hold(ctx)
+			err := yield(item)
+			release()
+			if err != nil {
+				return err
+			}
+			delivered++
+			return nil
+		})
+		return struct{}{}, err
+	}, opts...)
+	return err
+}
+
+// streamBufferSize is the size of the buffer each attempt of DoStreamReader
+// reads its stream into, and streamQueueSize the number of bytes read ahead
+// of the consumer that are queued before attempts wait for it to catch up.
+const (
+	streamBufferSize = 32 << 10
+	streamQueueSize  = 4 * streamBufferSize
+)
+
+// DoStreamReader speculatively reads a stream of bytes, e.g. a chunked HTTP
+// response, in the manner of DoStream, and returns it as a single continuous
+// stream.
+//
+// The given open func opens the stream starting at the given byte offset,
+// e.g. via a Range request, and is called again by a replacement attempt
+// once the stream stalls, i.e. when no data has been received for the given
+// patience duration.  Every open stream then races to deliver the next
+// bytes, so that the returned stream splices over to whichever is faster.
+// Time spent waiting for the returned stream to be read does not count as a
+// stall.
+//
+// Reading the returned stream fails with the error of the call, if any, once
+// the data delivered so far has been read.  Closing it cancels the call.
+func DoStreamReader(ctx context.Context, patience time.Duration, open func(ctx context.Context, offset int64) (io.ReadCloser, error), opts ...Option) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	q := newStreamQueue()
+	go func() {
+		defer cancel()
+		pw.CloseWithError(q.writeTo(pw))
+	}()
+	go func() {
+		_, err := Do(ctx, patience, func(ctx context.Context) (struct{}, error) {
+			offset := q.offset()
+			r, err := open(ctx, offset)
+			if err != nil {
+				return struct{}{}, err
+			}
+			defer r.Close()
+
+			position := offset
+			buf := make([]byte, streamBufferSize)
+			for {
+				n, err := r.Read(buf)
+				if n > 0 {
+					var derr error
+					if position, derr = q.deliver(ctx, position, buf[:n]); derr != nil {
+						return struct{}{}, derr
+					}
+				}
+				if err == io.EOF {
+					return struct{}{}, nil
+				}
+				if err != nil {
+					return struct{}{}, err
+				}
+			}
+		}, opts...)
+		q.close(err)
+	}()
+	return &cancelingReadCloser{ReadCloser: pr, cancel: cancel}
+}
+
+// streamQueue holds the bytes delivered by the attempts of DoStreamReader
+// until they are written to its pipe, so that attempts never wait on the
+// consumer while holding up each other.  The call is held while any bytes
+// are queued, since it is then waiting on the consumer rather than stalled.
+type streamQueue struct {
+	mu sync.Mutex
+
+	// delivered is the number of bytes delivered by every attempt, chunks
+	// holds those not yet written, and size is their total length
+	delivered int64
+	chunks    [][]byte
+	size      int
+
+	// ready is signaled when chunks are queued or the queue is closed, and
+	// drained is closed and replaced whenever a chunk is written
+	ready   chan struct{}
+	drained chan struct{}
+
+	// release releases the hold on the call while bytes are queued
+	release func()
+
+	closed bool
+	err    error
+}
+
+func newStreamQueue() *streamQueue {
+	return &streamQueue{
+		ready:   make(chan struct{}, 1),
+		drained: make(chan struct{}),
+	}
+}
+
+// offset returns the number of bytes delivered so far.
+func (q *streamQueue) offset() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.delivered
+}
+
+// deliver queues the part of chunk not already delivered by another attempt,
+// given the position of chunk in the stream, and returns the position after
+// it.  Once too many bytes are queued, it waits for the consumer to catch up.
+func (q *streamQueue) deliver(ctx context.Context, position int64, chunk []byte) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return position, err
+	}
+	start := position
+	position += int64(len(chunk))
+	if position <= q.delivered {
+		// Another attempt already delivered these bytes
+		return position, nil
+	}
+	data := append([]byte(nil), chunk[q.delivered-start:]...)
+	q.delivered = position
+	q.chunks = append(q.chunks, data)
+	if q.size == 0 {
+		q.release = hold(ctx)
+	}
+	q.size += len(data)
+	Progress(ctx)
+	q.signal()
+
+	for q.size > streamQueueSize {
+		drained := q.drained
+		q.mu.Unlock()
+		select {
+		case <-drained:
+		case <-ctx.Done():
+		}
+		q.mu.Lock()
+		if err := ctx.Err(); err != nil {
+			return position, err
+		}
+	}
+	return position, nil
+}
+
+// close marks the end of the stream, with the given error if any.
+func (q *streamQueue) close(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed, q.err = true, err
+	q.signal()
+}
+
+// signal wakes writeTo.  It must be called with q.mu held.
+func (q *streamQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// writeTo writes the queued chunks to w in order until the queue is closed
+// and empty, and returns the error the queue was closed with, if any.
+func (q *streamQueue) writeTo(w io.Writer) error {
+	for {
+		q.mu.Lock()
+		if len(q.chunks) == 0 {
+			if q.closed {
+				defer q.mu.Unlock()
+				return q.err
+			}
+			q.mu.Unlock()
+			<-q.ready
+			continue
+		}
+		chunk := q.chunks[0]
+		q.chunks[0] = nil
+		q.chunks = q.chunks[1:]
+		q.mu.Unlock()
+
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+
+		q.mu.Lock()
+		q.size -= len(chunk)
+		close(q.drained)
+		q.drained = make(chan struct{})
+		var release func()
+		if q.size == 0 {
+			release, q.release = q.release, nil
+		}
+		q.mu.Unlock()
+		if release != nil {
+			release()
+		}
+	}
+}
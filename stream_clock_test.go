@@ -0,0 +1,130 @@
+package speculatively_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mccutchen/speculatively"
+	"github.com/mccutchen/speculatively/speculativelytest"
+)
+
+func TestDoStreamSlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	// Items arrive right away, but the consumer takes several patience
+	// durations to accept each of them
+	const patience = 10 * time.Millisecond
+	clock := speculativelytest.NewClock(time.Time{})
+	var attempts int64
+	produce := func(ctx context.Context, offset int, emit func(int) error) error {
+		atomic.AddInt64(&attempts, 1)
+		for i := offset; i < 5; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var got []int
+	err := speculatively.DoStream(context.Background(), patience, produce, func(item int) error {
+		clock.Advance(3 * patience)
+		got = append(got, item)
+		return nil
+	}, speculatively.WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := []int{0, 1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected items %v, got %v", want, got)
+	}
+	if n := atomic.LoadInt64(&attempts); n != 1 {
+		t.Errorf("expected slow consumer not to hedge the stream, got %d attempts", n)
+	}
+}
+
+func TestDoStreamReaderSlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	const patience = 10 * time.Millisecond
+	clock := speculativelytest.NewClock(time.Time{})
+	data := bytes.Repeat([]byte("0123456789abcdef"), 32<<10)
+	src := newDeliveryReader(data)
+	var opens int64
+	r := speculatively.DoStreamReader(context.Background(), patience, func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		atomic.AddInt64(&opens, 1)
+		return io.NopCloser(src), nil
+	}, speculatively.WithClock(clock))
+	defer r.Close()
+
+	// The consumer takes several patience durations to read each part of
+	// the stream, which the source delivers right away
+	var got []byte
+	buf := make([]byte, 16<<10)
+	for len(got) < len(data) {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(got) < len(data) {
+			// Only let time pass once more of the stream is waiting to be
+			// read, as it would while a slow consumer reads it
+			src.waitDelivered(len(got) + 1)
+			clock.Advance(3 * patience)
+		}
+	}
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("expected end of stream, got %d bytes and err = %v", n, err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("expected %d bytes of stream, got %d", len(data), len(got))
+	}
+	if n := atomic.LoadInt64(&opens); n != 1 {
+		t.Errorf("expected slow consumer not to hedge the stream, got %d opens", n)
+	}
+}
+
+// deliveryReader reads from a byte slice and tracks how much of it has been
+// delivered, i.e. returned by a Read before the next Read began.
+type deliveryReader struct {
+	mu        sync.Mutex
+	changed   *sync.Cond
+	r         *bytes.Reader
+	read      int
+	delivered int
+}
+
+func newDeliveryReader(data []byte) *deliveryReader {
+	d := &deliveryReader{r: bytes.NewReader(data)}
+	d.changed = sync.NewCond(&d.mu)
+	return d
+}
+
+func (d *deliveryReader) Read(p []byte) (int, error) {
+	d.mu.Lock()
+	d.delivered = d.read
+	d.changed.Broadcast()
+	d.mu.Unlock()
+
+	n, err := d.r.Read(p)
+	d.mu.Lock()
+	d.read += n
+	d.mu.Unlock()
+	return n, err
+}
+
+// waitDelivered waits until at least n bytes have been delivered.
+func (d *deliveryReader) waitDelivered(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for d.delivered < n {
+		d.changed.Wait()
+	}
+}
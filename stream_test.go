@@ -0,0 +1,204 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestProducer returns a Producer of the ints 0 to n-1 that delivers them
+// every interval, except that its first attempt stalls forever after
+// stallAfter items, if positive.  Unless resume is set, every attempt goes
+// through the items before its offset again, without delay, rather than
+// skipping to it.  It also returns the number of attempts.
+func newTestProducer(n, stallAfter int, interval time.Duration, resume bool) (Producer[int], *int64) {
+	var attempts int64
+	return func(ctx context.Context, offset int, emit func(int) error) error {
+		first := atomic.AddInt64(&attempts, 1) == 1
+		start := 0
+		if resume {
+			start = offset
+		}
+		for i := start; i < n; i++ {
+			if first && stallAfter > 0 && i == stallAfter {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			if i < offset {
+				continue
+			}
+			if err := sleep(ctx, interval); err != nil {
+				return err
+			}
+			if err := emit(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, &attempts
+}
+
+func TestDoStream(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		stallAfter   int
+		resume       bool
+		wantAttempts int64
+	}{
+		"steady stream": {
+			stallAfter:   0,
+			wantAttempts: 1,
+		},
+		"stall resumed from offset": {
+			stallAfter:   5,
+			resume:       true,
+			wantAttempts: 2,
+		},
+		"stall restarted from scratch": {
+			stallAfter:   5,
+			wantAttempts: 2,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Items arrive faster than patience, so only a stall launches
+			// another attempt, however long the whole stream takes
+			produce, attempts := newTestProducer(10, tc.stallAfter, 5*time.Millisecond, tc.resume)
+			var got []int
+			err := DoStream(context.Background(), 30*time.Millisecond, produce, func(item int) error {
+				got = append(got, item)
+				return nil
+			}, WithMaxAttempts(3))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !reflect.DeepEqual(got, want) {
+				t.Errorf("expected items %v, got %v", want, got)
+			}
+			if n := atomic.LoadInt64(attempts); n != tc.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tc.wantAttempts, n)
+			}
+		})
+	}
+}
+
+func TestDoStreamYieldError(t *testing.T) {
+	t.Parallel()
+
+	errStop := errors.New("stop")
+	produce, _ := newTestProducer(10, 0, 0, false)
+	var got []int
+	err := DoStream(context.Background(), time.Second, produce, func(item int) error {
+		if item == 3 {
+			return errStop
+		}
+		got = append(got, item)
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("expected err = %v, got %v", errStop, err)
+	}
+	if len(got) != 3 {
+		t.Errorf("expected 3 items before stopping, got %v", got)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	if Progress(context.Background()) {
+		t.Errorf("expected Progress to fail outside of an attempt")
+	}
+}
+
+// chunkedReader returns data in chunks of the given size every interval, and
+// stalls until ctx is done after stallAfter bytes, if positive.
+type chunkedReader struct {
+	ctx        context.Context
+	data       []byte
+	chunk      int
+	interval   time.Duration
+	stallAfter int
+	read       int
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	if r.stallAfter > 0 && r.read >= r.stallAfter {
+		<-r.ctx.Done()
+		return 0, r.ctx.Err()
+	}
+	if err := sleep(r.ctx, r.interval); err != nil {
+		return 0, err
+	}
+	size := r.chunk
+	if size > len(p) {
+		size = len(p)
+	}
+	n := copy(p[:size], r.data)
+	r.data = r.data[n:]
+	r.read += n
+	return n, nil
+}
+
+func (r *chunkedReader) Close() error { return nil }
+
+func TestDoStreamReader(t *testing.T) {
+	t.Parallel()
+
+	const data = "the quick brown fox jumps over the lazy dog"
+	var attempts, offsets int64
+	open := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		r := &chunkedReader{ctx: ctx, data: []byte(data[offset:]), chunk: 5, interval: time.Millisecond}
+		if atomic.AddInt64(&attempts, 1) == 1 {
+			// The first stream stalls mid-transfer
+			r.chunk, r.stallAfter = 3, 12
+		} else {
+			atomic.StoreInt64(&offsets, offset)
+		}
+		return r, nil
+	}
+
+	start := time.Now()
+	r := DoStreamReader(context.Background(), 20*time.Millisecond, open)
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(got) != data {
+		t.Errorf("expected stream %q, got %q", data, got)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected stalled stream to be hedged, took %s", elapsed)
+	}
+	if got := atomic.LoadInt64(&attempts); got != 2 {
+		t.Errorf("expected %d attempts, got %d", 2, got)
+	}
+	if got := atomic.LoadInt64(&offsets); got != 12 {
+		t.Errorf("expected hedge to resume at offset %d, got %d", 12, got)
+	}
+}
+
+func TestDoStreamReaderError(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	r := DoStreamReader(context.Background(), time.Second, func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		return nil, failed
+	})
+	defer r.Close()
+	if _, err := io.ReadAll(r); err != failed {
+		t.Errorf("expected err = %v, got %v", failed, err)
+	}
+}
@@ -0,0 +1,112 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TaskGroup hedges several tasks, e.g. the dependencies of a single request,
+// in the spirit of errgroup.Group from golang.org/x/sync.  Every task shares
+// the group's context, and so its deadline, as well as its patience and
+// Options, e.g. a Budget or InflightLimit.
+//
+// A TaskGroup runs whole hedged calls and gathers their results, whereas
+// GroupRunner runs the individual attempts of calls in an existing Group,
+// e.g. to count them toward its concurrency limit.  A TaskGroup does not
+// implement Group, and the two may be combined by passing WithRunners and
+// GroupRunner to NewTaskGroup.
+//
+//	g := speculatively.NewTaskGroup(ctx, 50*time.Millisecond, speculatively.WithBudget(budget))
+//	user := speculatively.Go(g, fetchUser)
+//	prefs := speculatively.Go(g, fetchPrefs)
+//	if err := g.Wait(); err != nil {
+//		return err
+//	}
+//	u, _ := user.Get()
+//	p, _ := prefs.Get()
+type TaskGroup struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	patience time.Duration
+	opts     []Option
+	sem      chan struct{}
+
+	wg   sync.WaitGroup
+	once sync.Once
+	err  error
+}
+
+// NewTaskGroup returns a TaskGroup whose tasks are hedged with the given
+// patience and Options, and canceled once ctx is done, the first task fails
+// or Wait returns.  At most as many tasks as allowed by WithConcurrency run
+// at once.
+func NewTaskGroup(ctx context.Context, patience time.Duration, opts ...Option) *TaskGroup {
+	ctx, cancel := context.WithCancel(ctx)
+	g := &TaskGroup{ctx: ctx, cancel: cancel, patience: patience, opts: opts}
+	if limit := newConfig(opts).concurrency; limit > 0 {
+		g.sem = make(chan struct{}, limit)
+	}
+	return g
+}
+
+// Wait waits for every task to finish, cancels the group's context and
+// returns the error of the first task to fail, if any.
+func (g *TaskGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+// fail records the error of the first task to fail and cancels the others.
+func (g *TaskGroup) fail(err error) {
+	g.once.Do(func() {
+		g.err = err
+		g.cancel()
+	})
+}
+
+// Future is the eventual result of a task started by Go.
+type Future[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// Get waits for the task to finish and returns its result.
+func (f *Future[T]) Get() (T, error) {
+	<-f.done
+	return f.val, f.err
+}
+
+// Go starts a task in the given TaskGroup, speculatively executing thunk as
+// Do does, with the group's patience and Options followed by the given
+// Options, and returns its eventual result.
+func Go[T any](g *TaskGroup, thunk Thunk[T], opts ...Option) *Future[T] {
+	f := &Future[T]{done: make(chan struct{})}
+	if len(opts) > 0 {
+		opts = append(g.opts[:len(g.opts):len(g.opts)], opts...)
+	} else {
+		opts = g.opts
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer close(f.done)
+		if g.sem != nil {
+			select {
+			case g.sem <- struct{}{}:
+				defer func() { <-g.sem }()
+			case <-g.ctx.Done():
+				f.err = g.ctx.Err()
+				g.fail(f.err)
+				return
+			}
+		}
+		f.val, f.err = Do(g.ctx, g.patience, thunk, opts...)
+		if f.err != nil {
+			g.fail(f.err)
+		}
+	}()
+	return f
+}
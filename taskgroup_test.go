@@ -0,0 +1,71 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTaskGroup(t *testing.T) {
+	t.Parallel()
+
+	// The first attempt of every task is slow, so that each one is hedged
+	slowFirst := func(val string) Thunk[string] {
+		return func(ctx context.Context) (string, error) {
+			if !IsHedge(ctx) {
+				if err := sleep(ctx, time.Second); err != nil {
+					return "", err
+				}
+			}
+			return val, nil
+		}
+	}
+	budget := NewBudget(1, 10)
+	var hedges int64
+	hooks := WithHooks(Hooks{OnLaunch: func(a Attempt) {
+		if a.Index > 0 {
+			atomic.AddInt64(&hedges, 1)
+		}
+	}})
+
+	start := time.Now()
+	g := NewTaskGroup(context.Background(), 10*time.Millisecond, WithBudget(budget), hooks, WithConcurrency(1))
+	user := Go(g, slowFirst("user"))
+	prefs := Go(g, slowFirst("prefs"), WithMaxAttempts(2))
+	if err := g.Wait(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected every task to be hedged, took %s", elapsed)
+	}
+	for f, want := range map[*Future[string]]string{user: "user", prefs: "prefs"} {
+		if got, err := f.Get(); got != want || err != nil {
+			t.Errorf("expected result = %q, <nil>, got %q, %v", want, got, err)
+		}
+	}
+	if got := atomic.LoadInt64(&hedges); got != 2 {
+		t.Errorf("expected %d hedges, got %d", 2, got)
+	}
+}
+
+func TestTaskGroupError(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("failed")
+	g := NewTaskGroup(context.Background(), time.Second)
+	slow := Go(g, newSimpleTestThunk(1, nil, time.Second).call)
+	Go(g, newSimpleTestThunk(0, failed, 0).call)
+
+	start := time.Now()
+	if err := g.Wait(); err != failed {
+		t.Fatalf("expected err = %v, got %v", failed, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected failure to cancel other tasks, took %s", elapsed)
+	}
+	if _, err := slow.Get(); err != context.Canceled {
+		t.Errorf("expected err = %v, got %v", context.Canceled, err)
+	}
+}
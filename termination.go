@@ -0,0 +1,86 @@
+package speculatively
+
+// Termination classifies how an attempt ended, so that outcomes can be
+// aggregated consistently across services.
+type Termination int
+
+// Terminations.
+const (
+	// TerminationWon means the attempt's successful result was returned.
+	TerminationWon Termination = iota
+	// TerminationLost means another attempt won, whether the attempt was
+	// still running or its result was discarded.
+	TerminationLost
+	// TerminationCanceled means the call's context was done, e.g. because
+	// its deadline passed, before the attempt returned.
+	TerminationCanceled
+	// TerminationFatal means the attempt returned an error that was not
+	// retryable or that was returned by the call.
+	TerminationFatal
+	// TerminationRetryable means the attempt returned a retryable error
+	// and another attempt was launched or awaited in its place.
+	TerminationRetryable
+	// TerminationSuppressed means the attempt was due as a hedge but was
+	// never launched, e.g. because the Budget was exhausted.
+	TerminationSuppressed
+)
+
+func (t Termination) String() string {
+	switch t {
+	case TerminationWon:
+		return "won"
+	case TerminationLost:
+		return "lost"
+	case TerminationCanceled:
+		return "canceled"
+	case TerminationFatal:
+		return "fatal"
+	case TerminationRetryable:
+		return "retryable"
+	case TerminationSuppressed:
+		return "suppressed"
+	default:
+		return "unknown"
+	}
+}
+
+// suppressedHedge is a hedge that was due but not launched.
+type suppressedHedge struct {
+	attempt Attempt
+	reason  Suppression
+}
+
+// suppress records that the given task was due to be launched as a hedge but
+// was suppressed for the given reason.
+func (c *call[T]) suppress(t task[T], reason Suppression) {
+	c.cfg.hooks.suppressed(reason)
+	c.suppressed = &suppressedHedge{
+		attempt: Attempt{Index: len(c.attempts), Target: t.target, Call: c.id},
+		reason:  reason,
+	}
+}
+
+// termination classifies how the attempt with the given index ended.  See
+// call.end for the other arguments.
+func (c *call[T]) termination(i, winner, failed int) Termination {
+	switch {
+	case i == winner:
+		return TerminationWon
+	case i == failed:
+		return TerminationFatal
+	}
+	if _, ok := c.delivered[i]; ok {
+		switch err := c.errs[i]; {
+		case err == nil:
+			return TerminationLost
+		case c.cfg.retryable != nil && c.cfg.retryable(err):
+			return TerminationRetryable
+		default:
+			return TerminationFatal
+		}
+	}
+	if c.canceled {
+		return TerminationCanceled
+	}
+	return TerminationLost
+}
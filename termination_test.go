@@ -0,0 +1,149 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errRetryable = errors.New("retryable")
+
+// terminationRecorder records the Termination of every attempt by index.
+type terminationRecorder struct {
+	mu           sync.Mutex
+	terminations map[int]Termination
+}
+
+func (r *terminationRecorder) hooks() Hooks {
+	r.terminations = map[int]Termination{}
+	return Hooks{OnTermination: func(a Attempt, t Termination) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.terminations[a.Index] = t
+	}}
+}
+
+func (r *terminationRecorder) get() map[int]Termination {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.terminations
+}
+
+func TestTermination(t *testing.T) {
+	t.Parallel()
+
+	retryable := WithRetryable(func(err error) bool { return err == errRetryable })
+
+	testCases := map[string]struct {
+		results []result[int]
+		delays  []time.Duration
+		opts    []Option
+		timeout time.Duration
+		want    map[int]Termination
+	}{
+		"first attempt wins": {
+			results: []result[int]{{val: 1}},
+			delays:  []time.Duration{0},
+			want:    map[int]Termination{0: TerminationWon},
+		},
+		"hedge wins": {
+			results: []result[int]{{val: 1}, {val: 2}},
+			delays:  []time.Duration{time.Second, 5 * time.Millisecond},
+			opts:    []Option{WithMaxAttempts(2)},
+			want:    map[int]Termination{0: TerminationLost, 1: TerminationWon},
+		},
+		"retryable error then win": {
+			results: []result[int]{{err: errRetryable}, {val: 2}},
+			delays:  []time.Duration{0, 0},
+			opts:    []Option{retryable},
+			want:    map[int]Termination{0: TerminationRetryable, 1: TerminationWon},
+		},
+		"fatal error": {
+			results: []result[int]{{err: errors.New("fatal")}, {val: 2}},
+			delays:  []time.Duration{15 * time.Millisecond, time.Second},
+			opts:    []Option{WithMaxAttempts(2)},
+			want:    map[int]Termination{0: TerminationFatal, 1: TerminationLost},
+		},
+		"canceled": {
+			results: []result[int]{{val: 1}},
+			delays:  []time.Duration{time.Second},
+			opts:    []Option{WithMaxAttempts(1)},
+			timeout: 5 * time.Millisecond,
+			want:    map[int]Termination{0: TerminationCanceled},
+		},
+		"suppressed by budget": {
+			results: []result[int]{{val: 1}},
+			delays:  []time.Duration{30 * time.Millisecond},
+			opts:    []Option{WithBudget(NewBudget(0, 1)), WithMaxAttempts(3)},
+			want:    map[int]Termination{0: TerminationWon, 1: TerminationSuppressed},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+
+			// Exhaust any budget, so that every hedge is suppressed
+			for _, opt := range tc.opts {
+				if cfg := newConfig([]Option{opt}); cfg.budget != nil {
+					cfg.budget.withdraw()
+				}
+			}
+
+			rec := &terminationRecorder{}
+			e := NewExemplars(0, 1)
+			opts := append([]Option{WithHooks(rec.hooks()), WithExemplars(e)}, tc.opts...)
+			Do(ctx, 10*time.Millisecond, newTestThunk(tc.results, tc.delays).call, opts...) //nolint:errcheck
+
+			got := rec.get()
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected terminations %v, got %v", tc.want, got)
+			}
+			for i, want := range tc.want {
+				if got[i] != want {
+					t.Errorf("expected attempt %d termination = %s, got %s", i, want, got[i])
+				}
+			}
+
+			attempts := e.Recent()[0].Attempts
+			if len(attempts) != len(tc.want) {
+				t.Fatalf("expected %d attempts in report, got %d", len(tc.want), len(attempts))
+			}
+			for _, a := range attempts {
+				if a.Termination != tc.want[a.Index] {
+					t.Errorf("expected report attempt %d termination = %s, got %s", a.Index, tc.want[a.Index], a.Termination)
+				}
+				if a.Termination == TerminationSuppressed && a.Suppression != SuppressedByBudget {
+					t.Errorf("expected suppression by %s, got %s", SuppressedByBudget, a.Suppression)
+				}
+			}
+		})
+	}
+}
+
+func TestTerminationString(t *testing.T) {
+	t.Parallel()
+
+	for term, want := range map[Termination]string{
+		TerminationWon:        "won",
+		TerminationLost:       "lost",
+		TerminationCanceled:   "canceled",
+		TerminationFatal:      "fatal",
+		TerminationRetryable:  "retryable",
+		TerminationSuppressed: "suppressed",
+		Termination(-1):       "unknown",
+	} {
+		if got := term.String(); got != want {
+			t.Errorf("expected %d.String() = %q, got %q", term, want, got)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package speculatively
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is the error returned by a Thunk wrapped by TimeoutMiddleware
+// that overran its timeout.  It matches context.DeadlineExceeded via
+// errors.Is.
+type TimeoutError struct {
+	// Timeout is the timeout that was exceeded.
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("speculatively: thunk timed out after %s", e.Timeout)
+}
+
+// Is reports whether target is context.DeadlineExceeded.
+func (e *TimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// TimeoutMiddleware bounds each execution of the wrapped Thunk to the given
+// timeout, independently of the deadline of the call or attempt executing
+// it, and fails executions that overrun it with a *TimeoutError.
+//
+// The wrapped Thunk's context is canceled once the timeout elapses, but the
+// execution returns right away even if the Thunk ignores the cancelation, so
+// that e.g. each stage of a fallback chain is guaranteed to yield control
+// promptly.  The result of such a Thunk is discarded once it returns.
+func TimeoutMiddleware[T any](timeout time.Duration) Middleware[T] {
+	return func(thunk Thunk[T]) Thunk[T] {
+		return func(ctx context.Context) (T, error) {
+			timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			type result struct {
+				val T
+				err error
+			}
+			done := make(chan result, 1)
+			go func() {
+				val, err := thunk(timeoutCtx)
+				done <- result{val, err}
+			}()
+
+			select {
+			case r := <-done:
+				if r.err != nil && ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded {
+					return r.val, &TimeoutError{Timeout: timeout}
+				}
+				return r.val, r.err
+			case <-timeoutCtx.Done():
+				var zero T
+				if err := ctx.Err(); err != nil {
+					return zero, err
+				}
+				return zero, &TimeoutError{Timeout: timeout}
+			}
+		}
+	}
+}
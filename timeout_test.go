@@ -0,0 +1,73 @@
+package speculatively
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		thunk       Thunk[int]
+		ctxTimeout  time.Duration
+		wantTimeout bool
+		wantErr     error
+	}{
+		"completes in time": {
+			thunk: newSimpleTestThunk(1, nil, 0).call,
+		},
+		"respects cancelation": {
+			thunk:       newSimpleTestThunk(1, nil, time.Second).call,
+			wantTimeout: true,
+		},
+		"ignores cancelation": {
+			thunk: func(context.Context) (int, error) {
+				time.Sleep(time.Second)
+				return 1, nil
+			},
+			wantTimeout: true,
+		},
+		"outer deadline first": {
+			thunk:      newSimpleTestThunk(1, nil, time.Second).call,
+			ctxTimeout: 5 * time.Millisecond,
+			wantErr:    context.DeadlineExceeded,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tc.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.ctxTimeout)
+				defer cancel()
+			}
+			thunk := Wrap(tc.thunk, TimeoutMiddleware[int](20*time.Millisecond))
+
+			start := time.Now()
+			val, err := thunk(ctx)
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("expected thunk to yield promptly, took %s", elapsed)
+			}
+			var timeoutErr *TimeoutError
+			if got := errors.As(err, &timeoutErr); got != tc.wantTimeout {
+				t.Fatalf("expected timeout error = %v, got %v", tc.wantTimeout, err)
+			}
+			switch {
+			case tc.wantTimeout:
+				if timeoutErr.Timeout != 20*time.Millisecond || !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("expected 20ms timeout matching context.DeadlineExceeded, got %v", err)
+				}
+			case err != tc.wantErr:
+				t.Errorf("expected err = %v, got %v", tc.wantErr, err)
+			case err == nil && val != 1:
+				t.Errorf("expected val = %d, got %d", 1, val)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package speculatively
+
+import (
+	"context"
+	"fmt"
+	"runtime/trace"
+)
+
+// Region types and log category used by WithRuntimeTrace.
+const (
+	traceCategory     = "speculatively"
+	traceRegionFirst  = "speculatively.attempt"
+	traceRegionHedged = "speculatively.hedge"
+)
+
+// WithRuntimeTrace wraps every call in a runtime/trace Task with the given
+// name and every attempt in a Region, and logs when attempts are launched and
+// which one won, so that `go tool trace` shows the structure of hedged calls,
+// including how long losing attempts kept running.
+func WithRuntimeTrace(name string) Option {
+	return func(c *config) {
+		c.traceName = name
+	}
+}
+
+// traceLogf logs a message to the execution trace if WithRuntimeTrace is set.
+func (c *config) traceLogf(ctx context.Context, format string, args ...interface{}) {
+	if c.traceName != "" && trace.IsEnabled() {
+		trace.Log(ctx, traceCategory, fmt.Sprintf(format, args...))
+	}
+}
+
+// traceRegion calls fn within a trace Region for the given attempt if
+// WithRuntimeTrace is set.
+func (c *config) traceRegion(ctx context.Context, a Attempt, fn func()) {
+	if c.traceName == "" {
+		fn()
+		return
+	}
+	regionType := traceRegionFirst
+	if a.Index > 0 {
+		regionType = traceRegionHedged
+	}
+	trace.WithRegion(ctx, regionType, fn)
+}
@@ -0,0 +1,34 @@
+package speculatively
+
+import (
+	"bytes"
+	"context"
+	"runtime/trace"
+	"testing"
+	"time"
+)
+
+func TestWithRuntimeTrace(t *testing.T) {
+	// Not parallel, because only one execution trace may be active at a time
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skipf("cannot start execution trace: %s", err)
+	}
+
+	thunk := newTestThunk([]result[int]{{val: 1}, {val: 2}}, []time.Duration{time.Second, 5 * time.Millisecond})
+	val, err := Do(context.Background(), 10*time.Millisecond, thunk.call, WithRuntimeTrace("speculatively_test.call"))
+	trace.Stop()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if val != 2 {
+		t.Errorf("expected val = %d, got %d", 2, val)
+	}
+
+	for _, s := range []string{"speculatively_test.call", traceRegionFirst, traceRegionHedged, "attempt 1 won"} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("expected execution trace to contain %q", s)
+		}
+	}
+}
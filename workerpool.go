@@ -0,0 +1,100 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+)
+
+// WorkerPool is a Runner that executes attempts on a fixed number of
+// goroutines, so that the number of attempts executing at once is capped
+// across every call that shares it, however many calls are made.  Attempts
+// launched while every worker is busy are queued, in order, until a worker
+// is free.
+//
+// Since queued attempts wait for a worker, a pool that is too small for its
+// load delays first attempts along with hedges.  Calls whose patience
+// elapses while their first attempt is queued launch hedges that are queued
+// in turn, so the pool should be sized for the expected concurrency of
+// calls, not of hedges alone.
+//
+// A WorkerPool is safe for concurrent use.
+type WorkerPool struct {
+	mu     sync.Mutex
+	ready  *sync.Cond
+	queue  []job
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// job is an attempt waiting for a worker.
+type job struct {
+	ctx context.Context
+	run func(context.Context)
+}
+
+// NewWorkerPool creates a WorkerPool with the given number of workers.
+// Values less than 1 mean a single worker.
+func NewWorkerPool(workers int) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &WorkerPool{}
+	p.ready = sync.NewCond(&p.mu)
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Run queues the given attempt to be executed by the next free worker.
+// Attempts given to a closed WorkerPool run in a goroutine of their own.
+func (p *WorkerPool) Run(ctx context.Context, _ Attempt, run func(context.Context)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		go run(ctx)
+		return
+	}
+	p.queue = append(p.queue, job{ctx: ctx, run: run})
+	p.ready.Signal()
+}
+
+// Queued returns the number of attempts waiting for a worker.
+func (p *WorkerPool) Queued() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.queue)
+}
+
+// Close stops the workers once every queued attempt has been executed, and
+// waits for them to exit.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.ready.Broadcast()
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && !p.closed {
+			p.ready.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		j := p.queue[0]
+		p.queue[0] = job{}
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		// Attempts of calls that ended while queued return right away,
+		// as long as their Thunks respect cancelation
+		j.run(j.ctx)
+	}
+}
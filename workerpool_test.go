@@ -0,0 +1,74 @@
+package speculatively
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	t.Parallel()
+
+	pool := NewWorkerPool(2)
+	defer pool.Close()
+
+	// Every attempt blocks until released, so only two of them can execute
+	// at once and the rest must queue
+	var (
+		running, peak int64
+		wg            sync.WaitGroup
+	)
+	release := make(chan struct{})
+	thunk := func(ctx context.Context) (int, error) {
+		n := atomic.AddInt64(&running, 1)
+		defer atomic.AddInt64(&running, -1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		return 1, nil
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Do(context.Background(), time.Hour, thunk, WithRunners(pool)) //nolint:errcheck
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt64(&running); n != 2 {
+		t.Errorf("expected 2 running attempts, got %d", n)
+	}
+	if n := pool.Queued(); n != 2 {
+		t.Errorf("expected 2 queued attempts, got %d", n)
+	}
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt64(&peak); n != 2 {
+		t.Errorf("expected at most 2 attempts running at once, got %d", n)
+	}
+}
+
+func TestWorkerPoolClose(t *testing.T) {
+	t.Parallel()
+
+	pool := NewWorkerPool(1)
+	pool.Close()
+
+	// Attempts still run once the pool is closed
+	got, err := Do(context.Background(), time.Hour, func(context.Context) (int, error) {
+		return 1, nil
+	}, WithRunners(pool))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != 1 {
+		t.Errorf("expected result 1, got %d", got)
+	}
+}